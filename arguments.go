@@ -0,0 +1,262 @@
+package zen
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DecodeOptions tunes DecodeArgumentsWith.
+type DecodeOptions struct {
+	// DisallowUnknownFields rejects object keys the target type has no
+	// field for.
+	DisallowUnknownFields bool
+	// Coerce accepts the mistakes models commonly make: numbers sent as
+	// numeric strings ("42") and booleans sent as "true" or "false".
+	Coerce bool
+}
+
+// DecodeArguments decodes the arguments of call into a T. Unlike
+// json.Unmarshal it reports every mismatch at once as a *ToolArgumentsError
+// naming the JSON path and the expected and actual types, e.g.
+// "/days: expected integer, got string", which can be sent back to the model
+// as an error tool result. Empty arguments are treated as {}.
+func DecodeArguments[T any](call StreamToolCall) (T, error) {
+	return DecodeArgumentsWith[T](call, DecodeOptions{})
+}
+
+// DecodeArgumentsWith is DecodeArguments with options.
+func DecodeArgumentsWith[T any](call StreamToolCall, opts DecodeOptions) (T, error) {
+	var out T
+	args := bytes.TrimSpace(call.Arguments)
+	if len(args) == 0 {
+		args = []byte("{}")
+	}
+	value, err := decodeJSONNumbers(args)
+	if err != nil {
+		return out, &ToolArgumentsError{Tool: call.Name, Violations: []SchemaViolation{{Message: "arguments are not valid JSON: " + err.Error()}}}
+	}
+
+	d := &argumentDecoder{opts: opts}
+	value = d.check(value, reflect.TypeOf(&out).Elem(), "")
+	if len(d.violations) > 0 {
+		return out, &ToolArgumentsError{Tool: call.Name, Violations: d.violations}
+	}
+	if d.coerced {
+		if args, err = json.Marshal(value); err != nil {
+			return out, err
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(args))
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(&out); err != nil {
+		return out, &ToolArgumentsError{Tool: call.Name, Violations: []SchemaViolation{decodeViolation(err)}}
+	}
+	return out, nil
+}
+
+// decodeViolation describes an error of encoding/json that the type check
+// did not anticipate, such as a number out of range.
+func decodeViolation(err error) SchemaViolation {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		path := ""
+		if typeErr.Field != "" {
+			path = "/" + strings.ReplaceAll(typeErr.Field, ".", "/")
+		}
+		return SchemaViolation{Path: path, Message: fmt.Sprintf("cannot use %s as %s", typeErr.Value, typeErr.Type)}
+	}
+	return SchemaViolation{Message: strings.TrimPrefix(err.Error(), "json: ")}
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// argumentDecoder checks a decoded JSON value against the Go type it will be
+// decoded into, coercing strings where DecodeOptions.Coerce allows.
+type argumentDecoder struct {
+	opts       DecodeOptions
+	violations []SchemaViolation
+	coerced    bool
+}
+
+func (d *argumentDecoder) fail(path, format string, args ...any) {
+	d.violations = append(d.violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// check returns value, or its coerced replacement.
+func (d *argumentDecoder) check(value any, t reflect.Type, path string) any {
+	if value == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return value
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		// Decoded from a JSON string by UnmarshalText, e.g. netip.Addr.
+		if _, ok := value.(string); !ok {
+			d.mismatch("string", value, path)
+		}
+		return value
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return value
+	case reflect.Bool:
+		if s, ok := value.(string); ok && d.opts.Coerce && (s == "true" || s == "false") {
+			d.coerced = true
+			return s == "true"
+		}
+		if _, ok := value.(bool); !ok {
+			d.mismatch("boolean", value, path)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value = d.coerceNumber(value)
+		if n, ok := value.(json.Number); !ok {
+			d.mismatch("integer", value, path)
+		} else if _, err := strconv.ParseInt(n.String(), 10, 64); err != nil {
+			if _, err := strconv.ParseUint(n.String(), 10, 64); err != nil {
+				d.fail(path, "expected integer, got number %s", n)
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		value = d.coerceNumber(value)
+		if _, ok := value.(json.Number); !ok {
+			d.mismatch("number", value, path)
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			d.mismatch("string", value, path)
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// []byte is sent as a base64 string.
+			if _, ok := value.(string); !ok {
+				d.mismatch("string", value, path)
+			}
+			return value
+		}
+		items, ok := value.([]any)
+		if !ok {
+			d.mismatch("array", value, path)
+			return value
+		}
+		for i, item := range items {
+			items[i] = d.check(item, t.Elem(), fmt.Sprintf("%s/%d", path, i))
+		}
+	case reflect.Map:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			d.mismatch("object", value, path)
+			return value
+		}
+		if t.Key().Kind() != reflect.String && !reflect.PointerTo(t.Key()).Implements(textUnmarshalerType) {
+			return value
+		}
+		for _, key := range sortedSchemaKeys(obj) {
+			obj[key] = d.check(obj[key], t.Elem(), path+"/"+escapeJSONPointer(key))
+		}
+	case reflect.Struct:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			d.mismatch("object", value, path)
+			return value
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedSchemaKeys(obj) {
+			keyPath := path + "/" + escapeJSONPointer(key)
+			field, ok := matchJSONField(fields, key)
+			if !ok {
+				if d.opts.DisallowUnknownFields {
+					d.fail(keyPath, "unknown field")
+				}
+				continue
+			}
+			obj[key] = d.check(obj[key], field.typ, keyPath)
+		}
+	}
+	return value
+}
+
+func (d *argumentDecoder) mismatch(expected string, value any, path string) {
+	d.fail(path, "expected %s, got %s", expected, jsonTypeName(value))
+}
+
+// coerceNumber turns a numeric string into a number under Coerce.
+func (d *argumentDecoder) coerceNumber(value any) any {
+	s, ok := value.(string)
+	if !ok || !d.opts.Coerce {
+		return value
+	}
+	if _, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err != nil {
+		return value
+	}
+	d.coerced = true
+	return json.Number(strings.TrimSpace(s))
+}
+
+type jsonField struct {
+	name string
+	typ  reflect.Type
+}
+
+// jsonFields lists the fields encoding/json decodes into a struct of type t,
+// including those promoted from embedded structs.
+func jsonFields(t reflect.Type) []jsonField {
+	var out []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && tag == "" && ft.Kind() == reflect.Struct {
+			out = append(out, jsonFields(ft)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = f.Name
+		}
+		out = append(out, jsonField{name: name, typ: f.Type})
+	}
+	return out
+}
+
+// matchJSONField finds the field key decodes into, preferring an exact match
+// over a case-insensitive one as encoding/json does.
+func matchJSONField(fields []jsonField, key string) (jsonField, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
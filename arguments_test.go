@@ -0,0 +1,125 @@
+package zen
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+type forecastArgs struct {
+	City   string   `json:"city"`
+	Days   int      `json:"days"`
+	Metric *bool    `json:"metric,omitempty"`
+	Tags   []string `json:"tags"`
+	Point  struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	} `json:"point"`
+}
+
+func decodeForecast(t *testing.T, args string, opts DecodeOptions) (forecastArgs, *ToolArgumentsError) {
+	t.Helper()
+	out, err := DecodeArgumentsWith[forecastArgs](StreamToolCall{Name: "forecast", Arguments: json.RawMessage(args)}, opts)
+	if err == nil {
+		return out, nil
+	}
+	var argErr *ToolArgumentsError
+	if !errors.As(err, &argErr) {
+		t.Fatalf("expected *ToolArgumentsError, got %T: %v", err, err)
+	}
+	return out, argErr
+}
+
+func TestDecodeArguments(t *testing.T) {
+	out, err := DecodeArguments[forecastArgs](StreamToolCall{Name: "forecast", Arguments: json.RawMessage(`{"city":"Oslo","days":3,"metric":true,"tags":["a"],"point":{"lat":59.9,"lon":10.7}}`)})
+	if err != nil {
+		t.Fatalf("DecodeArguments: %v", err)
+	}
+	if out.City != "Oslo" || out.Days != 3 || out.Metric == nil || !*out.Metric || out.Point.Lon != 10.7 {
+		t.Fatalf("decoded mismatch: %+v", out)
+	}
+	if _, err := DecodeArguments[forecastArgs](StreamToolCall{}); err != nil {
+		t.Fatalf("empty arguments should decode as {}: %v", err)
+	}
+
+	_, argErr := decodeForecast(t, `{"city":7,"days":"3","metric":"true","tags":["a",1],"point":{"lat":"north"},"extra":1}`, DecodeOptions{})
+	if argErr == nil {
+		t.Fatal("expected an error")
+	}
+	want := []string{
+		"/city: expected string, got integer",
+		"/days: expected integer, got string",
+		"/metric: expected boolean, got string",
+		"/point/lat: expected number, got string",
+		"/tags/1: expected string, got integer",
+	}
+	if got := violationStrings(argErr); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("violations:\nwant %q\ngot  %q", want, got)
+	}
+	if !strings.Contains(argErr.Error(), `tool "forecast"`) {
+		t.Fatalf("error should name the tool: %v", argErr)
+	}
+
+	_, argErr = decodeForecast(t, `{"days":2.5}`, DecodeOptions{})
+	if argErr == nil || violationStrings(argErr)[0] != "/days: expected integer, got number 2.5" {
+		t.Fatalf("fractional integer: %v", argErr)
+	}
+	_, argErr = decodeForecast(t, `{"city":`, DecodeOptions{})
+	if argErr == nil || !strings.Contains(argErr.Error(), "not valid JSON") {
+		t.Fatalf("invalid JSON: %v", argErr)
+	}
+}
+
+func TestDecodeArgumentsOptions(t *testing.T) {
+	out, argErr := decodeForecast(t, `{"city":"Oslo","days":" 4 ","metric":"false","point":{"lat":"1.5"}}`, DecodeOptions{Coerce: true})
+	if argErr != nil {
+		t.Fatalf("coercion failed: %v", argErr)
+	}
+	if out.Days != 4 || out.Metric == nil || *out.Metric || out.Point.Lat != 1.5 {
+		t.Fatalf("coerced mismatch: %+v", out)
+	}
+	if _, argErr = decodeForecast(t, `{"days":"four","metric":"yes"}`, DecodeOptions{Coerce: true}); argErr == nil || len(argErr.Violations) != 2 {
+		t.Fatalf("non-numeric strings must still fail: %v", argErr)
+	}
+
+	if _, argErr = decodeForecast(t, `{"city":"Oslo","CITY":"x","region":"east"}`, DecodeOptions{}); argErr != nil {
+		t.Fatalf("unknown fields are ignored by default: %v", argErr)
+	}
+	_, argErr = decodeForecast(t, `{"city":"Oslo","region":"east"}`, DecodeOptions{DisallowUnknownFields: true})
+	if argErr == nil || violationStrings(argErr)[0] != "/region: unknown field" {
+		t.Fatalf("unknown field: %v", argErr)
+	}
+}
+
+func violationStrings(err *ToolArgumentsError) []string {
+	out := make([]string, len(err.Violations))
+	for i, v := range err.Violations {
+		out[i] = v.String()
+	}
+	return out
+}
+
+func TestDecodeArgumentsTextUnmarshaler(t *testing.T) {
+	type hostArgs struct {
+		Addr netip.Addr `json:"addr"`
+		At   time.Time  `json:"at"`
+	}
+	call := StreamToolCall{Name: "ping", Arguments: json.RawMessage(`{"addr":"10.0.0.1","at":"2024-05-01T12:00:00Z"}`)}
+	out, err := DecodeArguments[hostArgs](call)
+	if err != nil {
+		t.Fatalf("DecodeArguments: %v", err)
+	}
+	if out.Addr != netip.MustParseAddr("10.0.0.1") || out.At.Year() != 2024 {
+		t.Fatalf("decoded mismatch: %+v", out)
+	}
+
+	call.Arguments = json.RawMessage(`{"addr":5}`)
+	_, err = DecodeArguments[hostArgs](call)
+	var argErr *ToolArgumentsError
+	if !errors.As(err, &argErr) || strings.Join(violationStrings(argErr), "\n") != "/addr: expected string, got integer" {
+		t.Fatalf("expected a string violation, got %v", err)
+	}
+}
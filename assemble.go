@@ -0,0 +1,511 @@
+package zen
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AssembleResponse rebuilds the non-streaming response body of endpoint from
+// the events of a complete stream, as returned by Client.StreamEvents: a
+// chat.completion object, an Anthropic message, a Responses response object
+// or a Gemini generateContent response. Ids, models and usage are taken from
+// the events; content keeps the order the provider streamed it in. Fields the
+// assembler does not know are copied from the events where the stream carries
+// them in the same shape as the non-streaming body.
+func AssembleResponse(endpoint EndpointType, events []UnifiedEvent) ([]byte, error) {
+	switch endpoint {
+	case EndpointChatCompletions:
+		return assembleChatCompletion(events)
+	case EndpointMessages:
+		return assembleMessages(events)
+	case EndpointResponses:
+		return assembleResponses(events)
+	case EndpointModels:
+		return assembleGemini(events)
+	default:
+		return nil, errors.New("zen: unsupported endpoint")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// chat_completions
+// ---------------------------------------------------------------------------
+
+type chatAssemblyChunk struct {
+	ID                string          `json:"id"`
+	Created           int64           `json:"created"`
+	Model             string          `json:"model"`
+	SystemFingerprint string          `json:"system_fingerprint"`
+	ServiceTier       string          `json:"service_tier"`
+	Usage             json.RawMessage `json:"usage"`
+	Choices           []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Role             string       `json:"role"`
+			Content          *chatContent `json:"content"`
+			ReasoningContent string       `json:"reasoning_content"`
+			Reasoning        string       `json:"reasoning"`
+			Refusal          *string      `json:"refusal"`
+			Audio            *struct {
+				ID         string `json:"id"`
+				Data       string `json:"data"`
+				Transcript string `json:"transcript"`
+				ExpiresAt  int64  `json:"expires_at"`
+			} `json:"audio"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Type     string `json:"type"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+type chatAssemblyChoice struct {
+	role                string
+	content, reasoning  strings.Builder
+	refusal             strings.Builder
+	hasContent, refused bool
+	toolCalls           map[int]*chatAssemblyToolCall
+	finishReason        string
+	audio               map[string]any
+	audioData           []byte
+	audioTranscript     strings.Builder
+}
+
+type chatAssemblyToolCall struct {
+	id, typ, name string
+	arguments     strings.Builder
+}
+
+func assembleChatCompletion(events []UnifiedEvent) ([]byte, error) {
+	body := map[string]any{"object": "chat.completion"}
+	choices := map[int]*chatAssemblyChoice{}
+	var usage json.RawMessage
+	for _, ev := range events {
+		var chunk chatAssemblyChunk
+		if err := jsonUnmarshal(ev.Data, &chunk); err != nil {
+			continue
+		}
+		setIfNotEmpty(body, "id", chunk.ID)
+		setIfNotEmpty(body, "model", chunk.Model)
+		setIfNotEmpty(body, "system_fingerprint", chunk.SystemFingerprint)
+		setIfNotEmpty(body, "service_tier", chunk.ServiceTier)
+		if chunk.Created != 0 {
+			body["created"] = chunk.Created
+		}
+		if len(chunk.Usage) > 0 && string(chunk.Usage) != "null" {
+			usage = chunk.Usage
+		}
+		for _, c := range chunk.Choices {
+			choice := choices[c.Index]
+			if choice == nil {
+				choice = &chatAssemblyChoice{toolCalls: map[int]*chatAssemblyToolCall{}}
+				choices[c.Index] = choice
+			}
+			if c.Delta.Role != "" {
+				choice.role = c.Delta.Role
+			}
+			if c.Delta.Content != nil {
+				text := c.Delta.Content.text()
+				choice.hasContent = choice.hasContent || text != ""
+				choice.content.WriteString(text)
+			}
+			if c.Delta.Refusal != nil {
+				choice.refused = true
+				choice.refusal.WriteString(*c.Delta.Refusal)
+			}
+			if a := c.Delta.Audio; a != nil {
+				if choice.audio == nil {
+					choice.audio = map[string]any{}
+				}
+				setIfNotEmpty(choice.audio, "id", a.ID)
+				if a.ExpiresAt != 0 {
+					choice.audio["expires_at"] = a.ExpiresAt
+				}
+				// Each chunk is base64 on its own; decode before joining.
+				data, err := base64.StdEncoding.DecodeString(a.Data)
+				if err != nil {
+					return nil, fmt.Errorf("zen: invalid audio chunk: %w", err)
+				}
+				choice.audioData = append(choice.audioData, data...)
+				choice.audioTranscript.WriteString(a.Transcript)
+			}
+			choice.reasoning.WriteString(c.Delta.ReasoningContent)
+			choice.reasoning.WriteString(c.Delta.Reasoning)
+			for _, tc := range c.Delta.ToolCalls {
+				call := choice.toolCalls[tc.Index]
+				if call == nil {
+					call = &chatAssemblyToolCall{typ: "function"}
+					choice.toolCalls[tc.Index] = call
+				}
+				if tc.ID != "" {
+					call.id = tc.ID
+				}
+				if tc.Type != "" {
+					call.typ = tc.Type
+				}
+				if tc.Function.Name != "" {
+					call.name = tc.Function.Name
+				}
+				call.arguments.WriteString(tc.Function.Arguments)
+			}
+			if c.FinishReason != "" {
+				choice.finishReason = c.FinishReason
+			}
+		}
+	}
+
+	out := make([]any, 0, len(choices))
+	for _, index := range sortedKeys(choices) {
+		choice := choices[index]
+		role := choice.role
+		if role == "" {
+			role = "assistant"
+		}
+		message := map[string]any{"role": role, "content": nil}
+		if choice.hasContent {
+			message["content"] = choice.content.String()
+		}
+		if choice.refused {
+			message["refusal"] = choice.refusal.String()
+		}
+		if choice.reasoning.Len() > 0 {
+			message["reasoning_content"] = choice.reasoning.String()
+		}
+		if choice.audio != nil {
+			choice.audio["data"] = base64.StdEncoding.EncodeToString(choice.audioData)
+			choice.audio["transcript"] = choice.audioTranscript.String()
+			message["audio"] = choice.audio
+		}
+		if len(choice.toolCalls) > 0 {
+			calls := make([]any, 0, len(choice.toolCalls))
+			for _, i := range sortedKeys(choice.toolCalls) {
+				call := choice.toolCalls[i]
+				calls = append(calls, map[string]any{
+					"id":       call.id,
+					"type":     call.typ,
+					"function": map[string]any{"name": call.name, "arguments": call.arguments.String()},
+				})
+			}
+			message["tool_calls"] = calls
+		}
+		var finish any
+		if choice.finishReason != "" {
+			finish = choice.finishReason
+		}
+		out = append(out, map[string]any{
+			"index":         index,
+			"message":       message,
+			"logprobs":      nil,
+			"finish_reason": finish,
+		})
+	}
+	body["choices"] = out
+	if usage != nil {
+		body["usage"] = usage
+	}
+	return jsonMarshal(body)
+}
+
+// ---------------------------------------------------------------------------
+// messages
+// ---------------------------------------------------------------------------
+
+type messagesAssemblyEvent struct {
+	Type         string                     `json:"type"`
+	Index        int                        `json:"index"`
+	Message      map[string]json.RawMessage `json:"message"`
+	ContentBlock map[string]json.RawMessage `json:"content_block"`
+	Delta        struct {
+		Type         string  `json:"type"`
+		Text         string  `json:"text"`
+		Thinking     string  `json:"thinking"`
+		Signature    string  `json:"signature"`
+		PartialJSON  string  `json:"partial_json"`
+		StopReason   *string `json:"stop_reason"`
+		StopSequence *string `json:"stop_sequence"`
+	} `json:"delta"`
+	Usage map[string]json.RawMessage `json:"usage"`
+	Error json.RawMessage            `json:"error"`
+}
+
+type messagesAssemblyBlock struct {
+	fields                     map[string]any
+	text, thinking, signature  strings.Builder
+	input                      strings.Builder
+	hasText, hasThinking, sign bool
+}
+
+func assembleMessages(events []UnifiedEvent) ([]byte, error) {
+	body := map[string]any{"type": "message", "role": "assistant", "stop_reason": nil, "stop_sequence": nil}
+	usage := map[string]any{}
+	blocks := map[int]*messagesAssemblyBlock{}
+	for _, ev := range events {
+		var e messagesAssemblyEvent
+		if err := jsonUnmarshal(ev.Data, &e); err != nil {
+			continue
+		}
+		evType := e.Type
+		if evType == "" {
+			evType = ev.Event
+		}
+		switch evType {
+		case MessagesEventMessageStart:
+			for k, v := range e.Message {
+				switch k {
+				case "content":
+				case "usage":
+					mergeRawFields(usage, v)
+				default:
+					body[k] = v
+				}
+			}
+		case MessagesEventContentBlockStart:
+			block := &messagesAssemblyBlock{fields: map[string]any{}}
+			for k, v := range e.ContentBlock {
+				block.fields[k] = v
+			}
+			blocks[e.Index] = block
+		case MessagesEventContentBlockDelta:
+			block := blocks[e.Index]
+			if block == nil {
+				block = &messagesAssemblyBlock{fields: map[string]any{}}
+				blocks[e.Index] = block
+			}
+			switch e.Delta.Type {
+			case "text_delta":
+				block.hasText = true
+				block.text.WriteString(e.Delta.Text)
+			case "thinking_delta":
+				block.hasThinking = true
+				block.thinking.WriteString(e.Delta.Thinking)
+			case "signature_delta":
+				block.sign = true
+				block.signature.WriteString(e.Delta.Signature)
+			case "input_json_delta":
+				block.input.WriteString(e.Delta.PartialJSON)
+			}
+		case MessagesEventMessageDelta:
+			if e.Delta.StopReason != nil {
+				body["stop_reason"] = *e.Delta.StopReason
+			}
+			if e.Delta.StopSequence != nil {
+				body["stop_sequence"] = *e.Delta.StopSequence
+			}
+			for k, v := range e.Usage {
+				usage[k] = v
+			}
+		case MessagesEventError:
+			return nil, fmt.Errorf("zen: stream error event: %s", e.Error)
+		}
+	}
+
+	content := make([]any, 0, len(blocks))
+	for _, index := range sortedKeys(blocks) {
+		block := blocks[index]
+		if block.hasText {
+			block.fields["text"] = rawStringField(block.fields["text"]) + block.text.String()
+		}
+		if block.hasThinking {
+			block.fields["thinking"] = rawStringField(block.fields["thinking"]) + block.thinking.String()
+		}
+		if block.sign {
+			block.fields["signature"] = block.signature.String()
+		}
+		if block.input.Len() > 0 {
+			input := json.RawMessage(block.input.String())
+			if !json.Valid(input) {
+				return nil, fmt.Errorf("zen: content block %d has invalid input JSON", index)
+			}
+			block.fields["input"] = input
+		}
+		content = append(content, block.fields)
+	}
+	body["content"] = content
+	body["usage"] = usage
+	return jsonMarshal(body)
+}
+
+// ---------------------------------------------------------------------------
+// responses
+// ---------------------------------------------------------------------------
+
+type responsesAssemblyEvent struct {
+	Type        string          `json:"type"`
+	OutputIndex int             `json:"output_index"`
+	Item        json.RawMessage `json:"item"`
+	Response    json.RawMessage `json:"response"`
+}
+
+func assembleResponses(events []UnifiedEvent) ([]byte, error) {
+	var response json.RawMessage
+	items := map[int]json.RawMessage{}
+	for _, ev := range events {
+		var e responsesAssemblyEvent
+		if err := jsonUnmarshal(ev.Data, &e); err != nil {
+			continue
+		}
+		switch canonicalResponsesEvent(e.Type) {
+		case ResponsesEventCreated, ResponsesEventInProgress, ResponsesEventCompleted,
+			ResponsesEventIncomplete, ResponsesEventFailed:
+			if len(e.Response) > 0 {
+				response = e.Response
+			}
+		case ResponsesEventOutputItemDone:
+			items[e.OutputIndex] = e.Item
+		}
+	}
+	if response == nil {
+		return nil, errors.New("zen: stream has no response object")
+	}
+
+	var body map[string]json.RawMessage
+	if err := jsonUnmarshal(response, &body); err != nil {
+		return nil, fmt.Errorf("zen: decode response object: %w", err)
+	}
+	// response.completed carries the full output; earlier snapshots (an
+	// interrupted stream) have it empty, so rebuild it from finished items.
+	var output []json.RawMessage
+	_ = jsonUnmarshal(body["output"], &output)
+	if len(output) == 0 && len(items) > 0 {
+		for _, index := range sortedKeys(items) {
+			output = append(output, items[index])
+		}
+		raw, err := jsonMarshal(output)
+		if err != nil {
+			return nil, err
+		}
+		body["output"] = raw
+	}
+	return jsonMarshal(body)
+}
+
+// ---------------------------------------------------------------------------
+// models (Gemini)
+// ---------------------------------------------------------------------------
+
+type geminiAssemblyCandidate struct {
+	fields map[string]any
+	parts  []map[string]any
+}
+
+func assembleGemini(events []UnifiedEvent) ([]byte, error) {
+	body := map[string]any{}
+	candidates := map[int]*geminiAssemblyCandidate{}
+	for _, ev := range events {
+		var chunk map[string]json.RawMessage
+		if err := jsonUnmarshal(ev.Data, &chunk); err != nil {
+			continue
+		}
+		for k, v := range chunk {
+			if k != "candidates" {
+				body[k] = v
+			}
+		}
+		var chunkCandidates []map[string]json.RawMessage
+		_ = jsonUnmarshal(chunk["candidates"], &chunkCandidates)
+		for position, c := range chunkCandidates {
+			index := position
+			_ = jsonUnmarshal(c["index"], &index)
+			candidate := candidates[index]
+			if candidate == nil {
+				candidate = &geminiAssemblyCandidate{fields: map[string]any{}, parts: []map[string]any{}}
+				candidates[index] = candidate
+			}
+			for k, v := range c {
+				if k != "content" {
+					candidate.fields[k] = v
+				}
+			}
+			var content struct {
+				Parts []map[string]any `json:"parts"`
+			}
+			_ = jsonUnmarshal(c["content"], &content)
+			for _, part := range content.Parts {
+				candidate.parts = appendGeminiPart(candidate.parts, part)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		candidates[0] = &geminiAssemblyCandidate{fields: map[string]any{}, parts: []map[string]any{}}
+	}
+
+	out := make([]any, 0, len(candidates))
+	for _, index := range sortedKeys(candidates) {
+		candidate := candidates[index]
+		candidate.fields["content"] = map[string]any{"role": "model", "parts": candidate.parts}
+		out = append(out, candidate.fields)
+	}
+	body["candidates"] = out
+	return jsonMarshal(body)
+}
+
+// appendGeminiPart appends part to parts, merging a text chunk into the
+// previous part when both are text of the same kind (thought or not), the way
+// a non-streaming response returns one part per kind.
+func appendGeminiPart(parts []map[string]any, part map[string]any) []map[string]any {
+	text, isText := part["text"].(string)
+	if isText && len(parts) > 0 {
+		last := parts[len(parts)-1]
+		lastText, lastIsText := last["text"].(string)
+		if lastIsText && last["thought"] == part["thought"] {
+			last["text"] = lastText + text
+			if sig, ok := part["thoughtSignature"]; ok {
+				last["thoughtSignature"] = sig
+			}
+			return parts
+		}
+	}
+	return append(parts, part)
+}
+
+// ---------------------------------------------------------------------------
+// helpers
+// ---------------------------------------------------------------------------
+
+func setIfNotEmpty(m map[string]any, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
+
+// mergeRawFields copies the fields of the JSON object raw into dst.
+func mergeRawFields(dst map[string]any, raw json.RawMessage) {
+	var fields map[string]json.RawMessage
+	if err := jsonUnmarshal(raw, &fields); err != nil {
+		return
+	}
+	for k, v := range fields {
+		dst[k] = v
+	}
+}
+
+// rawStringField returns the string held by a json.RawMessage field, or "".
+func rawStringField(v any) string {
+	raw, ok := v.(json.RawMessage)
+	if !ok {
+		return ""
+	}
+	var s string
+	_ = jsonUnmarshal(raw, &s)
+	return s
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,172 @@
+package zen
+
+import "testing"
+
+func assembleEvents(endpoint EndpointType, data ...string) []UnifiedEvent {
+	events := make([]UnifiedEvent, len(data))
+	for i, d := range data {
+		events[i] = makeEvent(endpoint, d)
+	}
+	return events
+}
+
+func TestAssembleChatCompletionGolden(t *testing.T) {
+	events := assembleEvents(EndpointChatCompletions,
+		`{"id":"chatcmpl-abc","object":"chat.completion.chunk","created":1760000000,"model":"kimi-k2","system_fingerprint":"fp_1","choices":[{"index":0,"delta":{"role":"assistant","content":""},"logprobs":null,"finish_reason":null}]}`,
+		`{"id":"chatcmpl-abc","object":"chat.completion.chunk","created":1760000000,"model":"kimi-k2","system_fingerprint":"fp_1","choices":[{"index":0,"delta":{"content":"Checking."},"logprobs":null,"finish_reason":null}]}`,
+		`{"id":"chatcmpl-abc","object":"chat.completion.chunk","created":1760000000,"model":"kimi-k2","system_fingerprint":"fp_1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]},"logprobs":null,"finish_reason":null}]}`,
+		`{"id":"chatcmpl-abc","object":"chat.completion.chunk","created":1760000000,"model":"kimi-k2","system_fingerprint":"fp_1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]},"logprobs":null,"finish_reason":null}]}`,
+		`{"id":"chatcmpl-abc","object":"chat.completion.chunk","created":1760000000,"model":"kimi-k2","system_fingerprint":"fp_1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]},"logprobs":null,"finish_reason":null}]}`,
+		`{"id":"chatcmpl-abc","object":"chat.completion.chunk","created":1760000000,"model":"kimi-k2","system_fingerprint":"fp_1","choices":[{"index":0,"delta":{},"logprobs":null,"finish_reason":"tool_calls"}]}`,
+		`{"id":"chatcmpl-abc","object":"chat.completion.chunk","created":1760000000,"model":"kimi-k2","system_fingerprint":"fp_1","choices":[],"usage":{"prompt_tokens":20,"completion_tokens":9,"total_tokens":29}}`,
+		`[DONE]`,
+	)
+	want := `{
+		"id": "chatcmpl-abc",
+		"object": "chat.completion",
+		"created": 1760000000,
+		"model": "kimi-k2",
+		"system_fingerprint": "fp_1",
+		"choices": [{
+			"index": 0,
+			"message": {
+				"role": "assistant",
+				"content": "Checking.",
+				"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]
+			},
+			"logprobs": null,
+			"finish_reason": "tool_calls"
+		}],
+		"usage": {"prompt_tokens": 20, "completion_tokens": 9, "total_tokens": 29}
+	}`
+	got, err := AssembleResponse(EndpointChatCompletions, events)
+	if err != nil {
+		t.Fatalf("AssembleResponse: %v", err)
+	}
+	assertJSONEquivalent(t, []byte(want), got)
+}
+
+func TestAssembleMessagesGolden(t *testing.T) {
+	events := assembleEvents(EndpointMessages,
+		`{"type":"message_start","message":{"id":"msg_01","type":"message","role":"assistant","model":"claude-sonnet-4-6","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":312,"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Two lookups"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":" needed."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Checking Paris."}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_01","name":"get_weather","input":{}}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":""}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"city\": "}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"content_block_start","index":3,"content_block":{"type":"tool_use","id":"toolu_02","name":"get_time","input":{}}}`,
+		`{"type":"content_block_stop","index":3}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":88}}`,
+		`{"type":"message_stop"}`,
+	)
+	want := `{
+		"id": "msg_01",
+		"type": "message",
+		"role": "assistant",
+		"model": "claude-sonnet-4-6",
+		"content": [
+			{"type": "thinking", "thinking": "Two lookups needed.", "signature": "sig"},
+			{"type": "text", "text": "Checking Paris."},
+			{"type": "tool_use", "id": "toolu_01", "name": "get_weather", "input": {"city": "Paris"}},
+			{"type": "tool_use", "id": "toolu_02", "name": "get_time", "input": {}}
+		],
+		"stop_reason": "tool_use",
+		"stop_sequence": null,
+		"usage": {"input_tokens": 312, "cache_creation_input_tokens": 0, "cache_read_input_tokens": 0, "output_tokens": 88}
+	}`
+	got, err := AssembleResponse(EndpointMessages, events)
+	if err != nil {
+		t.Fatalf("AssembleResponse: %v", err)
+	}
+	assertJSONEquivalent(t, []byte(want), got)
+
+	parsed, err := ParseNormalizedResponse(EndpointMessages, got)
+	if err != nil {
+		t.Fatalf("assembled body should parse: %v", err)
+	}
+	if parsed.Text != "Checking Paris." || len(parsed.ToolCalls) != 2 || parsed.OutputTokens != 88 {
+		t.Fatalf("parsed assembly mismatch: %+v", parsed)
+	}
+
+	broken := assembleEvents(EndpointMessages,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_01","name":"f","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"a\":"}}`,
+	)
+	if _, err := AssembleResponse(EndpointMessages, broken); err == nil {
+		t.Fatalf("incomplete tool input should fail")
+	}
+}
+
+const responsesAssemblyItem = `{"id":"msg_1","type":"message","status":"completed","role":"assistant","content":[{"type":"output_text","text":"Hi there","annotations":[]}]}`
+
+func TestAssembleResponsesGolden(t *testing.T) {
+	completed := `{"id":"resp_1","object":"response","created_at":1760000000,"status":"completed","model":"gpt-5.2","output":[` + responsesAssemblyItem + `],"usage":{"input_tokens":9,"output_tokens":3,"total_tokens":12,"output_tokens_details":{"reasoning_tokens":0}}}`
+	events := assembleEvents(EndpointResponses,
+		`{"type":"response.created","sequence_number":0,"response":{"id":"resp_1","object":"response","created_at":1760000000,"status":"in_progress","model":"gpt-5.2","output":[],"usage":null}}`,
+		`{"type":"response.output_item.added","sequence_number":1,"output_index":0,"item":{"id":"msg_1","type":"message","status":"in_progress","role":"assistant","content":[]}}`,
+		`{"type":"response.output_text.delta","sequence_number":2,"item_id":"msg_1","output_index":0,"content_index":0,"delta":"Hi there"}`,
+		`{"type":"response.output_item.done","sequence_number":3,"output_index":0,"item":`+responsesAssemblyItem+`}`,
+		`{"type":"response.completed","sequence_number":4,"response":`+completed+`}`,
+	)
+	got, err := AssembleResponse(EndpointResponses, events)
+	if err != nil {
+		t.Fatalf("AssembleResponse: %v", err)
+	}
+	assertJSONEquivalent(t, []byte(completed), got)
+
+	// Without response.completed the output is rebuilt from finished items.
+	got, err = AssembleResponse(EndpointResponses, events[:4])
+	if err != nil {
+		t.Fatalf("AssembleResponse (interrupted): %v", err)
+	}
+	want := `{"id":"resp_1","object":"response","created_at":1760000000,"status":"in_progress","model":"gpt-5.2","output":[` + responsesAssemblyItem + `],"usage":null}`
+	assertJSONEquivalent(t, []byte(want), got)
+
+	if _, err := AssembleResponse(EndpointResponses, events[1:4]); err == nil {
+		t.Fatalf("a stream without a response object should fail")
+	}
+}
+
+func TestAssembleGeminiGolden(t *testing.T) {
+	events := assembleEvents(EndpointModels,
+		`{"candidates":[{"content":{"parts":[{"text":"Let me ","thought":true}],"role":"model"},"index":0}],"usageMetadata":{"promptTokenCount":8,"totalTokenCount":8},"modelVersion":"gemini-3-pro-preview","responseId":"r1"}`,
+		`{"candidates":[{"content":{"parts":[{"text":"think.","thought":true}],"role":"model"},"index":0}],"usageMetadata":{"promptTokenCount":8,"totalTokenCount":8},"modelVersion":"gemini-3-pro-preview","responseId":"r1"}`,
+		`{"candidates":[{"content":{"parts":[{"text":"Hello"}],"role":"model"},"index":0}],"usageMetadata":{"promptTokenCount":8,"totalTokenCount":8},"modelVersion":"gemini-3-pro-preview","responseId":"r1"}`,
+		`{"candidates":[{"content":{"parts":[{"text":", world","thoughtSignature":"c2ln"}],"role":"model"},"index":0}],"usageMetadata":{"promptTokenCount":8,"totalTokenCount":8},"modelVersion":"gemini-3-pro-preview","responseId":"r1"}`,
+		`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}],"role":"model"},"finishReason":"STOP","index":0}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":12,"thoughtsTokenCount":4,"totalTokenCount":24},"modelVersion":"gemini-3-pro-preview","responseId":"r1"}`,
+	)
+	want := `{
+		"candidates": [{
+			"content": {
+				"parts": [
+					{"text": "Let me think.", "thought": true},
+					{"text": "Hello, world", "thoughtSignature": "c2ln"},
+					{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}}
+				],
+				"role": "model"
+			},
+			"finishReason": "STOP",
+			"index": 0
+		}],
+		"usageMetadata": {"promptTokenCount": 8, "candidatesTokenCount": 12, "thoughtsTokenCount": 4, "totalTokenCount": 24},
+		"modelVersion": "gemini-3-pro-preview",
+		"responseId": "r1"
+	}`
+	got, err := AssembleResponse(EndpointModels, events)
+	if err != nil {
+		t.Fatalf("AssembleResponse: %v", err)
+	}
+	assertJSONEquivalent(t, []byte(want), got)
+
+	if _, err := AssembleResponse(EndpointAuto, events); err == nil {
+		t.Fatalf("unknown endpoints should fail")
+	}
+}
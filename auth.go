@@ -5,7 +5,7 @@ import (
 	"strings"
 )
 
-func (c *Client) applyRequestHeaders(req *http.Request, endpoint EndpointType, streaming bool, forceAllAuth bool) {
+func (c *Client) applyRequestHeaders(req *http.Request, endpoint EndpointType, streaming bool, forceAllAuth bool, key string) {
 	if req.Body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
@@ -14,56 +14,79 @@ func (c *Client) applyRequestHeaders(req *http.Request, endpoint EndpointType, s
 		req.Header.Set("Accept", "text/event-stream")
 	}
 
-	c.applyAuthHeaders(req, endpoint, forceAllAuth)
 	if endpoint == EndpointMessages {
 		req.Header.Set("anthropic-version", "2023-06-01")
-		if streaming {
-			req.Header.Set("anthropic-beta", "fine-grained-tool-streaming-2025-05-14")
+		if betas := c.anthropicBetas(); len(betas) > 0 {
+			req.Header.Set("anthropic-beta", strings.Join(betas, ","))
 		}
 	}
 
 	req.Header.Set("User-Agent", c.cfg.UserAgent)
+	if c.cfg.SendFeaturesHeader {
+		if features := featuresHeader(endpoint); features != "" {
+			req.Header.Set("x-zen-sdk-features", features)
+		}
+	}
+
+	for k, v := range c.cfg.DefaultHeaders {
+		k = http.CanonicalHeaderKey(k)
+		if !authHeaderNames[k] {
+			req.Header[k] = append([]string(nil), v...)
+		}
+	}
+	c.applyScopeHeaders(req)
+	applyContextHeaders(req)
+	c.applyAuthHeaders(req, endpoint, forceAllAuth, key)
+}
+
+// defaultAnthropicBetas are sent when Config.AnthropicBetas is nil.
+var defaultAnthropicBetas = []string{"fine-grained-tool-streaming-2025-05-14"}
+
+func (c *Client) anthropicBetas() []string {
+	if c.cfg.AnthropicBetas == nil {
+		return defaultAnthropicBetas
+	}
+	return c.cfg.AnthropicBetas
 }
 
-func (c *Client) applyAuthHeaders(req *http.Request, endpoint EndpointType, forceAll bool) {
+func (c *Client) applyAuthHeaders(req *http.Request, endpoint EndpointType, forceAll bool, key string) {
 	if forceAll {
-		c.setBearer(req)
-		c.setAPIKey(req)
-		c.setGoogAPIKey(req)
+		setBearer(req, key)
+		setAPIKey(req, key)
+		setGoogAPIKey(req, key)
 		return
 	}
 
 	switch c.cfg.AuthHeader {
 	case AuthHeaderBearer:
-		c.setBearer(req)
+		setBearer(req, key)
 	case AuthHeaderAPIKey:
-		c.setAPIKey(req)
+		setAPIKey(req, key)
 	case AuthHeaderGoogAPIKey:
-		c.setGoogAPIKey(req)
+		setGoogAPIKey(req, key)
 	default:
 		switch endpoint {
 		case EndpointMessages:
-			c.setAPIKey(req)
+			setAPIKey(req, key)
 		case EndpointModels:
-			c.setGoogAPIKey(req)
+			setGoogAPIKey(req, key)
 		default:
-			c.setBearer(req)
+			setBearer(req, key)
 		}
 	}
 }
 
-func (c *Client) setBearer(req *http.Request) {
-	key := c.cfg.APIKey
+func setBearer(req *http.Request, key string) {
 	if !strings.HasPrefix(strings.ToLower(key), "bearer ") {
 		key = "Bearer " + key
 	}
 	req.Header.Set("Authorization", key)
 }
 
-func (c *Client) setAPIKey(req *http.Request) {
-	req.Header.Set("x-api-key", c.cfg.APIKey)
+func setAPIKey(req *http.Request, key string) {
+	req.Header.Set("x-api-key", key)
 }
 
-func (c *Client) setGoogAPIKey(req *http.Request) {
-	req.Header.Set("x-goog-api-key", c.cfg.APIKey)
+func setGoogAPIKey(req *http.Request, key string) {
+	req.Header.Set("x-goog-api-key", key)
 }
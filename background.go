@@ -0,0 +1,151 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// StoredResponse is a Responses API response read back by id, e.g. one
+// running in the background. Body is the complete response object.
+type StoredResponse struct {
+	ID string `json:"id"`
+	// Status is "queued", "in_progress", "completed", "failed",
+	// "cancelled" or "incomplete".
+	Status string `json:"status"`
+	Error  *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+	Body json.RawMessage `json:"-"`
+}
+
+// Done reports whether the response reached a terminal status.
+func (r *StoredResponse) Done() bool {
+	switch r.Status {
+	case "queued", "in_progress":
+		return false
+	}
+	return true
+}
+
+// Normalized parses Body like ParseNormalizedResponse.
+func (r *StoredResponse) Normalized() (*NormalizedResponse, error) {
+	return ParseNormalizedResponse(EndpointResponses, r.Body)
+}
+
+// PollOptions controls Client.WaitForResponse. The wait between polls starts
+// at Interval (0 = 1s) and doubles up to MaxInterval (0 = 30s).
+type PollOptions struct {
+	Interval    time.Duration
+	MaxInterval time.Duration
+}
+
+// StartBackgroundResponse sends req to the Responses API with Background set
+// and returns the queued response without waiting for it.
+func (c *Client) StartBackgroundResponse(ctx context.Context, req NormalizedRequest) (*StoredResponse, error) {
+	req.Background = true
+	ctx, endpoint, path, payload, err := c.buildRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint != EndpointResponses {
+		return nil, fmt.Errorf("zen: background requests need the responses endpoint, %s routes to %s", req.Model, endpoint)
+	}
+	body, header, err := c.doRequest(ctx, "POST", path, payload, endpoint, false)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStoredResponse(body, header)
+}
+
+// GetResponse reads the response id back from the Responses API.
+func (c *Client) GetResponse(ctx context.Context, id string) (*StoredResponse, error) {
+	body, header, err := c.doRequest(ctx, "GET", responsePath(id), nil, EndpointResponses, false)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStoredResponse(body, header)
+}
+
+// DeleteResponse deletes the stored response id. The deletion object the
+// Responses API answers with is not decoded, so gateways answering 204 or
+// an empty 200 succeed too.
+func (c *Client) DeleteResponse(ctx context.Context, id string) error {
+	_, _, err := c.doRequest(ctx, "DELETE", responsePath(id), nil, EndpointResponses, false)
+	return err
+}
+
+// WaitForResponse polls the response id until its status is terminal and
+// returns it. A failed response is returned together with an error carrying
+// its message.
+func (c *Client) WaitForResponse(ctx context.Context, id string, opts PollOptions) (*StoredResponse, error) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	maxInterval := opts.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = 30 * time.Second
+	}
+	for {
+		resp, err := c.GetResponse(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Done() {
+			if resp.Status == "failed" {
+				msg := "no error reported"
+				if resp.Error != nil {
+					msg = resp.Error.Message
+				}
+				return resp, fmt.Errorf("zen: response %s failed: %s", id, msg)
+			}
+			return resp, nil
+		}
+		if err := c.cfg.Clock.Sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+		interval = min(interval*2, maxInterval)
+	}
+}
+
+// ResumeResponseStream reopens the event stream of a background response
+// started with streaming, continuing after the event with sequence number
+// startingAfter (-1 replays the stream from the start).
+func (c *Client) ResumeResponseStream(ctx context.Context, id string, startingAfter int) (*StreamHandle, error) {
+	query := url.Values{"stream": {"true"}}
+	if startingAfter >= 0 {
+		query.Set("starting_after", strconv.Itoa(startingAfter))
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	evCh, errCh, info, err := c.streamPath(ctx, EndpointResponses, "GET", responsePath(id)+"?"+query.Encode(), nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return c.deltaStream(ctx, cancel, NormalizedRequest{Endpoint: EndpointResponses}, evCh, errCh, info), nil
+}
+
+func responsePath(id string) string {
+	return "/responses/" + url.PathEscape(id)
+}
+
+// decodeStoredResponse decodes a response object. An empty body fails as a
+// response without an id.
+func decodeStoredResponse(body []byte, header http.Header) (*StoredResponse, error) {
+	var resp StoredResponse
+	if err := decodeJSONResponse(body, header, &resp); err != nil {
+		return nil, fmt.Errorf("zen: decode response: %w", err)
+	}
+	if resp.ID == "" {
+		return nil, errors.New("zen: response has no id")
+	}
+	resp.Body = json.RawMessage(body)
+	return &resp, nil
+}
@@ -0,0 +1,87 @@
+package zen
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackgroundResponse(t *testing.T) {
+	var polls atomic.Int32
+	var sent map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /responses":
+			data, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(data, &sent)
+			_, _ = w.Write([]byte(`{"id":"resp_1","object":"response","status":"queued","output":[]}`))
+		case "GET /responses/resp_1":
+			if r.URL.Query().Get("stream") == "true" {
+				if got := r.URL.Query().Get("starting_after"); got != "2" {
+					t.Errorf("starting_after: want 2, got %q", got)
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = w.Write([]byte("event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"sequence_number\":3,\"delta\":\"lo\"}\n\n" +
+					"event: response.completed\ndata: {\"type\":\"response.completed\",\"sequence_number\":4,\"response\":{\"id\":\"resp_1\",\"status\":\"completed\"}}\n\n"))
+				return
+			}
+			if polls.Add(1) < 3 {
+				_, _ = w.Write([]byte(`{"id":"resp_1","object":"response","status":"in_progress","output":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"resp_1","object":"response","status":"completed","output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Hello"}]}],"usage":{"input_tokens":5,"output_tokens":1}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	started, err := client.StartBackgroundResponse(testCtx(t), NormalizedRequest{
+		Model:    "gpt-5.1",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("StartBackgroundResponse: %v", err)
+	}
+	if started.ID != "resp_1" || started.Done() || sent["background"] != true {
+		t.Fatalf("unexpected start: %+v, body %v", started, sent)
+	}
+
+	done, err := client.WaitForResponse(testCtx(t), started.ID, PollOptions{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("WaitForResponse: %v", err)
+	}
+	if polls.Load() != 3 || done.Status != "completed" {
+		t.Fatalf("expected completion on the third poll, got %d polls and %+v", polls.Load(), done)
+	}
+	resp, err := done.Normalized()
+	if err != nil || resp.Text != "Hello" || resp.OutputTokens != 1 {
+		t.Fatalf("Normalized: %+v %v", resp, err)
+	}
+
+	h, err := client.ResumeResponseStream(testCtx(t), "resp_1", 2)
+	if err != nil {
+		t.Fatalf("ResumeResponseStream: %v", err)
+	}
+	var deltas []NormalizedDelta
+	for d := range h.Deltas {
+		deltas = append(deltas, d)
+	}
+	if err := <-h.Errs; err != nil {
+		t.Fatalf("resumed stream: %v", err)
+	}
+	assertDeltaSequence(t, deltas, DeltaText, DeltaDone)
+
+	if _, err := client.StartBackgroundResponse(testCtx(t), NormalizedRequest{Model: "claude-sonnet-4-6"}); err == nil {
+		t.Fatal("expected an error for a model that does not use the responses endpoint")
+	}
+}
@@ -0,0 +1,162 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const defaultBatchConcurrency = 4
+
+// BatchOptions controls Client.CreateNormalizedBatch.
+type BatchOptions struct {
+	// Concurrency is the maximum number of requests in flight at once.
+	// Values <= 0 use a default of 4.
+	Concurrency int
+	// Retry enables per-item retries. MaxRetries and Backoff are honoured;
+	// RetryOnNonIdempotent is ignored since every item is a POST the caller
+	// explicitly asked to retry. These are the only retries of a batch:
+	// items run as streams, which Config.Retry does not retry, and it is
+	// also turned off for the batch's requests so a failing item can never
+	// be retried at both levels. A nil Retry disables retries.
+	Retry *RetryConfig
+	// OnProgress, if set, is called after each item finishes (successfully or
+	// not) with the number of finished items and the total. Calls are
+	// serialized, so the callback does not need its own locking.
+	OnProgress func(done, total int)
+	// FailFast cancels all outstanding items as soon as one fails and returns
+	// that error. When false every item runs to completion and the returned
+	// error joins all item errors.
+	FailFast bool
+}
+
+// BatchResult is the outcome of a single item of a batch. Results are returned
+// in the same order as the input requests.
+type BatchResult struct {
+	Response *NormalizedResponse
+	Err      error
+	Attempts int
+}
+
+// CreateNormalizedBatch runs every request through CollectStream using a
+// bounded worker pool. This is client-side fan-out and is unrelated to any
+// provider batch API.
+func (c *Client) CreateNormalizedBatch(ctx context.Context, reqs []NormalizedRequest, opts BatchOptions) ([]BatchResult, error) {
+	results := make([]BatchResult, len(reqs))
+	if len(reqs) == 0 {
+		return results, nil
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	if concurrency > len(reqs) {
+		concurrency = len(reqs)
+	}
+
+	client := c
+	if opts.Retry != nil {
+		client = c.With(func(cfg *Config) { cfg.Retry.MaxRetries = 0 })
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		done     int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+
+	jobs := make(chan int)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = client.runBatchItem(ctx, reqs[i], opts.Retry)
+
+				mu.Lock()
+				done++
+				if results[i].Err != nil && firstErr == nil {
+					firstErr = results[i].Err
+					if opts.FailFast {
+						cancel()
+					}
+				}
+				if opts.OnProgress != nil {
+					opts.OnProgress(done, len(reqs))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range reqs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if opts.FailFast {
+		return results, firstErr
+	}
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (c *Client) runBatchItem(ctx context.Context, req NormalizedRequest, retry *RetryConfig) BatchResult {
+	retries := 0
+	backoff := c.cfg.Retry.Backoff
+	if retry != nil {
+		retries = retry.MaxRetries
+		if retry.Backoff != nil {
+			backoff = retry.Backoff
+		}
+	}
+
+	var result BatchResult
+	for attempt := 0; attempt <= retries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+			return result
+		}
+
+		result.Attempts++
+		resp, err := c.CollectStream(ctx, req)
+		result.Response = resp
+		result.Err = err
+		if err == nil || attempt == retries || !isRetryableBatchError(err) {
+			return result
+		}
+
+		if err := c.cfg.Clock.Sleep(ctx, backoff(attempt)); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+	return result
+}
+
+func isRetryableBatchError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrStreamDisconnected) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatus[apiErr.StatusCode]
+	}
+	// Transport errors and streams that died mid-body are worth another try.
+	return true
+}
@@ -0,0 +1,217 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBatchTestServer echoes the first user message back as the assistant text,
+// so results can be matched to their inputs. Requests whose content is "fail"
+// get a 400; "flaky" fails with a 503 the first time it is seen.
+func newBatchTestServer(t *testing.T, inFlight, maxInFlight *int32) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	seen := map[string]int{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+		for {
+			cur := atomic.LoadInt32(maxInFlight)
+			if n <= cur || atomic.CompareAndSwapInt32(maxInFlight, cur, n) {
+				break
+			}
+		}
+
+		payload, _ := io.ReadAll(r.Body)
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.Unmarshal(payload, &body)
+		content := body.Messages[0].Content
+
+		mu.Lock()
+		seen[content]++
+		count := seen[content]
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		switch {
+		case content == "fail":
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		case content == "flaky" && count == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		chunk, _ := json.Marshal(map[string]any{
+			"choices": []any{map[string]any{"delta": map[string]any{"content": content}, "finish_reason": "stop"}},
+		})
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: " + string(chunk) + "\n\ndata: [DONE]\n\n"))
+	}))
+}
+
+func batchRequests(contents ...string) []NormalizedRequest {
+	reqs := make([]NormalizedRequest, 0, len(contents))
+	for _, c := range contents {
+		reqs = append(reqs, NormalizedRequest{
+			Model:    "glm-5",
+			Messages: []NormalizedMessage{{Role: "user", Content: c}},
+		})
+	}
+	return reqs
+}
+
+func TestCreateNormalizedBatchPreservesOrder(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := newBatchTestServer(t, &inFlight, &maxInFlight)
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	contents := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	var progress []int
+	results, err := client.CreateNormalizedBatch(context.Background(), batchRequests(contents...), BatchOptions{
+		Concurrency: 3,
+		OnProgress:  func(done, total int) { progress = append(progress, done) },
+	})
+	if err != nil {
+		t.Fatalf("CreateNormalizedBatch: %v", err)
+	}
+
+	for i, want := range contents {
+		if results[i].Err != nil {
+			t.Fatalf("result[%d] error: %v", i, results[i].Err)
+		}
+		if results[i].Response.Text != want {
+			t.Fatalf("result[%d]: want %q, got %q", i, want, results[i].Response.Text)
+		}
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got > 3 {
+		t.Fatalf("concurrency limit exceeded: %d in flight", got)
+	}
+	if len(progress) != len(contents) || progress[len(progress)-1] != len(contents) {
+		t.Fatalf("progress callback mismatch: %v", progress)
+	}
+}
+
+func TestCreateNormalizedBatchCollectsErrors(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := newBatchTestServer(t, &inFlight, &maxInFlight)
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	results, err := client.CreateNormalizedBatch(context.Background(), batchRequests("a", "fail", "b"), BatchOptions{Concurrency: 2})
+	if err == nil {
+		t.Fatalf("expected joined error")
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected APIError 400 in joined error, got %v", err)
+	}
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Fatalf("successful items should not carry errors: %+v", results)
+	}
+	if results[1].Err == nil {
+		t.Fatalf("failing item should carry its error")
+	}
+}
+
+func TestCreateNormalizedBatchFailFast(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := newBatchTestServer(t, &inFlight, &maxInFlight)
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	results, err := client.CreateNormalizedBatch(context.Background(), batchRequests("fail", "a", "b", "c", "d"), BatchOptions{
+		Concurrency: 1,
+		FailFast:    true,
+	})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected the first APIError, got %v", err)
+	}
+	for i := 1; i < len(results); i++ {
+		if !errors.Is(results[i].Err, context.Canceled) {
+			t.Fatalf("result[%d]: expected context.Canceled, got %v", i, results[i].Err)
+		}
+	}
+}
+
+func TestCreateNormalizedBatchRetries(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := newBatchTestServer(t, &inFlight, &maxInFlight)
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	results, err := client.CreateNormalizedBatch(context.Background(), batchRequests("flaky", "fail"), BatchOptions{
+		Retry: &RetryConfig{
+			MaxRetries: 2,
+			Backoff:    func(int) time.Duration { return time.Millisecond },
+		},
+	})
+	if err == nil {
+		t.Fatalf("expected error from non-retryable item")
+	}
+	if results[0].Err != nil || results[0].Response.Text != "flaky" {
+		t.Fatalf("flaky item should succeed after retry: %+v", results[0])
+	}
+	if results[0].Attempts != 2 {
+		t.Fatalf("flaky item attempts: want 2, got %d", results[0].Attempts)
+	}
+	if results[1].Attempts != 1 {
+		t.Fatalf("400 must not be retried, got %d attempts", results[1].Attempts)
+	}
+}
+
+// TestCreateNormalizedBatchRetriesReplaceClientRetries checks that a client
+// retrying POSTs does not multiply the attempts of a batch item.
+func TestCreateNormalizedBatchRetriesReplaceClientRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	fast := func(int) time.Duration { return time.Millisecond }
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, Retry: RetryConfig{MaxRetries: 2, RetryOnNonIdempotent: true, Backoff: fast}})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	results, _ := client.CreateNormalizedBatch(context.Background(), batchRequests("down"), BatchOptions{
+		Retry: &RetryConfig{MaxRetries: 2, Backoff: fast},
+	})
+	if results[0].Attempts != 3 || atomic.LoadInt32(&requests) != 3 {
+		t.Fatalf("want 3 attempts and 3 requests, got %d attempts and %d requests", results[0].Attempts, requests)
+	}
+}
@@ -0,0 +1,180 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SDKVersion is the version of the SDK, sent in the default User-Agent.
+const SDKVersion = "0.1"
+
+// Feature names something the SDK supports on an endpoint, as reported by
+// Capabilities and the x-zen-sdk-features header.
+type Feature string
+
+const (
+	// FeatureReasoning means reasoning is requested and streamed as
+	// DeltaReasoning.
+	FeatureReasoning Feature = "reasoning"
+	// FeatureToolStreaming means tool call arguments are streamed as
+	// DeltaToolCallArgumentsDelta.
+	FeatureToolStreaming Feature = "tool_streaming"
+	// FeaturePromptCaching means cached input tokens are reported.
+	FeaturePromptCaching Feature = "prompt_caching"
+	// FeatureServerTools means tools the provider runs are reported as
+	// DeltaServerToolCall.
+	FeatureServerTools Feature = "server_tools"
+	// FeatureAudio means spoken output is streamed as DeltaAudio.
+	FeatureAudio Feature = "audio"
+	// FeatureCandidates means several candidates are streamed side by side.
+	FeatureCandidates Feature = "candidates"
+	// FeatureBackground means requests can run in the background.
+	FeatureBackground Feature = "background"
+)
+
+// endpointFeatures lists the features the SDK supports per endpoint.
+var endpointFeatures = map[EndpointType][]Feature{
+	EndpointChatCompletions: {FeatureReasoning, FeatureToolStreaming, FeaturePromptCaching, FeatureAudio},
+	EndpointResponses:       {FeatureReasoning, FeatureToolStreaming, FeaturePromptCaching, FeatureServerTools, FeatureBackground},
+	EndpointMessages:        {FeatureReasoning, FeatureToolStreaming, FeaturePromptCaching},
+	EndpointModels:          {FeatureReasoning, FeaturePromptCaching, FeatureCandidates},
+}
+
+// SDKCapabilities describes what this version of the SDK supports, for
+// auditing which clients understand which gateway behaviour.
+type SDKCapabilities struct {
+	Version   string
+	Endpoints []EndpointCapabilities
+}
+
+// EndpointCapabilities describes the SDK's support for one endpoint.
+type EndpointCapabilities struct {
+	Endpoint EndpointType
+	// Events lists the SSE event types the stream parser recognizes: the
+	// documented ones, then the alternative spellings it also accepts,
+	// sorted. It is nil for endpoints whose events carry no type, chat
+	// completions and Gemini.
+	Events   []string
+	Features []Feature
+}
+
+// Capabilities reports the endpoints, stream event types and features this
+// version of the SDK supports, read from the tables its parsers use.
+func Capabilities() SDKCapabilities {
+	out := SDKCapabilities{Version: SDKVersion}
+	for _, endpoint := range []EndpointType{EndpointChatCompletions, EndpointResponses, EndpointMessages, EndpointModels} {
+		ec := EndpointCapabilities{
+			Endpoint: endpoint,
+			Features: append([]Feature(nil), endpointFeatures[endpoint]...),
+		}
+		switch endpoint {
+		case EndpointResponses:
+			aliases := make([]string, 0, len(responsesEventAliases))
+			for alias := range responsesEventAliases {
+				aliases = append(aliases, alias)
+			}
+			sort.Strings(aliases)
+			ec.Events = append(append([]string(nil), responsesEventTypes...), aliases...)
+		case EndpointMessages:
+			ec.Events = append([]string(nil), messagesEventTypes...)
+		}
+		out.Endpoints = append(out.Endpoints, ec)
+	}
+	return out
+}
+
+// featuresHeader is the x-zen-sdk-features value for endpoint.
+func featuresHeader(endpoint EndpointType) string {
+	features := endpointFeatures[endpoint]
+	names := make([]string, len(features))
+	for i, f := range features {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ",")
+}
+
+// ErrReasoningUnsupported is wrapped by the error returned under
+// ReasoningFail for a request asking a model that cannot reason for
+// reasoning.
+var ErrReasoningUnsupported = errors.New("zen: model does not support reasoning")
+
+// ReasoningPolicy selects what Config.UnsupportedReasoning does with the
+// Reasoning of a request for a model known not to support it.
+type ReasoningPolicy int
+
+const (
+	// ReasoningPassThrough sends Reasoning as set; the provider may reject
+	// the request or ignore it.
+	ReasoningPassThrough ReasoningPolicy = iota
+	// ReasoningStrip removes Reasoning from the request and reports a
+	// Warning to Config.OnWarning.
+	ReasoningStrip
+	// ReasoningFail fails the request with ErrReasoningUnsupported before
+	// it is sent.
+	ReasoningFail
+)
+
+type reasoningCapability struct {
+	prefix    string
+	reasoning bool
+}
+
+// reasoningCapabilities is the built-in list of which models reason. The
+// first matching prefix wins, so more specific prefixes come first. Models
+// it does not list are assumed to support reasoning.
+var reasoningCapabilities = []reasoningCapability{
+	{"kimi-k2-thinking", true},
+	{"kimi-k2", false},
+	{"gemini-2.0-flash", false},
+	{"gemini-1.5", false},
+	{"gemini-2.5-flash-image", false},
+	{"gpt-4o", false},
+	{"gpt-4.1", false},
+	{"chatgpt-4o", false},
+	{"claude-3-5-", false},
+	{"claude-3-haiku", false},
+	{"claude-3-opus", false},
+	{"claude-3-sonnet", false},
+}
+
+// ModelSupportsReasoning reports whether model supports reasoning according
+// to the SDK's built-in list, and whether the list knows the model at all.
+func ModelSupportsReasoning(model string) (supported, known bool) {
+	m := normalizeModelID(model)
+	for _, c := range reasoningCapabilities {
+		if strings.HasPrefix(m, c.prefix) {
+			return c.reasoning, true
+		}
+	}
+	return true, false
+}
+
+// supportsReasoning reports whether model supports reasoning, preferring
+// ModelDefaults.SupportsReasoning over the built-in list.
+func (c Config) supportsReasoning(model string) bool {
+	for _, d := range c.defaultLayers(model) {
+		if d.SupportsReasoning != nil {
+			return *d.SupportsReasoning
+		}
+	}
+	supported, _ := ModelSupportsReasoning(model)
+	return supported
+}
+
+// checkReasoning applies Config.UnsupportedReasoning to req.
+func (c *Client) checkReasoning(ctx context.Context, endpoint EndpointType, req NormalizedRequest) (NormalizedRequest, error) {
+	if c.cfg.UnsupportedReasoning == ReasoningPassThrough || req.Reasoning == nil || c.cfg.supportsReasoning(req.Model) {
+		return req, nil
+	}
+	if c.cfg.UnsupportedReasoning == ReasoningFail {
+		return req, fmt.Errorf("%w: %s", ErrReasoningUnsupported, req.Model)
+	}
+	req.Reasoning = nil
+	if c.cfg.OnWarning != nil {
+		c.cfg.OnWarning(ctx, Warning{Field: "Reasoning", Endpoint: endpoint, Reason: req.Model + " does not support reasoning"})
+	}
+	return req, nil
+}
@@ -0,0 +1,151 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestUnsupportedReasoningPolicy(t *testing.T) {
+	req := NormalizedRequest{
+		Model:     "opencode/kimi-k2",
+		Messages:  []NormalizedMessage{{Role: "user", Content: "hi"}},
+		Reasoning: &NormalizedReasoning{Effort: "high"},
+	}
+	supported := true
+
+	cases := []struct {
+		name       string
+		policy     ReasoningPolicy
+		defaults   map[string]ModelDefaults
+		model      string
+		wantEffort string
+		wantWarned bool
+	}{
+		{name: "pass through", policy: ReasoningPassThrough, wantEffort: "high"},
+		{name: "strip", policy: ReasoningStrip, wantWarned: true},
+		{name: "thinking variant", policy: ReasoningStrip, model: "kimi-k2-thinking", wantEffort: "high"},
+		{name: "metadata override", policy: ReasoningStrip, defaults: map[string]ModelDefaults{"kimi-k2": {SupportsReasoning: &supported}}, wantEffort: "high"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newScriptedChatServer(t, []string{answerStep("hello", 0)})
+			defer server.Close()
+			var warnings []Warning
+			client, err := NewClient(Config{
+				APIKey:               "key",
+				BaseURL:              server.URL,
+				UnsupportedReasoning: tc.policy,
+				ModelDefaults:        tc.defaults,
+				OnWarning:            func(_ context.Context, w Warning) { warnings = append(warnings, w) },
+			})
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			r := req
+			if tc.model != "" {
+				r.Model = tc.model
+			}
+			if _, err := client.CollectStream(testCtx(t), r); err != nil {
+				t.Fatalf("CollectStream: %v", err)
+			}
+			if server.efforts[0] != tc.wantEffort {
+				t.Fatalf("reasoning_effort: want %q, got %q", tc.wantEffort, server.efforts[0])
+			}
+			if warned := len(warnings) == 1 && warnings[0].Field == "Reasoning"; warned != tc.wantWarned {
+				t.Fatalf("warnings mismatch: %v", warnings)
+			}
+		})
+	}
+
+	server := newScriptedChatServer(t, nil)
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, UnsupportedReasoning: ReasoningFail})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, err := client.CreateNormalized(testCtx(t), req); !errors.Is(err, ErrReasoningUnsupported) {
+		t.Fatalf("expected ErrReasoningUnsupported, got %v", err)
+	}
+	if len(server.bodies) != 0 {
+		t.Fatal("no request should be sent")
+	}
+}
+
+func TestModelSupportsReasoning(t *testing.T) {
+	cases := []struct {
+		model            string
+		supported, known bool
+	}{
+		{"kimi-k2", false, true},
+		{"opencode/Kimi-K2-0905", false, true},
+		{"kimi-k2-thinking", true, true},
+		{"gemini-2.0-flash-lite", false, true},
+		{"gemini-2.5-flash", true, false},
+		{"some-new-model", true, false},
+	}
+	for _, tc := range cases {
+		if supported, known := ModelSupportsReasoning(tc.model); supported != tc.supported || known != tc.known {
+			t.Fatalf("%s: want (%v, %v), got (%v, %v)", tc.model, tc.supported, tc.known, supported, known)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	caps := Capabilities()
+	if caps.Version != SDKVersion || len(caps.Endpoints) != 4 {
+		t.Fatalf("unexpected capabilities: %+v", caps)
+	}
+	byEndpoint := map[EndpointType]EndpointCapabilities{}
+	for _, ec := range caps.Endpoints {
+		byEndpoint[ec.Endpoint] = ec
+	}
+	responses := byEndpoint[EndpointResponses]
+	if !slices.Contains(responses.Events, ResponsesEventFunctionCallArgumentsDelta) ||
+		!slices.Contains(responses.Events, "response.function_call_arguments_delta") {
+		t.Fatalf("responses events should list documented and alternative spellings: %v", responses.Events)
+	}
+	if !slices.Contains(byEndpoint[EndpointMessages].Events, MessagesEventContentBlockDelta) || byEndpoint[EndpointChatCompletions].Events != nil {
+		t.Fatalf("events mismatch: %+v", caps.Endpoints)
+	}
+	if slices.Contains(byEndpoint[EndpointModels].Features, FeatureToolStreaming) || !slices.Contains(byEndpoint[EndpointResponses].Features, FeatureServerTools) {
+		t.Fatalf("features mismatch: %+v", caps.Endpoints)
+	}
+
+	// Every alternative spelling parses like the event it stands for.
+	for alias, canonical := range responsesEventAliases {
+		data := `{"type":%q,"delta":"x","arguments":"{}","call_id":"c","response":{"model":"m","usage":{"input_tokens":1}}}`
+		got := ParseNormalizedEvent(makeEvent(EndpointResponses, fmt.Sprintf(data, alias)))
+		want := ParseNormalizedEvent(makeEvent(EndpointResponses, fmt.Sprintf(data, canonical)))
+		if len(got) == 0 || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("%s: want %v, got %v", alias, want, got)
+		}
+	}
+}
+
+func TestFeaturesHeader(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("x-zen-sdk-features"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"hi"}]}`))
+	}))
+	defer server.Close()
+	req := NormalizedRequest{Model: "claude-sonnet-4-5", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+	for _, send := range []bool{false, true} {
+		client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, SendFeaturesHeader: send})
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		if _, err := client.CreateNormalized(testCtx(t), req); err != nil {
+			t.Fatalf("CreateNormalized: %v", err)
+		}
+	}
+	if want := []string{"", "reasoning,tool_streaming,prompt_caching"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("x-zen-sdk-features: want %q, got %q", want, got)
+	}
+}
@@ -5,6 +5,8 @@ import "net/http"
 type Client struct {
 	cfg        Config
 	httpClient *http.Client
+	limiter    *limiter
+	keys       *keyPool
 }
 
 func NewClient(cfg Config) (*Client, error) {
@@ -37,5 +39,81 @@ func NewClient(cfg Config) (*Client, error) {
 	return &Client{
 		cfg:        cfg,
 		httpClient: httpClient,
+		limiter:    newLimiter(cfg.MaxConcurrentRequests),
+		keys:       newKeyPool(cfg),
 	}, nil
 }
+
+// ClientOption overrides part of the configuration of a derived client; see
+// Client.With.
+type ClientOption func(*Config)
+
+// WithRetry replaces the retry policy. A nil Backoff keeps the parent's.
+func WithRetry(retry RetryConfig) ClientOption {
+	return func(cfg *Config) {
+		if retry.Backoff == nil {
+			retry.Backoff = cfg.Retry.Backoff
+		}
+		cfg.Retry = retry
+	}
+}
+
+// WithDefaults replaces the global request defaults (Config.Defaults).
+func WithDefaults(defaults ModelDefaults) ClientOption {
+	return func(cfg *Config) { cfg.Defaults = defaults }
+}
+
+// WithModelDefaults adds or replaces per-model defaults (Config.ModelDefaults).
+func WithModelDefaults(defaults map[string]ModelDefaults) ClientOption {
+	return func(cfg *Config) {
+		for k, v := range defaults {
+			cfg.ModelDefaults[k] = v
+		}
+	}
+}
+
+// WithDefaultHeaders adds headers to Config.DefaultHeaders, replacing values
+// for names already present.
+func WithDefaultHeaders(h http.Header) ClientOption {
+	return func(cfg *Config) {
+		for k, v := range h {
+			cfg.DefaultHeaders[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+		}
+	}
+}
+
+// WithScope replaces the organization and project (Config.Organization and
+// Config.Project) of the derived client.
+func WithScope(s Scope) ClientOption {
+	return func(cfg *Config) { cfg.Organization, cfg.Project = s.Organization, s.Project }
+}
+
+// WithUserAgentSuffix appends " "+suffix to the User-Agent.
+func WithUserAgentSuffix(suffix string) ClientOption {
+	return func(cfg *Config) { cfg.UserAgent += " " + suffix }
+}
+
+// With returns a derived client with opts applied on top of c's
+// configuration. The derived client shares c's http.Client, and therefore its
+// connection pool; http.Client is safe for concurrent use, so parent and
+// derived clients may be used from any number of goroutines. The concurrency
+// limit (Config.MaxConcurrentRequests) and the cooldowns of Config.APIKeys
+// are shared as well. Config maps are
+// copied, so options never affect c.
+func (c *Client) With(opts ...ClientOption) *Client {
+	cfg := c.cfg
+	cfg.DefaultHeaders = cfg.DefaultHeaders.Clone()
+	if cfg.DefaultHeaders == nil {
+		cfg.DefaultHeaders = http.Header{}
+	}
+	modelDefaults := make(map[string]ModelDefaults, len(c.cfg.ModelDefaults))
+	for k, v := range c.cfg.ModelDefaults {
+		modelDefaults[k] = v
+	}
+	cfg.ModelDefaults = modelDefaults
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Client{cfg: cfg, httpClient: c.httpClient, limiter: c.limiter, keys: c.keys}
+}
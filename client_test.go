@@ -0,0 +1,73 @@
+package zen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestClientWith(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Header.Get("X-Caller")] = r.Header.Clone()
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	parent, err := NewClient(Config{
+		APIKey:         "key",
+		BaseURL:        server.URL,
+		DefaultHeaders: http.Header{"X-Caller": {"parent"}},
+		ModelDefaults:  map[string]ModelDefaults{"kimi-": {MaxTokens: ptr(100)}},
+	})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+	child := parent.With(
+		WithDefaultHeaders(http.Header{"x-caller": {"child"}, "Authorization": {"Bearer other"}}),
+		WithUserAgentSuffix("jobs/1"),
+		WithRetry(RetryConfig{MaxRetries: 5}),
+		WithModelDefaults(map[string]ModelDefaults{"kimi-": {MaxTokens: ptr(200)}}),
+	)
+
+	if child.httpClient != parent.httpClient {
+		t.Fatalf("derived client should share the http.Client")
+	}
+	if parent.cfg.DefaultHeaders.Get("X-Caller") != "parent" || *parent.cfg.ModelDefaults["kimi-"].MaxTokens != 100 {
+		t.Fatalf("parent config must not change")
+	}
+	if parent.cfg.Retry.MaxRetries != 0 || child.cfg.Retry.MaxRetries != 5 || child.cfg.Retry.Backoff == nil {
+		t.Fatalf("retry override mismatch")
+	}
+
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		for _, c := range []*Client{parent, child} {
+			wg.Add(1)
+			go func(c *Client) {
+				defer wg.Done()
+				if _, err := drainStreamEvents(context.Background(), c, req); err != nil {
+					t.Errorf("stream error: %v", err)
+				}
+			}(c)
+		}
+	}
+	wg.Wait()
+
+	if ua := seen["parent"].Get("User-Agent"); ua != "go-opencode-zen-sdk/0.1" {
+		t.Fatalf("parent user agent changed: %q", ua)
+	}
+	if ua := seen["child"].Get("User-Agent"); ua != "go-opencode-zen-sdk/0.1 jobs/1" {
+		t.Fatalf("child user agent mismatch: %q", ua)
+	}
+	if auth := seen["child"].Get("Authorization"); auth != "Bearer key" {
+		t.Fatalf("default headers must not override auth, got %q", auth)
+	}
+}
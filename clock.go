@@ -0,0 +1,46 @@
+package zen
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the time source behind retry backoff and stream timeouts. The
+// default uses the time package; tests can set Config.Clock to a fake such
+// as zentest.FakeClock to run retries without waiting.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	// Sleep waits for d, returning ctx.Err() early if ctx is done first.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// Timer is the part of *time.Timer a Clock hands out.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
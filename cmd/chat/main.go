@@ -0,0 +1,313 @@
+// Command chat is an interactive streaming chat client for OpenCode Zen.
+//
+// Reasoning is printed dimmed as it streams, followed by the reply. The model
+// can call two small demo tools (add, now). Lines starting with a slash are
+// commands:
+//
+//	/model <id>  switch models for the following turns
+//	/reset       clear the conversation
+//	/save        write the transcript now
+//	/exit        save and quit (also on EOF)
+//
+// Ctrl-C interrupts the reply in progress, dropping the turn; at the prompt
+// it saves and quits. A turn that fails is dropped as well.
+//
+// With -transcript, the conversation is loaded from the file on start (if it
+// exists) and written back on exit, one JSON-encoded NormalizedMessage per line.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+const (
+	dim   = "\x1b[2m"
+	reset = "\x1b[0m"
+
+	maxToolSteps = 8
+)
+
+type chat struct {
+	client *zen.Client
+	conv   *zen.Conversation
+	tools  *zen.ToolSet
+	out    *printer
+	model  string
+	system string
+}
+
+func main() {
+	model := flag.String("model", "gpt-5.1", "model id")
+	system := flag.String("system", "You are a helpful assistant. Use the provided tools when they help.", "system prompt")
+	transcript := flag.String("transcript", "", "JSONL transcript to load on start and save on exit")
+	flag.Parse()
+
+	apiKey := os.Getenv("OPENCODE_API_KEY")
+	if apiKey == "" {
+		fmt.Fprintln(os.Stderr, "OPENCODE_API_KEY is required")
+		os.Exit(1)
+	}
+
+	client, err := zen.NewClient(zen.Config{APIKey: apiKey})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create client: %v\n", err)
+		os.Exit(1)
+	}
+
+	c := &chat{client: client, conv: zen.NewConversation(client), tools: demoTools(), out: &printer{}, model: *model, system: *system}
+	if *transcript != "" {
+		msgs, err := loadTranscript(*transcript)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "load transcript: %v\n", err)
+			os.Exit(1)
+		}
+		c.conv.Messages = msgs
+		if len(msgs) > 0 {
+			fmt.Printf("loaded %d messages from %s\n", len(msgs), *transcript)
+		}
+	}
+
+	c.repl(*transcript)
+	if *transcript != "" {
+		if err := saveTranscript(*transcript, c.conv.Messages); err != nil {
+			fmt.Fprintf(os.Stderr, "save transcript: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func (c *chat) repl(transcript string) {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	// Ctrl-C interrupts the turn in progress; at the prompt it quits.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	for {
+		fmt.Printf("[%s] > ", c.model)
+		var line string
+		select {
+		case <-interrupts:
+			fmt.Println()
+			return
+		case l, ok := <-lines:
+			if !ok {
+				fmt.Println()
+				return
+			}
+			line = strings.TrimSpace(l)
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "/") {
+			cmd, arg, _ := strings.Cut(line, " ")
+			switch cmd {
+			case "/exit", "/quit":
+				return
+			case "/model":
+				if arg = strings.TrimSpace(arg); arg == "" {
+					fmt.Println("usage: /model <id>")
+					continue
+				}
+				c.model = arg
+			case "/reset":
+				c.conv = zen.NewConversation(c.client)
+			case "/save":
+				if transcript == "" {
+					fmt.Println("no -transcript file given")
+					continue
+				}
+				if err := saveTranscript(transcript, c.conv.Messages); err != nil {
+					fmt.Fprintf(os.Stderr, "save transcript: %v\n", err)
+				}
+			default:
+				fmt.Println("commands: /model <id>, /reset, /save, /exit")
+			}
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-interrupts:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		err := c.runTurn(ctx, line)
+		interrupted := ctx.Err() != nil
+		cancel()
+		switch {
+		case err == nil:
+		case interrupted:
+			fmt.Fprintln(os.Stderr, "interrupted")
+		default:
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			var apiErr *zen.APIError
+			if errors.As(err, &apiErr) && len(apiErr.Body) > 0 {
+				fmt.Fprintf(os.Stderr, "api error body: %s\n", string(apiErr.Body))
+			}
+		}
+	}
+}
+
+// runTurn sends line and streams the model's reply, executing tool calls
+// until the model answers without calling a tool. On error the whole turn,
+// line included, is dropped from the conversation.
+func (c *chat) runTurn(ctx context.Context, line string) (err error) {
+	before := len(c.conv.Messages)
+	defer func() {
+		if err != nil {
+			c.conv.Messages = c.conv.Messages[:before]
+		}
+	}()
+
+	msgs := []zen.NormalizedMessage{{Role: "user", Content: line}}
+	for step := 0; step < maxToolSteps; step++ {
+		resp, err := c.conv.SendWithHandlers(ctx, zen.NormalizedRequest{
+			Model:      c.model,
+			System:     c.system,
+			Messages:   msgs,
+			Tools:      c.tools.Tools(),
+			ToolChoice: &zen.NormalizedToolChoice{Type: zen.ToolChoiceAuto},
+		}, c.out.echo)
+		c.out.finish()
+		if err != nil {
+			return err
+		}
+
+		if len(resp.ToolCalls) == 0 {
+			if resp.InputTokens > 0 || resp.OutputTokens > 0 {
+				fmt.Printf("%s[usage] in=%d out=%d%s\n", dim, resp.InputTokens, resp.OutputTokens, reset)
+			}
+			return nil
+		}
+
+		msgs = c.tools.ExecuteAll(ctx, resp.ToolCalls)
+		for _, result := range msgs {
+			fmt.Printf("%s[tool:%s] %s%s\n", dim, result.FunctionName, result.Content, reset)
+		}
+	}
+	return fmt.Errorf("no final answer after %d tool steps", maxToolSteps)
+}
+
+// printer echoes reasoning dimmed and text as they stream.
+type printer struct {
+	inReasoning bool
+}
+
+// echo prints d.
+func (p *printer) echo(d zen.NormalizedDelta) error {
+	switch d.Type {
+	case zen.DeltaReasoning:
+		if !p.inReasoning {
+			fmt.Print(dim)
+			p.inReasoning = true
+		}
+		fmt.Print(d.Content)
+	case zen.DeltaText:
+		if p.inReasoning {
+			fmt.Print(reset + "\n")
+			p.inReasoning = false
+		}
+		fmt.Print(d.Content)
+	}
+	return nil
+}
+
+// finish ends the output of a reply.
+func (p *printer) finish() {
+	if p.inReasoning {
+		fmt.Print(reset)
+		p.inReasoning = false
+	}
+	fmt.Println()
+}
+
+func demoTools() *zen.ToolSet {
+	set := zen.NewToolSet()
+	set.ValidateArguments = true
+	set.Add(zen.NormalizedTool{
+		Name:        "add",
+		Description: "Adds two numbers",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"a":{"type":"number"},"b":{"type":"number"}},"required":["a","b"]}`),
+	}, func(_ context.Context, args json.RawMessage) (string, error) {
+		var input struct {
+			A float64 `json:"a"`
+			B float64 `json:"b"`
+		}
+		if err := json.Unmarshal(args, &input); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%g", input.A+input.B), nil
+	})
+	set.Add(zen.NormalizedTool{
+		Name:        "now",
+		Description: "Returns the current local date and time",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{}}`),
+	}, func(context.Context, json.RawMessage) (string, error) {
+		return time.Now().Format(time.RFC1123), nil
+	})
+	return set
+}
+
+func loadTranscript(path string) ([]zen.NormalizedMessage, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var msgs []zen.NormalizedMessage
+	dec := json.NewDecoder(f)
+	for {
+		var msg zen.NormalizedMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return msgs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: message %d: %w", path, len(msgs)+1, err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func saveTranscript(path string, msgs []zen.NormalizedMessage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, msg := range msgs {
+		if err := enc.Encode(msg); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -34,7 +35,9 @@ func main() {
 		os.Exit(1)
 	}
 
-	client, err := zen.NewClient(zen.Config{APIKey: apiKey})
+	// Reasoning probes fail fast for models known not to reason, so the
+	// report tells "model can't" apart from "SDK didn't ask".
+	client, err := zen.NewClient(zen.Config{APIKey: apiKey, UnsupportedReasoning: zen.ReasoningFail})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create client: %v\n", err)
 		os.Exit(1)
@@ -81,29 +84,29 @@ func main() {
 
 func testStreamEvents(ctx context.Context, client *zen.Client, modelID string) testResult {
 	start := time.Now()
-	endpoint := routeForModel(modelID)
+	endpoint := string(zen.RouteForModel(modelID))
 	req := zen.NormalizedRequest{
 		Model:    modelID,
 		Messages: []zen.NormalizedMessage{{Role: "user", Content: "Say ok"}},
 	}
-	reqBody, _ := json.Marshal(req)
+	reqBody := requestBody(req)
 
 	eventCh, errCh, err := client.StreamEvents(ctx, req)
-	return drainUnifiedStream(modelID, endpoint, string(reqBody), eventCh, errCh, err, start)
+	return drainUnifiedStream(modelID, endpoint, reqBody, eventCh, errCh, err, start)
 }
 
 func testStreamParsed(ctx context.Context, client *zen.Client, modelID string) testResult {
 	start := time.Now()
-	endpoint := routeForModel(modelID)
+	endpoint := string(zen.RouteForModel(modelID))
 	req := zen.NormalizedRequest{
 		Model:    modelID,
 		Messages: []zen.NormalizedMessage{{Role: "user", Content: "Say ok"}},
 	}
-	reqBody, _ := json.Marshal(req)
+	reqBody := requestBody(req)
 
 	deltaCh, errCh, err := client.Stream(ctx, req)
 	if err != nil {
-		return makeResult(modelID, endpoint, true, string(reqBody), "", err, time.Since(start), 0, 0)
+		return makeResult(modelID, endpoint, true, reqBody, "", err, time.Since(start), 0, 0)
 	}
 
 	var textBuf, reasoningBuf strings.Builder
@@ -123,11 +126,11 @@ func testStreamParsed(ctx context.Context, client *zen.Client, modelID string) t
 		}
 	}
 	if streamErr := <-errCh; streamErr != nil {
-		return makeResult(modelID, endpoint, true, string(reqBody), "", streamErr, time.Since(start), 0, 0)
+		return makeResult(modelID, endpoint, true, reqBody, "", streamErr, time.Since(start), 0, 0)
 	}
 
 	resp := fmt.Sprintf("deltas=%d text=%s reasoning=%s", count, truncate(textBuf.String(), 40), truncate(reasoningBuf.String(), 40))
-	return makeResult(modelID, endpoint, true, string(reqBody), resp, nil, time.Since(start), inTok, outTok)
+	return makeResult(modelID, endpoint, true, reqBody, resp, nil, time.Since(start), inTok, outTok)
 }
 
 // toolHistory returns a pre-built two-turn tool-use conversation:
@@ -149,7 +152,7 @@ func toolHistory() []zen.NormalizedMessage {
 
 func testToolHistoryStream(ctx context.Context, client *zen.Client, modelID string) testResult {
 	start := time.Now()
-	endpoint := routeForModel(modelID)
+	endpoint := string(zen.RouteForModel(modelID))
 	req := zen.NormalizedRequest{
 		Model:    modelID,
 		System:   "You are a calculator assistant. When given a tool result, use it to answer the user.",
@@ -162,11 +165,11 @@ func testToolHistoryStream(ctx context.Context, client *zen.Client, modelID stri
 			},
 		},
 	}
-	reqBody, _ := json.Marshal(req)
+	reqBody := requestBody(req)
 
 	deltaCh, errCh, err := client.Stream(ctx, req)
 	if err != nil {
-		return makeResult(modelID, endpoint, true, string(reqBody), "", err, time.Since(start), 0, 0)
+		return makeResult(modelID, endpoint, true, reqBody, "", err, time.Since(start), 0, 0)
 	}
 
 	var textBuf strings.Builder
@@ -184,60 +187,45 @@ func testToolHistoryStream(ctx context.Context, client *zen.Client, modelID stri
 		}
 	}
 	if streamErr := <-errCh; streamErr != nil {
-		return makeResult(modelID, endpoint, true, string(reqBody), "", streamErr, time.Since(start), 0, 0)
+		return makeResult(modelID, endpoint, true, reqBody, "", streamErr, time.Since(start), 0, 0)
 	}
 
 	resp := fmt.Sprintf("deltas=%d text=%s", count, truncate(textBuf.String(), 60))
-	return makeResult(modelID, endpoint, true, string(reqBody), resp, nil, time.Since(start), inTok, outTok)
+	return makeResult(modelID, endpoint, true, reqBody, resp, nil, time.Since(start), inTok, outTok)
 }
 
 func testReasoningStream(ctx context.Context, client *zen.Client, modelID string) testResult {
 	start := time.Now()
-	endpoint := routeForModel(modelID)
+	endpoint := string(zen.RouteForModel(modelID))
 	req := zen.NormalizedRequest{
 		Model:     modelID,
 		Messages:  []zen.NormalizedMessage{{Role: "user", Content: "What is 2 + 2? Think step by step."}},
 		Reasoning: &zen.NormalizedReasoning{Effort: "low"},
 	}
-	reqBody, _ := json.Marshal(req)
+	reqBody := requestBody(req)
 
-	deltaCh, errCh, err := client.Stream(ctx, req)
-	if err != nil {
-		return makeResult(modelID, endpoint, true, string(reqBody), "", err, time.Since(start), 0, 0)
+	resp, err := client.CollectStream(ctx, req)
+	if errors.Is(err, zen.ErrReasoningUnsupported) {
+		return makeResult(modelID, endpoint, true, reqBody, "skipped: model does not support reasoning", nil, time.Since(start), 0, 0)
 	}
-
-	var textBuf, reasoningBuf strings.Builder
-	var deltaCount, inTok, outTok int
-	for d := range deltaCh {
-		deltaCount++
-		switch d.Type {
-		case zen.DeltaText:
-			textBuf.WriteString(d.Content)
-		case zen.DeltaReasoning:
-			reasoningBuf.WriteString(d.Content)
-		case zen.DeltaUsage:
-			if d.InputTokens > inTok {
-				inTok = d.InputTokens
-			}
-			outTok += d.OutputTokens
-		}
-	}
-	if streamErr := <-errCh; streamErr != nil {
-		return makeResult(modelID, endpoint, true, string(reqBody), "", streamErr, time.Since(start), 0, 0)
+	if err != nil {
+		return makeResult(modelID, endpoint, true, reqBody, "", err, time.Since(start), 0, 0)
 	}
 
-	if reasoningBuf.Len() == 0 {
-		return makeResult(modelID, endpoint, true, string(reqBody),
-			fmt.Sprintf("deltas=%d text=%s", deltaCount, truncate(textBuf.String(), 60)),
+	summary := resp.Summary
+	if !summary.HasReasoning {
+		return makeResult(modelID, endpoint, true, reqBody,
+			fmt.Sprintf("text_deltas=%d text=%s", summary.TextDeltas, truncate(resp.Text, 60)),
 			fmt.Errorf("model %s: stream contained no reasoning/thinking deltas", modelID),
-			time.Since(start), inTok, outTok)
-	}
-	return makeResult(modelID, endpoint, true, string(reqBody),
-		fmt.Sprintf("deltas=%d reasoning=%s text=%s",
-			deltaCount,
-			truncate(reasoningBuf.String(), 40),
-			truncate(textBuf.String(), 40)),
-		nil, time.Since(start), inTok, outTok)
+			time.Since(start), resp.InputTokens, resp.OutputTokens)
+	}
+	return makeResult(modelID, endpoint, true, reqBody,
+		fmt.Sprintf("reasoning_deltas=%d text_deltas=%d reasoning=%s text=%s",
+			summary.ReasoningDeltas,
+			summary.TextDeltas,
+			truncate(resp.Reasoning, 40),
+			truncate(resp.Text, 40)),
+		nil, time.Since(start), resp.InputTokens, resp.OutputTokens)
 }
 
 // drainUnifiedStream consumes a UnifiedEvent channel and builds a testResult.
@@ -285,23 +273,6 @@ func makeResult(modelID, endpoint string, stream bool, req, resp string, err err
 	return r
 }
 
-func routeForModel(model string) string {
-	m := strings.ToLower(strings.TrimSpace(model))
-	if strings.HasPrefix(m, "opencode/") {
-		m = strings.TrimPrefix(m, "opencode/")
-	}
-	switch {
-	case strings.HasPrefix(m, "gpt-"):
-		return "responses"
-	case strings.HasPrefix(m, "claude-"):
-		return "messages"
-	case strings.HasPrefix(m, "gemini-"):
-		return "models"
-	default:
-		return "chat_completions"
-	}
-}
-
 func printResult(r testResult) {
 	status := "✓"
 	if !r.Success {
@@ -315,6 +286,16 @@ func printResult(r testResult) {
 	fmt.Printf("  %s %-25s [%-15s] [%-10s] %-20s %v\n", status, r.Model, r.Endpoint, mode, usage, r.Latency)
 }
 
+// requestBody returns the provider body streamed for req, for the report.
+func requestBody(req zen.NormalizedRequest) string {
+	req.Stream = true
+	body, err := zen.MarshalForEndpoint(req, zen.EndpointAuto)
+	if err != nil {
+		return err.Error()
+	}
+	return string(body)
+}
+
 func truncate(s string, max int) string {
 	if len(s) <= max {
 		return s
@@ -0,0 +1,434 @@
+package zen
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// NormalizedResponse is a fully assembled, endpoint-agnostic result built from
+// the deltas of a completed stream.
+type NormalizedResponse struct {
+	Text string
+	// Reasoning is the text of all ReasoningBlocks concatenated.
+	Reasoning string
+	// ReasoningBlocks lists the reasoning segments in order, e.g. the
+	// reasoning before and after a tool call in one turn.
+	ReasoningBlocks []ReasoningBlock
+	ToolCalls       []StreamToolCall
+	InputTokens     int
+	OutputTokens    int
+	// Parts is the reply as text and ContentPartGemini parts in order when
+	// it contains Gemini parts without a normalized form (code execution),
+	// to be sent back as the Parts of the assistant message; nil otherwise.
+	Parts []NormalizedContentPart
+	// Audio is the audio output, when the request asked for one.
+	Audio *NormalizedAudio
+	// ProviderState lists the output items to send back verbatim as the
+	// ProviderState of the assistant message, such as Responses reasoning
+	// items with encrypted content; nil when there are none.
+	ProviderState []json.RawMessage
+	// ServerToolCalls lists the built-in tools the provider ran (Responses
+	// web_search and file_search) in order, each in its last reported state.
+	ServerToolCalls []ServerToolCall
+	// ReasoningTokens is the reported reasoning usage; it stays zero for
+	// providers that do not report it (Anthropic).
+	ReasoningTokens int
+	// CachedInputTokens and CacheWriteInputTokens are the input tokens
+	// read from and written to the prompt cache; see
+	// NormalizedDelta.CachedInputTokens.
+	CachedInputTokens     int
+	CacheWriteInputTokens int
+	// ServiceTier is the tier reported by the provider, if any.
+	ServiceTier string
+	// Model is the model the provider reports having served, if any.
+	Model string
+	// FinishReason and StopSequence are the last stop details reported; see
+	// NormalizedDelta.FinishReason.
+	FinishReason string
+	StopSequence string
+	// StopReason and FinishDetails are those of the last stop reported;
+	// see NormalizedDelta.StopReason.
+	StopReason    StopReason
+	FinishDetails json.RawMessage
+	// FirstDeltaAt and LastDeltaAt are the ReceivedAt times of the first and
+	// last delta. They are zero when no delta carried a timestamp.
+	FirstDeltaAt time.Time
+	LastDeltaAt  time.Time
+	Summary      StreamSummary
+	// Choices lists every candidate in index order when the provider
+	// returned more than one (Gemini with NormalizedRequest.CandidateCount).
+	// The fields above then describe the first candidate.
+	Choices []NormalizedChoice
+	// Info is set by CollectStream and left zero by CollectDeltas.
+	Info StreamInfo
+	// Stats holds the payload sizes of the request; like Info it is left
+	// zero by CollectDeltas.
+	Stats StreamStats
+}
+
+// NormalizedAudio is assembled audio output. Data holds the decoded audio
+// in the requested format; chunks that are not valid base64 are skipped.
+type NormalizedAudio struct {
+	ID         string
+	Data       []byte
+	Transcript string
+}
+
+// NormalizedChoice is one candidate of a response with several.
+type NormalizedChoice struct {
+	Index        int
+	Text         string
+	Reasoning    string
+	ToolCalls    []StreamToolCall
+	FinishReason string
+}
+
+// StreamSummary holds aggregate counts over the deltas of a stream.
+type StreamSummary struct {
+	TextDeltas      int
+	TextBytes       int
+	ReasoningDeltas int
+	ReasoningBytes  int
+	HasReasoning    bool
+	// ToolCallsBegun counts distinct tool calls seen; ToolCallsCompleted
+	// those whose arguments form valid JSON.
+	ToolCallsBegun     int
+	ToolCallsCompleted int
+	// ToolCallsTruncated counts calls dropped for exceeding the argument
+	// size limit; they are not part of ToolCalls.
+	ToolCallsTruncated int
+}
+
+// StreamError is returned by CollectDeltas and CollectStream when a stream
+// fails after it started. Partial holds everything assembled before the
+// failure; ToolCalls only contains calls whose arguments had completed.
+type StreamError struct {
+	Err     error
+	Partial *NormalizedResponse
+	// LastEventID is the SSE id of the last event received, when the
+	// provider sends ids. CollectDeltas cannot see event ids and leaves it
+	// empty.
+	LastEventID string
+}
+
+func (e *StreamError) Error() string {
+	return "zen: stream interrupted: " + e.Err.Error()
+}
+
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
+type responseCollector struct {
+	text, reasoning strings.Builder
+	reasoningBlocks []ReasoningBlock
+	// reasoningOpen reports whether the last of reasoningBlocks still
+	// takes reasoning deltas of its block.
+	reasoningOpen bool
+	audio         *NormalizedAudio
+	// parts holds the reply as content parts; it is only returned once
+	// hasRawParts is set.
+	parts                 []NormalizedContentPart
+	hasRawParts           bool
+	providerState         []json.RawMessage
+	serverTools           []ServerToolCall
+	accumulator           *ToolCallAccumulator
+	inputTokens           int
+	outputTokens          int
+	reasoningTokens       int
+	cachedInputTokens     int
+	cacheWriteInputTokens int
+	serviceTier           string
+	model                 string
+	finishReason          string
+	stopSequence          string
+	stopReason            StopReason
+	finishDetails         json.RawMessage
+	first, last           time.Time
+	summary               StreamSummary
+	// choices collects candidates other than the first.
+	choices map[int]*responseCollector
+	// suppressReasoning drops DeltaReasoning (NormalizedRequest.SuppressReasoning).
+	suppressReasoning bool
+}
+
+// newResponseCollector returns a collector that does not limit tool
+// arguments itself; streams apply the limit and report it with DeltaError.
+func newResponseCollector() *responseCollector {
+	acc := NewToolCallAccumulator()
+	acc.MaxArgumentBytes = -1
+	return &responseCollector{accumulator: acc}
+}
+
+func (rc *responseCollector) add(d NormalizedDelta) {
+	if rc.suppressReasoning && d.Type == DeltaReasoning {
+		return
+	}
+	if d.ServiceTier != "" {
+		rc.serviceTier = d.ServiceTier
+	}
+	if d.Model != "" {
+		rc.model = d.Model
+	}
+	if d.FinishReason != "" {
+		rc.finishReason = d.FinishReason
+		rc.stopSequence = d.StopSequence
+		rc.stopReason = d.StopReason
+		rc.finishDetails = d.FinishDetails
+	}
+	if !d.ReceivedAt.IsZero() {
+		if rc.first.IsZero() {
+			rc.first = d.ReceivedAt
+		}
+		rc.last = d.ReceivedAt
+	}
+	if d.ChoiceIndex > 0 {
+		if rc.choices == nil {
+			rc.choices = map[int]*responseCollector{}
+		}
+		if rc.choices[d.ChoiceIndex] == nil {
+			rc.choices[d.ChoiceIndex] = newResponseCollector()
+		}
+		sub := rc.choices[d.ChoiceIndex]
+		d.ChoiceIndex = 0
+		sub.add(d)
+		return
+	}
+	if endsReasoning(d.Type) {
+		rc.reasoningOpen = false
+	}
+	switch d.Type {
+	case DeltaReasoningBegin:
+		rc.reasoningBlocks = append(rc.reasoningBlocks, ReasoningBlock{Index: d.BlockIndex})
+		rc.reasoningOpen = true
+	case DeltaReasoningDone:
+		rc.reasoningOpen = false
+	case DeltaText:
+		rc.text.WriteString(d.Content)
+		if last := len(rc.parts) - 1; last >= 0 && rc.parts[last].Type == ContentPartText {
+			rc.parts[last].Text += d.Content
+		} else {
+			rc.parts = append(rc.parts, NormalizedContentPart{Type: ContentPartText, Text: d.Content})
+		}
+		rc.summary.TextDeltas++
+		rc.summary.TextBytes += len(d.Content)
+	case DeltaReasoning:
+		last := len(rc.reasoningBlocks) - 1
+		if !rc.reasoningOpen || rc.reasoningBlocks[last].Index != d.BlockIndex {
+			rc.reasoningBlocks = append(rc.reasoningBlocks, ReasoningBlock{Index: d.BlockIndex})
+			rc.reasoningOpen = true
+			last++
+		}
+		rc.reasoningBlocks[last].Text += d.Content
+		rc.reasoning.WriteString(d.Content)
+		rc.summary.ReasoningDeltas++
+		rc.summary.ReasoningBytes += len(d.Content)
+		rc.summary.HasReasoning = true
+	case DeltaGeminiPart:
+		rc.parts = append(rc.parts, NormalizedContentPart{Type: ContentPartGemini, Raw: d.Part})
+		rc.hasRawParts = true
+	case DeltaProviderState:
+		rc.providerState = append(rc.providerState, d.Part)
+	case DeltaAudio:
+		if rc.audio == nil {
+			rc.audio = &NormalizedAudio{}
+		}
+		if d.AudioID != "" {
+			rc.audio.ID = d.AudioID
+		}
+		if data, err := base64.StdEncoding.DecodeString(d.AudioData); err == nil {
+			rc.audio.Data = append(rc.audio.Data, data...)
+		}
+		rc.audio.Transcript += d.AudioTranscript
+	case DeltaServerToolCall:
+		if d.ServerToolCall != nil {
+			rc.addServerToolCall(*d.ServerToolCall)
+		}
+	case DeltaToolCallBegin, DeltaToolCallArgumentsDelta, DeltaToolCallDone, DeltaError:
+		rc.accumulator.Apply(d)
+	case DeltaUsage:
+		// Providers report cumulative counts (Gemini repeats usageMetadata
+		// on every chunk), so keep the largest value seen rather than summing.
+		if d.InputTokens > rc.inputTokens {
+			rc.inputTokens = d.InputTokens
+		}
+		if d.OutputTokens > rc.outputTokens {
+			rc.outputTokens = d.OutputTokens
+		}
+		if d.ReasoningTokens > rc.reasoningTokens {
+			rc.reasoningTokens = d.ReasoningTokens
+		}
+		if d.CachedInputTokens > rc.cachedInputTokens {
+			rc.cachedInputTokens = d.CachedInputTokens
+		}
+		if d.CacheWriteInputTokens > rc.cacheWriteInputTokens {
+			rc.cacheWriteInputTokens = d.CacheWriteInputTokens
+		}
+	}
+}
+
+// addServerToolCall records call, merging it into an earlier report of the
+// same call.
+func (rc *responseCollector) addServerToolCall(call ServerToolCall) {
+	for i := range rc.serverTools {
+		prev := &rc.serverTools[i]
+		if prev.ID != call.ID {
+			continue
+		}
+		if call.Status != "" {
+			prev.Status = call.Status
+		}
+		if len(call.Queries) > 0 {
+			prev.Queries = call.Queries
+		}
+		if call.Results != nil {
+			prev.Results = call.Results
+		}
+		return
+	}
+	rc.serverTools = append(rc.serverTools, call)
+}
+
+// response assembles the result. With partial set, tool calls whose
+// arguments are not yet valid JSON are left out.
+func (rc *responseCollector) response(partial bool) *NormalizedResponse {
+	calls := rc.accumulator.CompleteCalls()
+	summary := rc.summary
+	summary.ToolCallsTruncated = len(rc.accumulator.Truncated())
+	summary.ToolCallsBegun = len(calls) + summary.ToolCallsTruncated
+	complete := calls[:0]
+	for _, call := range calls {
+		if len(call.Arguments) == 0 || json.Valid(call.Arguments) {
+			summary.ToolCallsCompleted++
+			complete = append(complete, call)
+		} else if !partial {
+			complete = append(complete, call)
+		}
+	}
+	if len(complete) == 0 {
+		complete = nil
+	}
+
+	var choices []NormalizedChoice
+	if len(rc.choices) > 0 {
+		choices = append(choices, NormalizedChoice{Text: rc.text.String(), Reasoning: rc.reasoning.String(), ToolCalls: complete, FinishReason: rc.finishReason})
+		for _, index := range sortedKeys(rc.choices) {
+			c := rc.choices[index].response(partial)
+			choices = append(choices, NormalizedChoice{Index: index, Text: c.Text, Reasoning: c.Reasoning, ToolCalls: c.ToolCalls, FinishReason: c.FinishReason})
+		}
+	}
+
+	var parts []NormalizedContentPart
+	if rc.hasRawParts {
+		parts = rc.parts
+	}
+	return &NormalizedResponse{
+		Text:                  rc.text.String(),
+		Parts:                 parts,
+		Reasoning:             rc.reasoning.String(),
+		ReasoningBlocks:       rc.reasoningBlocks,
+		ToolCalls:             complete,
+		Audio:                 rc.audio,
+		ProviderState:         rc.providerState,
+		ServerToolCalls:       rc.serverTools,
+		InputTokens:           rc.inputTokens,
+		OutputTokens:          rc.outputTokens,
+		ReasoningTokens:       rc.reasoningTokens,
+		CachedInputTokens:     rc.cachedInputTokens,
+		CacheWriteInputTokens: rc.cacheWriteInputTokens,
+		ServiceTier:           rc.serviceTier,
+		Model:                 rc.model,
+		FinishReason:          rc.finishReason,
+		StopSequence:          rc.stopSequence,
+		StopReason:            rc.stopReason,
+		FinishDetails:         rc.finishDetails,
+		FirstDeltaAt:          rc.first,
+		LastDeltaAt:           rc.last,
+		Summary:               summary,
+		Choices:               choices,
+	}
+}
+
+// CollectDeltas drains a delta channel (as returned by Client.Stream) and then
+// reads its error channel exactly once, assembling the result into a
+// NormalizedResponse. On a stream error the partially assembled response is
+// returned together with a *StreamError carrying the same response.
+func CollectDeltas(deltas <-chan NormalizedDelta, errs <-chan error) (*NormalizedResponse, error) {
+	rc := newResponseCollector()
+	var failed error
+	for d := range deltas {
+		if err := streamFailure(d); err != nil {
+			failed = err
+		}
+		rc.add(d)
+	}
+
+	err := <-errs
+	if err == nil {
+		err = failed
+	}
+	if err != nil {
+		resp := rc.response(true)
+		return resp, &StreamError{Err: err, Partial: resp}
+	}
+	return rc.response(false), nil
+}
+
+// CollectStream runs req as a stream and returns the assembled response.
+// Errors before the stream starts (e.g. *APIError) are returned as is; later
+// failures are returned as *StreamError.
+func (c *Client) CollectStream(ctx context.Context, req NormalizedRequest) (*NormalizedResponse, error) {
+	return c.withToolChoiceEmulation(ctx, req, c.collectStream)
+}
+
+func (c *Client) collectStream(ctx context.Context, req NormalizedRequest) (*NormalizedResponse, error) {
+	return c.collectStreamWith(ctx, req)
+}
+
+func (c *Client) collectStreamWith(ctx context.Context, req NormalizedRequest, handlers ...func(NormalizedDelta) error) (*NormalizedResponse, error) {
+	h, err := c.OpenStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer h.Close()
+	return collectHandle(h, req.SuppressReasoning, handlers...)
+}
+
+// collectHandle drains h, which runs every stage of the stream pipeline,
+// into a response carrying h's Info and Stats. Each delta is also passed to
+// handlers; the first error one returns stops the stream and is returned
+// as is, with the partial response.
+func collectHandle(h *StreamHandle, suppressReasoning bool, handlers ...func(NormalizedDelta) error) (*NormalizedResponse, error) {
+	rc := newResponseCollector()
+	rc.suppressReasoning = suppressReasoning
+	var handlerErr error
+deltas:
+	for d := range h.Deltas {
+		rc.add(d)
+		for _, handle := range handlers {
+			if handlerErr = handle(d); handlerErr != nil {
+				break deltas
+			}
+		}
+	}
+	if handlerErr != nil {
+		h.pump.close()
+		resp := rc.response(true)
+		resp.Info = h.Info
+		resp.Stats = h.Info.stats()
+		return resp, handlerErr
+	}
+	err := <-h.Errs
+	h.pump.done, h.pump.err = true, err
+
+	resp := rc.response(err != nil)
+	resp.Info = h.Info
+	resp.Stats = h.Info.stats()
+	if err != nil {
+		return resp, &StreamError{Err: err, Partial: resp, LastEventID: h.lastEventID}
+	}
+	return resp, nil
+}
@@ -0,0 +1,291 @@
+package zen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectStreamPartialOnFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("id: 1\ndata: {\"choices\":[{\"delta\":{\"reasoning_content\":\"hmm\"}}]}\n\n" +
+			"id: 2\ndata: {\"choices\":[{\"delta\":{\"content\":\"The answer \"}}]}\n\n" +
+			"data: {\"choices\":[{\"delta\":{\"content\":\"is\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		// Drop the connection mid-body so the client sees a truncated stream.
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+
+	resp, err := client.CollectStream(testCtx(t), NormalizedRequest{
+		Model:    "kimi-k2",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	})
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("expected *StreamError, got %T: %v", err, err)
+	}
+	if streamErr.Err == nil || errors.Unwrap(err) != streamErr.Err {
+		t.Fatalf("StreamError should wrap the underlying error")
+	}
+	if !errors.Is(err, ErrStreamDisconnected) {
+		t.Fatalf("a dropped connection should be classified as ErrStreamDisconnected, got %v", err)
+	}
+	if streamErr.Partial.Text != "The answer is" || streamErr.Partial.Reasoning != "hmm" {
+		t.Fatalf("partial mismatch: %+v", streamErr.Partial)
+	}
+	if resp != streamErr.Partial {
+		t.Fatalf("returned response should be the partial result")
+	}
+	if streamErr.LastEventID != "2" {
+		t.Fatalf("last event id: want 2, got %q", streamErr.LastEventID)
+	}
+}
+
+func TestCollectDeltasSummary(t *testing.T) {
+	deltas := make(chan NormalizedDelta, 16)
+	errs := make(chan error, 1)
+	for _, d := range []NormalizedDelta{
+		{Type: DeltaReasoning, Content: "think"},
+		{Type: DeltaText, Content: "Hello"},
+		{Type: DeltaText, Content: ", world"},
+		{Type: DeltaToolCallBegin, ToolCallIndex: 0, ToolCallID: "a", ToolCallName: "one"},
+		{Type: DeltaToolCallArgumentsDelta, ToolCallIndex: 0, ArgumentsDelta: `{"x":1}`},
+		{Type: DeltaToolCallBegin, ToolCallIndex: 1, ToolCallID: "b", ToolCallName: "two"},
+		{Type: DeltaToolCallArgumentsDelta, ToolCallIndex: 1, ArgumentsDelta: `{"y":`},
+	} {
+		deltas <- d
+	}
+	close(deltas)
+	errs <- errors.New("connection reset")
+	close(errs)
+
+	resp, err := CollectDeltas(deltas, errs)
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("expected *StreamError, got %v", err)
+	}
+	want := StreamSummary{
+		TextDeltas:         2,
+		TextBytes:          12,
+		ReasoningDeltas:    1,
+		ReasoningBytes:     5,
+		HasReasoning:       true,
+		ToolCallsBegun:     2,
+		ToolCallsCompleted: 1,
+	}
+	if resp.Summary != want {
+		t.Fatalf("summary mismatch:\nwant %+v\ngot  %+v", want, resp.Summary)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "a" {
+		t.Fatalf("partial result should only keep completed tool calls, got %+v", resp.ToolCalls)
+	}
+}
+
+func TestSuppressReasoning(t *testing.T) {
+	server, client := newSSETestServer(t, "data: {\"choices\":[{\"delta\":{\"reasoning_content\":\"secret plan\"}}]}\n\n"+
+		"data: {\"choices\":[{\"delta\":{\"content\":\"Done\"},\"finish_reason\":\"stop\"}]}\n\n"+
+		"data: [DONE]\n\n")
+	defer server.Close()
+	req := NormalizedRequest{
+		Model:             "kimi-k2",
+		Messages:          []NormalizedMessage{{Role: "user", Content: "hi"}},
+		SuppressReasoning: true,
+	}
+
+	deltas, errs, err := client.Stream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var got []NormalizedDelta
+	for d := range deltas {
+		got = append(got, d)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	assertDeltaSequence(t, got, DeltaText, DeltaDone)
+
+	resp, err := client.CollectStream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	if resp.Reasoning != "" || resp.Summary.HasReasoning || resp.Text != "Done" {
+		t.Fatalf("reasoning should be dropped: %+v", resp)
+	}
+
+	responses, err := NormalizedRequest{Model: "gpt-5.2", Reasoning: &NormalizedReasoning{Effort: "high"}, SuppressReasoning: true}.ToResponsesRequest()
+	if err != nil {
+		t.Fatalf("ToResponsesRequest: %v", err)
+	}
+	if responses.Reasoning == nil || responses.Reasoning.Summary != "" {
+		t.Fatalf("reasoning summary should not be requested: %+v", responses.Reasoning)
+	}
+}
+
+func TestGenerationStallTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				_, _ = w.Write([]byte(": ping\n\n"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, GenerationStallTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	resp, err := client.CollectStream(testCtx(t), req)
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || !errors.Is(err, ErrGenerationStalled) {
+		t.Fatalf("expected stalled StreamError, got %v", err)
+	}
+	if resp == nil || resp.Text != "Hel" {
+		t.Fatalf("partial response not kept: %+v", resp)
+	}
+
+	deltas, errs, err := client.Stream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var got []NormalizedDelta
+	for d := range deltas {
+		got = append(got, d)
+	}
+	if err := <-errs; !errors.Is(err, ErrGenerationStalled) {
+		t.Fatalf("expected ErrGenerationStalled, got %v", err)
+	}
+	assertDeltaSequence(t, got, DeltaText)
+}
+
+// TestStreamPathsShareThePipeline checks that CollectStream and the
+// ForceStream path of CreateNormalizedInto run the same stages as Stream:
+// Config.DeltaFilter sees the same deltas, annotated and completed the same
+// way, and the collected responses match.
+func TestStreamPathsShareThePipeline(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no golden fixtures: %v", err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.ToSlash(file), ".json")
+		t.Run(strings.TrimPrefix(name, "testdata/"), func(t *testing.T) {
+			data, err := os.ReadFile(name + ".json")
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			var fixture goldenFixture
+			if err := json.Unmarshal(data, &fixture); err != nil {
+				t.Fatalf("decode fixture: %v", err)
+			}
+			var req NormalizedRequest
+			if err := json.Unmarshal(fixture.Request, &req); err != nil {
+				t.Fatalf("decode request: %v", err)
+			}
+			req.Endpoint = EndpointType(filepath.Base(filepath.Dir(name)))
+			sse, err := os.ReadFile(name + ".sse")
+			if err != nil {
+				t.Fatalf("read SSE: %v", err)
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = w.Write(sse)
+			}))
+			defer server.Close()
+
+			var seen []string
+			client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, AnnotateToolCalls: true, DeltaFilter: func(d NormalizedDelta) (NormalizedDelta, error) {
+				seen = append(seen, fmt.Sprintf("%s/%s/%s/%v", d.Type, d.FinishReason, d.StopReason, d.UnknownTool))
+				return d, nil
+			}})
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			ctx := testCtx(t)
+
+			deltas, errs, err := client.Stream(ctx, req)
+			if err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+			streamed, err := CollectDeltas(deltas, errs)
+			if err != nil {
+				t.Fatalf("CollectDeltas: %v", err)
+			}
+			want := strings.Join(seen, " ")
+
+			seen = nil
+			collected, err := client.CollectStream(ctx, req)
+			if err != nil {
+				t.Fatalf("CollectStream: %v", err)
+			}
+			if got := strings.Join(seen, " "); got != want {
+				t.Fatalf("CollectStream filtered\n%s\nStream filtered\n%s", got, want)
+			}
+			if collected.Text != streamed.Text || collected.Reasoning != streamed.Reasoning ||
+				len(collected.ReasoningBlocks) != len(streamed.ReasoningBlocks) || len(collected.ToolCalls) != len(streamed.ToolCalls) ||
+				collected.FinishReason != streamed.FinishReason || collected.StopReason != streamed.StopReason {
+				t.Fatalf("CollectStream response %+v differs from Stream %+v", collected, streamed)
+			}
+
+			seen = nil
+			forced := req
+			forced.ForceStream = true
+			var body map[string]any
+			if err := client.CreateNormalizedInto(ctx, forced, &body); err != nil {
+				t.Fatalf("CreateNormalizedInto: %v", err)
+			}
+			if got := strings.Join(seen, " "); got != want {
+				t.Fatalf("ForceStream filtered\n%s\nStream filtered\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestForceStreamDeltaFilterError(t *testing.T) {
+	server, _ := newSSETestServer(t, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"bad\"}}]}\n\ndata: [DONE]\n\n")
+	defer server.Close()
+	blocked := errors.New("blocked")
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, ForceStream: true, DeltaFilter: func(d NormalizedDelta) (NormalizedDelta, error) {
+		if d.Type == DeltaText {
+			return d, blocked
+		}
+		return d, nil
+	}})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	var body map[string]any
+	err = client.CreateNormalizedInto(testCtx(t), NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}, &body)
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || !errors.Is(err, ErrFilteredStream) || !errors.Is(err, blocked) {
+		t.Fatalf("expected a filtered *StreamError, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package zen
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
@@ -25,7 +26,17 @@ const (
 )
 
 type Config struct {
-	APIKey  string
+	APIKey string
+	// APIKeys, when set, replaces APIKey with several keys to spread
+	// requests over, picked per request by KeySelection; retries of a
+	// request reuse its key. A key answered with 429 rests for the
+	// response's Retry-After, or KeyCooldown (0 = DefaultKeyCooldown), and
+	// is only used meanwhile when every key is resting.
+	// RequestMetrics.KeyIndex reports the key of each request.
+	APIKeys      []string
+	KeySelection KeySelection
+	KeyCooldown  time.Duration
+
 	BaseURL string
 	// Timeout sets http.Client.Timeout on the internal HTTP client.
 	//
@@ -48,12 +59,210 @@ type Config struct {
 	HTTPClient            *http.Client
 	Retry                 RetryConfig
 	AuthHeader            AuthHeader
+
+	// RequestTimeout bounds each attempt of a non-streaming request, from
+	// sending it until its response body has been read (0 = none). Unlike
+	// Timeout it never applies to streams. Retries get a fresh timeout
+	// each, so the total time also includes every attempt and backoff;
+	// bound it with the request context. An attempt that times out fails
+	// with ErrRequestTimeout wrapping context.DeadlineExceeded and is
+	// retried like other transport errors. ContextWithRequestTimeout
+	// overrides it per request.
+	RequestTimeout time.Duration
+
+	// MaxConcurrentRequests bounds the number of requests in flight at once
+	// (0 = unlimited). Callers beyond the limit wait for a free slot until
+	// their context is done, or fail with ErrConcurrencyLimit when
+	// FailFastOnConcurrencyLimit is set. A stream holds its slot until its
+	// body has been read, or only until the response headers arrive when
+	// ReleaseSlotAfterHeaders is set. Clients derived with Client.With share
+	// the limit.
+	MaxConcurrentRequests      int
+	FailFastOnConcurrencyLimit bool
+	ReleaseSlotAfterHeaders    bool
+
+	// AnthropicBetas lists the anthropic-beta features requested on every
+	// messages request, streaming or not. nil selects the SDK default
+	// (fine-grained tool streaming); an empty non-nil slice sends no
+	// anthropic-beta header, for gateways that reject unknown betas.
+	AnthropicBetas []string
+
+	// StreamIncludeComments reports SSE comment lines, such as the ": ping"
+	// keep-alives some gateways send while a model is thinking, as events
+	// named CommentEvent instead of dropping them. Normalized streams ignore
+	// them.
+	StreamIncludeComments bool
+
+	// GenerationStallTimeout aborts a stream that has produced no text,
+	// reasoning or tool call deltas for this long, even while the
+	// connection is kept alive with pings. The stream then fails with
+	// ErrGenerationStalled. It applies to Stream, OpenStream and
+	// CollectStream; 0 disables it.
+	GenerationStallTimeout time.Duration
+
+	// ModelRoutes are consulted before the built-in table of RouteForModel
+	// to pick the endpoint of requests that leave Endpoint unset, so new
+	// models can be routed without an SDK release. The first matching
+	// prefix wins.
+	ModelRoutes []ModelRoute
+
+	// GeminiAPIVersion, when set, puts Gemini requests below a version
+	// segment: "v1beta" sends them to BaseURL + "/v1beta/models/...". Use
+	// it with a BaseURL that has no version of its own.
+	GeminiAPIVersion string
+
+	// DefaultHeaders are sent with every request. They replace the SDK's
+	// own headers of the same name (e.g. User-Agent) but never the
+	// authentication headers, and are themselves replaced by headers from
+	// ContextWithHeaders.
+	DefaultHeaders http.Header
+
+	// Organization and Project scope every request to a tenant of a
+	// gateway, sent in the OrganizationHeader and ProjectHeader headers
+	// (DefaultOrganizationHeader and DefaultProjectHeader when empty; e.g.
+	// "x-zen-project" for gateways using that name). ContextWithScope
+	// overrides them per request. Both are reported in RequestMetrics.
+	Organization       string
+	Project            string
+	OrganizationHeader string
+	ProjectHeader      string
+
+	// SendFeaturesHeader sends the features the SDK supports on a request's
+	// endpoint (see Capabilities) as a comma-separated x-zen-sdk-features
+	// header, so a gateway can adapt to older clients.
+	SendFeaturesHeader bool
+
+	// MaxToolArgumentBytes limits the arguments of a single streamed tool
+	// call (0 = DefaultMaxToolArgumentBytes, negative = unlimited). Larger
+	// arguments are dropped and reported with a DeltaError carrying
+	// ErrToolArgumentsTooLarge; raise it for tools that legitimately take
+	// large payloads.
+	MaxToolArgumentBytes int
+
+	// LogRequest, when set, is called with every normalized request just
+	// before it is sent, after model defaults are applied. With LogRedaction
+	// set it receives RedactRequest(req, *LogRedaction) instead.
+	LogRequest   func(ctx context.Context, endpoint EndpointType, req NormalizedRequest)
+	LogRedaction *RedactionPolicy
+
+	// OnWarning, when set, is called with every Warning about a request
+	// field the target endpoint drops (see ConversionWarnings) and about
+	// repairs made to malformed streams. StrictConversions fails requests
+	// with dropped fields with ErrUnsupportedField instead.
+	OnWarning         func(ctx context.Context, w Warning)
+	StrictConversions bool
+
+	// UnsupportedReasoning decides what happens to the Reasoning of a
+	// request for a model that does not support it, according to
+	// ModelDefaults.SupportsReasoning or else ModelSupportsReasoning. The
+	// default, ReasoningPassThrough, sends it anyway.
+	UnsupportedReasoning ReasoningPolicy
+
+	// OnRequestMetrics, when set, is called with the payload sizes and
+	// outcome of every HTTP request, streaming or not (see RequestMetrics).
+	OnRequestMetrics func(ctx context.Context, m RequestMetrics)
+
+	// MaxRequestBytes refuses to send normalized requests whose provider
+	// payload is larger (0 = unlimited), returning a *RequestTooLargeError
+	// that names the largest messages.
+	MaxRequestBytes int
+
+	// OnModelSubstitution, when set, is called when a response reports
+	// being served by a model of another family than requested (see
+	// ModelFamily), e.g. after server-side fallback routing. Dated
+	// snapshots of the requested alias are not reported. StrictModelMatch
+	// fails such requests with a *ModelSubstitutionError instead; streams
+	// are stopped as soon as the served model is known.
+	OnModelSubstitution func(ctx context.Context, sub ModelSubstitution)
+	StrictModelMatch    bool
+
+	// CaptureRequestOnError stores the request payload on the *APIError of
+	// a failed request or stream setup, for attaching to bug reports. At
+	// most CaptureRequestMaxBytes are kept (0 = DefaultCaptureRequestBytes,
+	// negative = unlimited). With CaptureRedaction set, normalized requests
+	// are captured as the payload of RedactRequest(req, *CaptureRedaction)
+	// instead, which costs an extra encoding per request.
+	CaptureRequestOnError  bool
+	CaptureRequestMaxBytes int
+	CaptureRedaction       *RedactionPolicy
+
+	// ForceStream makes CreateNormalized and CreateNormalizedInto send their
+	// requests as streams and assemble the result, for backends that only
+	// answer reliably when streaming. NormalizedRequest.ForceStream enables
+	// it for a single request.
+	ForceStream bool
+
+	// EmulateToolChoice works around backends that ignore a "required" or
+	// named tool choice: when CreateNormalized or CollectStream get a
+	// response without a tool call for such a request, they retry once,
+	// forcing the only declared tool or else instructing the model in the
+	// system prompt to call one. If the retry has no tool call either they
+	// return a *ToolCallExpectedError. Streams are not retried.
+	// OnToolChoiceEmulation, when set, is called after every such retry.
+	EmulateToolChoice     bool
+	OnToolChoiceEmulation func(ctx context.Context, ev ToolChoiceEmulation)
+
+	// DeltaFilter, when set, is called with every delta Stream, OpenStream,
+	// CollectStream and CreateNormalized deliver, before delivery, e.g. to
+	// moderate output. It returns the delta to deliver, possibly rewritten,
+	// a zero NormalizedDelta to drop it, or an error to stop the response:
+	// streams are then cancelled and fail with a *FilteredStreamError
+	// (ErrFilteredStream) holding the rejected delta. Raw events and
+	// CreateNormalizedInto are not filtered.
+	DeltaFilter func(NormalizedDelta) (NormalizedDelta, error)
+
+	// AnnotateToolCalls makes Client.Stream set NormalizedDelta.Tool and
+	// UnknownTool on tool-call begin and done deltas.
+	AnnotateToolCalls bool
+
+	// TokenCounter, when set, is used by Client.CountTokens instead of the
+	// EstimateTokens heuristic, e.g. to call a provider's count_tokens
+	// endpoint.
+	TokenCounter func(ctx context.Context, req NormalizedRequest) (int, error)
+
+	// AutoMaxTokens fills in MaxTokens for requests that leave it nil after
+	// defaults: the smallest of the model's MaxOutputTokens and its
+	// ContextWindow minus the input (see CountTokens) and
+	// AutoMaxTokensMargin (0 = DefaultAutoMaxTokensMargin), with the limits
+	// taken from Defaults and ModelDefaults. Requests whose input does not
+	// fit fail with ErrContextWindowExceeded. Models without limits keep
+	// the endpoint's default.
+	AutoMaxTokens       bool
+	AutoMaxTokensMargin int
+
+	// MessagesMaxTokensPolicy decides the max_tokens of messages requests
+	// that leave MaxTokens nil after defaults and AutoMaxTokens (see
+	// MaxTokensPolicy). MessagesMaxTokens is the value MaxTokensFixed
+	// sends; it must be positive under that policy.
+	MessagesMaxTokensPolicy MaxTokensPolicy
+	MessagesMaxTokens       int
+
+	// Defaults are request parameters applied to every request that leaves
+	// them unset.
+	Defaults ModelDefaults
+	// ModelDefaults holds per-model defaults, keyed by exact model id or by
+	// model id prefix (e.g. "claude-"). See ModelDefaults for the resolution
+	// order.
+	ModelDefaults map[string]ModelDefaults
+
+	// Clock replaces the real time source for retry backoff, stream
+	// timeouts and event timestamps, for tests. nil uses the time package.
+	Clock Clock
 }
 
 func (c *Config) applyDefaults() error {
-	if strings.TrimSpace(c.APIKey) == "" {
+	if len(c.APIKeys) == 0 && strings.TrimSpace(c.APIKey) == "" {
 		return errors.New("zen: API key is required")
 	}
+	for _, key := range c.APIKeys {
+		if strings.TrimSpace(key) == "" {
+			return errors.New("zen: APIKeys must not contain empty keys")
+		}
+	}
+
+	if c.MessagesMaxTokensPolicy == MaxTokensFixed && c.MessagesMaxTokens <= 0 {
+		return errors.New("zen: MessagesMaxTokens must be positive under MaxTokensFixed")
+	}
 
 	if strings.TrimSpace(c.BaseURL) == "" {
 		c.BaseURL = defaultBaseURL
@@ -62,13 +271,24 @@ func (c *Config) applyDefaults() error {
 	c.BaseURL = strings.TrimRight(c.BaseURL, "/")
 
 	if strings.TrimSpace(c.UserAgent) == "" {
-		c.UserAgent = "go-opencode-zen-sdk/0.1"
+		c.UserAgent = "go-opencode-zen-sdk/" + SDKVersion
 	}
 
 	if c.AuthHeader == "" {
 		c.AuthHeader = AuthHeaderAuto
 	}
 
+	if c.OrganizationHeader == "" {
+		c.OrganizationHeader = DefaultOrganizationHeader
+	}
+	if c.ProjectHeader == "" {
+		c.ProjectHeader = DefaultProjectHeader
+	}
+
+	if c.Clock == nil {
+		c.Clock = realClock{}
+	}
+
 	if c.Retry.Backoff == nil {
 		c.Retry.Backoff = func(attempt int) time.Duration {
 			base := 200 * time.Millisecond
@@ -0,0 +1,65 @@
+package zen
+
+import "context"
+
+// Conversation holds the history of a multi-turn exchange, so each turn
+// only supplies its new messages. Turns may switch models, even across
+// endpoints: the history is rewritten with PrepareHistoryFor whenever the
+// model changes, and for the first turn when it was seeded.
+type Conversation struct {
+	client *Client
+	// Messages is the history: the messages of every turn followed by its
+	// reply. It may be seeded, e.g. with a stored conversation.
+	Messages []NormalizedMessage
+	// Usage is the usage reported by every turn so far, including turns
+	// that failed after the reply had started.
+	Usage NormalizedUsage
+
+	model string // model of the previous turn
+}
+
+// NewConversation returns an empty conversation held through client.
+func NewConversation(client *Client) *Conversation {
+	return &Conversation{client: client}
+}
+
+// Send appends req.Messages to the history, streams a reply to the whole
+// history with req's other settings and appends the reply. On error the
+// history is left as it was.
+func (c *Conversation) Send(ctx context.Context, req NormalizedRequest) (*NormalizedResponse, error) {
+	return c.SendWithHandlers(ctx, req)
+}
+
+// SendWithHandlers is Send calling every handler with each delta of the
+// reply as it streams, e.g. to print it. The first error a handler returns
+// stops the stream and is returned; the history is then left as it was.
+func (c *Conversation) SendWithHandlers(ctx context.Context, req NormalizedRequest, handlers ...func(NormalizedDelta) error) (*NormalizedResponse, error) {
+	history := append(c.Messages[:len(c.Messages):len(c.Messages)], req.Messages...)
+	model := stripOpencodePrefix(req.Model)
+	if c.model != model && (c.model != "" || len(c.Messages) > 0) {
+		// A seeded history may come from any provider. The new messages
+		// take part, as they may answer tool calls whose ids are rewritten.
+		endpoint := req.Endpoint
+		if endpoint == EndpointAuto {
+			endpoint = c.client.routeForModel(model)
+		}
+		history = PrepareHistoryFor(endpoint, history)
+	}
+
+	req.Messages = history
+	resp, err := c.client.withToolChoiceEmulation(ctx, req, func(ctx context.Context, req NormalizedRequest) (*NormalizedResponse, error) {
+		return c.client.collectStreamWith(ctx, req, handlers...)
+	})
+	if resp != nil {
+		c.Usage = c.Usage.Add(resp.Usage())
+	}
+	if err != nil {
+		return resp, err
+	}
+	reply := AssistantMessage(resp.Text, resp.ToolCalls)
+	reply.Parts = resp.Parts
+	reply.ProviderState = resp.ProviderState
+	c.Messages = append(history, reply)
+	c.model = model
+	return resp, nil
+}
@@ -0,0 +1,94 @@
+package zen
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConversationAccumulatesUsage(t *testing.T) {
+	server, client := newSSETestServer(t, "event: message_start\n"+
+		`data: {"type":"message_start","message":{"model":"claude-sonnet-4-6","usage":{"input_tokens":10,"cache_creation_input_tokens":5,"cache_read_input_tokens":100,"output_tokens":1}}}`+"\n\n"+
+		"event: content_block_delta\n"+`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ok"}}`+"\n\n"+
+		"event: message_delta\n"+`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}`+"\n\n"+
+		"event: message_stop\n"+`data: {"type":"message_stop"}`+"\n\n")
+	defer server.Close()
+
+	conv := NewConversation(client)
+	for turn := 0; turn < 2; turn++ {
+		resp, err := conv.Send(testCtx(t), NormalizedRequest{Model: "claude-sonnet-4-6", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}})
+		if err != nil {
+			t.Fatalf("turn %d: %v", turn, err)
+		}
+		// InputTokens stays as Anthropic reports it, without the cache.
+		if resp.InputTokens != 10 || resp.CachedInputTokens != 100 || resp.CacheWriteInputTokens != 5 {
+			t.Fatalf("turn %d: unexpected usage %+v", turn, resp.Usage())
+		}
+	}
+	want := NormalizedUsage{InputTokens: 20, OutputTokens: 6, CachedInputTokens: 200, CacheWriteInputTokens: 10}
+	if conv.Usage != want {
+		t.Fatalf("want usage %+v, got %+v", want, conv.Usage)
+	}
+}
+
+func TestConversationPreparesSeededHistory(t *testing.T) {
+	var sent []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: content_block_delta\n"+`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ok"}}`+"\n\n"+
+			"event: message_stop\n"+`data: {"type":"message_stop"}`+"\n\n")
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// A stored Gemini conversation whose last call was never answered.
+	conv := NewConversation(client)
+	conv.Messages = []NormalizedMessage{
+		{Role: "user", Content: "look it up"},
+		{Role: "assistant", ToolCalls: []NormalizedToolCall{{ID: "gemini-0", Name: "lookup", Arguments: []byte(`{}`), ThoughtSignature: "sig-1"}}},
+	}
+	if _, err := conv.Send(testCtx(t), NormalizedRequest{Model: "claude-sonnet-4-6", Messages: []NormalizedMessage{{Role: "user", Content: "never mind"}}}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if !strings.Contains(string(sent), `"tool_use_id":"gemini-0"`) {
+		t.Fatalf("expected the unanswered call to get a result: %s", sent)
+	}
+	if strings.Contains(string(sent), "sig-1") {
+		t.Fatalf("Gemini thought signature sent to Anthropic: %s", sent)
+	}
+}
+
+func TestConversationSendWithHandlers(t *testing.T) {
+	server, client := newSSETestServer(t, "event: content_block_delta\n"+`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`+"\n\n"+
+		"event: content_block_delta\n"+`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`+"\n\n"+
+		"event: message_stop\n"+`data: {"type":"message_stop"}`+"\n\n")
+	defer server.Close()
+
+	conv := NewConversation(client)
+	req := NormalizedRequest{Model: "claude-sonnet-4-6", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+	var text []string
+	resp, err := conv.SendWithHandlers(testCtx(t), req, func(d NormalizedDelta) error {
+		if d.Type == DeltaText {
+			text = append(text, d.Content)
+		}
+		return nil
+	})
+	if err != nil || resp.Text != "Hello" || strings.Join(text, "|") != "Hel|lo" {
+		t.Fatalf("unexpected reply %+v, deltas %q (%v)", resp, text, err)
+	}
+
+	stop := errors.New("stop")
+	if _, err := conv.SendWithHandlers(testCtx(t), req, func(NormalizedDelta) error { return stop }); !errors.Is(err, stop) {
+		t.Fatalf("expected the handler error, got %v", err)
+	}
+	if len(conv.Messages) != 2 {
+		t.Fatalf("expected the failed turn to be dropped, got %d messages", len(conv.Messages))
+	}
+}
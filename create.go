@@ -0,0 +1,205 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// CreateNormalizedInto sends req as a non-streaming request to the endpoint
+// it routes to and decodes the provider's JSON response into v. The body is
+// decoded straight from the connection with a json.Decoder, without first
+// being read into a byte slice, unless SetJSONFuncs installed an Unmarshal
+// replacement. json.Decoder still holds the raw bytes of the value while
+// decoding it (compare BenchmarkCreateNormalizedInto with
+// BenchmarkBufferedDecode). v receives
+// the provider's native response shape (e.g. a chat.completion object for
+// chat completions). Non-2xx responses are returned as *APIError with the
+// body attached.
+//
+// With Config.ForceStream or req.ForceStream set the request is streamed and
+// v receives the body AssembleResponse rebuilds from its events.
+//
+// A request with Endpoint set to EndpointModels and no Model or Path lists
+// models instead, exactly as ListModels does: a GET of /models (with
+// req.Query) without a body. A *ModelsResponse v is filled as ListModels
+// fills it, Raw included; any other v receives the JSON list. Such a request
+// cannot be streamed, and the other methods taking a NormalizedRequest
+// reject it with an error wrapping ErrModelListing.
+func (c *Client) CreateNormalizedInto(ctx context.Context, req NormalizedRequest, v any) error {
+	if isModelListing(req) {
+		return c.listModelsInto(ctx, req, v)
+	}
+	if c.forceStream(req) {
+		events, info, err := c.collectEvents(ctx, req)
+		if err != nil {
+			return err
+		}
+		body, err := AssembleResponse(info.Endpoint, events)
+		if err != nil {
+			return err
+		}
+		return jsonUnmarshal(body, v)
+	}
+	ctx, endpoint, path, payload, err := c.buildRequest(ctx, req, false)
+	if err != nil {
+		return err
+	}
+	return c.decodeInto(ctx, "POST", endpoint, path, payload, v)
+}
+
+// CreateRawInto POSTs body to path (relative to Config.BaseURL) and decodes
+// the JSON response into v like CreateNormalizedInto. endpoint selects the
+// authentication and provider headers. body may be a json.RawMessage, []byte
+// holding JSON, or any value that marshals to JSON.
+func (c *Client) CreateRawInto(ctx context.Context, endpoint EndpointType, path string, body, v any) error {
+	payload, err := rawPayload(body)
+	if err != nil {
+		return err
+	}
+	return c.decodeInto(ctx, "POST", endpoint, path, payload, v)
+}
+
+// DoRawInto sends a request with any method to path, like CreateRawInto.
+// A nil body sends no body: GET, HEAD and DELETE requests then carry no
+// Content-Type either, as some gateways reject GET requests with a body. A
+// nil v discards the response body, e.g. for DELETE /responses/{id}, and an
+// empty body leaves v untouched. A body that fails to decode and is labelled
+// as something other than JSON is reported as ErrNotJSON; use DoRaw to read
+// such bodies.
+func (c *Client) DoRawInto(ctx context.Context, method string, endpoint EndpointType, path string, body, v any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = rawPayload(body); err != nil {
+			return err
+		}
+	}
+	return c.decodeInto(ctx, method, endpoint, path, payload, v)
+}
+
+func rawPayload(body any) ([]byte, error) {
+	switch b := body.(type) {
+	case json.RawMessage:
+		return b, nil
+	case []byte:
+		return b, nil
+	}
+	return jsonBody(body, nil)
+}
+
+// decodeInto sends a request and decodes the response body into v. An empty
+// body, as sent with 204 No Content, leaves v untouched.
+func (c *Client) decodeInto(ctx context.Context, method string, endpoint EndpointType, path string, payload []byte, v any) error {
+	_, err := c.doRequestFunc(ctx, method, path, payload, endpoint, false, func(resp *http.Response, r io.Reader) error {
+		if v == nil {
+			return nil
+		}
+		return decodeJSONReader(r, resp.Header, v)
+	})
+	return err
+}
+
+// listModelsInto runs the model listing req for CreateNormalizedInto.
+func (c *Client) listModelsInto(ctx context.Context, req NormalizedRequest, v any) error {
+	if req.Stream || c.forceStream(req) {
+		return modelListingError(true)
+	}
+	_, path, err := c.resolveEndpoint(req, false)
+	if err != nil {
+		return err
+	}
+	resp, err := c.listModels(ctx, path)
+	if err != nil {
+		return err
+	}
+	switch out := v.(type) {
+	case nil:
+		return nil
+	case *ModelsResponse:
+		*out = *resp
+		return nil
+	}
+	if len(resp.Raw) == 0 {
+		return nil
+	}
+	return jsonUnmarshal(resp.Raw, v)
+}
+
+// RawResponse is a successful response as DoRaw returns it.
+type RawResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// ContentType returns the media type of the response's Content-Type header,
+// lower-cased and without parameters, or "" when it has none.
+func (r *RawResponse) ContentType() string {
+	return mediaType(r.Header)
+}
+
+// IsJSON reports whether the response is labelled as JSON.
+func (r *RawResponse) IsJSON() bool {
+	return isJSONMediaType(r.ContentType())
+}
+
+// DoRaw sends a request like DoRawInto and returns the response body as it
+// is, for routes that answer with something other than JSON or with no body
+// at all. Non-2xx responses are returned as *APIError.
+func (c *Client) DoRaw(ctx context.Context, method string, endpoint EndpointType, path string, body any) (*RawResponse, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = rawPayload(body); err != nil {
+			return nil, err
+		}
+	}
+	out := &RawResponse{}
+	_, err := c.doRequestFunc(ctx, method, path, payload, endpoint, false, func(resp *http.Response, r io.Reader) error {
+		out.StatusCode = resp.StatusCode
+		out.Header = resp.Header
+		var err error
+		out.Body, err = io.ReadAll(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// collectEvents runs req as a stream and returns all of its events. The
+// events also go through the stream pipeline, so a stream failure, including
+// an error event sent by the provider or a delta rejected by
+// Config.DeltaFilter, is returned as *StreamError with the deltas parsed so
+// far.
+func (c *Client) collectEvents(ctx context.Context, req NormalizedRequest) ([]UnifiedEvent, *StreamInfo, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	evCh, errCh, info, err := c.streamEvents(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var events []UnifiedEvent
+	recorded := make(chan UnifiedEvent)
+	recordDone := make(chan struct{})
+	go func() {
+		defer close(recordDone)
+		defer close(recorded)
+		for ev := range evCh {
+			events = append(events, ev)
+			select {
+			case recorded <- ev:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	h := c.deltaStream(ctx, cancel, req, recorded, errCh, info)
+	_, err = collectHandle(h, req.SuppressReasoning)
+	cancel()
+	<-recordDone
+	return events, info, err
+}
@@ -0,0 +1,308 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type chatCompletionFixture struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+func TestCreateNormalizedInto(t *testing.T) {
+	var path string
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		payload, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(payload, &body)
+		if strings.Contains(path, "fail") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"bad model"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello"}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+
+	var out chatCompletionFixture
+	err = client.CreateNormalizedInto(context.Background(), NormalizedRequest{
+		Model:    "kimi-k2",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+		Stream:   true,
+	}, &out)
+	if err != nil {
+		t.Fatalf("CreateNormalizedInto: %v", err)
+	}
+	if path != "/chat/completions" || body["stream"] != nil {
+		t.Fatalf("expected a non-streaming chat completions request, got %s %v", path, body)
+	}
+	if len(out.Choices) != 1 || out.Choices[0].Message.Content != "hello" {
+		t.Fatalf("decoded response mismatch: %+v", out)
+	}
+
+	var gemini map[string]any
+	if err := client.CreateNormalizedInto(context.Background(), NormalizedRequest{
+		Model:    "gemini-3-flash",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	}, &gemini); err != nil {
+		t.Fatalf("gemini CreateNormalizedInto: %v", err)
+	}
+	if path != "/models/gemini-3-flash:generateContent" {
+		t.Fatalf("gemini path mismatch: %s", path)
+	}
+
+	err = client.CreateRawInto(context.Background(), EndpointChatCompletions, "/fail", json.RawMessage(`{"model":"x"}`), &out)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Message != "bad model" || len(apiErr.Body) == 0 {
+		t.Fatalf("expected APIError with body, got %v", err)
+	}
+	if body["model"] != "x" {
+		t.Fatalf("raw body should be sent unchanged, got %v", body)
+	}
+}
+
+// TestCreateNormalizedIntoDecodesFromConnection holds the response open
+// after a complete JSON body: reading the body to its end before decoding
+// would block until the server gives up.
+func TestCreateNormalizedIntoDecodesFromConnection(t *testing.T) {
+	finished := make(chan struct{})
+	handlerDone := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(handlerDone)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello"}}]}`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-finished:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(finished)
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+	var out chatCompletionFixture
+	err = client.CreateNormalizedInto(testCtx(t), NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}, &out)
+	if err != nil {
+		t.Fatalf("CreateNormalizedInto: %v", err)
+	}
+	select {
+	case <-handlerDone:
+		t.Fatal("the body was read to its end before decoding")
+	default:
+	}
+	if len(out.Choices) != 1 || out.Choices[0].Message.Content != "hello" {
+		t.Fatalf("decoded response mismatch: %+v", out)
+	}
+}
+
+func TestCaptureRequestOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"bad request"}}`))
+	}))
+	defer server.Close()
+
+	req := NormalizedRequest{
+		Model:    "kimi-k2",
+		Messages: []NormalizedMessage{{Role: "user", Content: "secret prompt"}},
+	}
+	requestBody := func(cfg Config, stream bool) []byte {
+		t.Helper()
+		cfg.APIKey = "key"
+		cfg.BaseURL = server.URL
+		client, err := NewClient(cfg)
+		if err != nil {
+			t.Fatalf("client error: %v", err)
+		}
+		if stream {
+			_, _, err = client.Stream(context.Background(), req)
+		} else {
+			_, err = client.CreateNormalized(context.Background(), req)
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("expected APIError, got %v", err)
+		}
+		return apiErr.RequestBody
+	}
+
+	if got := requestBody(Config{}, false); got != nil {
+		t.Fatalf("request body captured by default: %s", got)
+	}
+	for _, stream := range []bool{false, true} {
+		got := requestBody(Config{CaptureRequestOnError: true}, stream)
+		if !strings.Contains(string(got), "secret prompt") || !json.Valid(got) {
+			t.Fatalf("stream=%v: full request body not captured: %s", stream, got)
+		}
+		if got := requestBody(Config{CaptureRequestOnError: true, CaptureRequestMaxBytes: 10}, stream); len(got) != 10 {
+			t.Fatalf("stream=%v: capture not cut to 10 bytes: %q", stream, got)
+		}
+		got = requestBody(Config{CaptureRequestOnError: true, CaptureRedaction: &RedactionPolicy{}}, stream)
+		if strings.Contains(string(got), "secret prompt") || !strings.Contains(string(got), "[redacted 13 bytes]") {
+			t.Fatalf("stream=%v: capture not redacted: %s", stream, got)
+		}
+	}
+}
+
+func TestBodylessRequests(t *testing.T) {
+	var method, path, contentType string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, contentType = r.Method, r.URL.Path, r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[{"id":"kimi-k2"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+
+	if _, err := client.ListModels(context.Background()); err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if method != "GET" || path != "/models" || contentType != "" || len(body) != 0 {
+		t.Fatalf("GET /models should carry no body or Content-Type, got %s %s %q %q", method, path, contentType, body)
+	}
+
+	if err := client.DoRawInto(context.Background(), "DELETE", EndpointResponses, "/responses/resp_1", nil, nil); err != nil {
+		t.Fatalf("DoRawInto DELETE: %v", err)
+	}
+	if method != "DELETE" || path != "/responses/resp_1" || contentType != "" || len(body) != 0 {
+		t.Fatalf("DELETE should carry no body or Content-Type, got %s %s %q %q", method, path, contentType, body)
+	}
+
+	if err := client.DoRawInto(context.Background(), "DELETE", EndpointResponses, "/responses/resp_1", json.RawMessage(`{"force":true}`), nil); err != nil {
+		t.Fatalf("DoRawInto DELETE with body: %v", err)
+	}
+	if contentType != "application/json" || string(body) != `{"force":true}` {
+		t.Fatalf("explicit body should be sent with Content-Type, got %q %q", contentType, body)
+	}
+}
+
+func TestEmptyAndNonJSONSuccessBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/no-content", "/responses/resp_1":
+			w.WriteHeader(http.StatusNoContent)
+		case "/empty", "/models":
+			w.Header().Set("Content-Type", "application/json")
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte("OK"))
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, path := range []string{"/no-content", "/empty"} {
+		v := map[string]any{"kept": true}
+		if err := client.DoRawInto(ctx, "GET", EndpointResponses, path, nil, &v); err != nil {
+			t.Fatalf("DoRawInto %s: %v", path, err)
+		}
+		if len(v) != 1 {
+			t.Fatalf("empty body must leave v untouched, got %v", v)
+		}
+	}
+	if err := client.DeleteResponse(ctx, "resp_1"); err != nil {
+		t.Fatalf("DeleteResponse: %v", err)
+	}
+	models, err := client.ListModels(ctx)
+	if err != nil || len(models.Data) != 0 || models.Raw != nil {
+		t.Fatalf("empty model list expected, got %+v, %v", models, err)
+	}
+
+	var v map[string]any
+	err = client.DoRawInto(ctx, "GET", EndpointResponses, "/text", nil, &v)
+	if !errors.Is(err, ErrNotJSON) || !strings.Contains(err.Error(), "text/plain") {
+		t.Fatalf("expected ErrNotJSON naming text/plain, got %v", err)
+	}
+	raw, err := client.DoRaw(ctx, "GET", EndpointResponses, "/text", nil)
+	if err != nil {
+		t.Fatalf("DoRaw: %v", err)
+	}
+	if raw.StatusCode != http.StatusOK || raw.ContentType() != "text/plain" || raw.IsJSON() || string(raw.Body) != "OK" {
+		t.Fatalf("raw response mismatch: %d %q %q", raw.StatusCode, raw.ContentType(), raw.Body)
+	}
+	raw, err = client.DoRaw(ctx, "DELETE", EndpointResponses, "/no-content", nil)
+	if err != nil || raw.StatusCode != http.StatusNoContent || len(raw.Body) != 0 {
+		t.Fatalf("DoRaw 204: %+v, %v", raw, err)
+	}
+}
+
+// largeCompletionServer serves a ~20MB chat completion.
+func largeCompletionServer(b *testing.B) *httptest.Server {
+	b.Helper()
+	fixture, err := json.Marshal(map[string]any{
+		"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": strings.Repeat("lorem ipsum ", 20<<20/12)}}},
+	})
+	if err != nil {
+		b.Fatalf("fixture: %v", err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(fixture)
+	}))
+}
+
+func BenchmarkCreateNormalizedInto(b *testing.B) {
+	server := largeCompletionServer(b)
+	defer server.Close()
+	client, _ := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var out chatCompletionFixture
+		if err := client.CreateNormalizedInto(context.Background(), req, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkBufferedDecode is the read-then-unmarshal baseline.
+func BenchmarkBufferedDecode(b *testing.B) {
+	server := largeCompletionServer(b)
+	defer server.Close()
+	client, _ := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, _, err := client.doRequest(context.Background(), "POST", "/chat/completions", []byte(`{}`), EndpointChatCompletions, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var out chatCompletionFixture
+		if err := json.Unmarshal(data, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package zen
+
+import "strings"
+
+// ModelDefaults are request parameters filled into a NormalizedRequest before
+// it is converted to a provider payload. A field only applies when neither the
+// request nor a more specific layer sets it. Layers are applied in order:
+//
+//  1. values set on the NormalizedRequest itself
+//  2. Config.ModelDefaults entry whose key equals the model id
+//  3. Config.ModelDefaults entry with the longest key that prefixes the model id
+//  4. Config.Defaults
+//
+// Keys and model ids are compared case-insensitively, without the "opencode/"
+// prefix.
+type ModelDefaults struct {
+	Temperature *float64
+	MaxTokens   *int
+	Reasoning   *NormalizedReasoning
+	// ContextWindow and MaxOutputTokens are the model's limits in tokens,
+	// used by Config.AutoMaxTokens. They are not request parameters.
+	ContextWindow   int
+	MaxOutputTokens int
+	// InputPricePer1M, CachedInputPricePer1M and OutputPricePer1M are USD
+	// per million tokens, used by Client.EstimateCost and RunOptions.MaxCost.
+	InputPricePer1M       float64
+	CachedInputPricePer1M float64
+	OutputPricePer1M      float64
+	// SupportsReasoning overrides the SDK's built-in list of models that
+	// reason (see ModelSupportsReasoning) for Config.UnsupportedReasoning,
+	// e.g. with Model.SupportsReasoning from ListModels.
+	SupportsReasoning *bool
+	// SystemRole and SystemPosition fill the NormalizedRequest fields of
+	// the same name, for backends that want the system prompt elsewhere.
+	SystemRole     string
+	SystemPosition SystemPosition
+	// Extra keys are added to NormalizedRequest.Extra when not already present.
+	Extra map[string]any
+}
+
+// applyModelDefaults returns req with the configured defaults filled in.
+// req.Extra is copied before it is extended.
+func (c Config) applyModelDefaults(req NormalizedRequest) NormalizedRequest {
+	copied := false
+	for _, d := range c.defaultLayers(req.Model) {
+		if req.Temperature == nil && d.Temperature != nil {
+			v := *d.Temperature
+			req.Temperature = &v
+		}
+		if req.MaxTokens == nil && d.MaxTokens != nil {
+			v := *d.MaxTokens
+			req.MaxTokens = &v
+		}
+		if req.Reasoning == nil && d.Reasoning != nil {
+			v := *d.Reasoning
+			req.Reasoning = &v
+		}
+		if req.SystemRole == "" {
+			req.SystemRole = d.SystemRole
+		}
+		if req.SystemPosition == "" {
+			req.SystemPosition = d.SystemPosition
+		}
+		for k, v := range d.Extra {
+			if _, ok := req.Extra[k]; ok {
+				continue
+			}
+			if !copied {
+				extra := make(map[string]any, len(req.Extra)+len(d.Extra))
+				for ek, ev := range req.Extra {
+					extra[ek] = ev
+				}
+				req.Extra = extra
+				copied = true
+			}
+			req.Extra[k] = v
+		}
+	}
+	return req
+}
+
+// defaultLayers returns the defaults that apply to model, most specific first.
+// Keys that collide after case folding are resolved lexically so the result
+// never depends on map iteration order.
+func (c Config) defaultLayers(model string) []ModelDefaults {
+	model = strings.ToLower(stripOpencodePrefix(model))
+
+	var exactKey, prefixKey string
+	var exact, prefix ModelDefaults
+	for key, d := range c.ModelDefaults {
+		k := strings.ToLower(stripOpencodePrefix(key))
+		switch {
+		case k == "":
+		case k == model:
+			if exactKey == "" || key < exactKey {
+				exactKey, exact = key, d
+			}
+		case strings.HasPrefix(model, k):
+			pk := strings.ToLower(stripOpencodePrefix(prefixKey))
+			if prefixKey == "" || len(k) > len(pk) || (len(k) == len(pk) && key < prefixKey) {
+				prefixKey, prefix = key, d
+			}
+		}
+	}
+
+	layers := make([]ModelDefaults, 0, 3)
+	if exactKey != "" {
+		layers = append(layers, exact)
+	}
+	if prefixKey != "" {
+		layers = append(layers, prefix)
+	}
+	return append(layers, c.Defaults)
+}
+
+// modelLimits returns the context window and output limit configured for
+// model, taking each from the most specific layer that sets it.
+func (c Config) modelLimits(model string) (contextWindow, maxOutput int) {
+	for _, d := range c.defaultLayers(model) {
+		if contextWindow == 0 {
+			contextWindow = d.ContextWindow
+		}
+		if maxOutput == 0 {
+			maxOutput = d.MaxOutputTokens
+		}
+	}
+	return contextWindow, maxOutput
+}
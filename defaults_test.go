@@ -0,0 +1,87 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestModelDefaultsResolutionOrder(t *testing.T) {
+	cfg := Config{
+		Defaults: ModelDefaults{Temperature: ptr(0.7), MaxTokens: ptr(1000), Extra: map[string]any{"tier": "global", "seed": 1}},
+		ModelDefaults: map[string]ModelDefaults{
+			"kimi-k2-thinking": {MaxTokens: ptr(32000), Extra: map[string]any{"tier": "exact"}},
+			"kimi-":            {MaxTokens: ptr(8000), Temperature: ptr(0.6)},
+			"kimi-k2":          {Temperature: ptr(0.5), Reasoning: &NormalizedReasoning{Effort: "high"}},
+			"claude-":          {Temperature: ptr(1.0)},
+		},
+	}
+
+	got := cfg.applyModelDefaults(NormalizedRequest{Model: "kimi-k2-thinking"})
+	if *got.MaxTokens != 32000 {
+		t.Fatalf("exact match should beat prefix: max tokens %d", *got.MaxTokens)
+	}
+	if *got.Temperature != 0.5 {
+		t.Fatalf("longest prefix should beat shorter prefix and global: temperature %v", *got.Temperature)
+	}
+	if got.Reasoning == nil || got.Reasoning.Effort != "high" {
+		t.Fatalf("prefix reasoning should apply: %+v", got.Reasoning)
+	}
+	if got.Extra["tier"] != "exact" || got.Extra["seed"] != 1 {
+		t.Fatalf("extra layering mismatch: %v", got.Extra)
+	}
+
+	got = cfg.applyModelDefaults(NormalizedRequest{Model: "gpt-5.1"})
+	if *got.Temperature != 0.7 || *got.MaxTokens != 1000 || got.Reasoning != nil {
+		t.Fatalf("global defaults should apply to unmatched models: %+v", got)
+	}
+
+	callerExtra := map[string]any{"tier": "request"}
+	got = cfg.applyModelDefaults(NormalizedRequest{
+		Model:       "opencode/kimi-k2-thinking",
+		Temperature: ptr(0.1),
+		MaxTokens:   ptr(10),
+		Extra:       callerExtra,
+	})
+	if *got.Temperature != 0.1 || *got.MaxTokens != 10 || got.Extra["tier"] != "request" {
+		t.Fatalf("request values must win: %+v", got)
+	}
+	if len(callerExtra) != 1 {
+		t.Fatalf("caller's Extra map must not be modified: %v", callerExtra)
+	}
+}
+
+func TestModelDefaultsAppliedToPayload(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(payload, &body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{
+		APIKey:        "key",
+		BaseURL:       server.URL,
+		ModelDefaults: map[string]ModelDefaults{"kimi-k2-thinking": {MaxTokens: ptr(32000)}},
+	})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+
+	if _, err := drainStreamEvents(context.Background(), client, NormalizedRequest{
+		Model:    "kimi-k2-thinking",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	}); err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if body["max_tokens"] != float64(32000) {
+		t.Fatalf("expected max_tokens default in payload, got %v", body["max_tokens"])
+	}
+}
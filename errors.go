@@ -1,16 +1,62 @@
 package zen
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// StatusOverloaded is the non-standard status Anthropic answers with when
+// its models are overloaded.
+const StatusOverloaded = 529
+
 type APIError struct {
 	StatusCode int
 	RequestID  string
 	Message    string
-	Body       []byte
+	// Type is the provider's error type, e.g. "overloaded_error".
+	Type string
+	Body []byte
+	// RequestBody is the request payload that failed, set only with
+	// Config.CaptureRequestOnError and cut to Config.CaptureRequestMaxBytes.
+	RequestBody []byte
+	// Attempts lists every attempt of a request that was retried, the last
+	// being the one that returned this error. It is nil when the request
+	// was sent once.
+	Attempts []AttemptInfo
+}
+
+// AttemptInfo describes one attempt of a retried request.
+type AttemptInfo struct {
+	// StatusCode is zero when no response arrived.
+	StatusCode int
+	// Duration runs from sending the request to reading the response body.
+	Duration time.Duration
+	// RetryAfter is the delay asked for by the response's Retry-After
+	// header, zero when it had none.
+	RetryAfter time.Duration
+	// Err is the transport error of an attempt that got no response or
+	// failed reading it.
+	Err error
+}
+
+// RetryError is returned when the last attempt of a retried request got no
+// response or failed reading it. Err is that attempt's error; Attempts lists
+// every attempt, like APIError.Attempts.
+type RetryError struct {
+	Err      error
+	Attempts []AttemptInfo
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("zen: request failed after %d attempts: %v", len(e.Attempts), e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
 }
 
 func (e *APIError) Error() string {
@@ -20,6 +66,12 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("zen: request failed with status %d: %s", e.StatusCode, e.Message)
 }
 
+// IsOverloaded reports whether the provider was overloaded, either before
+// the response started or in an error event of a stream.
+func (e *APIError) IsOverloaded() bool {
+	return e.StatusCode == StatusOverloaded || e.Type == "overloaded_error"
+}
+
 type apiErrorEnvelope struct {
 	Error struct {
 		Message string `json:"message"`
@@ -29,15 +81,21 @@ type apiErrorEnvelope struct {
 	Message string `json:"message"`
 }
 
-func newAPIError(status int, header http.Header, body []byte) *APIError {
+func requestIDFromHeader(header http.Header) string {
 	reqID := header.Get("x-request-id")
 	if reqID == "" {
 		reqID = header.Get("request-id")
 	}
+	return reqID
+}
+
+func newAPIError(status int, header http.Header, body []byte) *APIError {
+	reqID := requestIDFromHeader(header)
 
-	msg := ""
+	msg, typ := "", ""
 	var env apiErrorEnvelope
-	if err := json.Unmarshal(body, &env); err == nil {
+	if err := jsonUnmarshal(body, &env); err == nil {
+		typ = env.Error.Type
 		if env.Error.Message != "" {
 			msg = env.Error.Message
 		} else if env.Message != "" {
@@ -49,6 +107,57 @@ func newAPIError(status int, header http.Header, body []byte) *APIError {
 		StatusCode: status,
 		RequestID:  reqID,
 		Message:    msg,
+		Type:       typ,
 		Body:       body,
 	}
 }
+
+// parseRetryAfter returns the delay of a Retry-After header, given either
+// in seconds or as an HTTP date, or zero when there is none.
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	v := strings.TrimSpace(header.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// DefaultCaptureRequestBytes is the RequestBody size limit used when
+// Config.CaptureRequestMaxBytes is 0.
+const DefaultCaptureRequestBytes = 64 << 10
+
+type capturedRequestKey struct{}
+
+// withCapturedRequest makes captureRequest record body in place of the
+// payload actually sent, e.g. a redacted copy of it.
+func withCapturedRequest(ctx context.Context, body []byte) context.Context {
+	return context.WithValue(ctx, capturedRequestKey{}, body)
+}
+
+// captureRequest sets apiErr.RequestBody when Config.CaptureRequestOnError
+// is set.
+func (c *Client) captureRequest(ctx context.Context, apiErr *APIError, body []byte) {
+	if !c.cfg.CaptureRequestOnError {
+		return
+	}
+	if captured, ok := ctx.Value(capturedRequestKey{}).([]byte); ok {
+		body = captured
+	}
+	limit := c.cfg.CaptureRequestMaxBytes
+	if limit == 0 {
+		limit = DefaultCaptureRequestBytes
+	}
+	if limit > 0 && len(body) > limit {
+		body = body[:limit]
+	}
+	apiErr.RequestBody = append([]byte(nil), body...)
+}
@@ -0,0 +1,122 @@
+package zen
+
+// Event types of Responses API streams, sent as the "type" field of each
+// event's data.
+const (
+	ResponsesEventCreated                    = "response.created"
+	ResponsesEventInProgress                 = "response.in_progress"
+	ResponsesEventCompleted                  = "response.completed"
+	ResponsesEventIncomplete                 = "response.incomplete"
+	ResponsesEventFailed                     = "response.failed"
+	ResponsesEventOutputItemAdded            = "response.output_item.added"
+	ResponsesEventOutputItemDone             = "response.output_item.done"
+	ResponsesEventOutputTextDelta            = "response.output_text.delta"
+	ResponsesEventReasoningSummaryTextDelta  = "response.reasoning_summary_text.delta"
+	ResponsesEventReasoningTextDelta         = "response.reasoning_text.delta"
+	ResponsesEventFunctionCallArgumentsDelta = "response.function_call_arguments.delta"
+	ResponsesEventFunctionCallArgumentsDone  = "response.function_call_arguments.done"
+	ResponsesEventWebSearchCallInProgress    = "response.web_search_call.in_progress"
+	ResponsesEventWebSearchCallSearching     = "response.web_search_call.searching"
+	ResponsesEventWebSearchCallCompleted     = "response.web_search_call.completed"
+	ResponsesEventFileSearchCallInProgress   = "response.file_search_call.in_progress"
+	ResponsesEventFileSearchCallSearching    = "response.file_search_call.searching"
+	ResponsesEventFileSearchCallCompleted    = "response.file_search_call.completed"
+)
+
+// responsesEventTypes lists the Responses event types the SDK recognizes.
+var responsesEventTypes = []string{
+	ResponsesEventCreated,
+	ResponsesEventInProgress,
+	ResponsesEventCompleted,
+	ResponsesEventIncomplete,
+	ResponsesEventFailed,
+	ResponsesEventOutputItemAdded,
+	ResponsesEventOutputItemDone,
+	ResponsesEventOutputTextDelta,
+	ResponsesEventReasoningSummaryTextDelta,
+	ResponsesEventReasoningTextDelta,
+	ResponsesEventFunctionCallArgumentsDelta,
+	ResponsesEventFunctionCallArgumentsDone,
+	ResponsesEventWebSearchCallInProgress,
+	ResponsesEventWebSearchCallSearching,
+	ResponsesEventWebSearchCallCompleted,
+	ResponsesEventFileSearchCallInProgress,
+	ResponsesEventFileSearchCallSearching,
+	ResponsesEventFileSearchCallCompleted,
+}
+
+// responsesEventAliases maps the spellings some gateways use in place of the
+// documented Responses events to the event they stand for.
+var responsesEventAliases = map[string]string{
+	"response.done":                          ResponsesEventCompleted,
+	"response.reasoning.delta":               ResponsesEventReasoningTextDelta,
+	"response.function_call_arguments_delta": ResponsesEventFunctionCallArgumentsDelta,
+	"response.function_call_arguments_done":  ResponsesEventFunctionCallArgumentsDone,
+}
+
+// canonicalResponsesEvent returns the documented event type typ stands for.
+func canonicalResponsesEvent(typ string) string {
+	if canonical, ok := responsesEventAliases[typ]; ok {
+		return canonical
+	}
+	return typ
+}
+
+// Event types of Anthropic Messages streams, sent both as the SSE event name
+// and as the "type" field of the event's data.
+const (
+	MessagesEventMessageStart      = "message_start"
+	MessagesEventMessageDelta      = "message_delta"
+	MessagesEventMessageStop       = "message_stop"
+	MessagesEventContentBlockStart = "content_block_start"
+	MessagesEventContentBlockDelta = "content_block_delta"
+	MessagesEventContentBlockStop  = "content_block_stop"
+	MessagesEventPing              = "ping"
+	MessagesEventError             = "error"
+)
+
+// messagesEventTypes lists the Messages event types the SDK recognizes.
+var messagesEventTypes = []string{
+	MessagesEventMessageStart,
+	MessagesEventMessageDelta,
+	MessagesEventMessageStop,
+	MessagesEventContentBlockStart,
+	MessagesEventContentBlockDelta,
+	MessagesEventContentBlockStop,
+	MessagesEventPing,
+	MessagesEventError,
+}
+
+// String returns t as a string.
+func (t NormalizedDeltaType) String() string {
+	return string(t)
+}
+
+// IsTerminal reports whether t ends a stream's deltas, which only DeltaDone
+// does. A DeltaError carrying an *APIError also ends the stream, but with an
+// error; see DeltaError.
+func (t NormalizedDeltaType) IsTerminal() bool {
+	return t == DeltaDone
+}
+
+// IsToolDelta reports whether t is one of the deltas of a tool call the
+// caller runs: DeltaToolCallBegin, DeltaToolCallArgumentsDelta or
+// DeltaToolCallDone. Tools the provider runs itself are reported as
+// DeltaServerToolCall, which is not included.
+func IsToolDelta(t NormalizedDeltaType) bool {
+	switch t {
+	case DeltaToolCallBegin, DeltaToolCallArgumentsDelta, DeltaToolCallDone:
+		return true
+	}
+	return false
+}
+
+// IsContentDelta reports whether t carries model output: DeltaText,
+// DeltaReasoning, DeltaAudio or DeltaGeminiPart.
+func IsContentDelta(t NormalizedDeltaType) bool {
+	switch t {
+	case DeltaText, DeltaReasoning, DeltaAudio, DeltaGeminiPart:
+		return true
+	}
+	return false
+}
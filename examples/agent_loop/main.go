@@ -36,12 +36,11 @@ func main() {
 			return fmt.Sprintf("%.0f", input.A+input.B), nil
 		},
 	}
-	toolName := singleToolName(tools)
 
 	models := resolveModels(os.Args[1:])
 	for _, model := range models {
 		fmt.Printf("=== Model: %s ===\n", model)
-		if err := runAgentLoop(client, model, debugSSE, tools, toolName); err != nil {
+		if err := runAgentLoop(client, model, debugSSE, tools); err != nil {
 			fmt.Fprintf(os.Stderr, "model %s failed: %v\n", model, err)
 			if apiErr, ok := err.(*zen.APIError); ok && len(apiErr.Body) > 0 {
 				fmt.Fprintf(os.Stderr, "api error body: %s\n", string(apiErr.Body))
@@ -50,7 +49,7 @@ func main() {
 	}
 }
 
-func runAgentLoop(client *zen.Client, model string, debugSSE bool, tools map[string]func(context.Context, json.RawMessage) (string, error), toolName string) error {
+func runAgentLoop(client *zen.Client, model string, debugSSE bool, tools map[string]func(context.Context, json.RawMessage) (string, error)) error {
 	messages := []zen.NormalizedMessage{{
 		Role:    "user",
 		Content: "What is 3 + 4, then double it?",
@@ -121,11 +120,11 @@ func runAgentLoop(client *zen.Client, model string, debugSSE bool, tools map[str
 			return nil
 		}
 
+		// Some backends drop the tool name; CheckToolCalls fills it in when
+		// only one tool is declared and rejects calls to unknown tools.
+		calls, rejected := zen.CheckToolCalls(calls, req.Tools)
 		assistant := zen.NormalizedMessage{Role: "assistant"}
 		for i := range calls {
-			if calls[i].Name == "" && toolName != "" {
-				calls[i].Name = toolName
-			}
 			assistant.ToolCalls = append(assistant.ToolCalls, zen.NormalizedToolCall{
 				ID:               calls[i].ID,
 				Name:             calls[i].Name,
@@ -136,6 +135,11 @@ func runAgentLoop(client *zen.Client, model string, debugSSE bool, tools map[str
 		messages = append(messages, assistant)
 
 		for _, call := range calls {
+			if msg, ok := rejected[call.ID]; ok {
+				fmt.Printf("\n[tool:%s] %s\n", call.Name, msg.Content)
+				messages = append(messages, msg)
+				continue
+			}
 			tool := tools[call.Name]
 			result := ""
 			if tool == nil {
@@ -224,16 +228,6 @@ func resolveModels(args []string) []string {
 	return []string{"gpt-5.1"}
 }
 
-func singleToolName(tools map[string]func(context.Context, json.RawMessage) (string, error)) string {
-	if len(tools) != 1 {
-		return ""
-	}
-	for name := range tools {
-		return name
-	}
-	return ""
-}
-
 func normalizeModelAlias(value string) string {
 	model := strings.ToLower(strings.TrimSpace(value))
 	if model == "" {
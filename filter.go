@@ -0,0 +1,39 @@
+package zen
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrFilteredStream is wrapped by the *FilteredStreamError returned when
+// Config.DeltaFilter stops a response.
+var ErrFilteredStream = errors.New("zen: response stopped by delta filter")
+
+// FilteredStreamError reports a response stopped by Config.DeltaFilter:
+// Delta is the delta it rejected, which was not delivered, and Err the
+// error it returned.
+type FilteredStreamError struct {
+	Delta NormalizedDelta
+	Err   error
+}
+
+func (e *FilteredStreamError) Error() string {
+	return fmt.Sprintf("%v: %s delta: %v", ErrFilteredStream, e.Delta.Type, e.Err)
+}
+
+func (e *FilteredStreamError) Unwrap() []error {
+	return []error{ErrFilteredStream, e.Err}
+}
+
+// filterDelta passes d through Config.DeltaFilter, reporting false when the
+// filter drops it.
+func (c *Client) filterDelta(d NormalizedDelta) (NormalizedDelta, bool, error) {
+	if c.cfg.DeltaFilter == nil {
+		return d, true, nil
+	}
+	out, err := c.cfg.DeltaFilter(d)
+	if err != nil {
+		return d, false, &FilteredStreamError{Delta: d, Err: err}
+	}
+	return out, out.Type != "", nil
+}
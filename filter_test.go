@@ -0,0 +1,76 @@
+package zen
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var errUnsafe = errors.New("unsafe output")
+
+// moderate upper-cases text, drops usage and rejects "forbidden".
+func moderate(d NormalizedDelta) (NormalizedDelta, error) {
+	switch {
+	case d.Type == DeltaUsage:
+		return NormalizedDelta{}, nil
+	case strings.Contains(d.Content, "forbidden"):
+		return NormalizedDelta{}, errUnsafe
+	}
+	d.Content = strings.ToUpper(d.Content)
+	return d, nil
+}
+
+func TestDeltaFilter(t *testing.T) {
+	chunk := func(text string) string {
+		return `data: {"choices":[{"index":0,"delta":{"content":"` + text + `"}}]}` + "\n\n"
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"stream":true`) {
+			_, _ = io.WriteString(w, `{"choices":[{"message":{"content":"calm reply"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, chunk("hello ")+chunk("forbidden")+chunk("world"))
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, DeltaFilter: moderate})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	deltas, errs, err := client.Stream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var got []string
+	for d := range deltas {
+		got = append(got, d.Content)
+	}
+	err = <-errs
+	var filtered *FilteredStreamError
+	if !errors.As(err, &filtered) || !errors.Is(err, ErrFilteredStream) || !errors.Is(err, errUnsafe) {
+		t.Fatalf("expected a filtered stream error, got %v", err)
+	}
+	if filtered.Delta.Content != "forbidden" || strings.Join(got, "|") != "HELLO " {
+		t.Fatalf("expected HELLO delivered and forbidden blocked, got %q and %+v", got, filtered.Delta)
+	}
+
+	resp, err := client.CollectStream(testCtx(t), req)
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || !errors.Is(err, ErrFilteredStream) || resp.Text != "HELLO " {
+		t.Fatalf("CollectStream: expected a filtered stream error after HELLO, got %+v, %v", resp, err)
+	}
+
+	resp, err = client.CreateNormalized(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("CreateNormalized: %v", err)
+	}
+	if resp.Text != "CALM REPLY" || resp.InputTokens != 0 || resp.FinishReason != "stop" {
+		t.Fatalf("non-streaming response not filtered: %+v", resp)
+	}
+}
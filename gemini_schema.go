@@ -0,0 +1,377 @@
+package zen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// geminiSchemaKeywords are the Schema fields Gemini's functionDeclarations
+// accept; sanitizeGeminiSchema removes every other keyword.
+var geminiSchemaKeywords = map[string]bool{
+	"type": true, "format": true, "title": true, "description": true,
+	"nullable": true, "enum": true, "default": true, "example": true,
+	"properties": true, "required": true, "propertyOrdering": true,
+	"minProperties": true, "maxProperties": true,
+	"items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+	"minimum": true, "maximum": true, "anyOf": true,
+}
+
+// geminiSchemaFormats are the formats Gemini accepts.
+var geminiSchemaFormats = map[string]bool{
+	"enum": true, "date-time": true, "float": true, "double": true, "int32": true, "int64": true,
+}
+
+// geminiTypeKeywords are the keywords that only constrain values of one
+// type. When a type list becomes an anyOf, they move into the branches of
+// the types they apply to.
+var geminiTypeKeywords = map[string][]string{
+	"string":  {"enum", "format", "minLength", "maxLength", "pattern"},
+	"number":  {"enum", "format", "minimum", "maximum"},
+	"integer": {"enum", "format", "minimum", "maximum"},
+	"boolean": {"enum"},
+	"array":   {"items", "minItems", "maxItems"},
+	"object":  {"properties", "required", "propertyOrdering", "minProperties", "maxProperties"},
+}
+
+// schemaChange records one rewrite sanitizeGeminiSchema made.
+type schemaChange struct {
+	path   string // JSON Pointer into the original schema
+	reason string
+}
+
+// sanitizeGeminiSchema rewrites a tool's Parameters into the JSON Schema
+// subset Gemini accepts: local $refs are inlined, oneOf becomes anyOf, allOf
+// is merged, const becomes a one-value enum, type lists become nullable or
+// anyOf, and unsupported keywords and formats are removed. Parameters that
+// need no change, or are not a JSON object, are returned as they are.
+func sanitizeGeminiSchema(params json.RawMessage) (json.RawMessage, []schemaChange) {
+	if len(bytes.TrimSpace(params)) == 0 {
+		return params, nil
+	}
+	schema, err := decodeJSONNumbers(params)
+	if err != nil {
+		return params, nil
+	}
+	root, ok := schema.(map[string]any)
+	if !ok {
+		return params, nil
+	}
+	s := &geminiSchemaSanitizer{root: root}
+	out := s.sanitize(root, "")
+	if len(s.changes) == 0 {
+		return params, nil
+	}
+	data, err := jsonMarshal(out)
+	if err != nil {
+		return params, nil
+	}
+	return data, s.changes
+}
+
+type geminiSchemaSanitizer struct {
+	root    map[string]any
+	changes []schemaChange
+	refs    []string // $refs being inlined, to stop at recursive ones
+}
+
+func (s *geminiSchemaSanitizer) change(path, format string, args ...any) {
+	s.changes = append(s.changes, schemaChange{path: path, reason: fmt.Sprintf(format, args...)})
+}
+
+func (s *geminiSchemaSanitizer) sanitize(node map[string]any, path string) map[string]any {
+	if ref, ok := node["$ref"].(string); ok {
+		return s.inline(node, ref, path)
+	}
+	out := make(map[string]any, len(node))
+	// allOf branches are merged once the node's own keywords are in out,
+	// so they add to its properties and required instead of being
+	// overwritten by them.
+	var allOf, types []any
+	var constValue any
+	hasConst := false
+	for _, key := range sortedSchemaKeys(node) {
+		value := node[key]
+		keyPath := path + "/" + escapeJSONPointer(key)
+		switch key {
+		case "type":
+			types = s.sanitizeType(out, value, keyPath)
+		case "const":
+			constValue, hasConst = value, true
+		case "oneOf", "anyOf":
+			branches := s.sanitizeList(value, keyPath)
+			if key == "oneOf" {
+				s.change(keyPath, "Gemini does not support oneOf; sent as anyOf")
+			}
+			if existing, ok := out["anyOf"].([]any); ok {
+				branches = append(existing, branches...)
+			}
+			out["anyOf"] = branches
+		case "allOf":
+			allOf = s.sanitizeList(value, keyPath)
+			s.change(keyPath, "Gemini does not support allOf; merged into the schema")
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				s.change(keyPath, "properties is not an object; removed")
+				continue
+			}
+			sanitized := make(map[string]any, len(props))
+			for _, name := range sortedSchemaKeys(props) {
+				if prop, ok := props[name].(map[string]any); ok {
+					sanitized[name] = s.sanitize(prop, keyPath+"/"+escapeJSONPointer(name))
+				} else {
+					sanitized[name] = map[string]any{}
+					s.change(keyPath+"/"+escapeJSONPointer(name), "boolean schema sent as an empty schema")
+				}
+			}
+			out[key] = sanitized
+		case "items":
+			if items, ok := value.(map[string]any); ok {
+				out[key] = s.sanitize(items, keyPath)
+			} else {
+				s.change(keyPath, "Gemini only supports a single items schema; removed")
+			}
+		case "format":
+			if f, ok := value.(string); ok && geminiSchemaFormats[f] {
+				out[key] = value
+			} else {
+				s.change(keyPath, "Gemini does not support format %v; removed", value)
+			}
+		case "$defs", "definitions":
+			s.change(keyPath, "Gemini does not support %s; references are inlined", key)
+		default:
+			if geminiSchemaKeywords[key] {
+				out[key] = value
+			} else {
+				s.change(keyPath, "Gemini does not support %s; removed", key)
+			}
+		}
+	}
+	if hasConst {
+		s.sanitizeConst(out, constValue, path+"/const")
+	}
+	for _, branch := range allOf {
+		mergeGeminiSchema(out, branch.(map[string]any))
+	}
+	if len(types) > 0 {
+		splitGeminiTypes(out, types)
+	}
+	return out
+}
+
+// sanitizeConst sends const as a one-value enum, the intersection with the
+// node's own enum when it has one.
+func (s *geminiSchemaSanitizer) sanitizeConst(out map[string]any, value any, path string) {
+	enum, hasEnum := out["enum"].([]any)
+	out["enum"] = []any{value}
+	switch {
+	case !hasEnum:
+		s.change(path, "Gemini does not support const; sent as a one-value enum")
+	case slices.ContainsFunc(enum, func(v any) bool { return reflect.DeepEqual(v, value) }):
+		s.change(path, "Gemini does not support const; sent as enum, narrowed to the const value")
+	default:
+		s.change(path, "const %v is not one of enum; sent as a one-value enum", value)
+	}
+}
+
+// sanitizeList sanitizes the schemas of an anyOf, oneOf or allOf list,
+// dropping entries that are not schema objects.
+func (s *geminiSchemaSanitizer) sanitizeList(value any, path string) []any {
+	list, _ := value.([]any)
+	out := make([]any, 0, len(list))
+	for i, item := range list {
+		branch, ok := item.(map[string]any)
+		if !ok {
+			s.change(fmt.Sprintf("%s/%d", path, i), "boolean schema removed")
+			continue
+		}
+		out = append(out, s.sanitize(branch, fmt.Sprintf("%s/%d", path, i)))
+	}
+	return out
+}
+
+// inline replaces a $ref with the schema it points to. Keywords next to the
+// $ref, such as a description, take precedence over the target's.
+func (s *geminiSchemaSanitizer) inline(node map[string]any, ref, path string) map[string]any {
+	refPath := path + "/$ref"
+	siblings := make(map[string]any, len(node))
+	for k, v := range node {
+		if k != "$ref" {
+			siblings[k] = v
+		}
+	}
+	if slices.Contains(s.refs, ref) {
+		s.change(refPath, "recursive reference %s sent as an object of any shape", ref)
+		out := s.sanitize(siblings, path)
+		if _, ok := out["type"]; !ok {
+			out["type"] = "object"
+		}
+		return out
+	}
+	target, err := resolveSchemaRef(s.root, ref)
+	targetSchema, ok := target.(map[string]any)
+	if err != nil || !ok {
+		s.change(refPath, "unresolvable reference %s removed", ref)
+		return s.sanitize(siblings, path)
+	}
+	s.change(refPath, "Gemini does not support $ref; inlined %s", ref)
+	s.refs = append(s.refs, ref)
+	out := s.sanitize(targetSchema, path)
+	s.refs = s.refs[:len(s.refs)-1]
+	maps.Copy(out, s.sanitize(siblings, path))
+	return out
+}
+
+// sanitizeType sets out's type from a JSON Schema type, which Gemini only
+// accepts as a single name: "null" in a list becomes nullable. Several
+// other names are returned, to become an anyOf of one schema per type once
+// the node's other keywords are known (see splitGeminiTypes).
+func (s *geminiSchemaSanitizer) sanitizeType(out map[string]any, value any, path string) []any {
+	list, ok := value.([]any)
+	if !ok {
+		out["type"] = value
+		return nil
+	}
+	var types []any
+	for _, t := range list {
+		if t == "null" {
+			out["nullable"] = true
+		} else {
+			types = append(types, t)
+		}
+	}
+	switch len(types) {
+	case 0:
+		s.change(path, "Gemini does not support type lists; sent as nullable")
+	case 1:
+		out["type"] = types[0]
+		s.change(path, "Gemini does not support type lists; sent as %v", types[0])
+	default:
+		s.change(path, "Gemini does not support type lists; sent as anyOf")
+		return types
+	}
+	return nil
+}
+
+// splitGeminiTypes adds an anyOf branch per type to out and moves the
+// keywords that constrain a single type into the branches of that type.
+// Annotations such as description stay on out.
+func splitGeminiTypes(out map[string]any, types []any) {
+	branches, _ := out["anyOf"].([]any)
+	for _, t := range types {
+		name, _ := t.(string)
+		branch := map[string]any{"type": t}
+		for _, k := range geminiTypeKeywords[name] {
+			v, ok := out[k]
+			if !ok {
+				continue
+			}
+			if k == "enum" {
+				enum := enumOfType(v, name)
+				if len(enum) == 0 {
+					continue
+				}
+				v = enum
+			}
+			branch[k] = v
+		}
+		branches = append(branches, branch)
+	}
+	for _, keywords := range geminiTypeKeywords {
+		for _, k := range keywords {
+			delete(out, k)
+		}
+	}
+	out["anyOf"] = branches
+}
+
+// enumOfType returns the values of an enum that are of JSON type typ.
+func enumOfType(enum any, typ string) []any {
+	list, _ := enum.([]any)
+	var out []any
+	for _, v := range list {
+		var match bool
+		switch v.(type) {
+		case string:
+			match = typ == "string"
+		case json.Number:
+			match = typ == "number" || typ == "integer"
+		case bool:
+			match = typ == "boolean"
+		}
+		if match {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// mergeGeminiSchema merges an allOf branch into out: properties and required
+// are combined, other keywords are kept from out when it sets them.
+func mergeGeminiSchema(out, branch map[string]any) {
+	for k, v := range branch {
+		switch k {
+		case "properties":
+			props, _ := out[k].(map[string]any)
+			if props == nil {
+				props = map[string]any{}
+			}
+			branchProps, _ := v.(map[string]any)
+			for name, prop := range branchProps {
+				if _, ok := props[name]; !ok {
+					props[name] = prop
+				}
+			}
+			out[k] = props
+		case "required":
+			required, _ := out[k].([]any)
+			required = slices.Clone(required)
+			names, _ := v.([]any)
+			for _, name := range names {
+				if !slices.Contains(required, name) {
+					required = append(required, name)
+				}
+			}
+			out[k] = required
+		default:
+			if _, ok := out[k]; !ok {
+				out[k] = v
+			}
+		}
+	}
+}
+
+func sortedSchemaKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// geminiSchemaWarnings returns a Warning per change sanitizing the tool
+// schemas of r for Gemini makes.
+func (r NormalizedRequest) geminiSchemaWarnings() []Warning {
+	if r.RawToolSchemas || r.toolsDisabled() {
+		return nil
+	}
+	var out []Warning
+	for _, t := range sortedNormalizedTools(r.Tools) {
+		_, changes := sanitizeGeminiSchema(t.Parameters)
+		for _, c := range changes {
+			out = append(out, Warning{
+				Field:    fmt.Sprintf("Tools[%s].Parameters%s", t.Name, c.path),
+				Endpoint: EndpointModels,
+				Reason:   c.reason,
+			})
+		}
+	}
+	return out
+}
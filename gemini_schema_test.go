@@ -0,0 +1,153 @@
+package zen
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// createEventTool has the shape of an OpenAI strict-mode tool: shared $defs,
+// additionalProperties: false everywhere and nullable fields as type lists.
+var createEventTool = NormalizedTool{
+	Name: "create_event",
+	Parameters: json.RawMessage(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"title": {"type": "string"},
+			"start": {"type": "string", "format": "date-time"},
+			"location": {"$ref": "#/$defs/place", "description": "Where it happens"},
+			"attendees": {"type": "array", "items": {"$ref": "#/$defs/person"}},
+			"notes": {"type": ["string", "null"]},
+			"kind": {"const": "meeting"},
+			"contact": {"oneOf": [{"type": "string", "format": "email"}, {"type": "integer"}]}
+		},
+		"required": ["title", "start", "location", "attendees", "notes", "kind", "contact"],
+		"additionalProperties": false,
+		"$defs": {
+			"place": {
+				"type": "object",
+				"properties": {"name": {"type": "string"}, "room": {"type": ["integer", "null"]}},
+				"required": ["name", "room"],
+				"additionalProperties": false
+			},
+			"person": {
+				"type": "object",
+				"properties": {"email": {"type": "string", "format": "email"}},
+				"required": ["email"],
+				"additionalProperties": false
+			}
+		}
+	}`),
+}
+
+func TestGeminiToolSchemaSanitized(t *testing.T) {
+	req := NormalizedRequest{
+		Model:    "gemini-2.5-pro",
+		Messages: []NormalizedMessage{{Role: "user", Content: "book it"}},
+		Tools:    []NormalizedTool{createEventTool},
+	}
+	out, err := req.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest: %v", err)
+	}
+	got := string(out.Tools[0].FunctionDeclarations[0].Parameters)
+	for _, keyword := range []string{"$ref", "$defs", "$schema", "additionalProperties", "oneOf", "const", `"email"}`} {
+		if strings.Contains(got, keyword) {
+			t.Fatalf("sanitized schema still contains %s: %s", keyword, got)
+		}
+	}
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(got), &schema); err != nil {
+		t.Fatalf("decode schema: %v", err)
+	}
+	want := map[string]string{
+		"location":  `{"description":"Where it happens","properties":{"name":{"type":"string"},"room":{"nullable":true,"type":"integer"}},"required":["name","room"],"type":"object"}`,
+		"attendees": `{"items":{"properties":{"email":{"type":"string"}},"required":["email"],"type":"object"},"type":"array"}`,
+		"notes":     `{"nullable":true,"type":"string"}`,
+		"kind":      `{"enum":["meeting"]}`,
+		"contact":   `{"anyOf":[{"type":"string"},{"type":"integer"}]}`,
+		"start":     `{"format":"date-time","type":"string"}`,
+	}
+	for name, w := range want {
+		if string(schema.Properties[name]) != w {
+			t.Fatalf("property %s:\nwant %s\ngot  %s", name, w, schema.Properties[name])
+		}
+	}
+
+	warnings := req.ConversionWarnings(EndpointModels)
+	fields := make([]string, len(warnings))
+	for i, w := range warnings {
+		fields[i] = w.Field
+	}
+	joined := strings.Join(fields, "\n")
+	for _, field := range []string{
+		"Tools[create_event].Parameters/$defs",
+		"Tools[create_event].Parameters/additionalProperties",
+		"Tools[create_event].Parameters/properties/location/$ref",
+		"Tools[create_event].Parameters/properties/notes/type",
+		"Tools[create_event].Parameters/properties/contact/oneOf/0/format",
+	} {
+		if !strings.Contains(joined, field) {
+			t.Fatalf("missing warning for %s in:\n%s", field, joined)
+		}
+	}
+	if w := req.ConversionWarnings(EndpointChatCompletions); len(w) != 0 {
+		t.Fatalf("schemas are only rewritten for Gemini, got %v", w)
+	}
+
+	req.RawToolSchemas = true
+	out, err = req.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest: %v", err)
+	}
+	if string(out.Tools[0].FunctionDeclarations[0].Parameters) != string(createEventTool.Parameters) {
+		t.Fatal("RawToolSchemas must send the schema as is")
+	}
+	if w := req.ConversionWarnings(EndpointModels); len(w) != 0 {
+		t.Fatalf("RawToolSchemas must not warn, got %v", w)
+	}
+}
+
+func TestGeminiToolSchemaEdgeCases(t *testing.T) {
+	// A schema Gemini accepts is sent byte for byte.
+	plain := json.RawMessage(`{"type":"object","properties":{"b":{"type":"string"},"a":{"type":"number"}}}`)
+	if got, changes := sanitizeGeminiSchema(plain); string(got) != string(plain) || len(changes) != 0 {
+		t.Fatalf("unchanged schema rewritten: %s %v", got, changes)
+	}
+
+	recursive := json.RawMessage(`{"$ref":"#/$defs/node","$defs":{"node":{"type":"object","properties":{"children":{"type":"array","items":{"$ref":"#/$defs/node"}}}}}}`)
+	got, _ := sanitizeGeminiSchema(recursive)
+	if want := `{"properties":{"children":{"items":{"type":"object"},"type":"array"}},"type":"object"}`; string(got) != want {
+		t.Fatalf("recursive schema:\nwant %s\ngot  %s", want, got)
+	}
+
+	merged := json.RawMessage(`{"allOf":[{"type":"object","properties":{"a":{"type":"string"}},"required":["a"]},{"properties":{"b":{"type":"integer","exclusiveMinimum":0}},"required":["b"]}]}`)
+	got, _ = sanitizeGeminiSchema(merged)
+	if want := `{"properties":{"a":{"type":"string"},"b":{"type":"integer"}},"required":["a","b"],"type":"object"}`; string(got) != want {
+		t.Fatalf("allOf schema:\nwant %s\ngot  %s", want, got)
+	}
+
+	// allOf next to the node's own properties and required adds to them.
+	siblings := json.RawMessage(`{"type":"object","properties":{"x":{"type":"string"}},"required":["x"],"allOf":[{"properties":{"y":{"type":"number"},"x":{"type":"integer"}},"required":["y"]}]}`)
+	got, _ = sanitizeGeminiSchema(siblings)
+	if want := `{"properties":{"x":{"type":"string"},"y":{"type":"number"}},"required":["x","y"],"type":"object"}`; string(got) != want {
+		t.Fatalf("allOf with own properties:\nwant %s\ngot  %s", want, got)
+	}
+	// const next to enum narrows it instead of replacing it.
+	constEnum := json.RawMessage(`{"type":"string","enum":["a","b"],"const":"b"}`)
+	got, _ = sanitizeGeminiSchema(constEnum)
+	if want := `{"enum":["b"],"type":"string"}`; string(got) != want {
+		t.Fatalf("const with enum:\nwant %s\ngot  %s", want, got)
+	}
+
+	// A type list keeps its annotations on the node and moves the keywords
+	// of each type into that type's branch.
+	typeList := json.RawMessage(`{"type":["string","integer","null"],"description":"An id","format":"int64","enum":["x",1],"minLength":1,"maximum":9}`)
+	got, _ = sanitizeGeminiSchema(typeList)
+	if want := `{"anyOf":[{"enum":["x"],"format":"int64","minLength":1,"type":"string"},{"enum":[1],"format":"int64","maximum":9,"type":"integer"}],"description":"An id","nullable":true}`; string(got) != want {
+		t.Fatalf("type list with siblings:\nwant %s\ngot  %s", want, got)
+	}
+}
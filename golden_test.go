@@ -0,0 +1,236 @@
+package zen
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Golden fixtures live in testdata/{endpoint}/{case}.json with the SSE body
+// the provider answered with in {case}.sse. The .json file holds the
+// normalized request, the exact provider payload and path the SDK must send
+// for it, and the deltas and response the SDK must parse from the .sse.
+//
+// After an intended change to request encoding or parsing, rewrite the
+// expectations from the current code and review the diff:
+//
+//	go test -run TestGolden -update
+//
+// To refresh the .sse files against the live gateway, set OPENCODE_API_KEY
+// and run with -record, which also implies -update. Recorded streams carry
+// real ids and model output; check them for anything that should not be
+// committed before doing so. Fixtures named synthetic_* hold hand-written
+// streams, such as a chunk split across events, and are not re-recorded.
+var (
+	updateGolden = flag.Bool("update", false, "rewrite golden expectations in testdata")
+	recordGolden = flag.Bool("record", false, "re-record golden SSE bodies against the live gateway")
+)
+
+type goldenFixture struct {
+	Request  json.RawMessage `json:"request"`
+	Path     string          `json:"path"`
+	Wire     json.RawMessage `json:"wire"`
+	Deltas   []string        `json:"deltas"`
+	Response goldenResponse  `json:"response"`
+}
+
+type goldenResponse struct {
+	Text          string           `json:"text,omitempty"`
+	Reasoning     string           `json:"reasoning,omitempty"`
+	ToolCalls     []StreamToolCall `json:"tool_calls,omitempty"`
+	FinishReason  string           `json:"finish_reason,omitempty"`
+	StopReason    StopReason       `json:"stop_reason,omitempty"`
+	FinishDetails json.RawMessage  `json:"finish_details,omitempty"`
+	InputTokens   int              `json:"input_tokens,omitempty"`
+	OutputTokens  int              `json:"output_tokens,omitempty"`
+	Model         string           `json:"model,omitempty"`
+}
+
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*", "*.json"))
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no golden fixtures found")
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.ToSlash(file), ".json")
+		t.Run(strings.TrimPrefix(name, "testdata/"), func(t *testing.T) {
+			runGoldenCase(t, name)
+		})
+	}
+}
+
+func runGoldenCase(t *testing.T, name string) {
+	data, err := os.ReadFile(name + ".json")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	var fixture goldenFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("decode fixture: %v", err)
+	}
+	var req NormalizedRequest
+	if err := json.Unmarshal(fixture.Request, &req); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+	req.Endpoint = EndpointType(filepath.Base(filepath.Dir(name)))
+
+	if *recordGolden && !strings.HasPrefix(filepath.Base(name), "synthetic_") {
+		recordGoldenSSE(t, name, req)
+	}
+	sse, err := os.ReadFile(name + ".sse")
+	if err != nil {
+		t.Fatalf("read SSE: %v", err)
+	}
+
+	var gotPath string
+	var gotWire []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.RequestURI()
+		gotWire, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write(sse)
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	deltaCh, errCh, err := client.Stream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var deltas []NormalizedDelta
+	gotDeltas := []string{}
+	for d := range deltaCh {
+		deltas = append(deltas, d)
+		gotDeltas = append(gotDeltas, string(d.Type))
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	resp, err := CollectDeltas(sliceDeltas(deltas), closedErrs())
+	if err != nil {
+		t.Fatalf("CollectDeltas: %v", err)
+	}
+	gotResponse := goldenResponse{
+		Text:          resp.Text,
+		Reasoning:     resp.Reasoning,
+		ToolCalls:     resp.ToolCalls,
+		FinishReason:  resp.FinishReason,
+		StopReason:    resp.StopReason,
+		FinishDetails: resp.FinishDetails,
+		InputTokens:   resp.InputTokens,
+		OutputTokens:  resp.OutputTokens,
+		Model:         resp.Model,
+	}
+
+	if *updateGolden || *recordGolden {
+		var wire bytes.Buffer
+		if err := json.Indent(&wire, gotWire, "", "  "); err != nil {
+			t.Fatalf("indent wire: %v", err)
+		}
+		fixture.Path = gotPath
+		fixture.Wire = wire.Bytes()
+		fixture.Deltas = gotDeltas
+		fixture.Response = gotResponse
+		out, err := json.MarshalIndent(fixture, "", "  ")
+		if err != nil {
+			t.Fatalf("encode fixture: %v", err)
+		}
+		if err := os.WriteFile(name+".json", append(out, '\n'), 0o644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		return
+	}
+
+	if gotPath != fixture.Path {
+		t.Errorf("path: want %s, got %s", fixture.Path, gotPath)
+	}
+	var wantWire bytes.Buffer
+	if err := json.Compact(&wantWire, fixture.Wire); err != nil {
+		t.Fatalf("compact wire: %v", err)
+	}
+	if !bytes.Equal(gotWire, wantWire.Bytes()) {
+		t.Errorf("wire payload changed:\nwant %s\ngot  %s", wantWire.Bytes(), gotWire)
+	}
+	if strings.Join(gotDeltas, ",") != strings.Join(fixture.Deltas, ",") {
+		t.Errorf("deltas: want %v, got %v", fixture.Deltas, gotDeltas)
+	}
+	want, _ := json.Marshal(fixture.Response)
+	got, _ := json.Marshal(gotResponse)
+	if !bytes.Equal(want, got) {
+		t.Errorf("response:\nwant %s\ngot  %s", want, got)
+	}
+}
+
+func sliceDeltas(deltas []NormalizedDelta) <-chan NormalizedDelta {
+	ch := make(chan NormalizedDelta, len(deltas))
+	for _, d := range deltas {
+		ch <- d
+	}
+	close(ch)
+	return ch
+}
+
+func closedErrs() <-chan error {
+	ch := make(chan error, 1)
+	close(ch)
+	return ch
+}
+
+// recordGoldenSSE sends req to the live gateway and stores the raw response
+// body as the case's .sse file.
+func recordGoldenSSE(t *testing.T, name string, req NormalizedRequest) {
+	apiKey := os.Getenv("OPENCODE_API_KEY")
+	if apiKey == "" {
+		t.Skip("-record needs OPENCODE_API_KEY")
+	}
+	var body bytes.Buffer
+	client, err := NewClient(Config{
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Transport: recordingTransport{body: &body}},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	events, errs, err := client.StreamEvents(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+	for range events {
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("record stream: %v", err)
+	}
+	if err := os.WriteFile(name+".sse", body.Bytes(), 0o644); err != nil {
+		t.Fatalf("write SSE: %v", err)
+	}
+}
+
+// recordingTransport copies every response body it returns into body.
+type recordingTransport struct {
+	body *bytes.Buffer
+}
+
+func (rt recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(resp.Body, rt.body), resp.Body}
+	return resp, nil
+}
@@ -0,0 +1,149 @@
+package zen
+
+import (
+	"context"
+	"io"
+)
+
+// channelPump reads a stream's item channel to its end and then its error
+// channel exactly once, which is the contract Client.Stream and
+// Client.StreamEvents leave to their callers.
+type channelPump[T any] struct {
+	items  <-chan T
+	errs   <-chan error
+	cancel context.CancelFunc
+	done   bool
+	err    error
+}
+
+func (p *channelPump[T]) next(ctx context.Context) (T, error) {
+	var zero T
+	if p.done {
+		return zero, p.end()
+	}
+	select {
+	case item, ok := <-p.items:
+		if ok {
+			return item, nil
+		}
+		p.done = true
+		p.err = <-p.errs
+		if p.cancel != nil {
+			p.cancel()
+		}
+		return zero, p.end()
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+func (p *channelPump[T]) end() error {
+	if p.err != nil {
+		return p.err
+	}
+	return io.EOF
+}
+
+func (p *channelPump[T]) close() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	if p.done {
+		return
+	}
+	for range p.items {
+	}
+	p.done = true
+	p.err = <-p.errs
+}
+
+// Next returns the next delta. Once the stream has ended it returns io.EOF,
+// or the error that ended it; a done ctx only abandons the wait and leaves
+// the stream open. Next must not be mixed with reading Deltas and Errs
+// directly, and a handle must not be used from several goroutines at once.
+func (h *StreamHandle) Next(ctx context.Context) (NormalizedDelta, error) {
+	return h.pump.next(ctx)
+}
+
+// Err returns the error that ended the stream, or nil if it has not ended
+// or ended cleanly.
+func (h *StreamHandle) Err() error {
+	return h.pump.err
+}
+
+// Close cancels the request if it is still running and drains the stream,
+// releasing its connection. It is safe to call after the stream ended and
+// more than once.
+func (h *StreamHandle) Close() error {
+	h.pump.close()
+	return nil
+}
+
+// StreamWithHandlers streams req and calls every handler with each delta,
+// in the order the handlers are given, so the slowest one sets the pace. It
+// returns once the stream has ended, with the error that ended it, or with
+// the first error a handler returns, which stops the stream.
+func (c *Client) StreamWithHandlers(ctx context.Context, req NormalizedRequest, handlers ...func(NormalizedDelta) error) error {
+	h, err := c.OpenStream(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+	for {
+		delta, err := h.Next(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, handle := range handlers {
+			if err := handle(delta); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// EventStream is an open stream of raw events, as returned by
+// Client.OpenStreamEvents. Events and Errs behave as the channels returned by
+// Client.StreamEvents; Next, Err and Close work as on StreamHandle.
+type EventStream struct {
+	Events <-chan UnifiedEvent
+	Errs   <-chan error
+	Info   StreamInfo
+
+	pump *channelPump[UnifiedEvent]
+}
+
+// OpenStreamEvents is StreamEvents returning an EventStream.
+func (c *Client) OpenStreamEvents(ctx context.Context, req NormalizedRequest) (*EventStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	events, errs, info, err := c.streamEvents(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &EventStream{
+		Events: events,
+		Errs:   errs,
+		Info:   *info,
+		pump:   &channelPump[UnifiedEvent]{items: events, errs: errs, cancel: cancel},
+	}, nil
+}
+
+// Next returns the next event; see StreamHandle.Next.
+func (s *EventStream) Next(ctx context.Context) (UnifiedEvent, error) {
+	return s.pump.next(ctx)
+}
+
+// Err returns the error that ended the stream; see StreamHandle.Err.
+func (s *EventStream) Err() error {
+	return s.pump.err
+}
+
+// Close cancels and drains the stream; see StreamHandle.Close.
+func (s *EventStream) Close() error {
+	s.pump.close()
+	return nil
+}
@@ -0,0 +1,57 @@
+package zen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// DefaultHashExclusions are the top-level body fields HashRequest ignores:
+// they change how a response is delivered, not what it contains.
+var DefaultHashExclusions = []string{"stream", "stream_options", "idempotency_key"}
+
+// HashRequest returns a stable hex SHA-256 digest identifying a request body
+// sent to endpoint, for caching, deduplication and idempotency keys. The body
+// is canonicalized first (object keys sorted, insignificant whitespace
+// removed, numbers kept as written) and the DefaultHashExclusions fields are
+// dropped, so a streaming and a non-streaming form of the same request hash
+// alike. Bodies that are not JSON are hashed as is.
+//
+// Bodies produced by this package (e.g. StreamInfo.Body) are already
+// deterministic: request types marshal through maps, whose keys
+// encoding/json sorts, and tools are sorted by name.
+func HashRequest(endpoint EndpointType, body []byte) string {
+	return HashRequestExcluding(endpoint, body, DefaultHashExclusions)
+}
+
+// HashRequestExcluding is HashRequest with a custom list of top-level fields
+// to ignore.
+func HashRequestExcluding(endpoint EndpointType, body []byte, exclude []string) string {
+	h := sha256.New()
+	h.Write([]byte(endpoint))
+	h.Write([]byte{0})
+	h.Write(canonicalJSON(body, exclude))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func canonicalJSON(body []byte, exclude []string) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return body
+	}
+	if obj, ok := v.(map[string]any); ok {
+		for _, key := range exclude {
+			delete(obj, key)
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
@@ -0,0 +1,68 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestHashRequestCanonical(t *testing.T) {
+	a := []byte(`{"model":"gpt-5.1","input":[{"role":"user","content":"hi"}],"temperature":0.5,"stream":true}`)
+	b := []byte(`{
+		"temperature": 0.5,
+		"input": [ { "content": "hi", "role": "user" } ],
+		"model": "gpt-5.1"
+	}`)
+	if HashRequest(EndpointResponses, a) != HashRequest(EndpointResponses, b) {
+		t.Fatalf("key order, whitespace and stream should not affect the hash")
+	}
+	if HashRequest(EndpointResponses, a) == HashRequest(EndpointChatCompletions, a) {
+		t.Fatalf("the endpoint should be part of the hash")
+	}
+
+	c := []byte(`{"model":"gpt-5.1","input":[{"role":"user","content":"hi"}],"temperature":0.7}`)
+	if HashRequest(EndpointResponses, a) == HashRequest(EndpointResponses, c) {
+		t.Fatalf("different parameters must hash differently")
+	}
+
+	if HashRequest(EndpointResponses, a) == HashRequestExcluding(EndpointResponses, a, nil) {
+		t.Fatalf("an empty exclusion list should keep stream")
+	}
+	if HashRequestExcluding(EndpointResponses, a, []string{"stream", "temperature"}) != HashRequestExcluding(EndpointResponses, c, []string{"stream", "temperature"}) {
+		t.Fatalf("custom exclusions should be ignored")
+	}
+
+	if got := HashRequest(EndpointResponses, []byte("not json")); len(got) != 64 {
+		t.Fatalf("non-JSON bodies should still hash, got %q", got)
+	}
+}
+
+func TestBuildRequestBodyIsDeterministic(t *testing.T) {
+	client, err := NewClient(Config{APIKey: "key"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req := NormalizedRequest{
+		Model:    "claude-sonnet-4-6",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+		Tools: []NormalizedTool{
+			{Name: "b", Parameters: json.RawMessage(`{"type":"object"}`)},
+			{Name: "a", Parameters: json.RawMessage(`{"type":"object"}`)},
+		},
+		Extra: map[string]any{"metadata": map[string]any{"z": 1, "a": 2, "m": 3}, "top_k": 5},
+	}
+
+	_, _, _, first, err := client.buildRequest(context.Background(), req, true)
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		_, _, _, body, err := client.buildRequest(context.Background(), req, true)
+		if err != nil {
+			t.Fatalf("buildRequest: %v", err)
+		}
+		if string(body) != string(first) {
+			t.Fatalf("body changed between builds:\n%s\n%s", first, body)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package zen
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextHeadersKey struct{}
+
+// authHeaderNames are never taken from context headers; authentication is
+// always derived from Config.
+var authHeaderNames = map[string]bool{
+	"Authorization":  true,
+	"X-Api-Key":      true,
+	"X-Goog-Api-Key": true,
+}
+
+// ContextWithHeaders returns a context whose outgoing SDK requests carry h,
+// e.g. trace or tenant ids. Headers from nested calls are merged, with values
+// from the innermost call replacing earlier ones. They are applied after the
+// SDK's default headers and so replace them, but authentication headers in h
+// are ignored.
+func ContextWithHeaders(ctx context.Context, h http.Header) context.Context {
+	merged := HeadersFromContext(ctx)
+	if merged == nil {
+		merged = make(http.Header, len(h))
+	}
+	for k, v := range h {
+		merged[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+	return context.WithValue(ctx, contextHeadersKey{}, merged)
+}
+
+// HeadersFromContext returns a copy of the headers attached with
+// ContextWithHeaders, or nil.
+func HeadersFromContext(ctx context.Context) http.Header {
+	h, _ := ctx.Value(contextHeadersKey{}).(http.Header)
+	if h == nil {
+		return nil
+	}
+	return h.Clone()
+}
+
+func applyContextHeaders(req *http.Request) {
+	h, _ := req.Context().Value(contextHeadersKey{}).(http.Header)
+	for k, v := range h {
+		if authHeaderNames[k] {
+			continue
+		}
+		req.Header[k] = append([]string(nil), v...)
+	}
+}
@@ -0,0 +1,174 @@
+package zen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextHeadersPropagate(t *testing.T) {
+	var captured []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = append(captured, r.Header.Clone())
+		if r.URL.Path == "/models" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+
+	ctx := ContextWithHeaders(context.Background(), http.Header{
+		"X-Trace-Id":    {"trace-1"},
+		"Authorization": {"Bearer stolen"},
+		"x-api-key":     {"stolen"},
+	})
+	ctx = ContextWithHeaders(ctx, http.Header{"X-Tenant-Id": {"acme"}, "User-Agent": {"svc/2"}})
+
+	if _, err := drainStreamEvents(ctx, client, NormalizedRequest{
+		Model:    "kimi-k2",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	}); err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if _, err := client.ListModels(ctx); err != nil {
+		t.Fatalf("list models error: %v", err)
+	}
+
+	if len(captured) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(captured))
+	}
+	for i, h := range captured {
+		if h.Get("X-Trace-Id") != "trace-1" || h.Get("X-Tenant-Id") != "acme" {
+			t.Fatalf("request %d: context headers missing: %v", i, h)
+		}
+		if h.Get("User-Agent") != "svc/2" {
+			t.Fatalf("request %d: context headers should replace defaults, got %q", i, h.Get("User-Agent"))
+		}
+		if h.Get("Authorization") != "Bearer key" {
+			t.Fatalf("request %d: auth must come from config, got %q", i, h.Get("Authorization"))
+		}
+	}
+	if captured[0].Get("X-Api-Key") != "" {
+		t.Fatalf("auth headers from context must be dropped, got %q", captured[0].Get("X-Api-Key"))
+	}
+}
+
+func TestAnthropicBetasConfig(t *testing.T) {
+	var captured []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = append(captured, r.Header.Clone())
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	req := NormalizedRequest{Model: "claude-sonnet-4-6", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+	run := func(cfg Config) []http.Header {
+		t.Helper()
+		captured = nil
+		cfg.APIKey = "key"
+		cfg.BaseURL = server.URL
+		client, err := NewClient(cfg)
+		if err != nil {
+			t.Fatalf("client error: %v", err)
+		}
+		if _, err := client.CollectStream(context.Background(), req); err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+		if _, err := client.CreateNormalized(context.Background(), req); err != nil {
+			t.Fatalf("create error: %v", err)
+		}
+		return captured
+	}
+
+	for i, h := range run(Config{}) {
+		if h.Get("anthropic-beta") != "fine-grained-tool-streaming-2025-05-14" {
+			t.Fatalf("request %d: default beta missing, got %q", i, h.Get("anthropic-beta"))
+		}
+	}
+	for i, h := range run(Config{AnthropicBetas: []string{"a-2025", "b-2025"}}) {
+		if h.Get("anthropic-beta") != "a-2025,b-2025" {
+			t.Fatalf("request %d: configured betas mismatch, got %q", i, h.Get("anthropic-beta"))
+		}
+	}
+	for i, h := range run(Config{AnthropicBetas: []string{}}) {
+		if _, ok := h["Anthropic-Beta"]; ok {
+			t.Fatalf("request %d: no anthropic-beta header should be sent, got %q", i, h.Get("anthropic-beta"))
+		}
+		if h.Get("anthropic-version") == "" {
+			t.Fatalf("request %d: anthropic-version must still be sent", i)
+		}
+	}
+}
+
+func TestScopeHeaders(t *testing.T) {
+	var captured []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = append(captured, r.Header.Clone())
+		if r.URL.Path == "/models" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	var metrics []RequestMetrics
+	var logged []Scope
+	client, err := NewClient(Config{
+		APIKey:        "key",
+		BaseURL:       server.URL,
+		Organization:  "org-1",
+		Project:       "proj-1",
+		ProjectHeader: "x-zen-project",
+		OnRequestMetrics: func(_ context.Context, m RequestMetrics) {
+			metrics = append(metrics, m)
+		},
+		LogRequest: func(ctx context.Context, _ EndpointType, _ NormalizedRequest) {
+			logged = append(logged, ScopeFromContext(ctx))
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	if _, err := client.ListModels(testCtx(t)); err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	ctx := ContextWithScope(testCtx(t), Scope{Project: "proj-2"})
+	if _, err := client.CollectStream(ctx, NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+
+	want := []Scope{{"org-1", "proj-1"}, {"org-1", "proj-2"}}
+	for i, h := range captured {
+		if got := (Scope{h.Get("OpenAI-Organization"), h.Get("x-zen-project")}); got != want[i] {
+			t.Fatalf("request %d: want scope headers %+v, got %+v", i, want[i], got)
+		}
+		if h.Get("OpenAI-Project") != "" {
+			t.Fatalf("request %d: default project header sent despite ProjectHeader", i)
+		}
+		if got := (Scope{metrics[i].Organization, metrics[i].Project}); got != want[i] {
+			t.Fatalf("request %d: want metrics scope %+v, got %+v", i, want[i], got)
+		}
+	}
+	if len(captured) != 2 || len(logged) != 1 || logged[0] != want[1] {
+		t.Fatalf("expected 2 requests and the stream's scope logged, got %d and %+v", len(captured), logged)
+	}
+}
@@ -0,0 +1,197 @@
+package zen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// geminiDummySignature is the thoughtSignature Gemini accepts in place of a
+// real one for function calls it did not produce itself.
+const geminiDummySignature = "skip_thought_signature_validator"
+
+// maxToolCallIDLength is the longest tool call id every endpoint accepts.
+const maxToolCallIDLength = 64
+
+// PrepareHistoryFor rewrites msgs, a conversation possibly held with another
+// provider, so that endpoint accepts it:
+//
+//   - For endpoints other than Responses, message ids and ProviderState
+//     are dropped, and for endpoints other than Messages, ReasoningBlocks.
+//   - For endpoints other than Gemini, thought signatures and Gemini parts
+//     (code execution) are dropped, and tool call ids are made unique and
+//     limited to letters, digits, "_" and "-", as Gemini's synthetic ids
+//     repeat every turn. Assistant messages left empty are dropped.
+//   - For Gemini, function calls without a thought signature get a
+//     placeholder Gemini accepts, and tool results get the name of their
+//     call.
+//   - Every tool call is followed by exactly one result, in call order:
+//     missing results are answered with an error result and results without
+//     a call are dropped.
+//   - For the messages and Gemini endpoints, consecutive plain text messages
+//     of the same role are merged.
+//
+// msgs is not modified.
+func PrepareHistoryFor(endpoint EndpointType, msgs []NormalizedMessage) []NormalizedMessage {
+	p := historyPreparer{endpoint: endpoint, usedIDs: map[string]bool{}}
+	out := make([]NormalizedMessage, 0, len(msgs))
+	for i := 0; i < len(msgs); i++ {
+		m := msgs[i]
+		switch strings.ToLower(strings.TrimSpace(m.Role)) {
+		case "tool":
+			// A result without a preceding call.
+			continue
+		case "assistant":
+			if len(m.ToolCalls) > 0 {
+				j := i + 1
+				for j < len(msgs) && strings.EqualFold(strings.TrimSpace(msgs[j].Role), "tool") {
+					j++
+				}
+				out = append(out, p.toolTurn(m, msgs[i+1:j])...)
+				i = j - 1
+				continue
+			}
+		}
+		m, ok := p.message(m)
+		if !ok {
+			continue
+		}
+		if n := len(out); n > 0 && p.mergesTurns() && mergeable(out[n-1]) && mergeable(m) && out[n-1].Role == m.Role {
+			out[n-1].Content += "\n\n" + m.Content
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+type historyPreparer struct {
+	endpoint EndpointType
+	usedIDs  map[string]bool
+}
+
+func (p *historyPreparer) mergesTurns() bool {
+	return p.endpoint == EndpointMessages || p.endpoint == EndpointModels
+}
+
+// message strips the provider artifacts of a message without tool calls,
+// reporting false when nothing is left of an assistant message.
+func (p *historyPreparer) message(m NormalizedMessage) (NormalizedMessage, bool) {
+	if p.endpoint != EndpointResponses {
+		m.ID = ""
+		m.ProviderState = nil
+	}
+	if p.endpoint != EndpointMessages {
+		m.ReasoningBlocks = nil
+	}
+	if p.endpoint != EndpointModels && len(m.Parts) > 0 {
+		var parts []NormalizedContentPart
+		textOnly := true
+		for _, part := range m.Parts {
+			if part.Type == ContentPartGemini {
+				continue
+			}
+			textOnly = textOnly && part.Type == ContentPartText
+			parts = append(parts, part)
+		}
+		m.Parts = parts
+		if textOnly {
+			var text strings.Builder
+			for _, part := range parts {
+				text.WriteString(part.Text)
+			}
+			m.Content = text.String()
+			m.Parts = nil
+		}
+	}
+	empty := strings.TrimSpace(m.Content) == "" && len(m.Parts) == 0 && len(m.ToolCalls) == 0 && len(m.ProviderState) == 0
+	return m, !(empty && strings.EqualFold(m.Role, "assistant"))
+}
+
+// toolTurn rewrites an assistant message with tool calls and the tool
+// results that follow it.
+func (p *historyPreparer) toolTurn(assistant NormalizedMessage, results []NormalizedMessage) []NormalizedMessage {
+	assistant, _ = p.message(assistant)
+	calls := make([]NormalizedToolCall, len(assistant.ToolCalls))
+	out := []NormalizedMessage{assistant}
+	used := make([]bool, len(results))
+	for i, call := range assistant.ToolCalls {
+		result, ok := matchToolResult(call.ID, results, used)
+		if p.endpoint == EndpointModels {
+			if call.ThoughtSignature == "" {
+				if _, sig := DecodeGeminiToolCallID(call.ID); sig == "" {
+					call.ThoughtSignature = geminiDummySignature
+				}
+			}
+		} else {
+			callID, _ := DecodeGeminiToolCallID(call.ID)
+			call.ID = p.uniqueID(callID)
+			call.ThoughtSignature = ""
+		}
+		calls[i] = call
+
+		if !ok {
+			result = NormalizedMessage{Role: "tool", Content: "error: no result was recorded for this call", IsError: true}
+		}
+		result.Role = "tool"
+		result.ToolCallID = call.ID
+		if result.FunctionName == "" {
+			result.FunctionName = call.Name
+		}
+		out = append(out, result)
+	}
+	out[0].ToolCalls = calls
+	return out
+}
+
+// matchToolResult finds the unused result of the call with id, comparing
+// ids without the thought signatures EncodeGeminiToolCallID folds in when
+// they differ.
+func matchToolResult(id string, results []NormalizedMessage, used []bool) (NormalizedMessage, bool) {
+	for pass := 0; pass < 2; pass++ {
+		for i, r := range results {
+			if used[i] {
+				continue
+			}
+			match := r.ToolCallID == id
+			if pass == 1 {
+				a, _ := DecodeGeminiToolCallID(r.ToolCallID)
+				b, _ := DecodeGeminiToolCallID(id)
+				match = a == b
+			}
+			if match {
+				used[i] = true
+				return r, true
+			}
+		}
+	}
+	return NormalizedMessage{}, false
+}
+
+// uniqueID returns id limited to the characters every endpoint accepts,
+// numbered when an earlier call already uses it.
+func (p *historyPreparer) uniqueID(id string) string {
+	clean := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, id)
+	if clean == "" {
+		clean = "call"
+	}
+	if len(clean) > maxToolCallIDLength {
+		clean = clean[:maxToolCallIDLength]
+	}
+	candidate := clean
+	for n := 2; p.usedIDs[candidate]; n++ {
+		suffix := fmt.Sprintf("_%d", n)
+		candidate = clean[:min(len(clean), maxToolCallIDLength-len(suffix))] + suffix
+	}
+	p.usedIDs[candidate] = true
+	return candidate
+}
+
+// mergeable reports whether m is a plain text user or assistant message.
+func mergeable(m NormalizedMessage) bool {
+	return (m.Role == "user" || m.Role == "assistant") && len(m.ToolCalls) == 0 && len(m.Parts) == 0 && m.ID == "" && len(m.ProviderState) == 0 && len(m.ReasoningBlocks) == 0
+}
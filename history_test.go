@@ -0,0 +1,181 @@
+package zen
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// geminiTurns are the streamed replies of a Gemini run that calls lookup in
+// two turns, each call numbered gemini-0, then answers.
+var geminiTurns = []string{
+	`data: {"candidates":[{"content":{"role":"model","parts":[` +
+		`{"text":"Checking.","thought":true},` +
+		`{"executableCode":{"language":"PYTHON","code":"print(1)"}},` +
+		`{"functionCall":{"name":"lookup","args":{"q":"a"}},"thoughtSignature":"sig-1"}]},"finishReason":"STOP"}]}` + "\n\n",
+	`data: {"candidates":[{"content":{"role":"model","parts":[` +
+		`{"functionCall":{"name":"lookup","args":{"q":"b"}},"thoughtSignature":"sig-2"}]},"finishReason":"STOP"}]}` + "\n\n",
+	`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"a and b"}]},"finishReason":"STOP"}]}` + "\n\n",
+}
+
+func TestConversationSwitchesFromGemini(t *testing.T) {
+	var mu sync.Mutex
+	geminiCalls := 0
+	var claudeBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		if strings.Contains(r.URL.Path, ":streamGenerateContent") {
+			_, _ = io.WriteString(w, geminiTurns[geminiCalls])
+			geminiCalls++
+			return
+		}
+		claudeBody = body
+		_, _ = io.WriteString(w, "event: content_block_delta\n"+`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"done"}}`+"\n\n"+
+			"event: message_stop\n"+`data: {"type":"message_stop"}`+"\n\n")
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	conv := NewConversation(client)
+	gemini := NormalizedRequest{Model: "gemini-3-flash", Messages: []NormalizedMessage{{Role: "user", Content: "look up a and b"}}}
+	for turn := 0; turn < 3; turn++ {
+		resp, err := conv.Send(testCtx(t), gemini)
+		if err != nil {
+			t.Fatalf("gemini turn %d: %v", turn, err)
+		}
+		gemini.Messages = nil
+		for _, call := range resp.ToolCalls {
+			gemini.Messages = append(gemini.Messages, NormalizedMessage{Role: "tool", ToolCallID: call.ID, FunctionName: call.Name, Content: "found"})
+		}
+	}
+	if ids := []string{conv.Messages[1].ToolCalls[0].ID, conv.Messages[3].ToolCalls[0].ID}; ids[0] != "gemini-0" || ids[1] != "gemini-0" {
+		t.Fatalf("expected Gemini to reuse its synthetic id, got %v", ids)
+	}
+
+	if _, err := conv.Send(testCtx(t), NormalizedRequest{Model: "claude-sonnet-4-6", Messages: []NormalizedMessage{{Role: "user", Content: "double check"}}}); err != nil {
+		t.Fatalf("claude turn: %v", err)
+	}
+	var sent struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"` // a string or blocks
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(claudeBody, &sent); err != nil {
+		t.Fatalf("decode claude request: %v (%s)", err, claudeBody)
+	}
+	var uses, results []string
+	for _, m := range sent.Messages {
+		var blocks []struct {
+			Type      string `json:"type"`
+			ID        string `json:"id"`
+			ToolUseID string `json:"tool_use_id"`
+		}
+		_ = json.Unmarshal(m.Content, &blocks)
+		for _, b := range blocks {
+			switch b.Type {
+			case "tool_use":
+				uses = append(uses, b.ID)
+			case "tool_result":
+				results = append(results, b.ToolUseID)
+			}
+		}
+	}
+	if strings.Join(uses, ",") != "gemini-0,gemini-0_2" || strings.Join(results, ",") != "gemini-0,gemini-0_2" {
+		t.Fatalf("tool ids not made unique: uses %v, results %v", uses, results)
+	}
+	if strings.Contains(string(claudeBody), "sig-") || strings.Contains(string(claudeBody), "executableCode") {
+		t.Fatalf("Gemini artifacts sent to Anthropic: %s", claudeBody)
+	}
+	if last := conv.Messages[len(conv.Messages)-1]; last.Content != "done" {
+		t.Fatalf("reply not appended: %+v", last)
+	}
+}
+
+func TestPrepareHistoryForEveryEndpoint(t *testing.T) {
+	history := []NormalizedMessage{
+		{Role: "user", Content: "look up a and b"},
+		{Role: "assistant", Parts: []NormalizedContentPart{
+			{Type: ContentPartText, Text: "Checking."},
+			{Type: ContentPartGemini, Raw: json.RawMessage(`{"executableCode":{"language":"PYTHON","code":"print(1)"}}`)},
+		}, ToolCalls: []NormalizedToolCall{{ID: "gemini-0", Name: "lookup", Arguments: json.RawMessage(`{"q":"a"}`), ThoughtSignature: "sig-1"}}},
+		{Role: "tool", ToolCallID: "gemini-0", FunctionName: "lookup", Content: "found a"},
+		{Role: "assistant", ToolCalls: []NormalizedToolCall{
+			{ID: EncodeGeminiToolCallID("gemini-0", "sig-2"), Name: "lookup", Arguments: json.RawMessage(`{"q":"b"}`)},
+			{ID: "gemini-1", Name: "lookup", Arguments: json.RawMessage(`{"q":"c"}`)},
+		}},
+		// Answers the first call of the turn under its plain id; the
+		// second call has no result.
+		{Role: "tool", ToolCallID: "gemini-0", Content: "found b"},
+		{Role: "tool", ToolCallID: "stale", Content: "orphan"},
+		{Role: "assistant", Parts: []NormalizedContentPart{{Type: ContentPartGemini, Raw: json.RawMessage(`{"codeExecutionResult":{"outcome":"OUTCOME_OK"}}`)}}},
+		{Role: "user", Content: "thanks"},
+		{Role: "user", Content: "one more thing"},
+	}
+
+	models := map[EndpointType]string{
+		EndpointChatCompletions: "kimi-k2",
+		EndpointResponses:       "gpt-5",
+		EndpointMessages:        "claude-sonnet-4-6",
+		EndpointModels:          "gemini-3-pro",
+	}
+	for endpoint, model := range models {
+		prepared := PrepareHistoryFor(endpoint, history)
+		req := NormalizedRequest{Model: model, Messages: prepared}
+		body, err := MarshalForEndpoint(req, endpoint)
+		if err != nil {
+			t.Fatalf("%s: %v", endpoint, err)
+		}
+
+		var calls, results []NormalizedMessage
+		for _, m := range prepared {
+			if len(m.ToolCalls) > 0 {
+				calls = append(calls, m)
+			}
+			if m.Role == "tool" {
+				results = append(results, m)
+			}
+		}
+		if len(calls) != 2 || len(results) != 3 {
+			t.Fatalf("%s: expected 2 tool turns and 3 results, got %+v", endpoint, prepared)
+		}
+		if results[1].Content != "found b" || !results[2].IsError || results[2].FunctionName != "lookup" {
+			t.Fatalf("%s: results not repaired: %+v", endpoint, results)
+		}
+
+		if endpoint == EndpointModels {
+			if calls[0].ToolCalls[0].ThoughtSignature != "sig-1" || !strings.Contains(string(body), "executableCode") {
+				t.Fatalf("%s: Gemini artifacts must be kept: %s", endpoint, body)
+			}
+			if calls[1].ToolCalls[1].ThoughtSignature != geminiDummySignature {
+				t.Fatalf("%s: unsigned call needs a placeholder signature: %+v", endpoint, calls[1].ToolCalls[1])
+			}
+			continue
+		}
+		ids := []string{calls[0].ToolCalls[0].ID, calls[1].ToolCalls[0].ID, calls[1].ToolCalls[1].ID}
+		if strings.Join(ids, ",") != "gemini-0,gemini-0_2,gemini-1" {
+			t.Fatalf("%s: unexpected call ids %v", endpoint, ids)
+		}
+		for i, r := range results {
+			if r.ToolCallID != ids[i] {
+				t.Fatalf("%s: result %d answers %q, want %q", endpoint, i, r.ToolCallID, ids[i])
+			}
+		}
+		if strings.Contains(string(body), "executableCode") || strings.Contains(string(body), "codeExecutionResult") || strings.Contains(string(body), "|ts=") {
+			t.Fatalf("%s: Gemini artifacts left in %s", endpoint, body)
+		}
+		if last := prepared[len(prepared)-1]; endpoint == EndpointMessages && last.Content != "thanks\n\none more thing" {
+			t.Fatalf("%s: consecutive user messages not merged: %+v", endpoint, last)
+		}
+	}
+}
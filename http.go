@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
-	"time"
+	"sync/atomic"
 )
 
 var retryableStatus = map[int]bool{
@@ -16,11 +18,28 @@ var retryableStatus = map[int]bool{
 	http.StatusBadGateway:          true,
 	http.StatusServiceUnavailable:  true,
 	http.StatusGatewayTimeout:      true,
+	StatusOverloaded:               true,
 }
 
 func (c *Client) doRequest(ctx context.Context, method, path string, body []byte, endpoint EndpointType, forceAllAuth bool) ([]byte, http.Header, error) {
+	var payload []byte
+	header, err := c.doRequestFunc(ctx, method, path, body, endpoint, forceAllAuth, func(_ *http.Response, r io.Reader) error {
+		var readErr error
+		payload, readErr = io.ReadAll(r)
+		return readErr
+	})
+	if err != nil {
+		return nil, header, err
+	}
+	return payload, header, nil
+}
+
+// doRequestFunc sends a request with retries and hands a successful response
+// and its body to handle, which runs before the body is closed. Non-2xx
+// responses are read in full and returned as *APIError.
+func (c *Client) doRequestFunc(ctx context.Context, method, path string, body []byte, endpoint EndpointType, forceAllAuth bool, handle func(*http.Response, io.Reader) error) (http.Header, error) {
 	url := joinURL(c.cfg.BaseURL, path)
-	if body == nil {
+	if body == nil && !isBodyless(method) {
 		body = []byte{}
 	}
 
@@ -29,45 +48,212 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body []byte
 		retries = 0
 	}
 
+	key := c.keys.pick(c.cfg.Clock.Now())
+	ctx = c.withScope(ctx)
+	scope := c.scope(ctx)
+
 	var lastErr error
+	// attempts is only collected when the request may be retried.
+	var attempts []AttemptInfo
 	for attempt := 0; attempt <= retries; attempt++ {
-		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		// A context cancelled during the previous attempt or its backoff
+		// must not send another request.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		req, err := newRequest(ctx, method, url, body)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
-		c.applyRequestHeaders(req, endpoint, false, forceAllAuth)
+		c.applyRequestHeaders(req, endpoint, false, forceAllAuth, c.keys.keys[key])
 
+		release, err := c.limiter.acquire(ctx, c.cfg.FailFastOnConcurrencyLimit)
+		if err != nil {
+			return nil, err
+		}
+		// The request timeout starts once a slot is free and covers reading
+		// the response body.
+		attemptCtx, cancelAttempt, timeout := c.withAttemptTimeout(ctx)
+		req = req.WithContext(attemptCtx)
+		metrics := RequestMetrics{
+			Method:       method,
+			Endpoint:     endpoint,
+			Path:         path,
+			Attempt:      attempt,
+			RequestBytes: len(body),
+			KeyIndex:     key,
+			Organization: scope.Organization,
+			Project:      scope.Project,
+		}
+		start := c.cfg.Clock.Now()
+		// report hands the finished attempt to the metrics hook and records
+		// it for APIError.Attempts.
+		report := func(header http.Header, err error) {
+			now := c.cfg.Clock.Now()
+			metrics.Duration = now.Sub(start)
+			metrics.Err = err
+			c.reportMetrics(ctx, metrics)
+			if retries > 0 {
+				info := AttemptInfo{StatusCode: metrics.StatusCode, Duration: metrics.Duration, RetryAfter: parseRetryAfter(header, now)}
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					info.Err = err
+				}
+				attempts = append(attempts, info)
+			}
+		}
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
+			release()
+			err = attemptTimeoutError(ctx, attemptCtx, timeout, err)
+			cancelAttempt()
+			report(nil, err)
 			lastErr = err
 			if attempt < retries {
-				time.Sleep(c.cfg.Retry.Backoff(attempt))
+				if err := c.cfg.Clock.Sleep(ctx, c.cfg.Retry.Backoff(attempt)); err != nil {
+					return nil, err
+				}
 				continue
 			}
-			return nil, nil, err
+			return nil, retryError(err, attempts)
+		}
+
+		metrics.StatusCode = resp.StatusCode
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			var received atomic.Int64
+			err := handle(resp, countingReader{r: resp.Body, n: &received})
+			_ = resp.Body.Close()
+			release()
+			err = attemptTimeoutError(ctx, attemptCtx, timeout, err)
+			cancelAttempt()
+			metrics.ResponseBytes = received.Load()
+			report(resp.Header, err)
+			return resp.Header, err
 		}
 
 		payload, readErr := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
+		release()
+		readErr = attemptTimeoutError(ctx, attemptCtx, timeout, readErr)
+		cancelAttempt()
+		metrics.ResponseBytes = int64(len(payload))
 		if readErr != nil {
-			return nil, resp.Header, readErr
-		}
-
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			return payload, resp.Header, nil
+			report(resp.Header, readErr)
+			return resp.Header, retryError(readErr, attempts)
 		}
 
 		apiErr := newAPIError(resp.StatusCode, resp.Header, payload)
+		c.captureRequest(ctx, apiErr, body)
+		c.keyRateLimited(key, resp)
+		report(resp.Header, apiErr)
 		lastErr = apiErr
 		if attempt < retries && retryableStatus[resp.StatusCode] {
-			time.Sleep(c.cfg.Retry.Backoff(attempt))
+			if err := c.cfg.Clock.Sleep(ctx, c.cfg.Retry.Backoff(attempt)); err != nil {
+				return resp.Header, err
+			}
 			continue
 		}
-		return nil, resp.Header, apiErr
+		if len(attempts) > 1 {
+			apiErr.Attempts = attempts
+		}
+		return resp.Header, apiErr
+	}
+
+	return nil, lastErr
+}
+
+// retryError returns err, the transport error of the last attempt, as a
+// *RetryError carrying attempts when the request was retried.
+func retryError(err error, attempts []AttemptInfo) error {
+	if len(attempts) < 2 {
+		return err
+	}
+	return &RetryError{Err: err, Attempts: attempts}
+}
+
+// newRequest builds a request whose body can be replayed from body, by
+// redirects and by transports retrying on a new connection. A nil body sends
+// none.
+func newRequest(ctx context.Context, method, url string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	return req, nil
+}
+
+// ErrNotJSON is returned when a successful response that should be JSON
+// fails to decode and its Content-Type says it is something else, e.g. a
+// text/plain page from a gateway.
+var ErrNotJSON = errors.New("zen: response is not JSON")
+
+// decodeJSONResponse unmarshals a successful response body into v. An empty
+// body, as sent with 204 No Content, leaves v untouched.
+func decodeJSONResponse(data []byte, header http.Header, v any) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := jsonUnmarshal(data, v); err != nil {
+		return notJSONError(header, err)
 	}
+	return nil
+}
 
-	return nil, nil, lastErr
+// decodeJSONReader decodes a successful response body from r into v like
+// decodeJSONResponse, without reading the body into memory first. Functions
+// installed with SetJSONFuncs take byte slices, so with them the body is
+// read in full.
+func decodeJSONReader(r io.Reader, header http.Header, v any) error {
+	if activeJSON.Load() != nil {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return decodeJSONResponse(data, header, v)
+	}
+	err := json.NewDecoder(r).Decode(v)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		// An empty body.
+		return nil
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return notJSONError(header, err)
+	}
+	return err
+}
+
+// notJSONError returns ErrNotJSON in place of a decoding error when header
+// declares a Content-Type other than JSON. Bodies without one, or mislabelled
+// ones that decode, are treated as JSON.
+func notJSONError(header http.Header, err error) error {
+	contentType := mediaType(header)
+	if contentType == "" || isJSONMediaType(contentType) {
+		return err
+	}
+	return fmt.Errorf("%w: got %s", ErrNotJSON, contentType)
+}
+
+// mediaType returns the Content-Type of header without its parameters.
+func mediaType(header http.Header) string {
+	contentType, _, _ := strings.Cut(header.Get("Content-Type"), ";")
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+func isJSONMediaType(contentType string) bool {
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
 }
 
 func jsonBody(v any, raw json.RawMessage) ([]byte, error) {
@@ -77,7 +263,10 @@ func jsonBody(v any, raw json.RawMessage) ([]byte, error) {
 	if v == nil {
 		return []byte("{}"), nil
 	}
-	return json.Marshal(v)
+	if m, ok := v.(verbatimMarshaler); ok {
+		return m.marshalVerbatim()
+	}
+	return jsonMarshal(v)
 }
 
 func joinURL(base, path string) string {
@@ -95,3 +284,14 @@ func isIdempotent(method string) bool {
 		return false
 	}
 }
+
+// isBodyless reports whether requests with method are sent without a body,
+// and so without a Content-Type, when none is given.
+func isBodyless(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,702 @@
+package zen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// decodeObject splits a JSON object into its top-level keys so importers can
+// consume the fields they understand and pass the rest through as Extra.
+func decodeObject(raw json.RawMessage) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return nil, errors.New("zen: request body must be a JSON object")
+	}
+	return fields, nil
+}
+
+// takeField unmarshals fields[key] into v (when present and not null) and
+// removes it from fields.
+func takeField(fields map[string]json.RawMessage, key string, v any) error {
+	raw, ok := fields[key]
+	if !ok {
+		return nil
+	}
+	delete(fields, key)
+	if string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("zen: invalid %q: %w", key, err)
+	}
+	return nil
+}
+
+// extraFromFields converts the unconsumed fields into an Extra map.
+func extraFromFields(fields map[string]json.RawMessage) map[string]any {
+	if len(fields) == 0 {
+		return nil
+	}
+	extra := make(map[string]any, len(fields))
+	for k, v := range fields {
+		extra[k] = v
+	}
+	return extra
+}
+
+// ---------------------------------------------------------------------------
+// chat/completions
+// ---------------------------------------------------------------------------
+
+type chatImportMessage struct {
+	Role       string               `json:"role"`
+	Content    json.RawMessage      `json:"content"`
+	ToolCalls  []chatImportToolCall `json:"tool_calls"`
+	ToolCallID string               `json:"tool_call_id"`
+}
+
+type chatImportToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// NormalizedRequestFromChatCompletions parses an OpenAI chat/completions
+// request body into a NormalizedRequest.
+//
+// A leading system message is hoisted into System; any later system or
+// developer messages stay in Messages at their original position. Array
+// content is flattened to its text parts unless it holds input_audio parts,
+// in which case it is kept in Parts (other part kinds are rejected).
+// "stop" (a string or an array) becomes StopSequences; unrecognized keys are
+// kept in Extra. The returned request uses EndpointAuto so it can be routed
+// to any model.
+func NormalizedRequestFromChatCompletions(raw json.RawMessage) (NormalizedRequest, error) {
+	var req NormalizedRequest
+
+	fields, err := decodeObject(raw)
+	if err != nil {
+		return req, err
+	}
+
+	if err := takeField(fields, "model", &req.Model); err != nil {
+		return req, err
+	}
+	if err := takeField(fields, "stream", &req.Stream); err != nil {
+		return req, err
+	}
+	if err := takeField(fields, "temperature", &req.Temperature); err != nil {
+		return req, err
+	}
+	if err := takeField(fields, "max_tokens", &req.MaxTokens); err != nil {
+		return req, err
+	}
+	if req.MaxTokens == nil {
+		if err := takeField(fields, "max_completion_tokens", &req.MaxTokens); err != nil {
+			return req, err
+		}
+	}
+	if err := takeField(fields, "service_tier", &req.ServiceTier); err != nil {
+		return req, err
+	}
+	var stop json.RawMessage
+	if err := takeField(fields, "stop", &stop); err != nil {
+		return req, err
+	}
+	if len(stop) > 0 {
+		var one string
+		if err := json.Unmarshal(stop, &one); err == nil {
+			req.StopSequences = []string{one}
+		} else if err := json.Unmarshal(stop, &req.StopSequences); err != nil {
+			return req, errors.New(`zen: invalid "stop": must be a string or an array of strings`)
+		}
+	}
+
+	var effort string
+	if err := takeField(fields, "reasoning_effort", &effort); err != nil {
+		return req, err
+	}
+	var reasoning *ChatReasoning
+	if err := takeField(fields, "reasoning", &reasoning); err != nil {
+		return req, err
+	}
+	if effort == "" && reasoning != nil {
+		effort = reasoning.Effort
+	}
+	if effort != "" {
+		req.Reasoning = &NormalizedReasoning{Effort: effort}
+	}
+
+	var messages []chatImportMessage
+	if err := takeField(fields, "messages", &messages); err != nil {
+		return req, err
+	}
+	for i, m := range messages {
+		content, parts, err := chatImportContent(m.Content)
+		if err != nil {
+			return req, fmt.Errorf("zen: messages[%d]: %w", i, err)
+		}
+		role := strings.ToLower(strings.TrimSpace(m.Role))
+		if i == 0 && role == "system" && parts == nil {
+			req.System = content
+			continue
+		}
+		nm := NormalizedMessage{Role: m.Role, Content: content, Parts: parts, ToolCallID: m.ToolCallID}
+		for _, tc := range m.ToolCalls {
+			nm.ToolCalls = append(nm.ToolCalls, NormalizedToolCall{
+				ID:        tc.ID,
+				Name:      tc.Function.Name,
+				Arguments: json.RawMessage(tc.Function.Arguments),
+			})
+		}
+		req.Messages = append(req.Messages, nm)
+	}
+
+	var tools []ChatTool
+	if err := takeField(fields, "tools", &tools); err != nil {
+		return req, err
+	}
+	for _, t := range tools {
+		req.Tools = append(req.Tools, NormalizedTool{Name: t.Function.Name, Description: t.Function.Description, Parameters: t.Function.Parameters})
+	}
+
+	var choice json.RawMessage
+	if err := takeField(fields, "tool_choice", &choice); err != nil {
+		return req, err
+	}
+	if len(choice) > 0 {
+		parsed, err := parseOpenAIToolChoice(choice)
+		if err != nil {
+			return req, err
+		}
+		req.ToolChoice = parsed
+	}
+
+	req.Extra = extraFromFields(fields)
+	return req, nil
+}
+
+// chatImportContent returns the content of a chat message, which may be a
+// string, null, or an array of content parts. Text-only arrays are joined to
+// a string; arrays with audio are returned as parts.
+func chatImportContent(raw json.RawMessage) (string, []NormalizedContentPart, error) {
+	var content chatContent
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &content); err != nil {
+			return "", nil, errors.New("content must be a string or an array of content parts")
+		}
+	}
+	var parts []NormalizedContentPart
+	audio := false
+	for _, p := range content.Parts {
+		switch p.Type {
+		case "text":
+			parts = append(parts, NormalizedContentPart{Type: ContentPartText, Text: p.Text})
+		case "input_audio":
+			if p.InputAudio == nil {
+				return "", nil, errors.New("input_audio content part is missing input_audio")
+			}
+			parts = append(parts, NormalizedContentPart{Type: ContentPartAudio, AudioData: p.InputAudio.Data, AudioFormat: p.InputAudio.Format})
+			audio = true
+		default:
+			return "", nil, fmt.Errorf("unsupported content part type %q", p.Type)
+		}
+	}
+	if audio {
+		return "", parts, nil
+	}
+	return content.text(), nil, nil
+}
+
+func parseOpenAIToolChoice(raw json.RawMessage) (*NormalizedToolChoice, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		switch s {
+		case "auto":
+			return &NormalizedToolChoice{Type: ToolChoiceAuto}, nil
+		case "none":
+			return &NormalizedToolChoice{Type: ToolChoiceNone}, nil
+		case "required":
+			return &NormalizedToolChoice{Type: ToolChoiceRequired}, nil
+		default:
+			return nil, fmt.Errorf("zen: unsupported tool choice %q", s)
+		}
+	}
+
+	var obj struct {
+		Type     string `json:"type"`
+		Name     string `json:"name"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, errors.New("zen: unsupported tool choice")
+	}
+	name := obj.Function.Name
+	if name == "" {
+		name = obj.Name
+	}
+	if obj.Type != "function" || name == "" {
+		return nil, errors.New("zen: unsupported tool choice")
+	}
+	return &NormalizedToolChoice{Type: ToolChoiceTool, Name: name}, nil
+}
+
+// ---------------------------------------------------------------------------
+// messages (Anthropic)
+// ---------------------------------------------------------------------------
+
+type messagesImportBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+	Thinking  string          `json:"thinking"`
+	Signature string          `json:"signature"`
+	Data      string          `json:"data"`
+}
+
+type messagesImportMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// NormalizedRequestFromMessages parses an Anthropic messages request body into
+// a NormalizedRequest.
+//
+// The system prompt may be a string or an array of text blocks. User turns
+// containing tool_result blocks are split into one "tool" message per result
+// (plus a user message for any accompanying text), and assistant tool_use
+// blocks become ToolCalls with their input preserved verbatim. Thinking and
+// redacted_thinking blocks of assistant turns become the message's
+// ReasoningBlocks, signature and data included. stop_sequences becomes StopSequences; unrecognized keys (metadata,
+// ...) are kept in Extra.
+func NormalizedRequestFromMessages(raw json.RawMessage) (NormalizedRequest, error) {
+	var req NormalizedRequest
+
+	fields, err := decodeObject(raw)
+	if err != nil {
+		return req, err
+	}
+
+	if err := takeField(fields, "model", &req.Model); err != nil {
+		return req, err
+	}
+	if err := takeField(fields, "stream", &req.Stream); err != nil {
+		return req, err
+	}
+	if err := takeField(fields, "temperature", &req.Temperature); err != nil {
+		return req, err
+	}
+	if err := takeField(fields, "max_tokens", &req.MaxTokens); err != nil {
+		return req, err
+	}
+	if err := takeField(fields, "stop_sequences", &req.StopSequences); err != nil {
+		return req, err
+	}
+
+	var system json.RawMessage
+	if err := takeField(fields, "system", &system); err != nil {
+		return req, err
+	}
+	if len(system) > 0 {
+		text, err := messagesContentText(system)
+		if err != nil {
+			return req, fmt.Errorf("zen: system: %w", err)
+		}
+		req.System = text
+	}
+
+	var thinking *AnthropicThinking
+	if err := takeField(fields, "thinking", &thinking); err != nil {
+		return req, err
+	}
+	if thinking != nil && thinking.Type == "enabled" && thinking.BudgetTokens > 0 {
+		req.Reasoning = &NormalizedReasoning{BudgetTokens: thinking.BudgetTokens}
+	}
+
+	var messages []messagesImportMessage
+	if err := takeField(fields, "messages", &messages); err != nil {
+		return req, err
+	}
+	for i, m := range messages {
+		converted, err := importMessagesTurn(m)
+		if err != nil {
+			return req, fmt.Errorf("zen: messages[%d]: %w", i, err)
+		}
+		req.Messages = append(req.Messages, converted...)
+	}
+
+	var tools []AnthropicTool
+	if err := takeField(fields, "tools", &tools); err != nil {
+		return req, err
+	}
+	for _, t := range tools {
+		req.Tools = append(req.Tools, NormalizedTool{
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  t.InputSchema,
+		})
+	}
+
+	var choice *AnthropicToolChoice
+	if err := takeField(fields, "tool_choice", &choice); err != nil {
+		return req, err
+	}
+	if choice != nil {
+		switch choice.Type {
+		case "auto":
+			req.ToolChoice = &NormalizedToolChoice{Type: ToolChoiceAuto}
+		case "any":
+			req.ToolChoice = &NormalizedToolChoice{Type: ToolChoiceRequired}
+		case "none":
+			req.ToolChoice = &NormalizedToolChoice{Type: ToolChoiceNone}
+		case "tool":
+			req.ToolChoice = &NormalizedToolChoice{Type: ToolChoiceTool, Name: choice.Name}
+		default:
+			return req, fmt.Errorf("zen: unsupported tool choice %q", choice.Type)
+		}
+	}
+
+	req.Extra = extraFromFields(fields)
+	return req, nil
+}
+
+func importMessagesTurn(m messagesImportMessage) ([]NormalizedMessage, error) {
+	role := strings.ToLower(strings.TrimSpace(m.Role))
+
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return []NormalizedMessage{{Role: role, Content: s}}, nil
+	}
+
+	var blocks []messagesImportBlock
+	if err := json.Unmarshal(m.Content, &blocks); err != nil {
+		return nil, errors.New("content must be a string or an array of content blocks")
+	}
+
+	var out []NormalizedMessage
+	var text strings.Builder
+	var calls []NormalizedToolCall
+	var reasoning []ReasoningBlock
+	flushText := func() {
+		if text.Len() == 0 {
+			return
+		}
+		out = append(out, NormalizedMessage{Role: role, Content: text.String()})
+		text.Reset()
+	}
+
+	for i, b := range blocks {
+		switch b.Type {
+		case "text":
+			text.WriteString(b.Text)
+		case "tool_use":
+			calls = append(calls, NormalizedToolCall{ID: b.ID, Name: b.Name, Arguments: b.Input})
+		case "tool_result":
+			flushText()
+			content, err := messagesContentText(b.Content)
+			if err != nil {
+				return nil, fmt.Errorf("tool_result: %w", err)
+			}
+			out = append(out, NormalizedMessage{Role: "tool", ToolCallID: b.ToolUseID, Content: content, IsError: b.IsError})
+		case "thinking":
+			reasoning = append(reasoning, ReasoningBlock{Index: i, Text: b.Thinking, Signature: b.Signature})
+		case "redacted_thinking":
+			reasoning = append(reasoning, ReasoningBlock{Index: i, Data: b.Data})
+		default:
+			return nil, fmt.Errorf("unsupported content block type %q", b.Type)
+		}
+	}
+
+	if len(calls) > 0 {
+		out = append(out, NormalizedMessage{Role: role, Content: text.String(), ToolCalls: calls})
+	} else {
+		flushText()
+		if len(out) == 0 {
+			out = append(out, NormalizedMessage{Role: role})
+		}
+	}
+	if role == "assistant" {
+		out[0].ReasoningBlocks = reasoning
+	}
+	return out, nil
+}
+
+// messagesContentText returns the text of an Anthropic content value, which
+// may be a string or an array of text blocks.
+func messagesContentText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var blocks []messagesImportBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return "", errors.New("content must be a string or an array of content blocks")
+	}
+	var b strings.Builder
+	for _, block := range blocks {
+		if block.Type != "text" {
+			return "", fmt.Errorf("unsupported content block type %q", block.Type)
+		}
+		b.WriteString(block.Text)
+	}
+	return b.String(), nil
+}
+
+// ---------------------------------------------------------------------------
+// models (Gemini)
+// ---------------------------------------------------------------------------
+
+type geminiImportPart struct {
+	Text             string    `json:"text"`
+	Thought          bool      `json:"thought"`
+	ThoughtSignature string    `json:"thoughtSignature"`
+	FunctionCall     *geminiFC `json:"functionCall"`
+	FunctionResponse *struct {
+		Name     string          `json:"name"`
+		Response json.RawMessage `json:"response"`
+	} `json:"functionResponse"`
+	FileData            *GeminiFileData `json:"fileData"`
+	ExecutableCode      json.RawMessage `json:"executableCode"`
+	CodeExecutionResult json.RawMessage `json:"codeExecutionResult"`
+}
+
+type geminiImportContent struct {
+	Role  string             `json:"role"`
+	Parts []geminiImportPart `json:"parts"`
+}
+
+// NormalizedRequestFromGemini parses a Gemini generateContent request body
+// into a NormalizedRequest.
+//
+// Gemini carries the model in the URL rather than the body, so Model is left
+// empty for the caller to fill in. Function calls have no ids on the wire;
+// they are given synthetic ids ("gemini-<n>", with any thoughtSignature folded
+// in by EncodeGeminiToolCallID) and each functionResponse is paired
+// with the earliest unanswered call of the same name. A response of the
+// form {"output": "..."} is unwrapped to its string; any other response object
+// is kept as its JSON text. executableCode and codeExecutionResult parts are
+// kept as ContentPartGemini parts. temperature, maxOutputTokens,
+// stopSequences, candidateCount and thinkingConfig are read from
+// generationConfig; its other settings (seed, topP, ...) are kept in
+// Extra["generationConfig"], which ToGeminiRequest merges back.
+func NormalizedRequestFromGemini(raw json.RawMessage) (NormalizedRequest, error) {
+	var req NormalizedRequest
+
+	fields, err := decodeObject(raw)
+	if err != nil {
+		return req, err
+	}
+
+	var system *geminiImportContent
+	if err := takeField(fields, "systemInstruction", &system); err != nil {
+		return req, err
+	}
+	if system != nil {
+		var b strings.Builder
+		for _, p := range system.Parts {
+			b.WriteString(p.Text)
+		}
+		req.System = b.String()
+	}
+
+	var config map[string]json.RawMessage
+	if err := takeField(fields, "generationConfig", &config); err != nil {
+		return req, err
+	}
+	if config != nil {
+		if err := takeField(config, "temperature", &req.Temperature); err != nil {
+			return req, err
+		}
+		if err := takeField(config, "maxOutputTokens", &req.MaxTokens); err != nil {
+			return req, err
+		}
+		if err := takeField(config, "stopSequences", &req.StopSequences); err != nil {
+			return req, err
+		}
+		if err := takeField(config, "candidateCount", &req.CandidateCount); err != nil {
+			return req, err
+		}
+		var tc *GeminiThinkingConfig
+		if err := takeField(config, "thinkingConfig", &tc); err != nil {
+			return req, err
+		}
+		if tc != nil {
+			reasoning := &NormalizedReasoning{Effort: tc.ThinkingLevel}
+			if tc.ThinkingBudget != nil && *tc.ThinkingBudget > 0 {
+				reasoning.BudgetTokens = *tc.ThinkingBudget
+			}
+			if reasoning.Effort != "" || reasoning.BudgetTokens > 0 {
+				req.Reasoning = reasoning
+			}
+		}
+		// The rest (seed, topP, responseMimeType, ...) stays in Extra.
+		if len(config) > 0 {
+			rest, err := jsonMarshal(config)
+			if err != nil {
+				return req, err
+			}
+			fields["generationConfig"] = rest
+		}
+	}
+
+	var contents []geminiImportContent
+	if err := takeField(fields, "contents", &contents); err != nil {
+		return req, err
+	}
+	// pending maps a function name to the ids of calls still awaiting a response.
+	pending := map[string][]string{}
+	callCount := 0
+	for i, c := range contents {
+		role := strings.ToLower(strings.TrimSpace(c.Role))
+		if role == "model" {
+			role = "assistant"
+		}
+		if role == "" {
+			role = "user"
+		}
+
+		var text strings.Builder
+		var calls []NormalizedToolCall
+		var results []NormalizedMessage
+		var parts []NormalizedContentPart
+		hasFile, hasRaw := false, false
+		for _, p := range c.Parts {
+			switch {
+			case p.ExecutableCode != nil || p.CodeExecutionResult != nil:
+				hasRaw = true
+				raw, err := jsonMarshal(GeminiPart{
+					ExecutableCode:      p.ExecutableCode,
+					CodeExecutionResult: p.CodeExecutionResult,
+					ThoughtSignature:    p.ThoughtSignature,
+				})
+				if err != nil {
+					return req, err
+				}
+				parts = append(parts, NormalizedContentPart{Type: ContentPartGemini, Raw: raw})
+			case p.FileData != nil:
+				hasFile = true
+				parts = append(parts, NormalizedContentPart{Type: ContentPartFile, FileURI: p.FileData.FileURI, MediaType: p.FileData.MimeType})
+			case p.FunctionCall != nil:
+				id := EncodeGeminiToolCallID(fmt.Sprintf("gemini-%d", callCount), p.ThoughtSignature)
+				callCount++
+				calls = append(calls, NormalizedToolCall{
+					ID:               id,
+					Name:             p.FunctionCall.Name,
+					Arguments:        p.FunctionCall.Args,
+					ThoughtSignature: p.ThoughtSignature,
+				})
+				pending[p.FunctionCall.Name] = append(pending[p.FunctionCall.Name], id)
+			case p.FunctionResponse != nil:
+				name := p.FunctionResponse.Name
+				var id string
+				if ids := pending[name]; len(ids) > 0 {
+					id = ids[0]
+					pending[name] = ids[1:]
+				}
+				results = append(results, NormalizedMessage{
+					Role:         "tool",
+					ToolCallID:   id,
+					FunctionName: name,
+					Content:      geminiResponseText(p.FunctionResponse.Response),
+				})
+			case p.Thought:
+				// Thought summaries from earlier turns are not replayed.
+			default:
+				text.WriteString(p.Text)
+				parts = append(parts, NormalizedContentPart{Type: ContentPartText, Text: p.Text})
+			}
+		}
+		if hasFile && (len(calls) > 0 || len(results) > 0) {
+			return req, fmt.Errorf("zen: contents[%d]: fileData cannot be mixed with function parts", i)
+		}
+
+		switch {
+		case len(results) > 0:
+			req.Messages = append(req.Messages, results...)
+			if text.Len() > 0 {
+				req.Messages = append(req.Messages, NormalizedMessage{Role: role, Content: text.String()})
+			}
+		case len(calls) > 0:
+			msg := NormalizedMessage{Role: role, Content: text.String(), ToolCalls: calls}
+			if hasRaw {
+				msg.Parts = parts
+			}
+			req.Messages = append(req.Messages, msg)
+		default:
+			if len(c.Parts) == 0 {
+				return req, fmt.Errorf("zen: contents[%d]: parts are required", i)
+			}
+			msg := NormalizedMessage{Role: role, Content: text.String()}
+			if hasFile || hasRaw {
+				msg.Parts = parts
+			}
+			req.Messages = append(req.Messages, msg)
+		}
+	}
+
+	var tools []GeminiTool
+	if err := takeField(fields, "tools", &tools); err != nil {
+		return req, err
+	}
+	for _, t := range tools {
+		for _, fd := range t.FunctionDeclarations {
+			req.Tools = append(req.Tools, NormalizedTool{Name: fd.Name, Description: fd.Description, Parameters: fd.Parameters})
+		}
+	}
+
+	var toolConfig *GeminiToolConfig
+	if err := takeField(fields, "toolConfig", &toolConfig); err != nil {
+		return req, err
+	}
+	if toolConfig != nil && toolConfig.FunctionCallingConfig != nil {
+		fc := toolConfig.FunctionCallingConfig
+		switch strings.ToUpper(fc.Mode) {
+		case "", "AUTO":
+			req.ToolChoice = &NormalizedToolChoice{Type: ToolChoiceAuto}
+		case "NONE":
+			req.ToolChoice = &NormalizedToolChoice{Type: ToolChoiceNone}
+		case "ANY":
+			if len(fc.AllowedFunctionNames) == 1 {
+				req.ToolChoice = &NormalizedToolChoice{Type: ToolChoiceTool, Name: fc.AllowedFunctionNames[0]}
+			} else {
+				req.ToolChoice = &NormalizedToolChoice{Type: ToolChoiceRequired}
+			}
+		default:
+			return req, fmt.Errorf("zen: unsupported function calling mode %q", fc.Mode)
+		}
+	}
+
+	req.Extra = extraFromFields(fields)
+	return req, nil
+}
+
+// geminiResponseText unwraps {"output": "..."} (the shape ToGeminiRequest
+// produces) and otherwise returns the response object as JSON text.
+func geminiResponseText(raw json.RawMessage) string {
+	var wrapped map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &wrapped); err == nil && len(wrapped) == 1 {
+		var output string
+		if err := json.Unmarshal(wrapped["output"], &output); err == nil {
+			return output
+		}
+	}
+	return string(raw)
+}
@@ -0,0 +1,403 @@
+package zen
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// assertJSONEquivalent compares two JSON documents ignoring key order and
+// whitespace.
+func assertJSONEquivalent(t *testing.T, want, got []byte) {
+	t.Helper()
+	var w, g any
+	if err := json.Unmarshal(want, &w); err != nil {
+		t.Fatalf("unmarshal want: %v", err)
+	}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("unmarshal got: %v", err)
+	}
+	if !reflect.DeepEqual(w, g) {
+		t.Fatalf("JSON mismatch:\nwant: %s\ngot:  %s", want, got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// chat/completions
+// ---------------------------------------------------------------------------
+
+func TestNormalizedRequestFromChatCompletionsRoundTrip(t *testing.T) {
+	raw := []byte(`{
+		"model": "gpt-5.2",
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": "What's the weather in Paris?"},
+			{"role": "assistant", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "Sunny"},
+			{"role": "assistant", "content": "It is sunny."}
+		],
+		"tools": [
+			{"type": "function", "function": {"name": "get_weather", "description": "Weather", "parameters": {"type": "object"}}}
+		],
+		"tool_choice": {"type": "function", "function": {"name": "get_weather"}},
+		"temperature": 0.2,
+		"max_tokens": 256,
+		"stop": ["END"],
+		"seed": 7,
+		"stream": true
+	}`)
+
+	req, err := NormalizedRequestFromChatCompletions(raw)
+	if err != nil {
+		t.Fatalf("NormalizedRequestFromChatCompletions: %v", err)
+	}
+	if req.System != "be brief" {
+		t.Fatalf("system not hoisted: %q", req.System)
+	}
+	if len(req.Messages) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(req.Messages))
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Type != ToolChoiceTool || req.ToolChoice.Name != "get_weather" {
+		t.Fatalf("tool choice not parsed: %+v", req.ToolChoice)
+	}
+	if !reflect.DeepEqual(req.StopSequences, []string{"END"}) {
+		t.Fatalf("stop not mapped to StopSequences: %+v", req.StopSequences)
+	}
+	if _, ok := req.Extra["stop"]; ok {
+		t.Fatalf("stop should not be left in Extra")
+	}
+	if _, ok := req.Extra["seed"]; !ok {
+		t.Fatalf("unknown keys should be kept in Extra")
+	}
+	if req.Endpoint != EndpointAuto {
+		t.Fatalf("imported requests should be routable, got endpoint %q", req.Endpoint)
+	}
+
+	chat, err := req.ToChatCompletionsRequest()
+	if err != nil {
+		t.Fatalf("ToChatCompletionsRequest: %v", err)
+	}
+	out, err := json.Marshal(chat)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	assertJSONEquivalent(t, raw, out)
+}
+
+func TestNormalizedRequestFromChatCompletionsContentParts(t *testing.T) {
+	req, err := NormalizedRequestFromChatCompletions([]byte(`{
+		"model": "glm-5",
+		"messages": [
+			{"role": "user", "content": [{"type": "text", "text": "Hello, "}, {"type": "text", "text": "world"}]},
+			{"role": "assistant", "content": null}
+		],
+		"reasoning_effort": "high",
+		"max_completion_tokens": 64,
+		"tool_choice": "required"
+	}`))
+	if err != nil {
+		t.Fatalf("NormalizedRequestFromChatCompletions: %v", err)
+	}
+	if req.Messages[0].Content != "Hello, world" {
+		t.Fatalf("content parts not flattened: %q", req.Messages[0].Content)
+	}
+	if req.Messages[1].Content != "" {
+		t.Fatalf("null content should be empty, got %q", req.Messages[1].Content)
+	}
+	if req.Reasoning == nil || req.Reasoning.Effort != "high" {
+		t.Fatalf("reasoning_effort not parsed: %+v", req.Reasoning)
+	}
+	if req.MaxTokens == nil || *req.MaxTokens != 64 {
+		t.Fatalf("max_completion_tokens not parsed: %v", req.MaxTokens)
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Type != ToolChoiceRequired {
+		t.Fatalf("tool choice not parsed: %+v", req.ToolChoice)
+	}
+
+	req, err = NormalizedRequestFromChatCompletions([]byte(`{"model":"gpt-4o-audio-preview","messages":[{"role":"user","content":[{"type":"text","text":"Transcribe"},{"type":"input_audio","input_audio":{"data":"AAEC","format":"wav"}}]}]}`))
+	if err != nil {
+		t.Fatalf("NormalizedRequestFromChatCompletions audio: %v", err)
+	}
+	parts := req.Messages[0].Parts
+	if len(parts) != 2 || parts[0].Text != "Transcribe" || parts[1].Type != ContentPartAudio || parts[1].AudioData != "AAEC" {
+		t.Fatalf("audio content should be kept as parts: %+v", parts)
+	}
+}
+
+func TestNormalizedRequestFromChatCompletionsErrors(t *testing.T) {
+	cases := map[string]string{
+		"not an object": `[]`,
+		"image part":    `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"x"}}]}]}`,
+		"bad choice":    `{"tool_choice":"sometimes"}`,
+		"bad type":      `{"temperature":"hot"}`,
+	}
+	for name, raw := range cases {
+		if _, err := NormalizedRequestFromChatCompletions([]byte(raw)); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// messages (Anthropic)
+// ---------------------------------------------------------------------------
+
+func TestNormalizedRequestFromMessages(t *testing.T) {
+	raw := []byte(`{
+		"model": "claude-sonnet-4-6",
+		"system": [{"type": "text", "text": "be "}, {"type": "text", "text": "brief"}],
+		"max_tokens": 2048,
+		"thinking": {"type": "enabled", "budget_tokens": 1024},
+		"stop_sequences": ["END"],
+		"messages": [
+			{"role": "user", "content": "What's the weather in Paris?"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "hmm", "signature": "sig"},
+				{"type": "redacted_thinking", "data": "opaque"},
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "Sunny"}]},
+				{"type": "text", "text": "Thanks!"}
+			]}
+		],
+		"tools": [{"name": "get_weather", "description": "Weather", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "any"}
+	}`)
+
+	req, err := NormalizedRequestFromMessages(raw)
+	if err != nil {
+		t.Fatalf("NormalizedRequestFromMessages: %v", err)
+	}
+	if req.System != "be brief" {
+		t.Fatalf("system blocks not joined: %q", req.System)
+	}
+	if req.Reasoning == nil || req.Reasoning.BudgetTokens != 1024 {
+		t.Fatalf("thinking not parsed: %+v", req.Reasoning)
+	}
+	if req.MaxTokens == nil || *req.MaxTokens != 2048 {
+		t.Fatalf("max_tokens not parsed")
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Type != ToolChoiceRequired {
+		t.Fatalf("tool choice not parsed: %+v", req.ToolChoice)
+	}
+	if !reflect.DeepEqual(req.StopSequences, []string{"END"}) || req.Extra["stop_sequences"] != nil {
+		t.Fatalf("stop_sequences not mapped to StopSequences: %+v, extra %v", req.StopSequences, req.Extra)
+	}
+	if len(req.Tools) != 1 || string(req.Tools[0].Parameters) != `{"type": "object"}` {
+		t.Fatalf("tools not parsed: %+v", req.Tools)
+	}
+
+	if len(req.Messages) != 4 {
+		t.Fatalf("expected 4 messages, got %d: %+v", len(req.Messages), req.Messages)
+	}
+	asst := req.Messages[1]
+	if asst.Role != "assistant" || asst.Content != "Let me check." || len(asst.ToolCalls) != 1 {
+		t.Fatalf("assistant turn mismatch: %+v", asst)
+	}
+	wantBlocks := []ReasoningBlock{{Index: 0, Text: "hmm", Signature: "sig"}, {Index: 1, Data: "opaque"}}
+	if !reflect.DeepEqual(asst.ReasoningBlocks, wantBlocks) {
+		t.Fatalf("thinking blocks not kept: %+v", asst.ReasoningBlocks)
+	}
+	if string(asst.ToolCalls[0].Arguments) != `{"city": "Paris"}` {
+		t.Fatalf("tool input should be preserved verbatim, got %s", asst.ToolCalls[0].Arguments)
+	}
+	if tool := req.Messages[2]; tool.Role != "tool" || tool.ToolCallID != "toolu_1" || tool.Content != "Sunny" {
+		t.Fatalf("tool result mismatch: %+v", tool)
+	}
+	if user := req.Messages[3]; user.Role != "user" || user.Content != "Thanks!" {
+		t.Fatalf("trailing user text mismatch: %+v", user)
+	}
+
+	// Re-converting for the messages endpoint must yield the same turn structure.
+	msg, err := req.ToMessagesRequest()
+	if err != nil {
+		t.Fatalf("ToMessagesRequest: %v", err)
+	}
+	if len(msg.Messages) != 4 || msg.Thinking == nil || msg.Thinking.BudgetTokens != 1024 {
+		t.Fatalf("re-conversion mismatch: %+v", msg)
+	}
+	blocks := msg.Messages[1].Content.([]AnthropicContentBlock)
+	if len(blocks) != 4 || blocks[0].Type != "thinking" || blocks[0].Thinking != "hmm" || blocks[0].Signature != "sig" ||
+		blocks[1].Type != "redacted_thinking" || blocks[1].Data != "opaque" || blocks[2].Type != "text" || blocks[3].Type != "tool_use" {
+		t.Fatalf("thinking blocks not sent back: %+v", blocks)
+	}
+	// Other endpoints have no use for them.
+	for _, m := range PrepareHistoryFor(EndpointChatCompletions, req.Messages) {
+		if m.ReasoningBlocks != nil {
+			t.Fatalf("reasoning blocks kept for chat completions: %+v", m)
+		}
+	}
+}
+
+func TestImportedStopSequencesRoundTrip(t *testing.T) {
+	chat, err := NormalizedRequestFromChatCompletions([]byte(`{"model":"gpt-5.2","messages":[{"role":"user","content":"hi"}],"stop":"END"}`))
+	if err != nil {
+		t.Fatalf("NormalizedRequestFromChatCompletions: %v", err)
+	}
+	if !reflect.DeepEqual(chat.StopSequences, []string{"END"}) {
+		t.Fatalf("string stop not mapped: %+v", chat.StopSequences)
+	}
+	if _, err := NormalizedRequestFromChatCompletions([]byte(`{"stop":5}`)); err == nil {
+		t.Fatalf("expected an error for a numeric stop")
+	}
+	out, err := chat.ToChatCompletionsRequest()
+	if err != nil {
+		t.Fatalf("ToChatCompletionsRequest: %v", err)
+	}
+	body, _ := json.Marshal(out)
+	back, err := NormalizedRequestFromChatCompletions(body)
+	if err != nil || !reflect.DeepEqual(back.StopSequences, []string{"END"}) {
+		t.Fatalf("chat stop lost on round trip: %s (%v)", body, err)
+	}
+	// Sent to another endpoint, the stop sequences use that endpoint's key.
+	msgs, err := chat.ToMessagesRequest()
+	if err != nil {
+		t.Fatalf("ToMessagesRequest: %v", err)
+	}
+	body, _ = json.Marshal(msgs)
+	if !strings.Contains(string(body), `"stop_sequences":["END"]`) || strings.Contains(string(body), `"stop":`) {
+		t.Fatalf("chat stop not sent as stop_sequences: %s", body)
+	}
+
+	messages, err := NormalizedRequestFromMessages([]byte(`{"model":"claude-sonnet-4-6","max_tokens":64,"messages":[{"role":"user","content":"hi"}],"stop_sequences":["A","B"]}`))
+	if err != nil {
+		t.Fatalf("NormalizedRequestFromMessages: %v", err)
+	}
+	msgs, err = messages.ToMessagesRequest()
+	if err != nil {
+		t.Fatalf("ToMessagesRequest: %v", err)
+	}
+	body, _ = json.Marshal(msgs)
+	back, err = NormalizedRequestFromMessages(body)
+	if err != nil || !reflect.DeepEqual(back.StopSequences, []string{"A", "B"}) {
+		t.Fatalf("stop_sequences lost on round trip: %s (%v)", body, err)
+	}
+	out, err = messages.ToChatCompletionsRequest()
+	if err != nil {
+		t.Fatalf("ToChatCompletionsRequest: %v", err)
+	}
+	body, _ = json.Marshal(out)
+	if !strings.Contains(string(body), `"stop":["A","B"]`) {
+		t.Fatalf("stop_sequences not sent as stop: %s", body)
+	}
+}
+
+func TestNormalizedRequestFromMessagesRejectsImages(t *testing.T) {
+	_, err := NormalizedRequestFromMessages([]byte(`{"messages":[{"role":"user","content":[{"type":"image","source":{}}]}]}`))
+	if err == nil {
+		t.Fatalf("expected error for image block")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// models (Gemini)
+// ---------------------------------------------------------------------------
+
+func TestNormalizedRequestFromGemini(t *testing.T) {
+	raw := []byte(`{
+		"systemInstruction": {"parts": [{"text": "be brief"}]},
+		"contents": [
+			{"role": "user", "parts": [{"text": "What's the weather in Paris?"}]},
+			{"role": "model", "parts": [
+				{"text": "thinking", "thought": true},
+				{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}, "thoughtSignature": "sig"}
+			]},
+			{"role": "user", "parts": [{"functionResponse": {"name": "get_weather", "response": {"output": "Sunny"}}}]},
+			{"role": "model", "parts": [{"text": "It is sunny."}]}
+		],
+		"generationConfig": {"temperature": 0.5, "maxOutputTokens": 100, "thinkingConfig": {"thinkingBudget": 512}, "stopSequences": ["END"], "candidateCount": 2, "seed": 7, "topP": 0.9},
+		"tools": [{"functionDeclarations": [{"name": "get_weather", "parameters": {"type": "object"}}]}],
+		"toolConfig": {"functionCallingConfig": {"mode": "ANY", "allowedFunctionNames": ["get_weather"]}},
+		"safetySettings": []
+	}`)
+
+	req, err := NormalizedRequestFromGemini(raw)
+	if err != nil {
+		t.Fatalf("NormalizedRequestFromGemini: %v", err)
+	}
+	if req.System != "be brief" {
+		t.Fatalf("system mismatch: %q", req.System)
+	}
+	if req.Temperature == nil || *req.Temperature != 0.5 || req.MaxTokens == nil || *req.MaxTokens != 100 {
+		t.Fatalf("generation config not parsed")
+	}
+	if req.Reasoning == nil || req.Reasoning.BudgetTokens != 512 {
+		t.Fatalf("thinking budget not parsed: %+v", req.Reasoning)
+	}
+	if !reflect.DeepEqual(req.StopSequences, []string{"END"}) || req.CandidateCount != 2 {
+		t.Fatalf("stopSequences and candidateCount not parsed: %+v %d", req.StopSequences, req.CandidateCount)
+	}
+	if rest, _ := req.Extra["generationConfig"].(json.RawMessage); string(rest) != `{"seed":7,"topP":0.9}` {
+		t.Fatalf("unmapped generationConfig keys not kept: %s", rest)
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Type != ToolChoiceTool || req.ToolChoice.Name != "get_weather" {
+		t.Fatalf("tool config not parsed: %+v", req.ToolChoice)
+	}
+	if _, ok := req.Extra["safetySettings"]; !ok {
+		t.Fatalf("unknown keys should be kept in Extra")
+	}
+
+	if len(req.Messages) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(req.Messages))
+	}
+	call := req.Messages[1].ToolCalls[0]
+	if req.Messages[1].Role != "assistant" || call.Name != "get_weather" || call.ThoughtSignature != "sig" {
+		t.Fatalf("function call mismatch: %+v", req.Messages[1])
+	}
+	result := req.Messages[2]
+	if result.Role != "tool" || result.ToolCallID != call.ID || result.FunctionName != "get_weather" || result.Content != "Sunny" {
+		t.Fatalf("function response mismatch: %+v (call id %q)", result, call.ID)
+	}
+
+	// The imported history must be valid for every other endpoint.
+	if _, err := req.ToMessagesRequest(); err != nil {
+		t.Fatalf("ToMessagesRequest: %v", err)
+	}
+	if _, err := req.ToChatCompletionsRequest(); err != nil {
+		t.Fatalf("ToChatCompletionsRequest: %v", err)
+	}
+	gem, err := req.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest: %v", err)
+	}
+	if gem.Contents[1].Parts[0].ThoughtSignature != "sig" {
+		t.Fatalf("thought signature lost on re-conversion")
+	}
+	body, err := json.Marshal(gem)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var sent struct {
+		GenerationConfig map[string]any `json:"generationConfig"`
+	}
+	_ = json.Unmarshal(body, &sent)
+	config := sent.GenerationConfig
+	if config["seed"] != 7.0 || config["topP"] != 0.9 || config["temperature"] != 0.5 || config["candidateCount"] != 2.0 || config["stopSequences"] == nil {
+		t.Fatalf("generationConfig not rebuilt: %v", config)
+	}
+}
+
+func TestNormalizedRequestFromGeminiStructuredResponse(t *testing.T) {
+	req, err := NormalizedRequestFromGemini([]byte(`{"contents":[
+		{"role":"model","parts":[{"functionCall":{"name":"a","args":{}}},{"functionCall":{"name":"a","args":{}}}]},
+		{"role":"user","parts":[
+			{"functionResponse":{"name":"a","response":{"temp":22}}},
+			{"functionResponse":{"name":"a","response":{"output":"second"}}}
+		]}
+	]}`))
+	if err != nil {
+		t.Fatalf("NormalizedRequestFromGemini: %v", err)
+	}
+	calls := req.Messages[0].ToolCalls
+	if req.Messages[1].ToolCallID != calls[0].ID || req.Messages[2].ToolCallID != calls[1].ID {
+		t.Fatalf("responses should pair with calls in order: %+v", req.Messages)
+	}
+	if req.Messages[1].Content != `{"temp":22}` {
+		t.Fatalf("structured response should be kept as JSON, got %q", req.Messages[1].Content)
+	}
+}
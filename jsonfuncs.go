@@ -0,0 +1,54 @@
+package zen
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+type jsonFuncs struct {
+	marshal   func(v any) ([]byte, error)
+	unmarshal func(data []byte, v any) error
+}
+
+// activeJSON holds the functions installed by SetJSONFuncs; nil means
+// encoding/json.
+var activeJSON atomic.Pointer[jsonFuncs]
+
+// SetJSONFuncs replaces encoding/json's Marshal and Unmarshal for request
+// bodies and response and stream parsing, e.g. with a faster drop-in. The
+// request types and parsers are shared by all clients, so the replacement
+// applies to every client in the process; a nil function restores
+// encoding/json's. A replacement must produce and accept what encoding/json
+// does, map keys sorted included; zentest.CheckJSONFuncs checks one against
+// the SDK's fixtures. Call it before making requests: requests already
+// running may use either set of functions.
+func SetJSONFuncs(marshal func(v any) ([]byte, error), unmarshal func(data []byte, v any) error) {
+	if marshal == nil && unmarshal == nil {
+		activeJSON.Store(nil)
+		return
+	}
+	next := jsonFuncs{marshal: json.Marshal, unmarshal: json.Unmarshal}
+	if marshal != nil {
+		next.marshal = marshal
+	}
+	if unmarshal != nil {
+		next.unmarshal = unmarshal
+	}
+	activeJSON.Store(&next)
+}
+
+// jsonMarshal encodes request bodies and assembled responses.
+func jsonMarshal(v any) ([]byte, error) {
+	if f := activeJSON.Load(); f != nil {
+		return f.marshal(v)
+	}
+	return json.Marshal(v)
+}
+
+// jsonUnmarshal decodes responses and stream events.
+func jsonUnmarshal(data []byte, v any) error {
+	if f := activeJSON.Load(); f != nil {
+		return f.unmarshal(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,36 @@
+package zen
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSetJSONFuncs(t *testing.T) {
+	var calls atomic.Int32
+	SetJSONFuncs(func(v any) ([]byte, error) {
+		calls.Add(1)
+		return json.Marshal(v)
+	}, nil)
+	defer SetJSONFuncs(nil, nil)
+
+	req := NormalizedRequest{Model: "gemini-3-pro", Messages: []NormalizedMessage{
+		{Role: "assistant", ToolCalls: []NormalizedToolCall{{ID: "c1", Name: "lookup", Arguments: json.RawMessage(`{}`)}}},
+		{Role: "tool", ToolCallID: "c1", FunctionName: "lookup", Content: "boom", IsError: true},
+	}}
+	if _, err := req.ToGeminiRequest(); err != nil {
+		t.Fatalf("ToGeminiRequest: %v", err)
+	}
+	if _, changes := sanitizeGeminiSchema(json.RawMessage(`{"type":"object","const":1}`)); len(changes) == 0 {
+		t.Fatalf("expected a schema change")
+	}
+	if n := calls.Load(); n < 2 {
+		t.Fatalf("expected the installed marshal to encode the error body and schema, got %d calls", n)
+	}
+
+	SetJSONFuncs(nil, nil)
+	before := calls.Load()
+	if _, err := jsonMarshal(map[string]int{"a": 1}); err != nil || calls.Load() != before {
+		t.Fatalf("SetJSONFuncs(nil, nil) should restore encoding/json")
+	}
+}
@@ -0,0 +1,112 @@
+package zen
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// KeySelection selects how a client with several Config.APIKeys picks the
+// key of each request.
+type KeySelection int
+
+const (
+	// KeyRoundRobin takes the keys in turn, skipping keys cooling down.
+	KeyRoundRobin KeySelection = iota
+	// KeyLeastRecentlyRateLimited takes the key whose last 429 is oldest,
+	// keys never rate limited first and in turn, skipping keys cooling
+	// down.
+	KeyLeastRecentlyRateLimited
+)
+
+// DefaultKeyCooldown is how long a key rests after a 429 without a
+// Retry-After header when Config.KeyCooldown is 0.
+const DefaultKeyCooldown = 30 * time.Second
+
+// keyPool hands out the API keys of a client and of all clients derived from
+// it with Client.With.
+type keyPool struct {
+	keys      []string
+	selection KeySelection
+	cooldown  time.Duration
+
+	mu          sync.Mutex
+	next        int
+	coolUntil   []time.Time
+	lastLimited []time.Time
+}
+
+func newKeyPool(cfg Config) *keyPool {
+	keys := cfg.APIKeys
+	if len(keys) == 0 {
+		keys = []string{cfg.APIKey}
+	}
+	cooldown := cfg.KeyCooldown
+	if cooldown == 0 {
+		cooldown = DefaultKeyCooldown
+	}
+	return &keyPool{
+		keys:        append([]string(nil), keys...),
+		selection:   cfg.KeySelection,
+		cooldown:    cooldown,
+		coolUntil:   make([]time.Time, len(keys)),
+		lastLimited: make([]time.Time, len(keys)),
+	}
+}
+
+// pick returns the index of the key for a request sent at now. When every
+// key is cooling down, the one that recovers first is used.
+func (p *keyPool) pick(now time.Time) int {
+	if len(p.keys) == 1 {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	best, soonest := -1, -1
+	for i := 0; i < len(p.keys); i++ {
+		k := (p.next + i) % len(p.keys)
+		if p.coolUntil[k].After(now) {
+			if soonest < 0 || p.coolUntil[k].Before(p.coolUntil[soonest]) {
+				soonest = k
+			}
+			continue
+		}
+		if best < 0 {
+			best = k
+			if p.selection == KeyRoundRobin {
+				break
+			}
+			continue
+		}
+		if p.lastLimited[k].Before(p.lastLimited[best]) {
+			best = k
+		}
+	}
+	if best < 0 {
+		best = soonest
+	}
+	p.next = (best + 1) % len(p.keys)
+	return best
+}
+
+// rateLimited rests key i after a 429 received at now, for retryAfter or
+// else the pool's cooldown.
+func (p *keyPool) rateLimited(i int, now time.Time, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = p.cooldown
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastLimited[i] = now
+	p.coolUntil[i] = now.Add(retryAfter)
+}
+
+// keyRateLimited rests key i when resp is a 429.
+func (c *Client) keyRateLimited(i int, resp *http.Response) {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	now := c.cfg.Clock.Now()
+	c.keys.rateLimited(i, now, parseRetryAfter(resp.Header, now))
+}
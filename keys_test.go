@@ -0,0 +1,100 @@
+package zen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAPIKeysRoundRobinWithCooldown(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	limited := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		mu.Lock()
+		seen = append(seen, key)
+		first429 := key == "b" && !limited
+		limited = limited || first429
+		mu.Unlock()
+		if first429 {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = io.WriteString(w, `{"error":{"message":"rate limited"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer server.Close()
+
+	perKey := map[int]int{}
+	client, err := NewClient(Config{
+		APIKeys: []string{"a", "b", "c"},
+		BaseURL: server.URL,
+		Retry: RetryConfig{
+			MaxRetries:           1,
+			RetryOnNonIdempotent: true,
+			Backoff:              func(int) time.Duration { return 0 },
+		},
+		OnRequestMetrics: func(_ context.Context, m RequestMetrics) {
+			mu.Lock()
+			perKey[m.KeyIndex]++
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+	for i := 0; i < 5; i++ {
+		if _, err := client.CreateNormalized(context.Background(), req); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+
+	// The retry of the second request stays on b; b then rests and the
+	// rotation continues over a and c.
+	if got := fmt.Sprint(seen); got != "[a b b c a c]" {
+		t.Fatalf("unexpected key sequence %s", got)
+	}
+	if perKey[0] != 2 || perKey[1] != 2 || perKey[2] != 2 {
+		t.Fatalf("unexpected per-key metrics %v", perKey)
+	}
+}
+
+func TestKeyPoolLeastRecentlyRateLimited(t *testing.T) {
+	now := time.Unix(1000, 0)
+	p := newKeyPool(Config{APIKeys: []string{"a", "b", "c"}, KeySelection: KeyLeastRecentlyRateLimited, KeyCooldown: time.Minute})
+
+	p.rateLimited(1, now.Add(-time.Hour), 0)
+	// a and c were never rate limited and take turns.
+	if got := []int{p.pick(now), p.pick(now), p.pick(now)}; fmt.Sprint(got) != "[0 2 0]" {
+		t.Fatalf("unexpected picks %v", got)
+	}
+	p.rateLimited(0, now.Add(-2*time.Hour), 0)
+	p.rateLimited(2, now.Add(-30*time.Minute), 0)
+	// a's 429 is now the oldest.
+	if got := p.pick(now); got != 0 {
+		t.Fatalf("expected a, got %d", got)
+	}
+
+	p.rateLimited(0, now, 0)
+	p.rateLimited(2, now, 10*time.Second)
+	if got := p.pick(now); got != 1 {
+		t.Fatalf("expected b, the only key not resting, got %d", got)
+	}
+	p.rateLimited(1, now, 0)
+	// Every key rests: c recovers first.
+	if got := p.pick(now); got != 2 {
+		t.Fatalf("expected c, which recovers first, got %d", got)
+	}
+}
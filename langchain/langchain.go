@@ -0,0 +1,337 @@
+// Package langchain adapts a zen.Client to the llms.Model interface of
+// langchaingo, so existing chains and agents can use zen's routing and
+// gateway features unchanged. It is a module of its own to keep langchaingo
+// out of the dependencies of the zen module.
+//
+// Messages, tools, tool calls and tool results are translated to and from
+// their zen.NormalizedRequest form; a StreamingFunc receives the text deltas
+// of a streamed reply. Token usage comes back in each choice's
+// GenerationInfo under the keys used by langchaingo's openai package
+// (PromptTokens, CompletionTokens, TotalTokens and ReasoningTokens) plus
+// CachedInputTokens.
+package langchain
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// LLM is an llms.Model backed by a zen.Client.
+type LLM struct {
+	client *zen.Client
+	model  string
+}
+
+var _ llms.Model = (*LLM)(nil)
+
+// New returns an LLM sending requests for model through client; the
+// llms.WithModel option overrides model per call.
+func New(client *zen.Client, model string) *LLM {
+	return &LLM{client: client, model: model}
+}
+
+// Call generates a reply to a single prompt.
+//
+// Deprecated: use llms.GenerateFromSinglePrompt, as for any llms.Model.
+func (l *LLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, l, prompt, options...)
+}
+
+// GenerateContent sends messages as one request and returns the reply. The
+// request is streamed when a StreamingFunc is set; an error returned by it
+// stops the stream and is returned. Options without a zen equivalent, such
+// as TopK, Seed and JSONMode, are ignored; a zero Temperature or MaxTokens
+// leaves the provider's default.
+func (l *LLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	req, err := l.request(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *zen.NormalizedResponse
+	if opts.StreamingFunc != nil {
+		resp, err = l.stream(ctx, req, opts.StreamingFunc)
+	} else {
+		resp, err = l.client.CreateNormalized(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return contentResponse(resp), nil
+}
+
+// stream runs req as a stream, handing each text delta to fn.
+func (l *LLM) stream(ctx context.Context, req zen.NormalizedRequest, fn func(context.Context, []byte) error) (*zen.NormalizedResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	deltas, errs, err := l.client.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	forwarded := make(chan zen.NormalizedDelta)
+	var fnErr error
+	go func() {
+		defer close(forwarded)
+		for d := range deltas {
+			if fnErr != nil {
+				continue
+			}
+			if d.Type == zen.DeltaText && d.Content != "" {
+				if fnErr = fn(ctx, []byte(d.Content)); fnErr != nil {
+					cancel()
+					continue
+				}
+			}
+			forwarded <- d
+		}
+	}()
+	resp, err := zen.CollectDeltas(forwarded, errs)
+	if fnErr != nil {
+		return nil, fnErr
+	}
+	return resp, err
+}
+
+// request translates messages and opts to a NormalizedRequest.
+func (l *LLM) request(messages []llms.MessageContent, opts llms.CallOptions) (zen.NormalizedRequest, error) {
+	req := zen.NormalizedRequest{
+		Model:          l.model,
+		StopSequences:  opts.StopWords,
+		CandidateCount: opts.CandidateCount,
+		Stream:         opts.StreamingFunc != nil,
+	}
+	if opts.Model != "" {
+		req.Model = opts.Model
+	}
+	if req.Model == "" {
+		return req, errors.New("langchain: no model set")
+	}
+	if opts.Temperature != 0 {
+		req.Temperature = &opts.Temperature
+	}
+	if opts.MaxTokens > 0 {
+		req.MaxTokens = &opts.MaxTokens
+	}
+
+	for _, m := range messages {
+		converted, err := convertMessage(m)
+		if err != nil {
+			return req, err
+		}
+		req.Messages = append(req.Messages, converted...)
+	}
+
+	tools := opts.Tools
+	for _, fn := range opts.Functions {
+		tools = append(tools, llms.Tool{Type: "function", Function: &fn})
+	}
+	for _, tool := range tools {
+		if tool.Function == nil {
+			return req, fmt.Errorf("langchain: unsupported tool type %q", tool.Type)
+		}
+		t := zen.NormalizedTool{Name: tool.Function.Name, Description: tool.Function.Description}
+		if tool.Function.Parameters != nil {
+			params, err := json.Marshal(tool.Function.Parameters)
+			if err != nil {
+				return req, fmt.Errorf("langchain: parameters of tool %q: %w", t.Name, err)
+			}
+			t.Parameters = params
+		}
+		req.Tools = append(req.Tools, t)
+	}
+
+	choice, err := convertToolChoice(opts.ToolChoice)
+	if err != nil {
+		return req, err
+	}
+	req.ToolChoice = choice
+	return req, nil
+}
+
+// convertMessage translates m to zen messages: one per tool result for tool
+// messages, a single one otherwise.
+func convertMessage(m llms.MessageContent) ([]zen.NormalizedMessage, error) {
+	switch m.Role {
+	case llms.ChatMessageTypeSystem:
+		text, err := textOf(m)
+		if err != nil {
+			return nil, err
+		}
+		return []zen.NormalizedMessage{{Role: "system", Content: text}}, nil
+
+	case llms.ChatMessageTypeHuman, llms.ChatMessageTypeGeneric:
+		msg := zen.NormalizedMessage{Role: "user"}
+		multimodal := false
+		for _, part := range m.Parts {
+			converted, err := convertPart(part)
+			if err != nil {
+				return nil, err
+			}
+			multimodal = multimodal || converted.Type != zen.ContentPartText
+			msg.Parts = append(msg.Parts, converted)
+		}
+		if !multimodal {
+			var text strings.Builder
+			for _, p := range msg.Parts {
+				text.WriteString(p.Text)
+			}
+			msg.Content, msg.Parts = text.String(), nil
+		}
+		return []zen.NormalizedMessage{msg}, nil
+
+	case llms.ChatMessageTypeAI:
+		msg := zen.NormalizedMessage{Role: "assistant"}
+		var text strings.Builder
+		for _, part := range m.Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				text.WriteString(p.Text)
+			case llms.ToolCall:
+				if p.FunctionCall == nil {
+					return nil, fmt.Errorf("langchain: tool call %q has no function", p.ID)
+				}
+				msg.ToolCalls = append(msg.ToolCalls, zen.NormalizedToolCall{
+					ID:        p.ID,
+					Name:      p.FunctionCall.Name,
+					Arguments: json.RawMessage(p.FunctionCall.Arguments),
+				})
+			default:
+				return nil, fmt.Errorf("langchain: unsupported %T in an ai message", part)
+			}
+		}
+		msg.Content = text.String()
+		return []zen.NormalizedMessage{msg}, nil
+
+	case llms.ChatMessageTypeTool:
+		var out []zen.NormalizedMessage
+		for _, part := range m.Parts {
+			p, ok := part.(llms.ToolCallResponse)
+			if !ok {
+				return nil, fmt.Errorf("langchain: unsupported %T in a tool message", part)
+			}
+			out = append(out, zen.NormalizedMessage{
+				Role:         "tool",
+				ToolCallID:   p.ToolCallID,
+				FunctionName: p.Name,
+				Content:      p.Content,
+			})
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("langchain: unsupported message role %q", m.Role)
+}
+
+// textOf returns the text of a message made only of text parts.
+func textOf(m llms.MessageContent) (string, error) {
+	var text strings.Builder
+	for _, part := range m.Parts {
+		p, ok := part.(llms.TextContent)
+		if !ok {
+			return "", fmt.Errorf("langchain: unsupported %T in a %s message", part, m.Role)
+		}
+		text.WriteString(p.Text)
+	}
+	return text.String(), nil
+}
+
+// convertPart translates a part of a human message. Images and other files
+// become file parts, which only the Gemini endpoint accepts.
+func convertPart(part llms.ContentPart) (zen.NormalizedContentPart, error) {
+	switch p := part.(type) {
+	case llms.TextContent:
+		return zen.NormalizedContentPart{Type: zen.ContentPartText, Text: p.Text}, nil
+	case llms.ImageURLContent:
+		return zen.NormalizedContentPart{Type: zen.ContentPartFile, FileURI: p.URL}, nil
+	case llms.BinaryContent:
+		switch p.MIMEType {
+		case "audio/wav", "audio/x-wav":
+			return zen.NormalizedContentPart{Type: zen.ContentPartAudio, AudioData: base64.StdEncoding.EncodeToString(p.Data), AudioFormat: "wav"}, nil
+		case "audio/mpeg", "audio/mp3":
+			return zen.NormalizedContentPart{Type: zen.ContentPartAudio, AudioData: base64.StdEncoding.EncodeToString(p.Data), AudioFormat: "mp3"}, nil
+		}
+		return zen.NormalizedContentPart{Type: zen.ContentPartFile, FileURI: p.String(), MediaType: p.MIMEType}, nil
+	}
+	return zen.NormalizedContentPart{}, fmt.Errorf("langchain: unsupported %T in a human message", part)
+}
+
+// convertToolChoice translates a CallOptions.ToolChoice: "auto", "none",
+// "required" or a function name as a string, or an llms.ToolChoice.
+func convertToolChoice(choice any) (*zen.NormalizedToolChoice, error) {
+	switch c := choice.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		switch zen.ToolChoiceType(c) {
+		case "":
+			return nil, nil
+		case zen.ToolChoiceAuto, zen.ToolChoiceNone, zen.ToolChoiceRequired:
+			return &zen.NormalizedToolChoice{Type: zen.ToolChoiceType(c)}, nil
+		}
+		return &zen.NormalizedToolChoice{Type: zen.ToolChoiceTool, Name: c}, nil
+	case llms.ToolChoice:
+		return convertToolChoice(&c)
+	case *llms.ToolChoice:
+		if c.Function != nil && c.Function.Name != "" {
+			return &zen.NormalizedToolChoice{Type: zen.ToolChoiceTool, Name: c.Function.Name}, nil
+		}
+		return convertToolChoice(c.Type)
+	}
+	return nil, fmt.Errorf("langchain: unsupported tool choice %T", choice)
+}
+
+// contentResponse translates resp, one choice per candidate.
+func contentResponse(resp *zen.NormalizedResponse) *llms.ContentResponse {
+	info := map[string]any{
+		"PromptTokens":      resp.InputTokens,
+		"CompletionTokens":  resp.OutputTokens,
+		"TotalTokens":       resp.InputTokens + resp.OutputTokens,
+		"ReasoningTokens":   resp.ReasoningTokens,
+		"CachedInputTokens": resp.CachedInputTokens,
+	}
+	candidates := resp.Choices
+	if len(candidates) == 0 {
+		candidates = []zen.NormalizedChoice{{
+			Text:         resp.Text,
+			Reasoning:    resp.Reasoning,
+			ToolCalls:    resp.ToolCalls,
+			FinishReason: resp.FinishReason,
+		}}
+	}
+
+	out := &llms.ContentResponse{}
+	for _, cand := range candidates {
+		choice := &llms.ContentChoice{
+			Content:          cand.Text,
+			StopReason:       cand.FinishReason,
+			GenerationInfo:   info,
+			ReasoningContent: cand.Reasoning,
+		}
+		for _, call := range cand.ToolCalls {
+			choice.ToolCalls = append(choice.ToolCalls, llms.ToolCall{
+				ID:   call.ID,
+				Type: "function",
+				FunctionCall: &llms.FunctionCall{
+					Name:      call.Name,
+					Arguments: string(call.Arguments),
+				},
+			})
+		}
+		if len(choice.ToolCalls) > 0 {
+			choice.FuncCall = choice.ToolCalls[0].FunctionCall
+		}
+		out.Choices = append(out.Choices, choice)
+	}
+	return out
+}
@@ -0,0 +1,211 @@
+package langchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+	"github.com/sacenox/go-opencode-ai-zen-sdk/zentest"
+	"github.com/tmc/langchaingo/chains"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/prompts"
+)
+
+const toolCallBody = `{
+  "choices": [{
+    "index": 0,
+    "message": {"role": "assistant", "content": null, "tool_calls": [
+      {"id": "call_1", "type": "function", "function": {"name": "add", "arguments": "{\"a\":2,\"b\":3}"}}
+    ]},
+    "finish_reason": "tool_calls"
+  }],
+  "usage": {"prompt_tokens": 20, "completion_tokens": 7, "total_tokens": 27}
+}`
+
+const answerBody = `{
+  "choices": [{"index": 0, "message": {"role": "assistant", "content": "2 + 3 = 5"}, "finish_reason": "stop"}],
+  "usage": {"prompt_tokens": 35, "completion_tokens": 6, "total_tokens": 41}
+}`
+
+const answerSSE = `data: {"choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"}}]}
+
+data: {"choices":[{"index":0,"delta":{"content":", world"}}]}
+
+data: {"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":4,"completion_tokens":3,"total_tokens":7}}
+
+data: [DONE]
+
+`
+
+func newTestLLM(t *testing.T, replies ...zentest.Reply) (*LLM, *zentest.Server) {
+	t.Helper()
+	server := zentest.NewServer(t, replies...)
+	client, err := server.NewClient(zen.Config{})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	return New(client, "kimi-k2"), server
+}
+
+func requestBody(t *testing.T, server *zentest.Server, i int) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(server.Requests()[i].Body, &body); err != nil {
+		t.Fatalf("decode request %d: %v", i, err)
+	}
+	return body
+}
+
+// TestToolCallingChain runs a tool-calling loop: the model asks for a tool,
+// the call and its result are sent back and the model answers.
+func TestToolCallingChain(t *testing.T) {
+	llm, server := newTestLLM(t, zentest.Reply{Body: []byte(toolCallBody)}, zentest.Reply{Body: []byte(answerBody)})
+	ctx := context.Background()
+	tools := []llms.Tool{{Type: "function", Function: &llms.FunctionDefinition{
+		Name:        "add",
+		Description: "Adds two numbers",
+		Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"a": map[string]any{"type": "number"}, "b": map[string]any{"type": "number"}},
+		},
+	}}}
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "Use the tools."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is 2 + 3?"),
+	}
+
+	resp, err := llm.GenerateContent(ctx, messages, llms.WithTools(tools), llms.WithToolChoice("required"))
+	if err != nil {
+		t.Fatalf("first turn: %v", err)
+	}
+	choice := resp.Choices[0]
+	if len(choice.ToolCalls) != 1 || choice.ToolCalls[0].ID != "call_1" || choice.ToolCalls[0].FunctionCall.Name != "add" {
+		t.Fatalf("unexpected tool calls: %+v", choice.ToolCalls)
+	}
+	if choice.ToolCalls[0].FunctionCall.Arguments != `{"a":2,"b":3}` {
+		t.Fatalf("unexpected arguments: %s", choice.ToolCalls[0].FunctionCall.Arguments)
+	}
+	if choice.FuncCall == nil || choice.FuncCall.Name != "add" || choice.StopReason != "tool_calls" {
+		t.Fatalf("unexpected choice: %+v", choice)
+	}
+	if choice.GenerationInfo["PromptTokens"] != 20 || choice.GenerationInfo["CompletionTokens"] != 7 || choice.GenerationInfo["TotalTokens"] != 27 {
+		t.Fatalf("unexpected usage: %v", choice.GenerationInfo)
+	}
+
+	first := requestBody(t, server, 0)
+	if first["model"] != "kimi-k2" || first["tool_choice"] != "required" {
+		t.Fatalf("unexpected first request: %v", first)
+	}
+	sent := first["tools"].([]any)[0].(map[string]any)["function"].(map[string]any)
+	if sent["name"] != "add" || sent["parameters"].(map[string]any)["type"] != "object" {
+		t.Fatalf("unexpected tool sent: %v", sent)
+	}
+
+	call := choice.ToolCalls[0]
+	messages = append(messages,
+		llms.MessageContent{Role: llms.ChatMessageTypeAI, Parts: []llms.ContentPart{call}},
+		llms.MessageContent{Role: llms.ChatMessageTypeTool, Parts: []llms.ContentPart{
+			llms.ToolCallResponse{ToolCallID: call.ID, Name: call.FunctionCall.Name, Content: "5"},
+		}},
+	)
+	resp, err = llm.GenerateContent(ctx, messages, llms.WithTools(tools))
+	if err != nil {
+		t.Fatalf("second turn: %v", err)
+	}
+	if resp.Choices[0].Content != "2 + 3 = 5" || resp.Choices[0].GenerationInfo["TotalTokens"] != 41 {
+		t.Fatalf("unexpected answer: %+v", resp.Choices[0])
+	}
+
+	sentMessages := requestBody(t, server, 1)["messages"].([]any)
+	if len(sentMessages) != 4 {
+		t.Fatalf("expected 4 messages, got %d: %v", len(sentMessages), sentMessages)
+	}
+	assistant := sentMessages[2].(map[string]any)
+	sentCall := assistant["tool_calls"].([]any)[0].(map[string]any)
+	if sentCall["id"] != "call_1" || sentCall["function"].(map[string]any)["arguments"] != `{"a":2,"b":3}` {
+		t.Fatalf("unexpected assistant message: %v", assistant)
+	}
+	result := sentMessages[3].(map[string]any)
+	if result["role"] != "tool" || result["tool_call_id"] != "call_1" || result["content"] != "5" {
+		t.Fatalf("unexpected tool message: %v", result)
+	}
+}
+
+func TestStreamingFunc(t *testing.T) {
+	llm, server := newTestLLM(t, zentest.Reply{SSE: []byte(answerSSE)})
+	var chunks []string
+	resp, err := llm.GenerateContent(context.Background(),
+		[]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Hi")},
+		llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			chunks = append(chunks, string(chunk))
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if strings.Join(chunks, "|") != "Hello|, world" {
+		t.Fatalf("unexpected chunks: %q", chunks)
+	}
+	choice := resp.Choices[0]
+	if choice.Content != "Hello, world" || choice.StopReason != "stop" {
+		t.Fatalf("unexpected choice: %+v", choice)
+	}
+	if choice.GenerationInfo["PromptTokens"] != 4 || choice.GenerationInfo["CompletionTokens"] != 3 {
+		t.Fatalf("unexpected usage: %v", choice.GenerationInfo)
+	}
+	if server.Requests()[0].Header.Get("Accept") != "text/event-stream" {
+		t.Fatal("expected a streaming request")
+	}
+}
+
+func TestStreamingFuncError(t *testing.T) {
+	llm, _ := newTestLLM(t, zentest.Reply{SSE: []byte(answerSSE)})
+	stop := errors.New("stop")
+	calls := 0
+	_, err := llm.GenerateContent(context.Background(),
+		[]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Hi")},
+		llms.WithStreamingFunc(func(context.Context, []byte) error {
+			calls++
+			return stop
+		}),
+	)
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("expected the streaming func error after 1 call, got %v after %d", err, calls)
+	}
+}
+
+func TestLLMChain(t *testing.T) {
+	llm, server := newTestLLM(t, zentest.Reply{Body: []byte(answerBody)})
+	chain := chains.NewLLMChain(llm, prompts.NewPromptTemplate("What is {{.a}} + {{.b}}?", []string{"a", "b"}))
+	out, err := chains.Call(context.Background(), chain, map[string]any{"a": 2, "b": 3})
+	if err != nil {
+		t.Fatalf("run chain: %v", err)
+	}
+	if out[chain.OutputKey] != "2 + 3 = 5" {
+		t.Fatalf("unexpected output: %v", out)
+	}
+	messages := requestBody(t, server, 0)["messages"].([]any)
+	if len(messages) != 1 || messages[0].(map[string]any)["content"] != "What is 2 + 3?" {
+		t.Fatalf("unexpected messages: %v", messages)
+	}
+}
+
+func TestUnsupportedInput(t *testing.T) {
+	llm, server := newTestLLM(t)
+	_, err := llm.GenerateContent(context.Background(), []llms.MessageContent{{
+		Role:  llms.ChatMessageTypeTool,
+		Parts: []llms.ContentPart{llms.TextPart("not a tool result")},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "tool message") {
+		t.Fatalf("expected an unsupported part error, got %v", err)
+	}
+	if len(server.Requests()) != 0 {
+		t.Fatal("expected no request")
+	}
+}
@@ -0,0 +1,66 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrConcurrencyLimit is returned when Config.MaxConcurrentRequests slots are
+// all taken and Config.FailFastOnConcurrencyLimit is set.
+var ErrConcurrencyLimit = errors.New("zen: concurrency limit reached")
+
+// limiter bounds the number of in-flight HTTP requests of a client and of all
+// clients derived from it with Client.With.
+type limiter struct {
+	slots    chan struct{} // nil when unlimited
+	inFlight atomic.Int64
+}
+
+func newLimiter(max int) *limiter {
+	l := &limiter{}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// acquire takes a slot, waiting until one frees up or ctx is done, or failing
+// immediately with failFast. The returned release func is safe to call more
+// than once.
+func (l *limiter) acquire(ctx context.Context, failFast bool) (func(), error) {
+	if l.slots != nil {
+		if failFast {
+			select {
+			case l.slots <- struct{}{}:
+			default:
+				return nil, ErrConcurrencyLimit
+			}
+		} else {
+			select {
+			case l.slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	l.inFlight.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.inFlight.Add(-1)
+			if l.slots != nil {
+				<-l.slots
+			}
+		})
+	}, nil
+}
+
+// InFlight returns the number of requests currently holding a slot,
+// including streams that are still being read unless
+// Config.ReleaseSlotAfterHeaders is set. Derived clients share the count.
+func (c *Client) InFlight() int {
+	return int(c.limiter.inFlight.Load())
+}
@@ -0,0 +1,96 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newBlockingStreamServer serves a stream that sends its headers and one
+// event, then stays open until unblock is closed.
+func newBlockingStreamServer(t *testing.T, unblock <-chan struct{}) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	}))
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	unblock := make(chan struct{})
+	server := newBlockingStreamServer(t, unblock)
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, MaxConcurrentRequests: 1})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	events, errs, err := client.StreamEvents(context.Background(), req)
+	if err != nil {
+		t.Fatalf("first stream: %v", err)
+	}
+	<-events
+	if got := client.InFlight(); got != 1 {
+		t.Fatalf("in-flight: want 1, got %d", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := client.StreamEvents(ctx, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waiting for a slot should end with the context, got %v", err)
+	}
+
+	failFast := client.With(func(cfg *Config) { cfg.FailFastOnConcurrencyLimit = true })
+	if _, _, err := failFast.StreamEvents(context.Background(), req); !errors.Is(err, ErrConcurrencyLimit) {
+		t.Fatalf("expected ErrConcurrencyLimit, got %v", err)
+	}
+
+	close(unblock)
+	for range events {
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if got := client.InFlight(); got != 0 {
+		t.Fatalf("slot should be released when the stream ends, in-flight %d", got)
+	}
+	if _, err := drainStreamEvents(context.Background(), client, req); err != nil {
+		t.Fatalf("stream after release: %v", err)
+	}
+}
+
+func TestConcurrencyLimitReleaseAfterHeaders(t *testing.T) {
+	unblock := make(chan struct{})
+	server := newBlockingStreamServer(t, unblock)
+	defer server.Close()
+	defer close(unblock)
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, MaxConcurrentRequests: 1, ReleaseSlotAfterHeaders: true})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		events, _, err := client.StreamEvents(ctx, req)
+		if err != nil {
+			t.Fatalf("stream %d: %v", i, err)
+		}
+		<-events
+	}
+	if got := client.InFlight(); got != 0 {
+		t.Fatalf("open streams should not hold slots, in-flight %d", got)
+	}
+}
@@ -2,9 +2,49 @@ package zen
 
 import "encoding/json"
 
+// MarshalForEndpoint returns the body a Client would send for req to
+// endpoint, without a Client: the conversion with its validation followed by
+// the endpoint request's MarshalJSON, which merges Extra. EndpointAuto
+// routes by RouteForModel, and an "opencode/" model prefix is stripped.
+// Client settings such as ModelDefaults, ModelRoutes and AutoMaxTokens are
+// not applied; req.Stream is sent as set.
+func MarshalForEndpoint(req NormalizedRequest, endpoint EndpointType) ([]byte, error) {
+	req.Model = stripOpencodePrefix(req.Model)
+	if endpoint == EndpointAuto {
+		endpoint = RouteForModel(req.Model)
+	}
+	return encodeRequest(endpoint, req)
+}
+
+// MarshalChatCompletionsRequest is MarshalForEndpoint for
+// EndpointChatCompletions.
+func MarshalChatCompletionsRequest(req NormalizedRequest) ([]byte, error) {
+	return MarshalForEndpoint(req, EndpointChatCompletions)
+}
+
+// MarshalResponsesRequest is MarshalForEndpoint for EndpointResponses.
+func MarshalResponsesRequest(req NormalizedRequest) ([]byte, error) {
+	return MarshalForEndpoint(req, EndpointResponses)
+}
+
+// MarshalMessagesRequest is MarshalForEndpoint for EndpointMessages.
+func MarshalMessagesRequest(req NormalizedRequest) ([]byte, error) {
+	return MarshalForEndpoint(req, EndpointMessages)
+}
+
+// MarshalGeminiRequest is MarshalForEndpoint for EndpointModels.
+func MarshalGeminiRequest(req NormalizedRequest) ([]byte, error) {
+	return MarshalForEndpoint(req, EndpointModels)
+}
+
+// marshalWithExtra marshals base with the keys of extra that base does not
+// set. Request bodies are deterministic: map keys, including Extra's, are
+// written in sorted order and struct fields in declaration order, so the same
+// request always produces the same bytes. Callers may rely on this, e.g. for
+// golden tests; keep it when changing the encoding.
 func marshalWithExtra(base map[string]any, extra map[string]any) ([]byte, error) {
 	if len(extra) == 0 {
-		return json.Marshal(base)
+		return jsonMarshal(base)
 	}
 
 	for k, v := range extra {
@@ -14,5 +54,36 @@ func marshalWithExtra(base map[string]any, extra map[string]any) ([]byte, error)
 		base[k] = v
 	}
 
-	return json.Marshal(base)
+	return jsonMarshal(base)
+}
+
+// withObjectExtra returns v with the keys of extra, a JSON object, that v
+// does not set; Gemini uses it to merge Extra["generationConfig"] into the
+// generated generationConfig. A nil or non-object extra leaves v as it is.
+func withObjectExtra(v any, extra any) (any, error) {
+	if extra == nil {
+		return v, nil
+	}
+	raw, err := jsonMarshal(extra)
+	if err != nil {
+		return nil, err
+	}
+	var more map[string]json.RawMessage
+	if jsonUnmarshal(raw, &more) != nil || len(more) == 0 {
+		return v, nil
+	}
+	data, err := jsonMarshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var merged map[string]json.RawMessage
+	if err := jsonUnmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for k, value := range more {
+		if _, exists := merged[k]; !exists {
+			merged[k] = value
+		}
+	}
+	return merged, nil
 }
@@ -0,0 +1,251 @@
+package zen
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRequestMarshalGolden(t *testing.T) {
+	temp := 0.2
+	maxTokens := 256
+	req := NormalizedRequest{
+		Model:       "m",
+		System:      "Be brief.",
+		Messages:    []NormalizedMessage{{Role: "user", Content: "Weather in Paris?"}},
+		Tools:       []NormalizedTool{{Name: "get_weather", Description: "Look up weather", Parameters: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`)}},
+		Temperature: &temp,
+		MaxTokens:   &maxTokens,
+		Stream:      true,
+		Extra:       map[string]any{"user": "u-1", "metadata": map[string]any{"z": 1, "a": 2}},
+	}
+
+	cases := []struct {
+		name    string
+		convert func() (any, error)
+		want    string
+	}{
+		{
+			"chat_completions",
+			func() (any, error) { return req.ToChatCompletionsRequest() },
+			`{"max_tokens":256,"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"Weather in Paris?"}],"metadata":{"a":2,"z":1},"model":"m","stream":true,"temperature":0.2,"tools":[{"type":"function","function":{"name":"get_weather","description":"Look up weather","parameters":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}}}],"user":"u-1"}`,
+		},
+		{
+			"messages",
+			func() (any, error) { return req.ToMessagesRequest() },
+			`{"max_tokens":256,"messages":[{"role":"user","content":"Weather in Paris?"}],"metadata":{"a":2,"z":1},"model":"m","stream":true,"system":"Be brief.","temperature":0.2,"tools":[{"name":"get_weather","description":"Look up weather","input_schema":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}}],"user":"u-1"}`,
+		},
+		{
+			"responses",
+			func() (any, error) { return req.ToResponsesRequest() },
+			`{"input":[{"type":"message","role":"system","content":[{"type":"input_text","text":"Be brief."}]},{"type":"message","role":"user","content":[{"type":"input_text","text":"Weather in Paris?"}]}],"max_output_tokens":256,"metadata":{"a":2,"z":1},"model":"m","stream":true,"temperature":0.2,"tools":[{"type":"function","name":"get_weather","description":"Look up weather","parameters":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}}],"user":"u-1"}`,
+		},
+		{
+			"models",
+			func() (any, error) { return req.ToGeminiRequest() },
+			`{"contents":[{"role":"user","parts":[{"text":"Weather in Paris?"}]}],"generationConfig":{"temperature":0.2,"maxOutputTokens":256},"metadata":{"a":2,"z":1},"systemInstruction":{"role":"system","parts":[{"text":"Be brief."}]},"tools":[{"functionDeclarations":[{"name":"get_weather","description":"Look up weather","parameters":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}}]}],"user":"u-1"}`,
+		},
+	}
+	for _, tc := range cases {
+		// Map iteration order is randomized, so repeat to catch any
+		// order-dependent output.
+		for i := 0; i < 20; i++ {
+			v, err := tc.convert()
+			if err != nil {
+				t.Fatalf("%s: convert: %v", tc.name, err)
+			}
+			got, err := json.Marshal(v)
+			if err != nil {
+				t.Fatalf("%s: marshal: %v", tc.name, err)
+			}
+			if string(got) != tc.want {
+				t.Fatalf("%s: body mismatch:\nwant: %s\ngot:  %s", tc.name, tc.want, got)
+			}
+		}
+	}
+}
+
+func TestMarshalForEndpointToolHistory(t *testing.T) {
+	req := NormalizedRequest{
+		Model:    "opencode/m",
+		System:   "Answer with the weather.",
+		Messages: toolHistory,
+		Tools:    []NormalizedTool{{Name: "get_weather", Parameters: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`)}},
+		Extra:    map[string]any{"user": "u-1"},
+	}
+
+	cases := []struct {
+		endpoint EndpointType
+		marshal  func(NormalizedRequest) ([]byte, error)
+		want     string
+	}{
+		{
+			EndpointChatCompletions,
+			MarshalChatCompletionsRequest,
+			`{"messages":[{"role":"system","content":"Answer with the weather."},{"role":"user","content":"What's the weather in Paris?"},{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},{"role":"tool","content":"Sunny, 22°C","tool_call_id":"call_1"},{"role":"assistant","content":"The weather in Paris is sunny and 22°C."}],"model":"m","tools":[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object","properties":{"city":{"type":"string"}}}}}],"user":"u-1"}`,
+		},
+		{
+			EndpointResponses,
+			MarshalResponsesRequest,
+			`{"input":[{"type":"message","role":"system","content":[{"type":"input_text","text":"Answer with the weather."}]},{"type":"message","role":"user","content":[{"type":"input_text","text":"What's the weather in Paris?"}]},{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Paris\"}"},{"type":"function_call_output","call_id":"call_1","output":"Sunny, 22°C"},{"type":"message","role":"assistant","content":[{"type":"output_text","text":"The weather in Paris is sunny and 22°C."}]}],"model":"m","tools":[{"type":"function","name":"get_weather","parameters":{"type":"object","properties":{"city":{"type":"string"}}}}],"user":"u-1"}`,
+		},
+		{
+			EndpointMessages,
+			MarshalMessagesRequest,
+			`{"max_tokens":1024,"messages":[{"role":"user","content":"What's the weather in Paris?"},{"role":"assistant","content":[{"type":"tool_use","id":"call_1","name":"get_weather","input":{"city":"Paris"}}]},{"role":"user","content":[{"type":"tool_result","tool_use_id":"call_1","content":"Sunny, 22°C"}]},{"role":"assistant","content":"The weather in Paris is sunny and 22°C."}],"model":"m","system":"Answer with the weather.","tools":[{"name":"get_weather","input_schema":{"type":"object","properties":{"city":{"type":"string"}}}}],"user":"u-1"}`,
+		},
+		{
+			EndpointModels,
+			MarshalGeminiRequest,
+			`{"contents":[{"role":"user","parts":[{"text":"What's the weather in Paris?"}]},{"role":"model","parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]},{"role":"user","parts":[{"functionResponse":{"name":"get_weather","response":{"output":"Sunny, 22°C"}}}]},{"role":"model","parts":[{"text":"The weather in Paris is sunny and 22°C."}]}],"systemInstruction":{"role":"system","parts":[{"text":"Answer with the weather."}]},"tools":[{"functionDeclarations":[{"name":"get_weather","parameters":{"type":"object","properties":{"city":{"type":"string"}}}}]}],"user":"u-1"}`,
+		},
+	}
+	for _, tc := range cases {
+		got, err := MarshalForEndpoint(req, tc.endpoint)
+		if err != nil {
+			t.Fatalf("%s: MarshalForEndpoint: %v", tc.endpoint, err)
+		}
+		if string(got) != tc.want {
+			t.Fatalf("%s: body mismatch:\nwant: %s\ngot:  %s", tc.endpoint, tc.want, got)
+		}
+		if got, err := tc.marshal(req); err != nil || string(got) != tc.want {
+			t.Fatalf("%s: per-endpoint function differs: %s, %v", tc.endpoint, got, err)
+		}
+	}
+
+	// EndpointAuto routes like a Client without ModelRoutes.
+	auto := req
+	auto.Model = "claude-sonnet-4-5"
+	got, err := MarshalForEndpoint(auto, EndpointAuto)
+	if err != nil {
+		t.Fatalf("MarshalForEndpoint auto: %v", err)
+	}
+	want, _ := MarshalMessagesRequest(auto)
+	if string(got) != string(want) {
+		t.Fatalf("auto should route claude models to messages:\nwant: %s\ngot:  %s", want, got)
+	}
+
+	invalid := req
+	invalid.SystemParts = []SystemPart{{Text: "x"}}
+	if _, err := MarshalForEndpoint(invalid, EndpointChatCompletions); !errors.Is(err, errSystemAndSystemParts) {
+		t.Fatalf("conversion errors must be returned, got %v", err)
+	}
+}
+
+func TestToolCallArgumentsReplayedVerbatim(t *testing.T) {
+	// Valid JSON the SDK must neither reorder, reformat nor re-escape.
+	args := "{ \"b\" :2,\t\"a\":[1, 2 ],\"s\":\"<x> & y\" ,\"u\":\"\\u00e9\" }"
+	first, second := args[:12], args[12:]
+	quote := func(s string) string {
+		data, _ := json.Marshal(s)
+		return string(data)
+	}
+
+	cases := []struct {
+		endpoint EndpointType
+		model    string
+		sse      string
+	}{
+		{EndpointChatCompletions, "kimi-k2", "" +
+			`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":` + quote(first) + `}}]}}]}` + "\n\n" +
+			`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":` + quote(second) + `}}]}}]}` + "\n\n" +
+			`data: {"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n" +
+			"data: [DONE]\n\n"},
+		{EndpointResponses, "gpt-5", "" +
+			`data: {"type":"response.output_item.added","output_index":0,"item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"lookup"}}` + "\n\n" +
+			`data: {"type":"response.function_call_arguments.delta","output_index":0,"delta":` + quote(first) + `}` + "\n\n" +
+			`data: {"type":"response.function_call_arguments.delta","output_index":0,"delta":` + quote(second) + `}` + "\n\n" +
+			`data: {"type":"response.function_call_arguments.done","output_index":0,"call_id":"call_1","name":"lookup","arguments":` + quote(args) + `}` + "\n\n" +
+			`data: {"type":"response.completed"}` + "\n\n"},
+		{EndpointMessages, "claude-sonnet-4", "" +
+			"event: content_block_start\n" + `data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"lookup","input":{}}}` + "\n\n" +
+			"event: content_block_delta\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":` + quote(first) + `}}` + "\n\n" +
+			"event: content_block_delta\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":` + quote(second) + `}}` + "\n\n" +
+			"event: content_block_stop\n" + `data: {"type":"content_block_stop","index":0}` + "\n\n" +
+			"event: message_stop\n" + `data: {"type":"message_stop"}` + "\n\n"},
+		{EndpointModels, "gemini-2.5-pro", "" +
+			`data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"lookup","args":` + args + `}}]},"finishReason":"STOP"}]}` + "\n\n"},
+	}
+	for _, tc := range cases {
+		server, client := newSSETestServer(t, tc.sse)
+		req := NormalizedRequest{
+			Model:    tc.model,
+			Endpoint: tc.endpoint,
+			Messages: []NormalizedMessage{{Role: "user", Content: "look it up"}},
+			Tools:    []NormalizedTool{{Name: "lookup", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		}
+		resp, err := client.CollectStream(testCtx(t), req)
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.endpoint, err)
+		}
+		if len(resp.ToolCalls) != 1 || string(resp.ToolCalls[0].Arguments) != args {
+			t.Fatalf("%s: arguments changed while parsing: %+v", tc.endpoint, resp.ToolCalls)
+		}
+
+		call := resp.ToolCalls[0]
+		req.Messages = append(req.Messages,
+			AssistantMessage(resp.Text, resp.ToolCalls),
+			NormalizedMessage{Role: "tool", ToolCallID: call.ID, FunctionName: call.Name, Content: "ok"},
+		)
+		if string(req.Messages[1].ToolCalls[0].Arguments) != args {
+			t.Fatalf("%s: arguments changed in the history: %s", tc.endpoint, req.Messages[1].ToolCalls[0].Arguments)
+		}
+		body, err := MarshalForEndpoint(req, tc.endpoint)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", tc.endpoint, err)
+		}
+		switch tc.endpoint {
+		case EndpointChatCompletions, EndpointResponses:
+			// Sent as a JSON string, which must decode to the exact bytes.
+			var decoded any
+			if err := json.Unmarshal(body, &decoded); err != nil {
+				t.Fatalf("%s: %v", tc.endpoint, err)
+			}
+			if !containsJSONString(decoded, args) {
+				t.Fatalf("%s: arguments not replayed verbatim: %s", tc.endpoint, body)
+			}
+		default:
+			// Sent as a JSON value, which must appear byte for byte.
+			if !strings.Contains(string(body), args) {
+				t.Fatalf("%s: arguments not replayed verbatim: %s", tc.endpoint, body)
+			}
+			if !json.Valid(body) {
+				t.Fatalf("%s: invalid body: %s", tc.endpoint, body)
+			}
+		}
+	}
+}
+
+// containsJSONString reports whether the decoded JSON value v holds the
+// string s anywhere.
+func containsJSONString(v any, s string) bool {
+	switch v := v.(type) {
+	case string:
+		return v == s
+	case []any:
+		for _, item := range v {
+			if containsJSONString(item, s) {
+				return true
+			}
+		}
+	case map[string]any:
+		for _, item := range v {
+			if containsJSONString(item, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestResponsesInputMessageDefaultsType(t *testing.T) {
+	data, err := json.Marshal(ResponsesInputMessage{Role: "user", Content: []ResponsesInputContent{{Type: "input_text", Text: "hi"}}})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if want := `{"type":"message","role":"user","content":[{"type":"input_text","text":"hi"}]}`; string(data) != want {
+		t.Fatalf("want %s, got %s", want, data)
+	}
+}
@@ -0,0 +1,187 @@
+// Package mcp bridges Model Context Protocol servers into the zen normalized
+// tool layer: MCP tool definitions become zen.NormalizedTool values and model
+// tool calls are forwarded to the server, with results returned as tool-result
+// zen.NormalizedMessage values ready to append to the conversation.
+//
+// The package does not depend on any particular MCP client library. Wrap the
+// client you already use in the small Client interface below.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+// Tool is an MCP tool definition as returned by tools/list.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// Content is a single MCP content item of a tools/call result.
+type Content struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Data     string          `json:"data,omitempty"`
+	MimeType string          `json:"mimeType,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+// CallToolResult is the result of an MCP tools/call request.
+type CallToolResult struct {
+	Content           []Content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// Client is the subset of an MCP client the adapter needs.
+type Client interface {
+	ListTools(ctx context.Context) ([]Tool, error)
+	CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error)
+}
+
+// Adapter exposes the tools of one MCP server.
+type Adapter struct {
+	client Client
+	tools  []Tool
+	byName map[string]Tool
+}
+
+// NewAdapter lists the server's tools and returns an adapter for them.
+func NewAdapter(ctx context.Context, client Client) (*Adapter, error) {
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: list tools: %w", err)
+	}
+	return NewAdapterWithTools(client, tools), nil
+}
+
+// NewAdapterWithTools returns an adapter for an already known tool list,
+// e.g. a filtered subset of the server's tools.
+func NewAdapterWithTools(client Client, tools []Tool) *Adapter {
+	a := &Adapter{client: client, tools: tools, byName: make(map[string]Tool, len(tools))}
+	for _, t := range tools {
+		a.byName[t.Name] = t
+	}
+	return a
+}
+
+// Tools returns the server's tools as normalized tool definitions. The input
+// schema is passed through unchanged.
+func (a *Adapter) Tools() []zen.NormalizedTool {
+	out := make([]zen.NormalizedTool, 0, len(a.tools))
+	for _, t := range a.tools {
+		schema := t.InputSchema
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		out = append(out, zen.NormalizedTool{
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  schema,
+		})
+	}
+	return out
+}
+
+// Has reports whether the adapter serves a tool with the given name.
+func (a *Adapter) Has(name string) bool {
+	_, ok := a.byName[name]
+	return ok
+}
+
+// Execute forwards call to the MCP server and returns the tool-result message
+// for it. Failures never return an error: unknown tools, transport errors and
+// results flagged isError all produce a message with IsError set, so the model
+// can see what went wrong and retry.
+func (a *Adapter) Execute(ctx context.Context, call zen.StreamToolCall) zen.NormalizedMessage {
+	msg := zen.NormalizedMessage{
+		Role:         "tool",
+		ToolCallID:   call.ID,
+		FunctionName: call.Name,
+	}
+
+	if !a.Has(call.Name) {
+		msg.Content = "unknown tool: " + call.Name
+		msg.IsError = true
+		return msg
+	}
+
+	args := call.Arguments
+	if len(strings.TrimSpace(string(args))) == 0 {
+		args = json.RawMessage(`{}`)
+	}
+
+	result, err := a.client.CallTool(ctx, call.Name, args)
+	if err != nil {
+		msg.Content = "error: " + err.Error()
+		msg.IsError = true
+		return msg
+	}
+
+	msg.Content = ResultText(result)
+	msg.IsError = result.IsError
+	return msg
+}
+
+// Register adds every tool of the adapter to set, so MCP tools can be served
+// alongside local tools by a single zen.ToolSet. Results flagged isError are
+// returned as errors and therefore become error tool results.
+func (a *Adapter) Register(set *zen.ToolSet) {
+	for _, t := range a.Tools() {
+		name := t.Name
+		set.Add(t, func(ctx context.Context, args json.RawMessage) (string, error) {
+			if len(strings.TrimSpace(string(args))) == 0 {
+				args = json.RawMessage(`{}`)
+			}
+			result, err := a.client.CallTool(ctx, name, args)
+			if err != nil {
+				return "", err
+			}
+			if result.IsError {
+				return "", errors.New(ResultText(result))
+			}
+			return ResultText(result), nil
+		})
+	}
+}
+
+// ExecuteAll runs Execute for each call in order.
+func (a *Adapter) ExecuteAll(ctx context.Context, calls []zen.StreamToolCall) []zen.NormalizedMessage {
+	out := make([]zen.NormalizedMessage, 0, len(calls))
+	for _, call := range calls {
+		out = append(out, a.Execute(ctx, call))
+	}
+	return out
+}
+
+// ResultText flattens an MCP result into tool-result content. Structured
+// content, when present, is returned as its JSON text; otherwise text items
+// are joined by newlines and non-text items are included as their JSON.
+func ResultText(result *CallToolResult) string {
+	if result == nil {
+		return ""
+	}
+	if len(result.StructuredContent) > 0 && string(result.StructuredContent) != "null" {
+		return string(result.StructuredContent)
+	}
+	parts := make([]string, 0, len(result.Content))
+	for _, c := range result.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+			continue
+		}
+		encoded, err := json.Marshal(c)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, string(encoded))
+	}
+	return strings.Join(parts, "\n")
+}
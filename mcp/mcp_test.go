@@ -0,0 +1,148 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+type fakeClient struct {
+	tools   []Tool
+	results map[string]*CallToolResult
+	errs    map[string]error
+	calls   []string
+	args    []string
+}
+
+func (f *fakeClient) ListTools(context.Context) ([]Tool, error) {
+	return f.tools, nil
+}
+
+func (f *fakeClient) CallTool(_ context.Context, name string, args json.RawMessage) (*CallToolResult, error) {
+	f.calls = append(f.calls, name)
+	f.args = append(f.args, string(args))
+	if err := f.errs[name]; err != nil {
+		return nil, err
+	}
+	return f.results[name], nil
+}
+
+func newFakeAdapter(t *testing.T) (*Adapter, *fakeClient) {
+	t.Helper()
+	client := &fakeClient{
+		tools: []Tool{
+			{Name: "read_file", Description: "Read a file", InputSchema: json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"}}}`)},
+			{Name: "stats"},
+			{Name: "broken"},
+		},
+		results: map[string]*CallToolResult{
+			"read_file": {Content: []Content{{Type: "text", Text: "line 1"}, {Type: "text", Text: "line 2"}}},
+			"stats":     {Content: []Content{{Type: "text", Text: "ignored"}}, StructuredContent: json.RawMessage(`{"files":3}`)},
+		},
+		errs: map[string]error{"broken": errors.New("connection closed")},
+	}
+	adapter, err := NewAdapter(context.Background(), client)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+	return adapter, client
+}
+
+func TestAdapterTools(t *testing.T) {
+	adapter, _ := newFakeAdapter(t)
+	tools := adapter.Tools()
+	if len(tools) != 3 {
+		t.Fatalf("expected 3 tools, got %d", len(tools))
+	}
+	if tools[0].Name != "read_file" || tools[0].Description != "Read a file" {
+		t.Fatalf("tool mismatch: %+v", tools[0])
+	}
+	if string(tools[0].Parameters) != `{"type":"object","properties":{"path":{"type":"string"}}}` {
+		t.Fatalf("schema should pass through, got %s", tools[0].Parameters)
+	}
+	if len(tools[1].Parameters) == 0 {
+		t.Fatalf("missing schema should default to an empty object schema")
+	}
+}
+
+func TestAdapterExecute(t *testing.T) {
+	adapter, client := newFakeAdapter(t)
+	msgs := adapter.ExecuteAll(context.Background(), []zen.StreamToolCall{
+		{ID: "call_1", Name: "read_file", Arguments: json.RawMessage(`{"path":"a.txt"}`)},
+		{ID: "call_2", Name: "stats"},
+		{ID: "call_3", Name: "broken", Arguments: json.RawMessage(`{}`)},
+		{ID: "call_4", Name: "made_up", Arguments: json.RawMessage(`{}`)},
+	})
+	if len(msgs) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(msgs))
+	}
+
+	if m := msgs[0]; m.Role != "tool" || m.ToolCallID != "call_1" || m.FunctionName != "read_file" || m.Content != "line 1\nline 2" || m.IsError {
+		t.Fatalf("text result mismatch: %+v", m)
+	}
+	if m := msgs[1]; m.Content != `{"files":3}` || m.IsError {
+		t.Fatalf("structured result mismatch: %+v", m)
+	}
+	if m := msgs[2]; !m.IsError || m.Content != "error: connection closed" {
+		t.Fatalf("transport error mismatch: %+v", m)
+	}
+	if m := msgs[3]; !m.IsError || m.Content != "unknown tool: made_up" {
+		t.Fatalf("unknown tool mismatch: %+v", m)
+	}
+
+	if len(client.calls) != 3 {
+		t.Fatalf("unknown tools must not reach the server, got calls %v", client.calls)
+	}
+	if client.args[1] != `{}` {
+		t.Fatalf("empty arguments should be sent as {}, got %q", client.args[1])
+	}
+}
+
+func TestAdapterIsErrorMapsToAnthropicToolResult(t *testing.T) {
+	client := &fakeClient{
+		tools:   []Tool{{Name: "fail"}},
+		results: map[string]*CallToolResult{"fail": {Content: []Content{{Type: "text", Text: "denied"}}, IsError: true}},
+	}
+	adapter := NewAdapterWithTools(client, client.tools)
+	result := adapter.Execute(context.Background(), zen.StreamToolCall{ID: "toolu_1", Name: "fail"})
+	if !result.IsError {
+		t.Fatalf("isError should map to IsError")
+	}
+
+	req := zen.NormalizedRequest{
+		Model: "claude-sonnet-4-6",
+		Messages: []zen.NormalizedMessage{
+			{Role: "user", Content: "go"},
+			{Role: "assistant", ToolCalls: []zen.NormalizedToolCall{{ID: "toolu_1", Name: "fail", Arguments: json.RawMessage(`{}`)}}},
+			result,
+		},
+	}
+	msg, err := req.ToMessagesRequest()
+	if err != nil {
+		t.Fatalf("ToMessagesRequest: %v", err)
+	}
+	blocks := msg.Messages[2].Content.([]zen.AnthropicContentBlock)
+	if !blocks[0].IsError {
+		t.Fatalf("tool_result should carry is_error")
+	}
+}
+
+func TestAdapterRegister(t *testing.T) {
+	adapter, _ := newFakeAdapter(t)
+	set := zen.NewToolSet()
+	adapter.Register(set)
+	if len(set.Tools()) != 3 {
+		t.Fatalf("expected 3 registered tools, got %d", len(set.Tools()))
+	}
+	ok := set.Execute(context.Background(), zen.StreamToolCall{ID: "1", Name: "stats"})
+	if ok.IsError || ok.Content != `{"files":3}` {
+		t.Fatalf("unexpected result: %+v", ok)
+	}
+	failed := set.Execute(context.Background(), zen.StreamToolCall{ID: "2", Name: "broken"})
+	if !failed.IsError || failed.Content != "error: connection closed" {
+		t.Fatalf("unexpected error result: %+v", failed)
+	}
+}
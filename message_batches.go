@@ -0,0 +1,163 @@
+package zen
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// MessageBatchRequest is one request of an Anthropic message batch.
+// CustomID identifies its result and must be unique within the batch.
+type MessageBatchRequest struct {
+	CustomID string           `json:"custom_id"`
+	Params   *MessagesRequest `json:"params"`
+}
+
+// MessageBatch is the state of an Anthropic message batch.
+type MessageBatch struct {
+	ID string `json:"id"`
+	// ProcessingStatus is "in_progress", "canceling" or "ended".
+	ProcessingStatus  string                    `json:"processing_status"`
+	RequestCounts     MessageBatchRequestCounts `json:"request_counts"`
+	CreatedAt         string                    `json:"created_at"`
+	ExpiresAt         string                    `json:"expires_at"`
+	EndedAt           string                    `json:"ended_at,omitempty"`
+	CancelInitiatedAt string                    `json:"cancel_initiated_at,omitempty"`
+	ResultsURL        string                    `json:"results_url,omitempty"`
+	Raw               json.RawMessage           `json:"-"`
+}
+
+type MessageBatchRequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// MessageBatchResult is the result of one request of a batch. Type is
+// "succeeded", "errored", "canceled" or "expired". Succeeded results carry
+// the provider's message in Message and its NormalizedResponse in Response;
+// errored results carry an *APIError in Err.
+type MessageBatchResult struct {
+	CustomID string
+	Type     string
+	Message  json.RawMessage
+	Response *NormalizedResponse
+	Err      error
+}
+
+// CreateMessageBatch submits reqs as an Anthropic message batch, which is
+// processed asynchronously at a lower price. Stream is ignored.
+func (c *Client) CreateMessageBatch(ctx context.Context, reqs []MessageBatchRequest) (*MessageBatch, error) {
+	if len(reqs) == 0 {
+		return nil, errors.New("zen: message batch has no requests")
+	}
+	items := make([]MessageBatchRequest, len(reqs))
+	for i, r := range reqs {
+		if r.CustomID == "" || r.Params == nil {
+			return nil, fmt.Errorf("zen: message batch request %d needs a custom id and params", i)
+		}
+		params := *r.Params
+		params.Model = stripOpencodePrefix(params.Model)
+		params.Stream = false
+		items[i] = MessageBatchRequest{CustomID: r.CustomID, Params: &params}
+	}
+	body, err := jsonMarshal(struct {
+		Requests []MessageBatchRequest `json:"requests"`
+	}{items})
+	if err != nil {
+		return nil, err
+	}
+	return c.messageBatch(ctx, "POST", "/messages/batches", body)
+}
+
+// GetMessageBatch returns the current state of batch id.
+func (c *Client) GetMessageBatch(ctx context.Context, id string) (*MessageBatch, error) {
+	return c.messageBatch(ctx, "GET", messageBatchPath(id), nil)
+}
+
+// CancelMessageBatch asks for batch id to be cancelled and returns its state,
+// usually "canceling". Requests already processed keep their results.
+func (c *Client) CancelMessageBatch(ctx context.Context, id string) (*MessageBatch, error) {
+	return c.messageBatch(ctx, "POST", messageBatchPath(id)+"/cancel", []byte{})
+}
+
+// ListMessageBatchResults streams the results of an ended batch, calling fn
+// for each as it is decoded. Results come in no particular order; match
+// them by CustomID. An error returned by fn stops the listing and is
+// returned.
+func (c *Client) ListMessageBatchResults(ctx context.Context, id string, fn func(MessageBatchResult) error) error {
+	_, err := c.doRequestFunc(ctx, "GET", messageBatchPath(id)+"/results", nil, EndpointMessages, false, func(_ *http.Response, r io.Reader) error {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+			result, err := decodeMessageBatchResult(line)
+			if err != nil {
+				return err
+			}
+			if err := fn(result); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	})
+	return err
+}
+
+func (c *Client) messageBatch(ctx context.Context, method, path string, body []byte) (*MessageBatch, error) {
+	data, header, err := c.doRequest(ctx, method, path, body, EndpointMessages, false)
+	if err != nil {
+		return nil, err
+	}
+	var batch MessageBatch
+	if err := decodeJSONResponse(data, header, &batch); err != nil {
+		return nil, fmt.Errorf("zen: decode message batch: %w", err)
+	}
+	if len(data) > 0 {
+		batch.Raw = json.RawMessage(data)
+	}
+	return &batch, nil
+}
+
+func messageBatchPath(id string) string {
+	return "/messages/batches/" + url.PathEscape(id)
+}
+
+func decodeMessageBatchResult(line []byte) (MessageBatchResult, error) {
+	var raw struct {
+		CustomID string `json:"custom_id"`
+		Result   struct {
+			Type    string          `json:"type"`
+			Message json.RawMessage `json:"message"`
+			Error   json.RawMessage `json:"error"`
+		} `json:"result"`
+	}
+	if err := jsonUnmarshal(line, &raw); err != nil {
+		return MessageBatchResult{}, fmt.Errorf("zen: decode message batch result: %w", err)
+	}
+	result := MessageBatchResult{CustomID: raw.CustomID, Type: raw.Result.Type, Message: raw.Result.Message}
+	switch raw.Result.Type {
+	case "succeeded":
+		resp, err := ParseNormalizedResponse(EndpointMessages, raw.Result.Message)
+		if err != nil {
+			return MessageBatchResult{}, err
+		}
+		result.Response = resp
+	case "errored":
+		// The error is an error response body: {"type":"error","error":{...}}.
+		apiErr := newAPIError(0, nil, raw.Result.Error)
+		apiErr.StatusCode = anthropicErrorStatus[apiErr.Type]
+		result.Err = apiErr
+	}
+	return result, nil
+}
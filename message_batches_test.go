@@ -0,0 +1,102 @@
+package zen
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMessageBatches(t *testing.T) {
+	const batch = `{"id":"msgbatch_01","type":"message_batch","processing_status":"in_progress","request_counts":{"processing":2,"succeeded":0,"errored":0,"canceled":0,"expired":0},"created_at":"2026-10-15T10:00:00Z","expires_at":"2026-10-16T10:00:00Z"}`
+	var created struct {
+		Requests []struct {
+			CustomID string         `json:"custom_id"`
+			Params   map[string]any `json:"params"`
+		} `json:"requests"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("anthropic-version") == "" {
+			t.Errorf("%s %s: missing anthropic-version", r.Method, r.URL.Path)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /messages/batches":
+			data, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(data, &created); err != nil {
+				t.Errorf("decode batch: %v", err)
+			}
+			_, _ = w.Write([]byte(batch))
+		case "GET /messages/batches/msgbatch_01":
+			_, _ = w.Write([]byte(batch))
+		case "POST /messages/batches/msgbatch_01/cancel":
+			_, _ = w.Write([]byte(`{"id":"msgbatch_01","processing_status":"canceling"}`))
+		case "GET /messages/batches/msgbatch_01/results":
+			w.Header().Set("Content-Type", "application/binary")
+			_, _ = w.Write([]byte(`{"custom_id":"paris","result":{"type":"succeeded","message":{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"Sunny."}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":3}}}}` + "\n" +
+				`{"custom_id":"rome","result":{"type":"errored","error":{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: Field required"}}}}` + "\n" +
+				`{"custom_id":"oslo","result":{"type":"expired"}}` + "\n"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	params, err := NormalizedRequest{Model: "opencode/claude-sonnet-4-6", Messages: []NormalizedMessage{{Role: "user", Content: "Weather in Paris?"}}, Stream: true}.ToMessagesRequest()
+	if err != nil {
+		t.Fatalf("ToMessagesRequest: %v", err)
+	}
+	b, err := client.CreateMessageBatch(testCtx(t), []MessageBatchRequest{{CustomID: "paris", Params: params}, {CustomID: "rome", Params: params}})
+	if err != nil {
+		t.Fatalf("CreateMessageBatch: %v", err)
+	}
+	if b.ID != "msgbatch_01" || b.RequestCounts.Processing != 2 {
+		t.Fatalf("unexpected batch: %+v", b)
+	}
+	if len(created.Requests) != 2 || created.Requests[0].CustomID != "paris" || created.Requests[0].Params["model"] != "claude-sonnet-4-6" || created.Requests[0].Params["stream"] != nil {
+		t.Fatalf("unexpected batch body: %+v", created)
+	}
+	if _, err := client.CreateMessageBatch(testCtx(t), []MessageBatchRequest{{Params: params}}); err == nil {
+		t.Fatal("expected an error for a request without custom id")
+	}
+
+	if b, err := client.GetMessageBatch(testCtx(t), "msgbatch_01"); err != nil || b.ProcessingStatus != "in_progress" {
+		t.Fatalf("GetMessageBatch: %+v %v", b, err)
+	}
+	if b, err := client.CancelMessageBatch(testCtx(t), "msgbatch_01"); err != nil || b.ProcessingStatus != "canceling" {
+		t.Fatalf("CancelMessageBatch: %+v %v", b, err)
+	}
+
+	var results []MessageBatchResult
+	err = client.ListMessageBatchResults(testCtx(t), "msgbatch_01", func(r MessageBatchResult) error {
+		results = append(results, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ListMessageBatchResults: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if r := results[0]; r.Type != "succeeded" || r.Response == nil || r.Response.Text != "Sunny." || r.Response.OutputTokens != 3 {
+		t.Fatalf("unexpected succeeded result: %+v", r)
+	}
+	var apiErr *APIError
+	if r := results[1]; !errors.As(r.Err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Type != "invalid_request_error" || apiErr.Message != "max_tokens: Field required" {
+		t.Fatalf("unexpected errored result: %+v", r)
+	}
+	if r := results[2]; r.CustomID != "oslo" || r.Type != "expired" || r.Err != nil || r.Response != nil {
+		t.Fatalf("unexpected expired result: %+v", r)
+	}
+
+	stop := errors.New("stop")
+	if err := client.ListMessageBatchResults(testCtx(t), "msgbatch_01", func(MessageBatchResult) error { return stop }); !errors.Is(err, stop) {
+		t.Fatalf("expected the callback error, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// StreamStats reports the payload sizes of a request: the provider payload
+// sent and the response body read, which for a stream is every byte
+// received so far, including pings and event framing.
+type StreamStats struct {
+	RequestBytes  int
+	ResponseBytes int64
+}
+
+// RequestMetrics describes one HTTP request sent by the client, reported to
+// Config.OnRequestMetrics once its response has been read. Retried requests
+// are reported once per attempt.
+type RequestMetrics struct {
+	Method   string
+	Endpoint EndpointType
+	Path     string
+	// Stream reports whether the response was read as an SSE stream.
+	Stream bool
+	// Attempt is the number of the attempt, 0 for the first, and Duration
+	// runs from sending it to reading its response body.
+	Attempt  int
+	Duration time.Duration
+	// StatusCode is zero when no response arrived.
+	StatusCode    int
+	RequestBytes  int
+	ResponseBytes int64
+	// KeyIndex is the index in Config.APIKeys of the key the request was
+	// sent with, 0 for Config.APIKey.
+	KeyIndex int
+	// Organization and Project are the Scope the request was made for.
+	Organization string
+	Project      string
+	// Err is the transport or API error that ended the request, if any.
+	Err error
+}
+
+func (c *Client) reportMetrics(ctx context.Context, m RequestMetrics) {
+	if c.cfg.OnRequestMetrics != nil {
+		c.cfg.OnRequestMetrics(ctx, m)
+	}
+}
+
+// countingReader counts the bytes read through it into n.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (cr countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n.Add(int64(n))
+	return n, err
+}
+
+// Stats returns the bytes sent and received by the stream so far.
+func (h *StreamHandle) Stats() StreamStats {
+	return h.Info.stats()
+}
+
+// Stats returns the bytes sent and received by the stream so far.
+func (s *EventStream) Stats() StreamStats {
+	return s.Info.stats()
+}
+
+func (info StreamInfo) stats() StreamStats {
+	stats := StreamStats{RequestBytes: len(info.Body)}
+	if info.received != nil {
+		stats.ResponseBytes = info.received.Load()
+	}
+	return stats
+}
+
+// ErrRequestTooLarge is wrapped by the *RequestTooLargeError returned for
+// requests over Config.MaxRequestBytes.
+var ErrRequestTooLarge = errors.New("zen: request too large")
+
+// maxReportedMessages is the number of messages RequestTooLargeError lists.
+const maxReportedMessages = 3
+
+// RequestTooLargeError is returned instead of sending a request whose
+// provider payload exceeds Config.MaxRequestBytes. Largest lists the biggest
+// messages of the request, largest first, which usually points at an
+// embedded file or base64 blob.
+type RequestTooLargeError struct {
+	Size    int
+	Limit   int
+	Largest []MessageSize
+}
+
+// MessageSize is the JSON size of the message at Index in
+// NormalizedRequest.Messages.
+type MessageSize struct {
+	Index int
+	Role  string
+	Bytes int
+}
+
+func (e *RequestTooLargeError) Error() string {
+	msg := fmt.Sprintf("zen: request payload is %d bytes, over the limit of %d", e.Size, e.Limit)
+	if len(e.Largest) == 0 {
+		return msg
+	}
+	parts := make([]string, len(e.Largest))
+	for i, m := range e.Largest {
+		parts[i] = fmt.Sprintf("#%d (%s, %d bytes)", m.Index, m.Role, m.Bytes)
+	}
+	return msg + "; largest messages: " + strings.Join(parts, ", ")
+}
+
+func (e *RequestTooLargeError) Unwrap() error {
+	return ErrRequestTooLarge
+}
+
+// checkRequestSize enforces Config.MaxRequestBytes on the encoded payload
+// of req.
+func (c *Client) checkRequestSize(req NormalizedRequest, payload []byte) error {
+	limit := c.cfg.MaxRequestBytes
+	if limit <= 0 || len(payload) <= limit {
+		return nil
+	}
+	sizes := make([]MessageSize, 0, len(req.Messages))
+	for i, msg := range req.Messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		sizes = append(sizes, MessageSize{Index: i, Role: msg.Role, Bytes: len(data)})
+	}
+	sort.SliceStable(sizes, func(i, j int) bool { return sizes[i].Bytes > sizes[j].Bytes })
+	return &RequestTooLargeError{
+		Size:    len(payload),
+		Limit:   limit,
+		Largest: sizes[:min(len(sizes), maxReportedMessages)],
+	}
+}
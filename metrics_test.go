@@ -0,0 +1,234 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestMetricsGeminiCollect(t *testing.T) {
+	sse, err := os.ReadFile("testdata/models/text.sse")
+	if err != nil {
+		t.Fatalf("read SSE: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write(sse)
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var got []RequestMetrics
+	client, err := NewClient(Config{
+		APIKey:  "key",
+		BaseURL: server.URL,
+		OnRequestMetrics: func(_ context.Context, m RequestMetrics) {
+			mu.Lock()
+			got = append(got, m)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	resp, err := client.CollectStream(testCtx(t), NormalizedRequest{
+		Model:    "gemini-3-pro",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	want := StreamStats{RequestBytes: len(resp.Info.Body), ResponseBytes: int64(len(sse))}
+	if resp.Stats != want || want.RequestBytes == 0 {
+		t.Fatalf("Stats: want %+v, got %+v", want, resp.Stats)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 {
+		t.Fatalf("expected one metrics report, got %d", len(got))
+	}
+	m := got[0]
+	if !m.Stream || m.Endpoint != EndpointModels || m.StatusCode != http.StatusOK ||
+		m.RequestBytes != want.RequestBytes || m.ResponseBytes != want.ResponseBytes || m.Err != nil {
+		t.Fatalf("unexpected metrics: %+v", m)
+	}
+}
+
+func TestRequestMetricsNonStreaming(t *testing.T) {
+	const body = `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	var got RequestMetrics
+	client, err := NewClient(Config{
+		APIKey:           "key",
+		BaseURL:          server.URL,
+		OnRequestMetrics: func(_ context.Context, m RequestMetrics) { got = m },
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	resp, err := client.CreateNormalized(testCtx(t), NormalizedRequest{
+		Model:    "claude-sonnet-4-6",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateNormalized: %v", err)
+	}
+	if resp.Stats.ResponseBytes != int64(len(body)) || resp.Stats.RequestBytes != len(resp.Info.Body) {
+		t.Fatalf("unexpected stats: %+v", resp.Stats)
+	}
+	if got.Stream || got.Path != "/messages" || got.ResponseBytes != int64(len(body)) || got.RequestBytes != len(resp.Info.Body) {
+		t.Fatalf("unexpected metrics: %+v", got)
+	}
+}
+
+func TestMaxRequestBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request sent despite MaxRequestBytes")
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, MaxRequestBytes: 4096})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req := NormalizedRequest{
+		Model: "gpt-5.1",
+		Messages: []NormalizedMessage{
+			{Role: "user", Content: "hi"},
+			{Role: "assistant", Content: strings.Repeat("a", 1000)},
+			{Role: "user", Content: strings.Repeat("QUJD", 2000)},
+			{Role: "user", Content: "what is this?"},
+		},
+	}
+	_, err = client.CreateNormalized(testCtx(t), req)
+	var tooLarge *RequestTooLargeError
+	if !errors.As(err, &tooLarge) || !errors.Is(err, ErrRequestTooLarge) {
+		t.Fatalf("expected *RequestTooLargeError, got %v", err)
+	}
+	if tooLarge.Limit != 4096 || tooLarge.Size <= 4096 || len(tooLarge.Largest) != 3 {
+		t.Fatalf("unexpected error: %+v", tooLarge)
+	}
+	if tooLarge.Largest[0].Index != 2 || tooLarge.Largest[1].Index != 1 {
+		t.Fatalf("largest messages out of order: %+v", tooLarge.Largest)
+	}
+	if !strings.Contains(err.Error(), "#2 (user") {
+		t.Fatalf("error does not name the largest message: %v", err)
+	}
+
+	if _, _, err := client.Stream(testCtx(t), req); !errors.Is(err, ErrRequestTooLarge) {
+		t.Fatalf("Stream: expected ErrRequestTooLarge, got %v", err)
+	}
+	req.Messages = req.Messages[:1]
+	if err := client.checkRequestSize(req, []byte("{}")); err != nil {
+		t.Fatalf("small request rejected: %v", err)
+	}
+}
+
+func TestAPIErrorAttempts(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var got []RequestMetrics
+	client, err := NewClient(Config{
+		APIKey:  "key",
+		BaseURL: server.URL,
+		Retry: RetryConfig{
+			MaxRetries:           2,
+			RetryOnNonIdempotent: true,
+			Backoff:              func(int) time.Duration { return 0 },
+		},
+		OnRequestMetrics: func(_ context.Context, m RequestMetrics) { got = append(got, m) },
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	_, _, err = client.doRequest(testCtx(t), "POST", "/chat/completions", []byte(`{}`), EndpointChatCompletions, false)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected a 503 APIError, got %v", err)
+	}
+	if len(apiErr.Attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %+v", apiErr.Attempts)
+	}
+	for i, want := range []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		a := apiErr.Attempts[i]
+		wantRetryAfter := 2 * time.Second
+		if i == 2 {
+			wantRetryAfter = 0
+		}
+		if a.StatusCode != want || a.RetryAfter != wantRetryAfter || a.Err != nil {
+			t.Fatalf("attempt %d: unexpected %+v", i, a)
+		}
+	}
+	if len(got) != 3 || got[0].Attempt != 0 || got[2].Attempt != 2 {
+		t.Fatalf("expected one metrics report per attempt, got %+v", got)
+	}
+
+	client, err = NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	_, _, err = client.doRequest(testCtx(t), "POST", "/chat/completions", []byte(`{}`), EndpointChatCompletions, false)
+	if !errors.As(err, &apiErr) || apiErr.Attempts != nil {
+		t.Fatalf("a request sent once should have no attempts, got %v", err)
+	}
+}
+
+func TestRetryErrorAttempts(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		// Drop the connection without answering.
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{
+		APIKey:  "key",
+		BaseURL: server.URL,
+		Retry: RetryConfig{
+			MaxRetries:           1,
+			RetryOnNonIdempotent: true,
+			Backoff:              func(int) time.Duration { return 0 },
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	_, _, err = client.doRequest(testCtx(t), "POST", "/chat/completions", []byte(`{}`), EndpointChatCompletions, false)
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("expected a RetryError, got %v", err)
+	}
+	if len(retryErr.Attempts) != 2 || retryErr.Attempts[0].StatusCode != http.StatusServiceUnavailable || retryErr.Attempts[1].Err == nil {
+		t.Fatalf("unexpected attempts: %+v", retryErr.Attempts)
+	}
+	if !errors.Is(err, retryErr.Attempts[1].Err) {
+		t.Fatalf("the RetryError should wrap the last transport error, got %v", err)
+	}
+}
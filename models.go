@@ -3,6 +3,10 @@ package zen
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 )
 
 type ModelsResponse struct {
@@ -10,22 +14,178 @@ type ModelsResponse struct {
 	Raw  json.RawMessage `json:"-"`
 }
 
+// Model is an entry of the models list. The capability and pricing fields
+// are nil when the gateway does not report them.
 type Model struct {
 	ID      string `json:"id"`
 	Object  string `json:"object"`
 	OwnedBy string `json:"owned_by"`
+
+	SupportsTools     *bool `json:"supports_tools,omitempty"`
+	SupportsReasoning *bool `json:"supports_reasoning,omitempty"`
+	ContextWindow     *int  `json:"context_window,omitempty"`
+	// InputPricePer1M and OutputPricePer1M are USD per million tokens.
+	InputPricePer1M  *float64 `json:"input_price_per_1m,omitempty"`
+	OutputPricePer1M *float64 `json:"output_price_per_1m,omitempty"`
 }
 
+// ListModels returns the models the gateway serves. An empty response body
+// is returned as an empty list, and a body that is neither JSON nor
+// labelled as JSON as an error wrapping ErrNotJSON.
 func (c *Client) ListModels(ctx context.Context) (*ModelsResponse, error) {
-	data, _, err := c.doRequest(ctx, "GET", "/models", nil, EndpointModels, true)
+	return c.listModels(ctx, "/models")
+}
+
+// ErrModelListing is wrapped by the error returned when a request that lists
+// models (see CreateNormalizedInto) is streamed or sent with a method that
+// expects a reply from a model.
+var ErrModelListing = errors.New("zen: request lists models")
+
+// isModelListing reports whether req asks for the models list: Endpoint set
+// to EndpointModels with neither a Model nor a Path.
+func isModelListing(req NormalizedRequest) bool {
+	return req.Endpoint == EndpointModels && req.Path == "" && strings.TrimSpace(stripOpencodePrefix(req.Model)) == ""
+}
+
+// modelListingError is returned for a model listing sent as a stream, or
+// with a method other than CreateNormalizedInto.
+func modelListingError(stream bool) error {
+	if stream {
+		return fmt.Errorf("%w and cannot be streamed", ErrModelListing)
+	}
+	return fmt.Errorf("%w: use ListModels or CreateNormalizedInto", ErrModelListing)
+}
+
+func (c *Client) listModels(ctx context.Context, path string) (*ModelsResponse, error) {
+	data, header, err := c.doRequest(ctx, "GET", path, nil, EndpointModels, true)
 	if err != nil {
 		return nil, err
 	}
 
 	var resp ModelsResponse
-	if err := json.Unmarshal(data, &resp); err != nil {
+	if err := decodeJSONResponse(data, header, &resp); err != nil {
 		return nil, err
 	}
-	resp.Raw = json.RawMessage(data)
+	if len(data) > 0 {
+		resp.Raw = json.RawMessage(data)
+	}
 	return &resp, nil
 }
+
+// ModelSortKey orders the result of ListModelsFiltered.
+type ModelSortKey string
+
+const (
+	ModelSortID ModelSortKey = ""
+	// ModelSortInputPrice and ModelSortOutputPrice sort cheapest first.
+	ModelSortInputPrice  ModelSortKey = "input_price"
+	ModelSortOutputPrice ModelSortKey = "output_price"
+	// ModelSortContextWindow sorts the largest context window first.
+	ModelSortContextWindow ModelSortKey = "context_window"
+)
+
+// ModelFilter selects models in ListModelsFiltered. Zero fields match every
+// model. A model the gateway reports nothing about for a filtered field is
+// kept, unless MustKnow is set.
+type ModelFilter struct {
+	// Endpoint keeps models the client routes to this endpoint (see
+	// RouteForModel and Config.ModelRoutes).
+	Endpoint           EndpointType
+	SupportsTools      bool
+	SupportsReasoning  bool
+	MaxPriceInputPer1M float64
+	MinContextWindow   int
+	MustKnow           bool
+	// SortBy orders the result; models without the value go last, and
+	// ties are ordered by id.
+	SortBy ModelSortKey
+}
+
+// ListModelsFiltered returns the models of ListModels matching filter,
+// sorted by filter.SortBy.
+func (c *Client) ListModelsFiltered(ctx context.Context, filter ModelFilter) ([]Model, error) {
+	resp, err := c.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []Model
+	for _, m := range resp.Data {
+		if c.matchModel(m, filter) {
+			out = append(out, m)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b Model) int {
+		if n := compareModels(a, b, filter.SortBy); n != 0 {
+			return n
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return out, nil
+}
+
+func (c *Client) matchModel(m Model, f ModelFilter) bool {
+	if f.Endpoint != EndpointAuto && c.routeForModel(m.ID) != f.Endpoint {
+		return false
+	}
+	known := func(v bool, ok bool) bool {
+		if !ok {
+			return !f.MustKnow
+		}
+		return v
+	}
+	if f.SupportsTools && !known(deref(m.SupportsTools), m.SupportsTools != nil) {
+		return false
+	}
+	if f.SupportsReasoning && !known(deref(m.SupportsReasoning), m.SupportsReasoning != nil) {
+		return false
+	}
+	if f.MaxPriceInputPer1M > 0 && !known(deref(m.InputPricePer1M) <= f.MaxPriceInputPer1M, m.InputPricePer1M != nil) {
+		return false
+	}
+	if f.MinContextWindow > 0 && !known(deref(m.ContextWindow) >= f.MinContextWindow, m.ContextWindow != nil) {
+		return false
+	}
+	return true
+}
+
+func compareModels(a, b Model, key ModelSortKey) int {
+	switch key {
+	case ModelSortInputPrice:
+		return compareKnown(a.InputPricePer1M, b.InputPricePer1M, false)
+	case ModelSortOutputPrice:
+		return compareKnown(a.OutputPricePer1M, b.OutputPricePer1M, false)
+	case ModelSortContextWindow:
+		return compareKnown(a.ContextWindow, b.ContextWindow, true)
+	}
+	return 0
+}
+
+// compareKnown orders nil values after all others.
+func compareKnown[T int | float64](a, b *T, desc bool) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	n := 0
+	if *a < *b {
+		n = -1
+	} else if *a > *b {
+		n = 1
+	}
+	if desc {
+		n = -n
+	}
+	return n
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
@@ -0,0 +1,147 @@
+package zen
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestListModelsFiltered(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[
+			{"id":"claude-opus-4-6","supports_tools":true,"supports_reasoning":true,"input_price_per_1m":15,"context_window":200000},
+			{"id":"claude-haiku-4-5","supports_tools":true,"supports_reasoning":false,"input_price_per_1m":1,"context_window":200000},
+			{"id":"claude-sonnet-4-6","supports_tools":true,"supports_reasoning":true,"input_price_per_1m":3,"context_window":1000000},
+			{"id":"claude-3-5-haiku"},
+			{"id":"gpt-5.1","supports_tools":true,"supports_reasoning":true,"input_price_per_1m":1.25}
+		]}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ids := func(filter ModelFilter) string {
+		t.Helper()
+		models, err := client.ListModelsFiltered(testCtx(t), filter)
+		if err != nil {
+			t.Fatalf("ListModelsFiltered: %v", err)
+		}
+		var out []string
+		for _, m := range models {
+			out = append(out, m.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	cases := []struct {
+		name   string
+		filter ModelFilter
+		want   string
+	}{
+		{"all", ModelFilter{}, "claude-3-5-haiku,claude-haiku-4-5,claude-opus-4-6,claude-sonnet-4-6,gpt-5.1"},
+		{"unknown kept", ModelFilter{Endpoint: EndpointMessages, SupportsReasoning: true}, "claude-3-5-haiku,claude-opus-4-6,claude-sonnet-4-6"},
+		{"must know", ModelFilter{Endpoint: EndpointMessages, SupportsReasoning: true, MustKnow: true}, "claude-opus-4-6,claude-sonnet-4-6"},
+		{"price and sort", ModelFilter{SupportsTools: true, MaxPriceInputPer1M: 3, MustKnow: true, SortBy: ModelSortInputPrice}, "claude-haiku-4-5,gpt-5.1,claude-sonnet-4-6"},
+		{"context window sort", ModelFilter{Endpoint: EndpointMessages, SortBy: ModelSortContextWindow}, "claude-sonnet-4-6,claude-haiku-4-5,claude-opus-4-6,claude-3-5-haiku"},
+	}
+	for _, tc := range cases {
+		if got := ids(tc.filter); got != tc.want {
+			t.Fatalf("%s: want %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestModelListingRequest(t *testing.T) {
+	type received struct {
+		method, uri, contentType string
+		body                     int
+		header                   http.Header
+	}
+	var requests []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, received{r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), len(body), r.Header.Clone()})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"gemini-3-pro"},{"id":"claude-sonnet-4-6"}]}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := testCtx(t)
+	listing := NormalizedRequest{Endpoint: EndpointModels}
+
+	var models ModelsResponse
+	if err := client.CreateNormalizedInto(ctx, listing, &models); err != nil {
+		t.Fatalf("CreateNormalizedInto: %v", err)
+	}
+	if len(models.Data) != 2 || models.Data[0].ID != "gemini-3-pro" || len(models.Raw) == 0 {
+		t.Fatalf("unexpected models: %+v", models)
+	}
+	if _, err := client.ListModels(ctx); err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	for _, r := range requests {
+		if r.method != "GET" || r.uri != "/models" || r.contentType != "" || r.body != 0 {
+			t.Fatalf("expected a GET of /models without body, got %s %s (Content-Type %q, %d bytes)", r.method, r.uri, r.contentType, r.body)
+		}
+	}
+	for _, name := range []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key"} {
+		if got, want := requests[0].header.Get(name), requests[1].header.Get(name); got != want {
+			t.Fatalf("%s: listing sent %q, ListModels %q", name, got, want)
+		}
+	}
+
+	var raw map[string]any
+	withQuery := NormalizedRequest{Endpoint: EndpointModels, Model: "opencode/", Query: url.Values{"limit": {"1"}}}
+	if err := client.CreateNormalizedInto(ctx, withQuery, &raw); err != nil {
+		t.Fatalf("CreateNormalizedInto with a map: %v", err)
+	}
+	if raw["object"] != "list" || requests[2].uri != "/models?limit=1" {
+		t.Fatalf("unexpected listing %v of %s", raw, requests[2].uri)
+	}
+
+	rejected := map[string]func() error{
+		"Stream": func() error {
+			_, _, err := client.Stream(ctx, listing)
+			return err
+		},
+		"CollectStream": func() error {
+			_, err := client.CollectStream(ctx, listing)
+			return err
+		},
+		"CreateNormalized": func() error {
+			_, err := client.CreateNormalized(ctx, listing)
+			return err
+		},
+		"CreateNormalizedInto with Stream": func() error {
+			streamed := listing
+			streamed.Stream = true
+			return client.CreateNormalizedInto(ctx, streamed, &models)
+		},
+		"CreateNormalizedInto with ForceStream": func() error {
+			streamed := listing
+			streamed.ForceStream = true
+			return client.CreateNormalizedInto(ctx, streamed, &models)
+		},
+	}
+	for name, call := range rejected {
+		if err := call(); !errors.Is(err, ErrModelListing) {
+			t.Fatalf("%s: expected ErrModelListing, got %v", name, err)
+		}
+	}
+	if len(requests) != 3 {
+		t.Fatalf("expected no request for rejected listings, got %d requests", len(requests))
+	}
+}
@@ -0,0 +1,156 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// NDJSONLine is one line written by StreamNDJSON. Content lines carry the
+// fields of the NormalizedDelta they were built from; the last line has Type
+// "summary" and reports usage and how the stream finished.
+type NDJSONLine struct {
+	Type string `json:"type"`
+	// Time is when the delta was read off the wire (when the summary was
+	// written, for the summary line) and Seq the position of the event it
+	// was parsed from.
+	Time time.Time `json:"time"`
+	Seq  int64     `json:"seq,omitempty"`
+
+	Content string `json:"content,omitempty"`
+	// ChoiceIndex is NormalizedDelta.ChoiceIndex, omitted for the first
+	// candidate.
+	ChoiceIndex int `json:"choice_index,omitempty"`
+
+	ToolCallIndex     *int   `json:"tool_call_index,omitempty"`
+	ToolCallID        string `json:"tool_call_id,omitempty"`
+	ToolCallName      string `json:"tool_call_name,omitempty"`
+	ToolCallSignature string `json:"tool_call_signature,omitempty"`
+	ArgumentsDelta    string `json:"arguments_delta,omitempty"`
+	Arguments         string `json:"arguments,omitempty"`
+
+	AudioID         string `json:"audio_id,omitempty"`
+	AudioData       string `json:"audio_data,omitempty"`
+	AudioTranscript string `json:"audio_transcript,omitempty"`
+
+	ServerToolCall *ServerToolCall `json:"server_tool_call,omitempty"`
+
+	InputTokens  int `json:"input_tokens,omitempty"`
+	OutputTokens int `json:"output_tokens,omitempty"`
+
+	// Summary fields. FinishReason is the provider's finish reason, as on
+	// the DeltaDone delta, and StopReason its normalized form. Streams
+	// ending without one report FinishStop or FinishToolCalls, and failed
+	// streams FinishError.
+	FinishReason string     `json:"finish_reason,omitempty"`
+	StopReason   StopReason `json:"stop_reason,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// Finish reasons reported on the NDJSON summary line when the provider gave
+// none.
+const (
+	FinishStop      = "stop"
+	FinishToolCalls = "tool_calls"
+	FinishError     = "error"
+)
+
+// StreamNDJSON runs req as a stream and writes every delta to w as one JSON
+// line as soon as it arrives, followed by a summary line. Writes happen on the
+// goroutine reading the stream, so a slow writer slows the HTTP read.
+//
+// A stream error is reported on the summary line and also returned. If writing
+// to w fails the stream is cancelled and the write error is returned.
+func StreamNDJSON(ctx context.Context, client *Client, req NormalizedRequest, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	deltas, errs, err := client.Stream(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	clock := client.cfg.Clock
+	summary := NDJSONLine{Type: "summary", FinishReason: FinishStop}
+	var reported bool
+
+	for d := range deltas {
+		switch d.Type {
+		case DeltaToolCallBegin, DeltaToolCallDone:
+			if !reported {
+				summary.FinishReason = FinishToolCalls
+			}
+		case DeltaUsage:
+			// Usage is cumulative; see CollectDeltas.
+			summary.InputTokens = max(summary.InputTokens, d.InputTokens)
+			summary.OutputTokens = max(summary.OutputTokens, d.OutputTokens)
+		case DeltaDone:
+			if d.ChoiceIndex == 0 && d.FinishReason != "" {
+				summary.FinishReason, summary.StopReason = d.FinishReason, d.StopReason
+				reported = true
+			}
+			continue
+		case DeltaUnknown:
+			continue
+		}
+		if err := enc.Encode(ndjsonLineFromDelta(d, clock)); err != nil {
+			cancel()
+			for range deltas {
+			}
+			<-errs
+			return err
+		}
+	}
+
+	streamErr := <-errs
+	if streamErr != nil {
+		summary.FinishReason, summary.StopReason = FinishError, ""
+		summary.Error = streamErr.Error()
+	}
+	summary.Time = clock.Now().UTC()
+	if err := enc.Encode(summary); err != nil {
+		return err
+	}
+	return streamErr
+}
+
+func ndjsonLineFromDelta(d NormalizedDelta, clock Clock) NDJSONLine {
+	received := d.ReceivedAt
+	if received.IsZero() {
+		received = clock.Now()
+	}
+	line := NDJSONLine{Type: string(d.Type), Time: received.UTC(), Seq: d.Seq, ChoiceIndex: d.ChoiceIndex}
+	switch d.Type {
+	case DeltaText, DeltaReasoning:
+		line.Content = d.Content
+	case DeltaError:
+		index := d.ToolCallIndex
+		line.ToolCallIndex = &index
+		line.ToolCallID = d.ToolCallID
+		line.ToolCallName = d.ToolCallName
+		if d.Err != nil {
+			line.Error = d.Err.Error()
+		}
+	case DeltaToolCallBegin, DeltaToolCallArgumentsDelta, DeltaToolCallDone:
+		index := d.ToolCallIndex
+		line.ToolCallIndex = &index
+		line.ToolCallID = d.ToolCallID
+		line.ToolCallName = d.ToolCallName
+		line.ToolCallSignature = d.ToolCallSignature
+		line.ArgumentsDelta = d.ArgumentsDelta
+		line.Arguments = d.ArgumentsFull
+	case DeltaAudio:
+		line.AudioID = d.AudioID
+		line.AudioData = d.AudioData
+		line.AudioTranscript = d.AudioTranscript
+	case DeltaServerToolCall:
+		line.ServerToolCall = d.ServerToolCall
+	case DeltaUsage:
+		line.InputTokens = d.InputTokens
+		line.OutputTokens = d.OutputTokens
+	}
+	return line
+}
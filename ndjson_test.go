@@ -0,0 +1,101 @@
+package zen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStreamNDJSON(t *testing.T) {
+	sse := "data: {\"choices\":[{\"delta\":{\"content\":\"Checking\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"function\":{\"name\":\"get_weather\",\"arguments\":\"\"}}]}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"{\\\"city\\\":\\\"Paris\\\"}\"}}]}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"tool_calls\"}],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":7}}\n\n" +
+		"data: [DONE]\n\n"
+
+	server, client := newSSETestServer(t, sse)
+	defer server.Close()
+
+	var buf bytes.Buffer
+	err := StreamNDJSON(testCtx(t), client, NormalizedRequest{
+		Model:    "kimi-k2",
+		Messages: []NormalizedMessage{{Role: "user", Content: "weather?"}},
+	}, &buf)
+	if err != nil {
+		t.Fatalf("StreamNDJSON: %v", err)
+	}
+
+	var lines []NDJSONLine
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line NDJSONLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		if line.Type != "summary" && line.Seq == 0 {
+			t.Fatalf("line missing seq: %q", scanner.Text())
+		}
+		if line.Time.IsZero() {
+			t.Fatalf("line missing timestamp: %q", scanner.Text())
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 || lines[0].Type != "text" || lines[0].Content != "Checking" {
+		t.Fatalf("first line should be text, got %+v", lines)
+	}
+	begin, args := lines[1], lines[2]
+	if begin.Type != string(DeltaToolCallBegin) || begin.ToolCallID != "call_1" || begin.ToolCallName != "get_weather" || begin.ToolCallIndex == nil || *begin.ToolCallIndex != 0 {
+		t.Fatalf("tool_call_begin line mismatch: %+v", begin)
+	}
+	if args.Type != string(DeltaToolCallArgumentsDelta) || args.ArgumentsDelta != `{"city":"Paris"}` {
+		t.Fatalf("arguments line mismatch: %+v", args)
+	}
+
+	summary := lines[len(lines)-1]
+	if summary.Type != "summary" || summary.FinishReason != FinishToolCalls {
+		t.Fatalf("summary mismatch: %+v", summary)
+	}
+	if summary.InputTokens != 12 || summary.OutputTokens != 7 {
+		t.Fatalf("summary usage mismatch: %+v", summary)
+	}
+}
+
+// fixedClock is a Clock whose Now never moves.
+type fixedClock struct {
+	realClock
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time { return c.now }
+
+func TestStreamNDJSONMaxTokens(t *testing.T) {
+	server, client := newSSETestServer(t, "event: content_block_delta\n"+`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Once upon"}}`+"\n\n"+
+		"event: message_delta\n"+`data: {"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":2}}`+"\n\n"+
+		"event: message_stop\n"+`data: {"type":"message_stop"}`+"\n\n")
+	defer server.Close()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	client = client.With(func(cfg *Config) { cfg.Clock = fixedClock{now: now} })
+
+	var buf bytes.Buffer
+	if err := StreamNDJSON(testCtx(t), client, NormalizedRequest{
+		Model:    "claude-sonnet-4-6",
+		Messages: []NormalizedMessage{{Role: "user", Content: "tell a story"}},
+	}, &buf); err != nil {
+		t.Fatalf("StreamNDJSON: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	var first, summary NDJSONLine
+	if err := json.Unmarshal(lines[0], &first); err != nil || !first.Time.Equal(now) {
+		t.Fatalf("expected the client clock on delta lines, got %s (%v)", lines[0], err)
+	}
+	if err := json.Unmarshal(lines[len(lines)-1], &summary); err != nil {
+		t.Fatalf("invalid summary %s: %v", lines[len(lines)-1], err)
+	}
+	if summary.Type != "summary" || summary.FinishReason != "max_tokens" || summary.StopReason != StopReasonMaxTokens || !summary.Time.Equal(now) {
+		t.Fatalf("summary mismatch: %+v", summary)
+	}
+}
@@ -43,11 +43,14 @@ func TestMapGeminiToolChoice(t *testing.T) {
 }
 
 func TestNormalizeAnthropicMessages(t *testing.T) {
-	system, msgs := normalizeAnthropicMessages("base", []NormalizedMessage{
+	system, msgs, err := normalizeAnthropicMessages("base", []NormalizedMessage{
 		{Role: "system", Content: "sys"},
 		{Role: "developer", Content: "dev"},
 		{Role: "user", Content: "hi"},
 	})
+	if err != nil {
+		t.Fatalf("normalizeAnthropicMessages: %v", err)
+	}
 	if system == "" {
 		t.Fatalf("expected system to be combined")
 	}
@@ -73,3 +76,31 @@ func TestMapEffortToThinkingLevel(t *testing.T) {
 		t.Fatalf("expected empty level")
 	}
 }
+
+func TestGeminiToolCallIDRoundTrip(t *testing.T) {
+	if got := EncodeGeminiToolCallID("gemini-0", "sig"); got != "gemini-0|ts=c2ln" {
+		t.Fatalf("encoded format changed: %s", got)
+	}
+	if got := EncodeGeminiToolCallID("gemini-0", ""); got != "gemini-0" {
+		t.Fatalf("id without signature should be unchanged: %s", got)
+	}
+	cases := []struct{ id, sig string }{
+		{"gemini-0", ""},
+		{"gemini-1", "sig\x00with/bytes+="},
+		{"call|ts=abc", ""},
+		{"call|ts=abc", "sig"},
+		{"100%|done%7C", "sig"},
+		{"%7C", ""},
+		{"", "sig"},
+	}
+	for _, tc := range cases {
+		encoded := EncodeGeminiToolCallID(tc.id, tc.sig)
+		id, sig := DecodeGeminiToolCallID(encoded)
+		if id != tc.id || sig != tc.sig {
+			t.Fatalf("round trip of (%q, %q) via %q gave (%q, %q)", tc.id, tc.sig, encoded, id, sig)
+		}
+	}
+	if id, sig := DecodeGeminiToolCallID("gemini-0|ts=!!"); id != "gemini-0" || sig != "" {
+		t.Fatalf("invalid signature should decode to empty, got (%q, %q)", id, sig)
+	}
+}
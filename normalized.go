@@ -1,9 +1,13 @@
 package zen
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -11,12 +15,29 @@ import (
 type ToolChoiceType string
 
 const (
-	ToolChoiceAuto     ToolChoiceType = "auto"
+	ToolChoiceAuto ToolChoiceType = "auto"
+	// ToolChoiceNone forbids tool calls. Every conversion omits both the
+	// tools and the tool choice from the request, because some backends
+	// ignore a "none" choice and call declared tools anyway.
 	ToolChoiceNone     ToolChoiceType = "none"
 	ToolChoiceRequired ToolChoiceType = "required"
 	ToolChoiceTool     ToolChoiceType = "tool"
 )
 
+// SystemPosition places NormalizedRequest.System among the chat completions
+// messages. The zero value leaves it to ModelDefaults, and otherwise sends
+// System first.
+type SystemPosition string
+
+const (
+	// SystemFirst sends System as the first message, also when a model
+	// default says otherwise.
+	SystemFirst SystemPosition = "first"
+	// SystemAfterSystemMessages sends System after the system and developer
+	// messages that open Messages, e.g. behind a cached prompt prefix.
+	SystemAfterSystemMessages SystemPosition = "after_system"
+)
+
 type NormalizedToolChoice struct {
 	Type ToolChoiceType
 	Name string
@@ -26,6 +47,15 @@ type NormalizedTool struct {
 	Name        string
 	Description string
 	Parameters  json.RawMessage
+
+	// Type, when set, declares a provider built-in tool instead of a
+	// function, e.g. Anthropic's computer-use tools "computer", "bash" and
+	// "text_editor" with a Version such as "20250124". Parameters is then
+	// ignored and Extra holds the tool's settings (e.g. display_width_px).
+	// Only the messages endpoint accepts built-in tools.
+	Type    string
+	Version string
+	Extra   map[string]any
 }
 
 type NormalizedReasoning struct {
@@ -34,40 +64,53 @@ type NormalizedReasoning struct {
 }
 
 type NormalizedToolCall struct {
-	ID               string
-	Name             string
+	ID   string
+	Name string
+	// Arguments are opaque bytes: every endpoint sends them back exactly as
+	// the model produced them, formatting and key order included.
 	Arguments        json.RawMessage
 	ThoughtSignature string
 }
 
 const geminiSignatureSeparator = "|ts="
 
-func encodeGeminiToolCallID(callID, signature string) string {
-	if signature == "" {
-		return callID
-	}
-	if strings.Contains(callID, geminiSignatureSeparator) {
+var (
+	geminiIDEscaper   = strings.NewReplacer("%", "%25", "|", "%7C")
+	geminiIDUnescaper = strings.NewReplacer("%25", "%", "%7C", "|")
+)
+
+// EncodeGeminiToolCallID folds a Gemini thoughtSignature into a tool call
+// id, for applications that can only persist the id. The format is stable:
+//
+//	<callID>|ts=<signature as unpadded base64url>
+//
+// with "%" and "|" in callID escaped as "%25" and "%7C". Without a
+// signature callID is returned unchanged, unless it contains "|ts=", in
+// which case it is escaped and followed by an empty "|ts=" so that
+// DecodeGeminiToolCallID returns it intact.
+func EncodeGeminiToolCallID(callID, signature string) string {
+	if signature == "" && !strings.Contains(callID, geminiSignatureSeparator) {
 		return callID
 	}
 	encoded := base64.RawURLEncoding.EncodeToString([]byte(signature))
-	return callID + geminiSignatureSeparator + encoded
+	return geminiIDEscaper.Replace(callID) + geminiSignatureSeparator + encoded
 }
 
-func decodeGeminiThoughtSignature(callID string) (string, string) {
-	idx := strings.Index(callID, geminiSignatureSeparator)
+// DecodeGeminiToolCallID splits an id built by EncodeGeminiToolCallID into
+// the call id and signature. Ids without a signature are returned as is
+// with an empty signature, as is the signature when it is not valid
+// base64url.
+func DecodeGeminiToolCallID(id string) (callID, signature string) {
+	idx := strings.LastIndex(id, geminiSignatureSeparator)
 	if idx == -1 {
-		return callID, ""
-	}
-	base := callID[:idx]
-	encoded := callID[idx+len(geminiSignatureSeparator):]
-	if encoded == "" {
-		return base, ""
+		return id, ""
 	}
-	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	callID = geminiIDUnescaper.Replace(id[:idx])
+	decoded, err := base64.RawURLEncoding.DecodeString(id[idx+len(geminiSignatureSeparator):])
 	if err != nil {
-		return base, ""
+		return callID, ""
 	}
-	return base, string(decoded)
+	return callID, string(decoded)
 }
 
 func sortedNormalizedTools(tools []NormalizedTool) []NormalizedTool {
@@ -83,16 +126,108 @@ func sortedNormalizedTools(tools []NormalizedTool) []NormalizedTool {
 }
 
 type NormalizedMessage struct {
+	// ID is the provider's id for an earlier assistant output (e.g. a
+	// Responses "msg_..." item id). It is only sent to the Responses API.
+	ID           string
 	Role         string
 	Content      string
 	ToolCalls    []NormalizedToolCall // set on assistant messages that invoked tools
 	ToolCallID   string               // set on tool-result messages (role "tool")
 	FunctionName string               // set on tool-result messages (role "tool"): name of the called function; required by Gemini
+	IsError      bool                 // set on tool-result messages (role "tool") when the tool failed
+	// ResultJSON, set on tool-result messages instead of Content, is a
+	// result that is JSON. The Responses endpoint sends it as the output
+	// as is and Gemini as the functionResponse when it is an object; other
+	// endpoints send its text.
+	ResultJSON json.RawMessage
+
+	// Parts, when set on a user or assistant message, replaces Content with
+	// multimodal content. Only the Gemini endpoint accepts file parts and
+	// only Chat Completions audio parts; otherwise text parts are joined into
+	// a single string and messages containing other parts are rejected. On a
+	// tool message sent to the messages endpoint, text parts and file parts
+	// holding a data: URI or http(s) URL become the tool_result's content
+	// blocks, e.g. for a screenshot.
+	Parts []NormalizedContentPart
+
+	// ProviderState, set on assistant messages, holds output items the
+	// provider needs back verbatim in later requests, such as the encrypted
+	// reasoning items of a Responses reply made with Store false (see
+	// NormalizedResponse.ProviderState). Only the Responses endpoint sends
+	// them, in order and ahead of the message's own items.
+	ProviderState []json.RawMessage
+
+	// ReasoningBlocks, set on assistant messages, is the reasoning of an
+	// earlier reply, such as the thinking blocks NormalizedRequestFromMessages
+	// imports. Only the Messages endpoint sends it, and only the blocks with
+	// a Signature or Data, ahead of the message's own blocks.
+	ReasoningBlocks []ReasoningBlock
+}
+
+// resultContent returns the text of a tool result: Content, or ResultJSON
+// when Content is empty.
+func (m NormalizedMessage) resultContent() string {
+	if m.Content == "" && len(m.ResultJSON) > 0 {
+		return string(m.ResultJSON)
+	}
+	return m.Content
+}
+
+// MaxFunctionCallOutputBytes is the largest tool result the Responses API
+// accepts in a function_call_output item.
+const MaxFunctionCallOutputBytes = 10 << 20
+
+// ErrToolResultTooLarge is returned for tool results over
+// MaxFunctionCallOutputBytes sent to the Responses API.
+var ErrToolResultTooLarge = errors.New("zen: tool result too large")
+
+type ContentPartType string
+
+const (
+	ContentPartText ContentPartType = "text"
+	// ContentPartFile references an uploaded file by URI (Gemini fileData),
+	// e.g. a video or long audio file from the Files API.
+	ContentPartFile ContentPartType = "file"
+	// ContentPartAudio is inline audio input, only accepted by the Chat
+	// Completions endpoint (input_audio).
+	ContentPartAudio ContentPartType = "audio"
+	// ContentPartGemini is a complete Gemini part kept as JSON, such as the
+	// executableCode and codeExecutionResult parts of a turn that used code
+	// execution, which must be sent back unchanged in later turns. Only the
+	// Gemini endpoint accepts it; NormalizedResponse.Parts returns them.
+	ContentPartGemini ContentPartType = "gemini"
+)
+
+type NormalizedContentPart struct {
+	Type        ContentPartType
+	Text        string          // set for ContentPartText
+	FileURI     string          // set for ContentPartFile
+	MediaType   string          // set for ContentPartFile, e.g. "video/mp4"
+	AudioData   string          // set for ContentPartAudio: base64-encoded audio
+	AudioFormat string          // set for ContentPartAudio: "wav" or "mp3"
+	Raw         json.RawMessage // set for ContentPartGemini: the part object
+}
+
+// NormalizedAudioOutput requests spoken output in addition to text. Only
+// the Chat Completions endpoint supports it.
+type NormalizedAudioOutput struct {
+	Voice  string
+	Format string
 }
 
 type NormalizedRequest struct {
-	Model       string
-	System      string
+	Model  string
+	System string
+	// SystemParts is an alternative to System for a system prompt built
+	// from pieces, e.g. a static prefix followed by a dynamic suffix. The
+	// Messages endpoint sends one system block per part, Gemini one
+	// systemInstruction part per part; other endpoints concatenate the
+	// parts as they are. Setting both System and SystemParts is an error.
+	SystemParts []SystemPart
+	// Messages may hold system and developer messages. The Messages and
+	// Gemini endpoints, which have no such roles, fold them into the system
+	// prompt after System in order; Chat Completions and Responses send them
+	// in place.
 	Messages    []NormalizedMessage
 	Tools       []NormalizedTool
 	ToolChoice  *NormalizedToolChoice
@@ -101,15 +236,170 @@ type NormalizedRequest struct {
 	MaxTokens   *int
 	Stream      bool
 	Endpoint    EndpointType
-	Extra       map[string]any
+	// Path, when set, is the path (relative to Config.BaseURL) the request
+	// is sent to instead of the endpoint's own, e.g. a gateway extension
+	// that takes the same payload. Routing by Model is skipped: Endpoint
+	// must be set and selects the payload format, the authentication and
+	// provider headers and how the response is parsed. Gemini streams must
+	// then ask for SSE themselves with alt=sse in Query.
+	Path string
+	// Query holds query parameters added to the request path.
+	Query url.Values
+	// Truncation is forwarded to the Responses API (see
+	// ResponsesRequest.Truncation) and ignored by other endpoints.
+	Truncation string
+	// Store and Include are forwarded to the Responses API and ignored by
+	// other endpoints. With Store false the provider keeps no state between
+	// requests; include "reasoning.encrypted_content" so the reasoning of
+	// each reply comes back in NormalizedResponse.ProviderState.
+	Store   *bool
+	Include []string
+	// ServiceTier is forwarded as service_tier to the Responses and Chat
+	// Completions endpoints and ignored by others.
+	ServiceTier string
+	// StopSequences end generation when produced. The Responses API has no
+	// stop parameter, so setting them for that endpoint is an error.
+	StopSequences []string
+	// CandidateCount asks Gemini for several alternative candidates (see
+	// NormalizedDelta.ChoiceIndex); other endpoints ignore it.
+	CandidateCount int
+	// GeminiMethod replaces the Gemini method the request is sent to,
+	// generateContent or streamGenerateContent by default, for models a
+	// gateway serves under another method. Streams still ask for SSE.
+	// Other endpoints ignore it.
+	GeminiMethod string
+	// AudioOutput asks for spoken output (Chat Completions only), delivered
+	// as DeltaAudio and NormalizedResponse.Audio.
+	AudioOutput *NormalizedAudioOutput
+	// ForceStream sends CreateNormalized and CreateNormalizedInto requests as
+	// streams (see Config.ForceStream).
+	ForceStream bool
+	// SuppressReasoning keeps reasoning out of the results: Stream,
+	// OpenStream, CollectStream, CreateNormalized and StreamNDJSON drop
+	// DeltaReasoning and leave NormalizedResponse.Reasoning empty, and the
+	// Responses API is not asked for reasoning summaries. Raw events and
+	// CreateNormalizedInto still carry what the provider sends.
+	SuppressReasoning bool
+	// EmulateToolChoice enables tool choice emulation for this request
+	// (see Config.EmulateToolChoice).
+	EmulateToolChoice bool
+	// SystemRole and SystemPosition control the chat completions message
+	// built from System: its role ("" = "system", e.g. "developer") and
+	// where it goes. Other endpoints ignore them.
+	SystemRole     string
+	SystemPosition SystemPosition
+	// Background runs a Responses API request asynchronously (see
+	// Client.StartBackgroundResponse). Other endpoints ignore it.
+	Background bool
+	// RawToolSchemas sends tool Parameters to Gemini as they are. By default
+	// they are rewritten to the JSON Schema subset Gemini accepts, inlining
+	// $refs and removing keywords such as additionalProperties; each change
+	// is reported by ConversionWarnings.
+	RawToolSchemas bool
+	// Extra holds body keys without a normalized equivalent; keys the
+	// request body already sets are skipped. For Gemini, the keys of an
+	// Extra["generationConfig"] object are also merged into a generated
+	// generationConfig.
+	Extra map[string]any
+}
+
+// SystemPart is one piece of NormalizedRequest.SystemParts.
+type SystemPart struct {
+	Text string
+	// Cache marks the end of a prompt prefix to cache: the Messages
+	// endpoint sends the part with cache_control. Other endpoints, which
+	// cache prefixes implicitly, ignore it.
+	Cache bool
+}
+
+var errSystemAndSystemParts = errors.New("zen: System and SystemParts cannot both be set")
+
+// systemText returns System, or the concatenated SystemParts.
+func (r NormalizedRequest) systemText() string {
+	if len(r.SystemParts) == 0 {
+		return r.System
+	}
+	var b strings.Builder
+	for _, p := range r.SystemParts {
+		b.WriteString(p.Text)
+	}
+	return b.String()
+}
+
+// joinSystemParts returns r with SystemParts concatenated into System, for
+// endpoints that take the system prompt as a single string.
+func (r NormalizedRequest) joinSystemParts() (NormalizedRequest, error) {
+	if len(r.SystemParts) == 0 {
+		return r, nil
+	}
+	if r.System != "" {
+		return r, errSystemAndSystemParts
+	}
+	r.System = r.systemText()
+	r.SystemParts = nil
+	return r, nil
+}
+
+func (r NormalizedRequest) chatSystemMessage() ChatMessage {
+	role := r.SystemRole
+	if role == "" {
+		role = "system"
+	}
+	return ChatMessage{Role: role, Content: r.System}
+}
+
+func isSystemRole(role string) bool {
+	return role == "system" || role == "developer"
+}
+
+// responsesMessageItem builds a message input item. Assistant messages with
+// an ID replay an output item, which the API expects as completed.
+func responsesMessageItem(m NormalizedMessage, contentType string) ResponsesInputMessage {
+	item := ResponsesInputMessage{
+		Type: "message",
+		Role: m.Role,
+		Content: []ResponsesInputContent{{
+			Type: contentType,
+			Text: m.Content,
+		}},
+	}
+	if m.ID != "" && contentType == "output_text" {
+		item.ID = m.ID
+		item.Status = "completed"
+	}
+	return item
 }
 
 func (r NormalizedRequest) ToResponsesRequest() (*ResponsesRequest, error) {
+	if err := r.rejectAudioOutput("responses"); err != nil {
+		return nil, err
+	}
+	r, err := r.joinSystemParts()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.rejectBuiltInTools("responses"); err != nil {
+		return nil, err
+	}
+	msgs, err := textOnlyMessages(r.Messages, "responses")
+	if err != nil {
+		return nil, err
+	}
+	r.Messages = msgs
+	if len(r.StopSequences) > 0 {
+		return nil, errors.New("zen: stop sequences are not supported by the responses endpoint")
+	}
+
 	req := &ResponsesRequest{
 		Model:           r.Model,
 		Temperature:     r.Temperature,
 		MaxOutputTokens: r.MaxTokens,
 		Stream:          r.Stream,
+		Truncation:      r.Truncation,
+		Store:           r.Store,
+		Include:         r.Include,
+		ServiceTier:     r.ServiceTier,
+		Background:      r.Background,
 		Extra:           r.Extra,
 	}
 
@@ -124,25 +414,41 @@ func (r NormalizedRequest) ToResponsesRequest() (*ResponsesRequest, error) {
 	} else {
 		items := make([]any, 0, len(messages))
 		for _, m := range messages {
+			for _, item := range m.ProviderState {
+				if !json.Valid(item) {
+					return nil, errors.New("zen: provider state item is not valid JSON")
+				}
+				items = append(items, item)
+			}
+			if len(m.ProviderState) > 0 && strings.TrimSpace(m.Content) == "" && len(m.ToolCalls) == 0 {
+				// A reply with nothing but reasoning.
+				continue
+			}
 			// Tool result message → function_call_output item.
 			if strings.ToLower(strings.TrimSpace(m.Role)) == "tool" {
-				items = append(items, ResponsesFunctionCallOutput{
+				item := ResponsesFunctionCallOutput{
 					Type:   "function_call_output",
 					CallID: m.ToolCallID,
 					Output: m.Content,
-				})
+				}
+				size := len(m.Content)
+				if len(m.ResultJSON) > 0 {
+					if !json.Valid(m.ResultJSON) {
+						return nil, fmt.Errorf("zen: tool result for %s: ResultJSON is not valid JSON", m.ToolCallID)
+					}
+					item.Output, item.OutputJSON = "", m.ResultJSON
+					size = len(m.ResultJSON)
+				}
+				if size > MaxFunctionCallOutputBytes {
+					return nil, fmt.Errorf("%w: tool result for %s has %d bytes, the limit is %d", ErrToolResultTooLarge, m.ToolCallID, size, MaxFunctionCallOutputBytes)
+				}
+				items = append(items, item)
 				continue
 			}
 			// Assistant message with tool calls → optional text item + function_call items.
 			if strings.ToLower(strings.TrimSpace(m.Role)) == "assistant" && len(m.ToolCalls) > 0 {
 				if strings.TrimSpace(m.Content) != "" {
-					items = append(items, ResponsesInputMessage{
-						Role: m.Role,
-						Content: []ResponsesInputContent{{
-							Type: "output_text",
-							Text: m.Content,
-						}},
-					})
+					items = append(items, responsesMessageItem(m, "output_text"))
 				}
 				for _, tc := range m.ToolCalls {
 					items = append(items, ResponsesFunctionCall{
@@ -160,20 +466,14 @@ func (r NormalizedRequest) ToResponsesRequest() (*ResponsesRequest, error) {
 			if role == "assistant" {
 				contentType = "output_text"
 			}
-			items = append(items, ResponsesInputMessage{
-				Role: m.Role,
-				Content: []ResponsesInputContent{{
-					Type: contentType,
-					Text: m.Content,
-				}},
-			})
+			items = append(items, responsesMessageItem(m, contentType))
 		}
 		req.Input = items
 	}
 
 	if r.Reasoning != nil {
 		reasoning := &ResponsesReasoning{Effort: r.Reasoning.Effort}
-		if reasoning.Summary == "" {
+		if reasoning.Summary == "" && !r.SuppressReasoning {
 			reasoning.Summary = "auto"
 		}
 		if reasoning.Effort != "" || reasoning.Summary != "" {
@@ -181,6 +481,10 @@ func (r NormalizedRequest) ToResponsesRequest() (*ResponsesRequest, error) {
 		}
 	}
 
+	if r.toolsDisabled() {
+		return req, nil
+	}
+
 	if len(r.Tools) > 0 {
 		tools := sortedNormalizedTools(r.Tools)
 		req.Tools = make([]ResponsesTool, 0, len(tools))
@@ -206,12 +510,42 @@ func (r NormalizedRequest) ToResponsesRequest() (*ResponsesRequest, error) {
 }
 
 func (r NormalizedRequest) ToChatCompletionsRequest() (*ChatCompletionsRequest, error) {
+	if err := r.rejectBuiltInTools("chat completions"); err != nil {
+		return nil, err
+	}
+	r, err := r.joinSystemParts()
+	if err != nil {
+		return nil, err
+	}
+	// Messages with parts are sent with array content; the rest keep their
+	// string content.
+	contentParts := map[int][]ChatContentPart{}
+	for i, m := range r.Messages {
+		if len(m.Parts) == 0 {
+			continue
+		}
+		parts, err := chatContentParts(m.Parts)
+		if err != nil {
+			return nil, err
+		}
+		contentParts[i] = parts
+	}
+
 	messages := make([]ChatMessage, 0, len(r.Messages)+1)
+	insertSystem := -1
 	if strings.TrimSpace(r.System) != "" {
-		messages = append(messages, ChatMessage{Role: "system", Content: r.System})
+		insertSystem = 0
+		if r.SystemPosition == SystemAfterSystemMessages {
+			for insertSystem < len(r.Messages) && isSystemRole(r.Messages[insertSystem].Role) {
+				insertSystem++
+			}
+		}
 	}
-	for _, m := range r.Messages {
-		cm := ChatMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+	for i, m := range r.Messages {
+		if i == insertSystem {
+			messages = append(messages, r.chatSystemMessage())
+		}
+		cm := ChatMessage{Role: m.Role, Content: m.resultContent(), ToolCallID: m.ToolCallID, Parts: contentParts[i]}
 		if len(m.ToolCalls) > 0 {
 			cm.ToolCalls = make([]ChatMessageToolCall, 0, len(m.ToolCalls))
 			for _, tc := range m.ToolCalls {
@@ -227,15 +561,24 @@ func (r NormalizedRequest) ToChatCompletionsRequest() (*ChatCompletionsRequest,
 		}
 		messages = append(messages, cm)
 	}
+	if insertSystem == len(r.Messages) {
+		messages = append(messages, r.chatSystemMessage())
+	}
 
 	req := &ChatCompletionsRequest{
 		Model:       r.Model,
 		Messages:    messages,
 		Temperature: r.Temperature,
 		MaxTokens:   r.MaxTokens,
+		Stop:        r.StopSequences,
 		Stream:      r.Stream,
+		ServiceTier: r.ServiceTier,
 		Extra:       r.Extra,
 	}
+	if r.AudioOutput != nil {
+		req.Modalities = []string{"text", "audio"}
+		req.Audio = &ChatAudioConfig{Voice: r.AudioOutput.Voice, Format: r.AudioOutput.Format}
+	}
 
 	if r.Reasoning != nil && r.Reasoning.Effort != "" {
 		if req.Extra == nil {
@@ -245,13 +588,17 @@ func (r NormalizedRequest) ToChatCompletionsRequest() (*ChatCompletionsRequest,
 		req.Extra["reasoning_effort"] = r.Reasoning.Effort
 	}
 
+	if r.toolsDisabled() {
+		return req, nil
+	}
+
 	if len(r.Tools) > 0 {
 		tools := sortedNormalizedTools(r.Tools)
 		req.Tools = make([]ChatTool, 0, len(tools))
 		for _, t := range tools {
 			req.Tools = append(req.Tools, ChatTool{
 				Type:     "function",
-				Function: ChatToolFunction(t),
+				Function: ChatToolFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters},
 			})
 		}
 	}
@@ -268,7 +615,29 @@ func (r NormalizedRequest) ToChatCompletionsRequest() (*ChatCompletionsRequest,
 }
 
 func (r NormalizedRequest) ToMessagesRequest() (*MessagesRequest, error) {
-	system, messages := normalizeAnthropicMessages(r.System, r.Messages)
+	if err := r.rejectAudioOutput("messages"); err != nil {
+		return nil, err
+	}
+	msgs, err := textOnlyMessages(r.Messages, "messages", "tool")
+	if err != nil {
+		return nil, err
+	}
+	r.Messages = msgs
+
+	if len(r.SystemParts) > 0 && r.System != "" {
+		return nil, errSystemAndSystemParts
+	}
+	system, messages, err := normalizeAnthropicMessages(r.System, r.Messages)
+	if err != nil {
+		return nil, err
+	}
+	var systemBlocks []AnthropicSystemBlock
+	if len(r.SystemParts) > 0 {
+		// System and developer messages follow the parts in a block of
+		// their own.
+		systemBlocks = anthropicSystemBlocks(r.SystemParts, system)
+		system = ""
+	}
 
 	// Anthropic's messages API requires max_tokens; apply a default when the
 	// caller did not specify one so the normalized path works out of the box.
@@ -276,13 +645,7 @@ func (r NormalizedRequest) ToMessagesRequest() (*MessagesRequest, error) {
 
 	// Resolve the thinking budget early so we can ensure max_tokens > budget_tokens,
 	// which is required by Anthropic's API.
-	var thinkingBudget int
-	if r.Reasoning != nil {
-		thinkingBudget = r.Reasoning.BudgetTokens
-		if thinkingBudget == 0 && r.Reasoning.Effort != "" {
-			thinkingBudget = mapEffortToBudget(r.Reasoning.Effort)
-		}
-	}
+	thinkingBudget := r.thinkingBudget()
 
 	if maxTokens == nil {
 		defaultMax := 1024
@@ -296,20 +659,22 @@ func (r NormalizedRequest) ToMessagesRequest() (*MessagesRequest, error) {
 	}
 
 	req := &MessagesRequest{
-		Model:       r.Model,
-		System:      system,
-		Messages:    messages,
-		Temperature: r.Temperature,
-		MaxTokens:   maxTokens,
-		Stream:      r.Stream,
-		Extra:       r.Extra,
+		Model:         r.Model,
+		System:        system,
+		SystemBlocks:  systemBlocks,
+		Messages:      messages,
+		Temperature:   r.Temperature,
+		MaxTokens:     maxTokens,
+		StopSequences: r.StopSequences,
+		Stream:        r.Stream,
+		Extra:         r.Extra,
 	}
 
 	if thinkingBudget > 0 {
 		req.Thinking = &AnthropicThinking{Type: "enabled", BudgetTokens: thinkingBudget}
 	}
 
-	if r.ToolChoice != nil && r.ToolChoice.Type == ToolChoiceNone {
+	if r.toolsDisabled() {
 		return req, nil
 	}
 
@@ -317,6 +682,10 @@ func (r NormalizedRequest) ToMessagesRequest() (*MessagesRequest, error) {
 		tools := sortedNormalizedTools(r.Tools)
 		req.Tools = make([]AnthropicTool, 0, len(tools))
 		for _, t := range tools {
+			if t.Type != "" {
+				req.Tools = append(req.Tools, AnthropicTool{Type: t.Type, Version: t.Version, Name: t.Name, Extra: t.Extra})
+				continue
+			}
 			req.Tools = append(req.Tools, AnthropicTool{
 				Name:        t.Name,
 				Description: t.Description,
@@ -337,7 +706,19 @@ func (r NormalizedRequest) ToMessagesRequest() (*MessagesRequest, error) {
 }
 
 func (r NormalizedRequest) ToGeminiRequest() (*GeminiRequest, error) {
-	systemText, messages := splitSystemMessages(r.System, r.Messages)
+	if err := r.rejectAudioOutput("models"); err != nil {
+		return nil, err
+	}
+	if err := r.rejectBuiltInTools("models"); err != nil {
+		return nil, err
+	}
+	if len(r.SystemParts) > 0 && r.System != "" {
+		return nil, errSystemAndSystemParts
+	}
+	systemText, messages, err := splitSystemMessages(r.System, r.Messages, "models")
+	if err != nil {
+		return nil, err
+	}
 
 	// Build a call-id → function-name index from all assistant tool calls so
 	// that tool-result messages can have their FunctionName derived
@@ -366,12 +747,25 @@ func (r NormalizedRequest) ToGeminiRequest() (*GeminiRequest, error) {
 			if name == "" {
 				return nil, errors.New("zen: tool result message is missing FunctionName (required by Gemini)")
 			}
+			response := GeminiFunctionResponseBody{Output: m.resultContent()}
+			if trimmed := bytes.TrimSpace(m.ResultJSON); len(trimmed) > 0 && trimmed[0] == '{' && !m.IsError {
+				response.Content = m.ResultJSON
+			}
+			if m.IsError {
+				// Gemini has no error flag; its documented convention is an
+				// "error" key in place of the output.
+				errBody, err := jsonMarshal(map[string]string{"error": m.resultContent()})
+				if err != nil {
+					return nil, err
+				}
+				response.Content = errBody
+			}
 			contents = append(contents, GeminiContent{
 				Role: "user",
 				Parts: []GeminiPart{{
 					FunctionResponse: &GeminiFunctionResponse{
 						Name:     name,
-						Response: GeminiFunctionResponseBody{Output: m.Content},
+						Response: response,
 					},
 				}},
 			})
@@ -382,13 +776,17 @@ func (r NormalizedRequest) ToGeminiRequest() (*GeminiRequest, error) {
 			role = "model"
 		}
 
-		// Assistant message with tool calls → model turn with functionCall parts.
+		// Assistant message with tool calls → model turn with functionCall
+		// parts, after the message's own parts if it has any.
 		if role == "model" && len(m.ToolCalls) > 0 {
-			parts := make([]GeminiPart, 0, len(m.ToolCalls))
+			parts, err := geminiContentParts(m.Parts)
+			if err != nil {
+				return nil, err
+			}
 			for _, tc := range m.ToolCalls {
 				signature := tc.ThoughtSignature
 				if signature == "" {
-					_, signature = decodeGeminiThoughtSignature(tc.ID)
+					_, signature = DecodeGeminiToolCallID(tc.ID)
 				}
 				parts = append(parts, GeminiPart{
 					FunctionCall: &GeminiFunctionCall{
@@ -402,17 +800,35 @@ func (r NormalizedRequest) ToGeminiRequest() (*GeminiRequest, error) {
 			continue
 		}
 
+		if len(m.Parts) > 0 {
+			parts, err := geminiContentParts(m.Parts)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, GeminiContent{Role: role, Parts: parts})
+			continue
+		}
+
 		contents = append(contents, GeminiContent{
 			Role:  role,
 			Parts: []GeminiPart{{Text: m.Content}},
 		})
 	}
 
-	var systemInstruction *GeminiContent
+	var systemParts []GeminiPart
+	for _, p := range r.SystemParts {
+		if p.Text != "" {
+			systemParts = append(systemParts, GeminiPart{Text: p.Text})
+		}
+	}
 	if strings.TrimSpace(systemText) != "" {
+		systemParts = append(systemParts, GeminiPart{Text: systemText})
+	}
+	var systemInstruction *GeminiContent
+	if len(systemParts) > 0 {
 		systemInstruction = &GeminiContent{
 			Role:  "system",
-			Parts: []GeminiPart{{Text: systemText}},
+			Parts: systemParts,
 		}
 	}
 
@@ -426,6 +842,11 @@ func (r NormalizedRequest) ToGeminiRequest() (*GeminiRequest, error) {
 	config := &GeminiGenerationConfig{
 		Temperature:     r.Temperature,
 		MaxOutputTokens: r.MaxTokens,
+		StopSequences:   r.StopSequences,
+	}
+	if r.CandidateCount > 0 {
+		count := r.CandidateCount
+		config.CandidateCount = &count
 	}
 	if r.Reasoning != nil {
 		thinking := &GeminiThinkingConfig{}
@@ -433,7 +854,9 @@ func (r NormalizedRequest) ToGeminiRequest() (*GeminiRequest, error) {
 			budget := r.Reasoning.BudgetTokens
 			thinking.ThinkingBudget = &budget
 		}
-		if r.Reasoning.Effort != "" {
+		// Gemini rejects requests that set both a budget and a level; an
+		// explicit budget is the more specific of the two.
+		if r.Reasoning.Effort != "" && thinking.ThinkingBudget == nil {
 			level := mapEffortToThinkingLevel(r.Reasoning.Effort)
 			if level != "" {
 				thinking.ThinkingLevel = level
@@ -445,15 +868,27 @@ func (r NormalizedRequest) ToGeminiRequest() (*GeminiRequest, error) {
 			config.ThinkingConfig = thinking
 		}
 	}
-	if config.Temperature != nil || config.MaxOutputTokens != nil || config.ThinkingConfig != nil {
+	if config.Temperature != nil || config.MaxOutputTokens != nil || config.ThinkingConfig != nil || len(config.StopSequences) > 0 || config.CandidateCount != nil {
 		req.GenerationConfig = config
 	}
 
+	if r.toolsDisabled() {
+		return req, nil
+	}
+
 	if len(r.Tools) > 0 {
 		tools := sortedNormalizedTools(r.Tools)
 		tool := GeminiTool{FunctionDeclarations: make([]GeminiFunctionDeclaration, 0, len(tools))}
 		for _, t := range tools {
-			tool.FunctionDeclarations = append(tool.FunctionDeclarations, GeminiFunctionDeclaration(t))
+			parameters := t.Parameters
+			if !r.RawToolSchemas {
+				parameters, _ = sanitizeGeminiSchema(parameters)
+			}
+			tool.FunctionDeclarations = append(tool.FunctionDeclarations, GeminiFunctionDeclaration{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  parameters,
+			})
 		}
 		req.Tools = []GeminiTool{tool}
 	}
@@ -471,6 +906,12 @@ func (r NormalizedRequest) ToGeminiRequest() (*GeminiRequest, error) {
 	return req, nil
 }
 
+// toolsDisabled reports whether the request forbids tool calls, in which
+// case tools and tool choice are left out of the converted request.
+func (r NormalizedRequest) toolsDisabled() bool {
+	return r.ToolChoice != nil && r.ToolChoice.Type == ToolChoiceNone
+}
+
 func mapOpenAIToolChoice(choice NormalizedToolChoice) (any, error) {
 	switch choice.Type {
 	case ToolChoiceAuto:
@@ -512,7 +953,7 @@ func mapAnthropicToolChoice(choice NormalizedToolChoice) (*AnthropicToolChoice,
 	}
 }
 
-func normalizeAnthropicMessages(system string, msgs []NormalizedMessage) (string, []AnthropicMessage) {
+func normalizeAnthropicMessages(system string, msgs []NormalizedMessage) (string, []AnthropicMessage, error) {
 	combinedSystem := strings.TrimSpace(system)
 	out := make([]AnthropicMessage, 0, len(msgs))
 
@@ -530,10 +971,18 @@ func normalizeAnthropicMessages(system string, msgs []NormalizedMessage) (string
 
 		// Tool result: role "tool" maps to a "user" message with a tool_result block.
 		if role == "tool" {
+			var content any = m.resultContent()
+			if len(m.Parts) > 0 {
+				blocks, err := anthropicToolResultBlocks(m.Parts)
+				if err != nil {
+					return "", nil, err
+				}
+				content = blocks
+			}
 			out = append(out, AnthropicMessage{
 				Role: "user",
 				Content: []AnthropicContentBlock{
-					{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content},
+					{Type: "tool_result", ToolUseID: m.ToolCallID, Content: content, IsError: m.IsError},
 				},
 			})
 			continue
@@ -541,7 +990,7 @@ func normalizeAnthropicMessages(system string, msgs []NormalizedMessage) (string
 
 		// Assistant message with tool calls: emit content blocks of type "tool_use".
 		if role == "assistant" && len(m.ToolCalls) > 0 {
-			blocks := make([]AnthropicContentBlock, 0, len(m.ToolCalls)+1)
+			blocks := anthropicThinkingBlocks(m.ReasoningBlocks)
 			if strings.TrimSpace(m.Content) != "" {
 				blocks = append(blocks, AnthropicContentBlock{Type: "text", Text: m.Content})
 			}
@@ -557,34 +1006,238 @@ func normalizeAnthropicMessages(system string, msgs []NormalizedMessage) (string
 			continue
 		}
 
+		if role == "assistant" {
+			if blocks := anthropicThinkingBlocks(m.ReasoningBlocks); len(blocks) > 0 {
+				if strings.TrimSpace(m.Content) != "" {
+					blocks = append(blocks, AnthropicContentBlock{Type: "text", Text: m.Content})
+				}
+				out = append(out, AnthropicMessage{Role: "assistant", Content: blocks})
+				continue
+			}
+		}
+
 		out = append(out, AnthropicMessage{
 			Role:    role,
 			Content: m.Content,
 		})
 	}
 
-	return combinedSystem, out
+	return combinedSystem, out, nil
+}
+
+// anthropicThinkingBlocks returns the reasoning blocks Anthropic takes back:
+// thinking blocks with their signature and redacted_thinking blocks.
+func anthropicThinkingBlocks(blocks []ReasoningBlock) []AnthropicContentBlock {
+	var out []AnthropicContentBlock
+	for _, b := range blocks {
+		switch {
+		case b.Data != "":
+			out = append(out, AnthropicContentBlock{Type: "redacted_thinking", Data: b.Data})
+		case b.Signature != "":
+			out = append(out, AnthropicContentBlock{Type: "thinking", Thinking: b.Text, Signature: b.Signature})
+		}
+	}
+	return out
+}
+
+// anthropicToolResultBlocks converts the parts of a tool result into the
+// nested content of a tool_result block. File parts must be data: URIs or
+// http(s) URLs and become image blocks, or document blocks for PDFs.
+func anthropicToolResultBlocks(parts []NormalizedContentPart) ([]AnthropicContentBlock, error) {
+	blocks := make([]AnthropicContentBlock, 0, len(parts))
+	for _, p := range parts {
+		switch p.Type {
+		case ContentPartText:
+			blocks = append(blocks, AnthropicContentBlock{Type: "text", Text: p.Text})
+		case ContentPartFile:
+			source, err := anthropicSource(p)
+			if err != nil {
+				return nil, err
+			}
+			typ := "image"
+			if source.MediaType == "application/pdf" {
+				typ = "document"
+			}
+			blocks = append(blocks, AnthropicContentBlock{Type: typ, Source: source})
+		default:
+			return nil, fmt.Errorf("zen: %s content parts are not supported in messages tool results", p.Type)
+		}
+	}
+	return blocks, nil
+}
+
+func anthropicSource(p NormalizedContentPart) (*AnthropicImageSource, error) {
+	uri := p.FileURI
+	if rest, ok := strings.CutPrefix(uri, "data:"); ok {
+		meta, data, ok := strings.Cut(rest, ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil, errors.New("zen: tool result data URIs must be base64-encoded")
+		}
+		mediaType := strings.TrimSuffix(meta, ";base64")
+		if mediaType == "" {
+			mediaType = p.MediaType
+		}
+		return &AnthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}, nil
+	}
+	if strings.HasPrefix(uri, "https://") || strings.HasPrefix(uri, "http://") {
+		return &AnthropicImageSource{Type: "url", MediaType: p.MediaType, URL: uri}, nil
+	}
+	return nil, fmt.Errorf("zen: unsupported tool result file URI %q for the messages endpoint", uri)
+}
+
+// textOnlyMessages returns msgs with Parts folded into Content, for endpoints
+// that only take text. It fails on file parts. Messages with one of
+// keepRoles are left alone.
+func textOnlyMessages(msgs []NormalizedMessage, endpoint string, keepRoles ...string) ([]NormalizedMessage, error) {
+	var out []NormalizedMessage
+	for i, m := range msgs {
+		if len(m.Parts) == 0 || slices.Contains(keepRoles, strings.ToLower(strings.TrimSpace(m.Role))) {
+			continue
+		}
+		if out == nil {
+			out = make([]NormalizedMessage, len(msgs))
+			copy(out, msgs)
+		}
+		var text strings.Builder
+		for _, p := range m.Parts {
+			switch p.Type {
+			case ContentPartText:
+				text.WriteString(p.Text)
+			case ContentPartFile, ContentPartAudio, ContentPartGemini:
+				return nil, fmt.Errorf("zen: %s content parts are not supported by the %s endpoint", p.Type, endpoint)
+			default:
+				return nil, fmt.Errorf("zen: unsupported content part type %q", p.Type)
+			}
+		}
+		out[i].Content = text.String()
+		out[i].Parts = nil
+	}
+	if out == nil {
+		return msgs, nil
+	}
+	return out, nil
+}
+
+// chatContentParts converts parts to Chat Completions content parts. File
+// parts are rejected.
+func chatContentParts(parts []NormalizedContentPart) ([]ChatContentPart, error) {
+	out := make([]ChatContentPart, 0, len(parts))
+	for _, p := range parts {
+		switch p.Type {
+		case ContentPartText:
+			out = append(out, ChatContentPart{Type: "text", Text: p.Text})
+		case ContentPartAudio:
+			if p.AudioData == "" {
+				return nil, errors.New("zen: audio content part is missing AudioData")
+			}
+			out = append(out, ChatContentPart{Type: "input_audio", InputAudio: &ChatInputAudio{Data: p.AudioData, Format: p.AudioFormat}})
+		case ContentPartFile, ContentPartGemini:
+			return nil, fmt.Errorf("zen: %s content parts are not supported by the chat completions endpoint", p.Type)
+		default:
+			return nil, fmt.Errorf("zen: unsupported content part type %q", p.Type)
+		}
+	}
+	return out, nil
+}
+
+func (r NormalizedRequest) rejectBuiltInTools(endpoint string) error {
+	for _, t := range r.Tools {
+		if t.Type != "" {
+			return fmt.Errorf("zen: built-in tool %q is not supported by the %s endpoint", t.Type, endpoint)
+		}
+	}
+	return nil
+}
+
+func (r NormalizedRequest) rejectAudioOutput(endpoint string) error {
+	if r.AudioOutput != nil {
+		return fmt.Errorf("zen: audio output is not supported by the %s endpoint", endpoint)
+	}
+	return nil
 }
 
-func splitSystemMessages(system string, msgs []NormalizedMessage) (string, []NormalizedMessage) {
+func geminiContentParts(parts []NormalizedContentPart) ([]GeminiPart, error) {
+	out := make([]GeminiPart, 0, len(parts))
+	for _, p := range parts {
+		switch p.Type {
+		case ContentPartText:
+			out = append(out, GeminiPart{Text: p.Text})
+		case ContentPartFile:
+			if p.FileURI == "" {
+				return nil, errors.New("zen: file content part is missing FileURI")
+			}
+			out = append(out, GeminiPart{FileData: &GeminiFileData{FileURI: p.FileURI, MimeType: p.MediaType}})
+		case ContentPartGemini:
+			if !json.Valid(p.Raw) {
+				return nil, errors.New("zen: gemini content part does not hold valid JSON")
+			}
+			out = append(out, GeminiPart{Raw: p.Raw})
+		case ContentPartAudio:
+			return nil, errors.New("zen: audio content parts are not supported by the models endpoint; upload the audio and use a file part")
+		default:
+			return nil, fmt.Errorf("zen: unsupported content part type %q", p.Type)
+		}
+	}
+	return out, nil
+}
+
+// anthropicSystemBlocks returns the system blocks for parts, followed by a
+// block holding rest when it is not empty.
+func anthropicSystemBlocks(parts []SystemPart, rest string) []AnthropicSystemBlock {
+	blocks := make([]AnthropicSystemBlock, 0, len(parts)+1)
+	for _, p := range parts {
+		if p.Text == "" {
+			continue
+		}
+		block := AnthropicSystemBlock{Type: "text", Text: p.Text}
+		if p.Cache {
+			block.CacheControl = &AnthropicCacheControl{Type: "ephemeral"}
+		}
+		blocks = append(blocks, block)
+	}
+	if rest != "" {
+		blocks = append(blocks, AnthropicSystemBlock{Type: "text", Text: rest})
+	}
+	return blocks
+}
+
+// splitSystemMessages folds the system and developer messages of msgs, text
+// parts included, into system and returns the remaining messages.
+func splitSystemMessages(system string, msgs []NormalizedMessage, endpoint string) (string, []NormalizedMessage, error) {
 	combinedSystem := strings.TrimSpace(system)
 	out := make([]NormalizedMessage, 0, len(msgs))
 
 	for _, m := range msgs {
 		role := strings.ToLower(strings.TrimSpace(m.Role))
 		if role == "system" || role == "developer" {
-			if strings.TrimSpace(m.Content) != "" {
+			folded, err := textOnlyMessages([]NormalizedMessage{m}, endpoint)
+			if err != nil {
+				return "", nil, err
+			}
+			if content := folded[0].Content; strings.TrimSpace(content) != "" {
 				if combinedSystem != "" {
 					combinedSystem += "\n\n"
 				}
-				combinedSystem += m.Content
+				combinedSystem += content
 			}
 			continue
 		}
 		out = append(out, m)
 	}
 
-	return combinedSystem, out
+	return combinedSystem, out, nil
+}
+
+// thinkingBudget is the budget_tokens Anthropic's thinking is sent with, or 0
+// when r does not ask for reasoning.
+func (r NormalizedRequest) thinkingBudget() int {
+	if r.Reasoning == nil {
+		return 0
+	}
+	if r.Reasoning.BudgetTokens == 0 && r.Reasoning.Effort != "" {
+		return mapEffortToBudget(r.Reasoning.Effort)
+	}
+	return r.Reasoning.BudgetTokens
 }
 
 func mapEffortToBudget(effort string) int {
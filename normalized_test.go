@@ -2,6 +2,9 @@ package zen
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -299,6 +302,7 @@ func TestNormalizedToResponses(t *testing.T) {
 		},
 		ToolChoice: &NormalizedToolChoice{Type: ToolChoiceTool, Name: "tool"},
 		Reasoning:  &NormalizedReasoning{Effort: "low"},
+		Truncation: "auto",
 	}
 
 	resp, err := req.ToResponsesRequest()
@@ -325,6 +329,20 @@ func TestNormalizedToResponses(t *testing.T) {
 	if !ok || function["name"] != "tool" {
 		t.Fatalf("tool choice name mismatch")
 	}
+
+	resp.Include = []string{"reasoning.encrypted_content"}
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	var body map[string]any
+	_ = json.Unmarshal(raw, &body)
+	if body["truncation"] != "auto" {
+		t.Fatalf("truncation not serialized: %s", raw)
+	}
+	if include, ok := body["include"].([]any); !ok || len(include) != 1 || include[0] != "reasoning.encrypted_content" {
+		t.Fatalf("include not serialized: %s", raw)
+	}
 }
 
 func TestNormalizedToChatCompletions(t *testing.T) {
@@ -334,7 +352,8 @@ func TestNormalizedToChatCompletions(t *testing.T) {
 		Messages: []NormalizedMessage{
 			{Role: "user", Content: "hi"},
 		},
-		ToolChoice: &NormalizedToolChoice{Type: ToolChoiceAuto},
+		ToolChoice:  &NormalizedToolChoice{Type: ToolChoiceAuto},
+		ServiceTier: "gateway-batch",
 	}
 
 	chat, err := req.ToChatCompletionsRequest()
@@ -347,6 +366,13 @@ func TestNormalizedToChatCompletions(t *testing.T) {
 	if chat.ToolChoice != "auto" {
 		t.Fatalf("tool choice not mapped")
 	}
+	raw, err := json.Marshal(chat)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if !strings.Contains(string(raw), `"service_tier":"gateway-batch"`) {
+		t.Fatalf("unknown service tier should pass through: %s", raw)
+	}
 }
 
 func TestNormalizedToMessages(t *testing.T) {
@@ -403,3 +429,624 @@ func TestNormalizedToGemini(t *testing.T) {
 		t.Fatalf("thinking config missing")
 	}
 }
+
+func TestNormalizedToolChoiceNoneOmitsTools(t *testing.T) {
+	base := NormalizedRequest{
+		Messages:   []NormalizedMessage{{Role: "user", Content: "hi"}},
+		Tools:      []NormalizedTool{weatherTool},
+		ToolChoice: &NormalizedToolChoice{Type: ToolChoiceNone},
+	}
+	convert := map[string]func(NormalizedRequest) (any, error){
+		"responses": func(r NormalizedRequest) (any, error) { return r.ToResponsesRequest() },
+		"chat":      func(r NormalizedRequest) (any, error) { return r.ToChatCompletionsRequest() },
+		"messages":  func(r NormalizedRequest) (any, error) { return r.ToMessagesRequest() },
+		"gemini":    func(r NormalizedRequest) (any, error) { return r.ToGeminiRequest() },
+	}
+	for name, fn := range convert {
+		out, err := fn(base)
+		if err != nil {
+			t.Fatalf("%s: conversion error: %v", name, err)
+		}
+		raw, err := json.Marshal(out)
+		if err != nil {
+			t.Fatalf("%s: marshal error: %v", name, err)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Fatalf("%s: unmarshal error: %v", name, err)
+		}
+		for _, key := range []string{"tools", "tool_choice", "toolConfig"} {
+			if _, ok := body[key]; ok {
+				t.Fatalf("%s: %s should be omitted with ToolChoiceNone: %s", name, key, raw)
+			}
+		}
+	}
+}
+
+func TestMessagesToolResultContent(t *testing.T) {
+	call := []NormalizedToolCall{{ID: "toolu_1", Name: "screenshot", Arguments: json.RawMessage(`{}`)}}
+	toolResult := func(result NormalizedMessage) string {
+		t.Helper()
+		result.Role = "tool"
+		result.ToolCallID = "toolu_1"
+		req, err := NormalizedRequest{
+			Model:    "claude-sonnet-4-6",
+			Messages: []NormalizedMessage{{Role: "user", Content: "look"}, {Role: "assistant", ToolCalls: call}, result},
+		}.ToMessagesRequest()
+		if err != nil {
+			t.Fatalf("ToMessagesRequest error: %v", err)
+		}
+		raw, err := json.Marshal(req.Messages[2])
+		if err != nil {
+			t.Fatalf("marshal error: %v", err)
+		}
+		return string(raw)
+	}
+
+	if got, want := toolResult(NormalizedMessage{Content: "Sunny", IsError: true}), `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"Sunny","is_error":true}]}`; got != want {
+		t.Fatalf("plain tool result changed:\n got %s\nwant %s", got, want)
+	}
+	if got, want := toolResult(NormalizedMessage{}), `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1"}]}`; got != want {
+		t.Fatalf("empty tool result changed:\n got %s\nwant %s", got, want)
+	}
+
+	got := toolResult(NormalizedMessage{Parts: []NormalizedContentPart{
+		{Type: ContentPartText, Text: "Current screen:"},
+		{Type: ContentPartFile, FileURI: "data:image/png;base64,iVBORw0KGgo="},
+		{Type: ContentPartFile, FileURI: "https://example.com/report.pdf", MediaType: "application/pdf"},
+	}})
+	want := `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[` +
+		`{"type":"text","text":"Current screen:"},` +
+		`{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBORw0KGgo="}},` +
+		`{"type":"document","source":{"type":"url","media_type":"application/pdf","url":"https://example.com/report.pdf"}}]}]}`
+	if got != want {
+		t.Fatalf("rich tool result mismatch:\n got %s\nwant %s", got, want)
+	}
+
+	_, err := NormalizedRequest{
+		Model:    "claude-sonnet-4-6",
+		Messages: []NormalizedMessage{{Role: "tool", ToolCallID: "toolu_1", Parts: []NormalizedContentPart{{Type: ContentPartFile, FileURI: "gs://bucket/shot.png"}}}},
+	}.ToMessagesRequest()
+	if err == nil {
+		t.Fatalf("expected an error for a file URI the messages endpoint cannot fetch")
+	}
+}
+
+func TestMessagesBuiltInTools(t *testing.T) {
+	req := NormalizedRequest{
+		Model:    "claude-sonnet-4-6",
+		Messages: []NormalizedMessage{{Role: "user", Content: "open the settings"}},
+		Tools: []NormalizedTool{
+			{Type: "computer", Version: "20250124", Name: "computer", Extra: map[string]any{"display_width_px": 1024, "display_height_px": 768}},
+			{Type: "bash_20250124", Name: "bash"},
+			{Name: "get_weather", Parameters: json.RawMessage(`{"type":"object"}`)},
+		},
+	}
+	msg, err := req.ToMessagesRequest()
+	if err != nil {
+		t.Fatalf("ToMessagesRequest error: %v", err)
+	}
+	raw, err := json.Marshal(msg.Tools)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	want := `[{"name":"bash","type":"bash_20250124"},` +
+		`{"display_height_px":768,"display_width_px":1024,"name":"computer","type":"computer_20250124"},` +
+		`{"name":"get_weather","input_schema":{"type":"object"}}]`
+	if string(raw) != want {
+		t.Fatalf("tools mismatch:\n got %s\nwant %s", raw, want)
+	}
+
+	if _, err := req.ToChatCompletionsRequest(); err == nil || !strings.Contains(err.Error(), "built-in tool") {
+		t.Fatalf("chat completions should reject built-in tools, got %v", err)
+	}
+}
+
+func TestNormalizedToolErrorMapping(t *testing.T) {
+	history := []NormalizedMessage{
+		{Role: "user", Content: "weather?"},
+		{Role: "assistant", ToolCalls: []NormalizedToolCall{{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{}`)}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "city is required", IsError: true},
+	}
+
+	gem, err := NormalizedRequest{Model: "gemini-3-pro", Messages: history}.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest error: %v", err)
+	}
+	resp, err := json.Marshal(gem.Contents[2].Parts[0].FunctionResponse.Response)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(resp) != `{"error":"city is required"}` {
+		t.Fatalf("gemini error response: got %s", resp)
+	}
+
+	msg, err := NormalizedRequest{Model: "claude-sonnet-4-6", Messages: history}.ToMessagesRequest()
+	if err != nil {
+		t.Fatalf("ToMessagesRequest error: %v", err)
+	}
+	blocks := msg.Messages[2].Content.([]AnthropicContentBlock)
+	if !blocks[0].IsError {
+		t.Fatalf("anthropic tool_result should set is_error")
+	}
+}
+
+func TestNormalizedFileParts(t *testing.T) {
+	req := NormalizedRequest{
+		Model: "gemini-3-pro",
+		Messages: []NormalizedMessage{{
+			Role: "user",
+			Parts: []NormalizedContentPart{
+				{Type: ContentPartText, Text: "Summarize this video."},
+				{Type: ContentPartFile, FileURI: "https://generativelanguage.googleapis.com/v1beta/files/abc", MediaType: "video/mp4"},
+			},
+		}},
+	}
+
+	gemini, err := req.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest error: %v", err)
+	}
+	raw, err := json.Marshal(gemini)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	want := `{"contents":[{"role":"user","parts":[{"text":"Summarize this video."},{"fileData":{"fileUri":"https://generativelanguage.googleapis.com/v1beta/files/abc","mimeType":"video/mp4"}}]}]}`
+	if string(raw) != want {
+		t.Fatalf("gemini payload mismatch:\nwant %s\ngot  %s", want, raw)
+	}
+
+	if _, err := req.ToChatCompletionsRequest(); err == nil || !strings.Contains(err.Error(), "file content parts") {
+		t.Fatalf("chat completions should reject file parts, got %v", err)
+	}
+	if _, err := req.ToResponsesRequest(); err == nil {
+		t.Fatalf("responses should reject file parts")
+	}
+	if _, err := req.ToMessagesRequest(); err == nil {
+		t.Fatalf("messages should reject file parts")
+	}
+
+	textOnly := NormalizedRequest{
+		Model:    "claude-sonnet-4-6",
+		Messages: []NormalizedMessage{{Role: "user", Parts: []NormalizedContentPart{{Type: ContentPartText, Text: "a"}, {Type: ContentPartText, Text: "b"}}}},
+	}
+	chat, err := textOnly.ToChatCompletionsRequest()
+	if err != nil {
+		t.Fatalf("text parts should be accepted: %v", err)
+	}
+	raw, err = json.Marshal(chat.Messages[0])
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if want := `{"role":"user","content":[{"type":"text","text":"a"},{"type":"text","text":"b"}]}`; string(raw) != want {
+		t.Fatalf("text parts should be sent as an array:\nwant %s\ngot  %s", want, raw)
+	}
+}
+
+func TestNormalizedToGeminiThinkingConfig(t *testing.T) {
+	cases := []struct {
+		name      string
+		reasoning *NormalizedReasoning
+		want      string
+	}{
+		{"budget", &NormalizedReasoning{BudgetTokens: 2048}, `{"thinkingConfig":{"thinkingBudget":2048,"includeThoughts":true}}`},
+		{"effort", &NormalizedReasoning{Effort: "high"}, `{"thinkingConfig":{"includeThoughts":true,"thinkingLevel":"high"}}`},
+		{"budget wins over effort", &NormalizedReasoning{Effort: "low", BudgetTokens: 8192}, `{"thinkingConfig":{"thinkingBudget":8192,"includeThoughts":true}}`},
+	}
+	for _, tc := range cases {
+		req := NormalizedRequest{
+			Model:     "gemini-2.5-flash",
+			Messages:  []NormalizedMessage{{Role: "user", Content: "hi"}},
+			Reasoning: tc.reasoning,
+		}
+		gem, err := req.ToGeminiRequest()
+		if err != nil {
+			t.Fatalf("%s: ToGeminiRequest error: %v", tc.name, err)
+		}
+		raw, err := json.Marshal(gem.GenerationConfig)
+		if err != nil {
+			t.Fatalf("%s: marshal error: %v", tc.name, err)
+		}
+		if string(raw) != tc.want {
+			t.Fatalf("%s: generationConfig mismatch:\nwant %s\ngot  %s", tc.name, tc.want, raw)
+		}
+	}
+}
+
+func TestNormalizedStopSequences(t *testing.T) {
+	req := NormalizedRequest{
+		Model:         "m",
+		Messages:      []NormalizedMessage{{Role: "user", Content: "hi"}},
+		StopSequences: []string{"[END]"},
+	}
+
+	chat, err := req.ToChatCompletionsRequest()
+	if err != nil || len(chat.Stop) != 1 || chat.Stop[0] != "[END]" {
+		t.Fatalf("chat stop mismatch: %+v, %v", chat, err)
+	}
+	gemini, err := req.ToGeminiRequest()
+	if err != nil || gemini.GenerationConfig == nil || len(gemini.GenerationConfig.StopSequences) != 1 {
+		t.Fatalf("gemini stopSequences mismatch: %+v, %v", gemini, err)
+	}
+	if _, err := req.ToResponsesRequest(); err == nil {
+		t.Fatalf("responses should reject stop sequences")
+	}
+}
+
+func TestNormalizedToResponsesInputItemsGolden(t *testing.T) {
+	req := NormalizedRequest{
+		Model:  "gpt-5.1",
+		System: "Be brief.",
+		Messages: []NormalizedMessage{
+			{Role: "developer", Content: "Prefer metric units."},
+			{Role: "user", Content: "Weather in Paris?"},
+			{ID: "msg_1", Role: "assistant", Content: "Checking.", ToolCalls: []NormalizedToolCall{{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "Sunny, 22°C"},
+			{ID: "msg_2", Role: "assistant", Content: "Sunny and 22°C."},
+			{Role: "user", Content: "And tomorrow?"},
+			{Role: "assistant", Content: "No forecast yet."},
+		},
+	}
+	resp, err := req.ToResponsesRequest()
+	if err != nil {
+		t.Fatalf("ToResponsesRequest: %v", err)
+	}
+	got, err := json.Marshal(resp.Input)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `[
+		{"type":"message","role":"system","content":[{"type":"input_text","text":"Be brief."}]},
+		{"type":"message","role":"developer","content":[{"type":"input_text","text":"Prefer metric units."}]},
+		{"type":"message","role":"user","content":[{"type":"input_text","text":"Weather in Paris?"}]},
+		{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"Checking."}]},
+		{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Paris\"}"},
+		{"type":"function_call_output","call_id":"call_1","output":"Sunny, 22°C"},
+		{"type":"message","id":"msg_2","role":"assistant","status":"completed","content":[{"type":"output_text","text":"Sunny and 22°C."}]},
+		{"type":"message","role":"user","content":[{"type":"input_text","text":"And tomorrow?"}]},
+		{"type":"message","role":"assistant","content":[{"type":"output_text","text":"No forecast yet."}]}
+	]`
+	assertJSONEquivalent(t, []byte(want), got)
+}
+
+func TestNormalizedToChatCompletionsAudio(t *testing.T) {
+	req := NormalizedRequest{
+		Model: "gpt-4o-audio-preview",
+		Messages: []NormalizedMessage{
+			{Role: "user", Content: "earlier question"},
+			{Role: "user", Parts: []NormalizedContentPart{
+				{Type: ContentPartText, Text: "What is said here?"},
+				{Type: ContentPartAudio, AudioData: "UklGRg==", AudioFormat: "wav"},
+			}},
+		},
+		AudioOutput: &NormalizedAudioOutput{Voice: "alloy", Format: "wav"},
+	}
+	chat, err := req.ToChatCompletionsRequest()
+	if err != nil {
+		t.Fatalf("ToChatCompletionsRequest: %v", err)
+	}
+	got, err := json.Marshal(chat)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{
+		"model": "gpt-4o-audio-preview",
+		"messages": [
+			{"role": "user", "content": "earlier question"},
+			{"role": "user", "content": [
+				{"type": "text", "text": "What is said here?"},
+				{"type": "input_audio", "input_audio": {"data": "UklGRg==", "format": "wav"}}
+			]}
+		],
+		"modalities": ["text", "audio"],
+		"audio": {"voice": "alloy", "format": "wav"}
+	}`
+	assertJSONEquivalent(t, []byte(want), got)
+	if req.Messages[1].Parts == nil {
+		t.Fatalf("input request must not be modified")
+	}
+
+	if _, err := req.ToMessagesRequest(); err == nil || !strings.Contains(err.Error(), "audio output is not supported by the messages endpoint") {
+		t.Fatalf("messages should reject audio output, got %v", err)
+	}
+	req.AudioOutput = nil
+	for name, convert := range map[string]func() error{
+		"messages":  func() error { _, err := req.ToMessagesRequest(); return err },
+		"responses": func() error { _, err := req.ToResponsesRequest(); return err },
+		"models":    func() error { _, err := req.ToGeminiRequest(); return err },
+	} {
+		if err := convert(); err == nil || !strings.Contains(err.Error(), "audio content parts are not supported") {
+			t.Fatalf("%s should reject audio parts, got %v", name, err)
+		}
+	}
+}
+
+func TestChatCompletionsSystemPlacement(t *testing.T) {
+	history := []NormalizedMessage{
+		{Role: "system", Content: "cached prefix"},
+		{Role: "developer", Content: "house rules"},
+		{Role: "user", Content: "hi"},
+	}
+	cases := []struct {
+		name     string
+		role     string
+		position SystemPosition
+		messages []NormalizedMessage
+		want     string
+	}{
+		{"default", "", "", history, "system:sys,system:cached prefix,developer:house rules,user:hi"},
+		{"developer role", "developer", SystemFirst, history, "developer:sys,system:cached prefix,developer:house rules,user:hi"},
+		{"after system", "", SystemAfterSystemMessages, history, "system:cached prefix,developer:house rules,system:sys,user:hi"},
+		{"after system without any", "", SystemAfterSystemMessages, history[2:], "system:sys,user:hi"},
+		{"only system messages", "developer", SystemAfterSystemMessages, history[:2], "system:cached prefix,developer:house rules,developer:sys"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := NormalizedRequest{Model: "kimi-k2", System: "sys", SystemRole: tc.role, SystemPosition: tc.position, Messages: tc.messages}
+			chat, err := req.ToChatCompletionsRequest()
+			if err != nil {
+				t.Fatalf("ToChatCompletionsRequest: %v", err)
+			}
+			var got []string
+			for _, m := range chat.Messages {
+				got = append(got, m.Role+":"+m.Content)
+			}
+			if strings.Join(got, ",") != tc.want {
+				t.Fatalf("messages: want %s, got %s", tc.want, strings.Join(got, ","))
+			}
+		})
+	}
+
+	c, err := NewClient(Config{APIKey: "key", ModelDefaults: map[string]ModelDefaults{
+		"kimi-": {SystemRole: "developer", SystemPosition: SystemAfterSystemMessages},
+	}})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req := c.cfg.applyModelDefaults(NormalizedRequest{Model: "kimi-k2"})
+	if req.SystemRole != "developer" || req.SystemPosition != SystemAfterSystemMessages {
+		t.Fatalf("model defaults not applied: %+v", req)
+	}
+	req = c.cfg.applyModelDefaults(NormalizedRequest{Model: "kimi-k2", SystemRole: "system", SystemPosition: SystemFirst})
+	if req.SystemRole != "system" || req.SystemPosition != SystemFirst {
+		t.Fatalf("explicit settings must override model defaults: %+v", req)
+	}
+}
+
+func TestGeminiCodeExecutionParts(t *testing.T) {
+	const code = `{"executableCode":{"language":"PYTHON","code":"print(2**10)"}}`
+	const result = `{"codeExecutionResult":{"outcome":"OUTCOME_OK","output":"1024\n"}}`
+	sse := "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Computing. \"}," + code + "]}}]}\n\n" +
+		"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[" + result + ",{\"text\":\"It is 1024.\"}]},\"finishReason\":\"STOP\"}]}\n\n"
+	server, client := newSSETestServer(t, sse)
+	defer server.Close()
+
+	req := NormalizedRequest{Model: "gemini-3-pro", Messages: []NormalizedMessage{{Role: "user", Content: "2^10?"}}}
+	resp, err := client.CollectStream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	if resp.Text != "Computing. It is 1024." {
+		t.Fatalf("Text: got %q", resp.Text)
+	}
+	wantTypes := []ContentPartType{ContentPartText, ContentPartGemini, ContentPartGemini, ContentPartText}
+	if len(resp.Parts) != len(wantTypes) {
+		t.Fatalf("Parts: got %+v", resp.Parts)
+	}
+	for i, typ := range wantTypes {
+		if resp.Parts[i].Type != typ {
+			t.Fatalf("Parts[%d]: want %s, got %+v", i, typ, resp.Parts[i])
+		}
+	}
+
+	// The parts must go back to Gemini exactly as they came.
+	req.Messages = append(req.Messages,
+		NormalizedMessage{Role: "assistant", Content: resp.Text, Parts: resp.Parts},
+		NormalizedMessage{Role: "user", Content: "and 2^11?"})
+	gem, err := req.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest: %v", err)
+	}
+	got, err := json.Marshal(gem.Contents[1])
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := `{"role":"model","parts":[{"text":"Computing. "},` + code + `,` + result + `,{"text":"It is 1024."}]}`
+	if string(got) != want {
+		t.Fatalf("model turn:\nwant %s\ngot  %s", want, got)
+	}
+	if _, err := req.ToChatCompletionsRequest(); err == nil {
+		t.Fatal("expected chat completions to reject gemini parts")
+	}
+
+	imported, err := NormalizedRequestFromGemini([]byte(`{"contents":[{"role":"user","parts":[{"text":"2^10?"}]},` + want + `]}`))
+	if err != nil {
+		t.Fatalf("NormalizedRequestFromGemini: %v", err)
+	}
+	again, err := imported.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest: %v", err)
+	}
+	if got, _ := json.Marshal(again.Contents[1]); string(got) != want {
+		t.Fatalf("imported model turn:\nwant %s\ngot  %s", want, got)
+	}
+}
+
+func TestToolResultJSON(t *testing.T) {
+	result := json.RawMessage(`{"temp":22,"unit":"C"}`)
+	history := func(tool NormalizedMessage) NormalizedRequest {
+		return NormalizedRequest{
+			Model: "gpt-5.1",
+			Messages: []NormalizedMessage{
+				{Role: "user", Content: "weather?"},
+				{Role: "assistant", ToolCalls: []NormalizedToolCall{{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{}`)}}},
+				tool,
+			},
+		}
+	}
+	outputOf := func(req NormalizedRequest) string {
+		t.Helper()
+		rr, err := req.ToResponsesRequest()
+		if err != nil {
+			t.Fatalf("ToResponsesRequest: %v", err)
+		}
+		item, err := json.Marshal(rr.Input.([]any)[2])
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		return string(item)
+	}
+
+	text := outputOf(history(NormalizedMessage{Role: "tool", ToolCallID: "call_1", Content: `{"temp":22}`}))
+	if want := `{"type":"function_call_output","call_id":"call_1","output":"{\"temp\":22}"}`; text != want {
+		t.Fatalf("string output:\nwant %s\ngot  %s", want, text)
+	}
+	structured := outputOf(history(NormalizedMessage{Role: "tool", ToolCallID: "call_1", ResultJSON: result}))
+	if want := `{"type":"function_call_output","call_id":"call_1","output":{"temp":22,"unit":"C"}}`; structured != want {
+		t.Fatalf("JSON output:\nwant %s\ngot  %s", want, structured)
+	}
+
+	req := history(NormalizedMessage{Role: "tool", ToolCallID: "call_1", FunctionName: "get_weather", ResultJSON: result})
+	chat, err := req.ToChatCompletionsRequest()
+	if err != nil || chat.Messages[2].Content != string(result) {
+		t.Fatalf("chat tool content: %+v %v", chat.Messages[2], err)
+	}
+	gem, err := req.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest: %v", err)
+	}
+	if got, _ := json.Marshal(gem.Contents[2].Parts[0].FunctionResponse.Response); string(got) != string(result) {
+		t.Fatalf("gemini response: got %s", got)
+	}
+
+	large := history(NormalizedMessage{Role: "tool", ToolCallID: "call_1", Content: strings.Repeat("x", MaxFunctionCallOutputBytes+1)})
+	_, err = large.ToResponsesRequest()
+	if !errors.Is(err, ErrToolResultTooLarge) || !strings.Contains(err.Error(), fmt.Sprint(MaxFunctionCallOutputBytes+1)) {
+		t.Fatalf("expected ErrToolResultTooLarge with the size, got %v", err)
+	}
+	if _, err := large.ToMessagesRequest(); err != nil {
+		t.Fatalf("the limit only applies to the responses endpoint: %v", err)
+	}
+	if _, err := history(NormalizedMessage{Role: "tool", ToolCallID: "call_1", ResultJSON: json.RawMessage(`{`)}).ToResponsesRequest(); err == nil {
+		t.Fatal("expected an error for invalid ResultJSON")
+	}
+}
+
+func TestSystemParts(t *testing.T) {
+	req := NormalizedRequest{
+		Model: "claude-sonnet-4-6",
+		SystemParts: []SystemPart{
+			{Text: "You are a careful assistant.", Cache: true},
+			{Text: " Today is 2026-10-15."},
+		},
+		Messages: []NormalizedMessage{{Role: "developer", Content: "Be brief."}, {Role: "user", Content: "hi"}},
+	}
+
+	messages, err := req.ToMessagesRequest()
+	if err != nil {
+		t.Fatalf("ToMessagesRequest: %v", err)
+	}
+	raw, err := json.Marshal(messages)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var wire struct {
+		System json.RawMessage `json:"system"`
+	}
+	if err := json.Unmarshal(raw, &wire); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := `[{"type":"text","text":"You are a careful assistant.","cache_control":{"type":"ephemeral"}},{"type":"text","text":" Today is 2026-10-15."},{"type":"text","text":"Be brief."}]`
+	if string(wire.System) != want {
+		t.Fatalf("messages system mismatch:\nwant %s\ngot  %s", want, wire.System)
+	}
+
+	chat, err := req.ToChatCompletionsRequest()
+	if err != nil {
+		t.Fatalf("ToChatCompletionsRequest: %v", err)
+	}
+	if chat.Messages[0].Content != "You are a careful assistant. Today is 2026-10-15." {
+		t.Fatalf("chat system should be concatenated, got %q", chat.Messages[0].Content)
+	}
+	responses, err := req.ToResponsesRequest()
+	if err != nil {
+		t.Fatalf("ToResponsesRequest: %v", err)
+	}
+	raw, err = json.Marshal(responses)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(raw), `"You are a careful assistant. Today is 2026-10-15."`) {
+		t.Fatalf("responses system should be concatenated: %s", raw)
+	}
+
+	gemini, err := req.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest: %v", err)
+	}
+	parts := gemini.SystemInstruction.Parts
+	if len(parts) != 3 || parts[0].Text != "You are a careful assistant." || parts[1].Text != " Today is 2026-10-15." || parts[2].Text != "Be brief." {
+		t.Fatalf("gemini system parts mismatch: %+v", parts)
+	}
+
+	req.System = "both"
+	if _, err := req.ToMessagesRequest(); err == nil {
+		t.Fatalf("System and SystemParts together should be rejected")
+	}
+	if _, err := req.ToChatCompletionsRequest(); err == nil {
+		t.Fatalf("System and SystemParts together should be rejected")
+	}
+}
+
+func TestNormalizedSystemMessagesInHistory(t *testing.T) {
+	req := NormalizedRequest{
+		Model:  "gemini-3-pro",
+		System: "sys",
+		Messages: []NormalizedMessage{
+			{Role: "system", Content: "rules"},
+			{Role: "user", Content: "hi"},
+			{Role: "developer", Parts: []NormalizedContentPart{{Type: ContentPartText, Text: "be "}, {Type: ContentPartText, Text: "brief"}}},
+			{Role: "assistant", Content: "hello"},
+		},
+	}
+
+	gemini, err := req.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest: %v", err)
+	}
+	if got := gemini.SystemInstruction.Parts[0].Text; got != "sys\n\nrules\n\nbe brief" {
+		t.Fatalf("gemini systemInstruction mismatch: %q", got)
+	}
+	var roles []string
+	for _, c := range gemini.Contents {
+		roles = append(roles, c.Role)
+	}
+	if strings.Join(roles, ",") != "user,model" {
+		t.Fatalf("gemini contents should only hold user and model turns, got %v", roles)
+	}
+
+	messages, err := req.ToMessagesRequest()
+	if err != nil {
+		t.Fatalf("ToMessagesRequest: %v", err)
+	}
+	if messages.System != "sys\n\nrules\n\nbe brief" || len(messages.Messages) != 2 {
+		t.Fatalf("messages system mismatch: %q, %d messages", messages.System, len(messages.Messages))
+	}
+
+	chat, err := req.ToChatCompletionsRequest()
+	if err != nil {
+		t.Fatalf("ToChatCompletionsRequest: %v", err)
+	}
+	roles = roles[:0]
+	for _, m := range chat.Messages {
+		roles = append(roles, m.Role)
+	}
+	if strings.Join(roles, ",") != "system,system,user,developer,assistant" {
+		t.Fatalf("chat should keep system messages in place, got %v", roles)
+	}
+
+	req.Messages[0].Parts = []NormalizedContentPart{{Type: ContentPartFile, FileURI: "gs://bucket/doc.pdf", MediaType: "application/pdf"}}
+	if _, err := req.ToGeminiRequest(); err == nil {
+		t.Fatalf("file parts in a system message should be rejected")
+	}
+}
@@ -2,8 +2,11 @@ package zen
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 )
 
 // NormalizedDeltaType identifies what kind of content a NormalizedDelta carries.
@@ -14,16 +17,48 @@ const (
 	DeltaText NormalizedDeltaType = "text"
 	// DeltaReasoning is a fragment of the model's reasoning / thinking output.
 	DeltaReasoning NormalizedDeltaType = "reasoning"
+	// DeltaReasoningBegin and DeltaReasoningDone bracket the DeltaReasoning
+	// fragments of one reasoning block, identified by BlockIndex. Client.Stream
+	// emits them; ParseNormalizedEvent does not, as providers do not all
+	// mark where a block ends.
+	DeltaReasoningBegin NormalizedDeltaType = "reasoning_begin"
+	DeltaReasoningDone  NormalizedDeltaType = "reasoning_done"
 	// DeltaToolCallBegin signals the start of a tool call (Name is set, ArgumentsDelta is empty).
 	DeltaToolCallBegin NormalizedDeltaType = "tool_call_begin"
 	// DeltaToolCallArgumentsDelta is an incremental JSON fragment of a tool call's arguments.
 	DeltaToolCallArgumentsDelta NormalizedDeltaType = "tool_call_arguments_delta"
 	// DeltaToolCallDone signals that a tool call is complete (ID, Name, Arguments all set).
 	DeltaToolCallDone NormalizedDeltaType = "tool_call_done"
+	// DeltaAudio carries a chunk of audio output (Chat Completions with
+	// NormalizedRequest.AudioOutput): AudioData holds base64 audio and
+	// AudioTranscript the matching transcript text.
+	DeltaAudio NormalizedDeltaType = "audio"
+	// DeltaServerToolCall reports progress of a tool the provider runs
+	// itself, such as the Responses API's web_search and file_search.
+	// ServerToolCall holds its status and, once known, queries and results.
+	DeltaServerToolCall NormalizedDeltaType = "server_tool_call"
 	// DeltaDone signals that the stream has finished (no content fields are set).
+	// Client.Stream sends at most one per choice, after the stream's other
+	// deltas; ParseNormalizedEvent returns one per terminal event.
 	DeltaDone NormalizedDeltaType = "done"
 	// DeltaUsage carries token-count information (InputTokens / OutputTokens).
 	DeltaUsage NormalizedDeltaType = "usage"
+	// DeltaError reports a problem with otherwise well-formed stream
+	// content; Err is set and the stream continues. For tool calls
+	// (ErrToolArgumentsTooLarge) ToolCallIndex identifies the call. An
+	// *APIError Err is an error event sent by the provider, which ends
+	// the stream: Stream and CollectStream then fail with it.
+	DeltaError NormalizedDeltaType = "error"
+	// DeltaMeta carries response metadata from events without content
+	// (Anthropic message_start, Responses response.created); Model is set.
+	DeltaMeta NormalizedDeltaType = "meta"
+	// DeltaGeminiPart carries a Gemini part without a normalized form, such
+	// as executableCode or codeExecutionResult, in Part.
+	DeltaGeminiPart NormalizedDeltaType = "gemini_part"
+	// DeltaProviderState carries an output item the provider needs back in
+	// later requests, in Part: a Responses reasoning item with encrypted
+	// content (see NormalizedMessage.ProviderState).
+	DeltaProviderState NormalizedDeltaType = "provider_state"
 	// DeltaUnknown is emitted for events that carry no recognized content.
 	DeltaUnknown NormalizedDeltaType = "unknown"
 )
@@ -43,9 +78,88 @@ type NormalizedDelta struct {
 	ArgumentsDelta    string // set on DeltaToolCallArgumentsDelta
 	ArgumentsFull     string // set on DeltaToolCallDone (fully accumulated)
 
-	// Usage fields (set for DeltaUsage).
-	InputTokens  int
-	OutputTokens int
+	// Audio fields (set for DeltaAudio). AudioID identifies the audio output,
+	// which later requests can refer to.
+	AudioID         string
+	AudioData       string
+	AudioTranscript string
+
+	// ServerToolCall is set for DeltaServerToolCall.
+	ServerToolCall *ServerToolCall
+
+	// Part is the complete part object for DeltaGeminiPart and the output
+	// item for DeltaProviderState.
+	Part json.RawMessage
+
+	// BlockIndex is the position of the content block or output item a
+	// content delta belongs to: the Anthropic content block index or the
+	// Responses output_index. Endpoints without blocks leave it 0.
+	BlockIndex int
+
+	// ChoiceIndex is the candidate a content, tool call or done delta
+	// belongs to when Gemini returns several (NormalizedRequest.CandidateCount);
+	// it is 0 otherwise. Tool call indexes are per candidate.
+	ChoiceIndex int
+
+	// Usage fields (set for DeltaUsage). ReasoningTokens is set when the
+	// provider reports reasoning usage (Anthropic does not). OpenAI counts
+	// it within OutputTokens; Gemini reports it separately.
+	// CachedInputTokens is the number of input tokens read from the
+	// provider's prompt cache; the other providers count them within
+	// InputTokens. Anthropic reports cache reads and writes apart from
+	// input_tokens, which InputTokens keeps as reported, so there
+	// CachedInputTokens and CacheWriteInputTokens add to it.
+	InputTokens           int
+	OutputTokens          int
+	ReasoningTokens       int
+	CachedInputTokens     int
+	CacheWriteInputTokens int
+
+	// Err is set for DeltaError.
+	Err error
+
+	// Tool and UnknownTool annotate DeltaToolCallBegin and DeltaToolCallDone
+	// when Config.AnnotateToolCalls is set: Tool points to the request's
+	// definition of the called tool (shared by all deltas of the stream; do
+	// not modify it), and UnknownTool reports a call to a tool the request
+	// did not declare.
+	Tool        *NormalizedTool
+	UnknownTool bool
+
+	// FinishReason is the provider's raw stop reason (e.g. "stop",
+	// "end_turn", "stop_sequence") and StopSequence the stop sequence that
+	// ended generation. Both are set on DeltaDone. Anthropic reports them on
+	// message_delta, ahead of message_stop, so ParseNormalizedEvent sets them
+	// on that event's DeltaUsage and Client.Stream carries them onto the
+	// DeltaDone. Chat Completions reports finish_reason "stop" without
+	// saying which stop string matched, so StopSequence stays empty there.
+	FinishReason string
+	StopSequence string
+	// StopReason is FinishReason mapped by NormalizeStopReason. Gemini
+	// reports the finish reason on its last content chunk, which therefore
+	// also carries the DeltaDone.
+	StopReason StopReason
+	// FinishDetails explains a stop for safety or recitation: for Gemini an
+	// object holding the candidate's safetyRatings and citationMetadata.
+	// It is set on DeltaDone.
+	FinishDetails json.RawMessage
+
+	// ServiceTier is the processing tier the provider reports having used.
+	// It is set on the deltas parsed from events that echo it (every Chat
+	// Completions chunk, the Responses completion event).
+	ServiceTier string
+
+	// Model is the model the provider reports serving, which can be a
+	// dated snapshot of the requested alias or a different model altogether
+	// (see Config.OnModelSubstitution). It is set on DeltaMeta and on the
+	// deltas parsed from events that echo it (every Chat Completions and
+	// Gemini chunk, the Responses completion event).
+	Model string
+
+	// ReceivedAt and Seq are copied from the event the delta was parsed
+	// from; deltas parsed from the same event share them.
+	ReceivedAt time.Time
+	Seq        int64
 }
 
 // ParseNormalizedEvent parses a single UnifiedEvent into zero or more NormalizedDelta values.
@@ -58,17 +172,100 @@ func ParseNormalizedEvent(ev UnifiedEvent) []NormalizedDelta {
 		return nil
 	}
 
+	var out []NormalizedDelta
 	switch ev.Endpoint {
 	case EndpointChatCompletions:
-		return parseChatCompletionsDelta(ev)
+		out = parseChatCompletionsDelta(ev)
 	case EndpointResponses:
-		return parseResponsesDelta(ev)
+		out = parseResponsesDelta(ev)
 	case EndpointMessages:
-		return parseMessagesDelta(ev)
+		out = parseMessagesDelta(ev)
 	case EndpointModels:
-		return parseGeminiDelta(ev)
-	default:
-		return nil
+		out = parseGeminiDelta(ev)
+	}
+	for i := range out {
+		out[i].ReceivedAt = ev.ReceivedAt
+		out[i].Seq = ev.Seq
+		out[i].StopReason = NormalizeStopReason(out[i].FinishReason)
+	}
+	return out
+}
+
+// streamFailure returns the provider error carried by d, if any.
+func streamFailure(d NormalizedDelta) error {
+	var apiErr *APIError
+	if d.Type == DeltaError && errors.As(d.Err, &apiErr) {
+		return d.Err
+	}
+	return nil
+}
+
+// doneTracker holds DeltaDone back until the stream ends, so that each
+// choice gets exactly one, after all of its other deltas, even when a
+// provider sends both a finishing chunk and a terminal event or reports a
+// finish reason before its last chunk. Details of a later terminal event
+// fill what the held one left empty.
+type doneTracker struct {
+	held []NormalizedDelta
+}
+
+// hold keeps d and reports whether it was a DeltaDone.
+func (t *doneTracker) hold(d NormalizedDelta) bool {
+	if d.Type != DeltaDone {
+		return false
+	}
+	for i := range t.held {
+		h := &t.held[i]
+		if h.ChoiceIndex != d.ChoiceIndex {
+			continue
+		}
+		if h.FinishReason == "" {
+			h.FinishReason, h.StopSequence, h.StopReason = d.FinishReason, d.StopSequence, d.StopReason
+		}
+		if h.FinishDetails == nil {
+			h.FinishDetails = d.FinishDetails
+		}
+		if h.ServiceTier == "" {
+			h.ServiceTier = d.ServiceTier
+		}
+		if h.Model == "" {
+			h.Model = d.Model
+		}
+		return true
+	}
+	t.held = append(t.held, d)
+	return true
+}
+
+// flush returns the held DeltaDone values, completed by stop.
+func (t *doneTracker) flush(stop *stopTracker) []NormalizedDelta {
+	out := t.held
+	t.held = nil
+	for i := range out {
+		stop.apply(&out[i])
+	}
+	return out
+}
+
+// stopTracker carries stop details reported before the terminal event onto
+// the DeltaDone.
+type stopTracker struct {
+	reason, sequence string
+}
+
+func (s *stopTracker) apply(d *NormalizedDelta) {
+	if d.Type == DeltaDone {
+		if d.FinishReason == "" {
+			d.FinishReason = s.reason
+			d.StopReason = NormalizeStopReason(s.reason)
+		}
+		if d.StopSequence == "" {
+			d.StopSequence = s.sequence
+		}
+		return
+	}
+	if d.FinishReason != "" {
+		s.reason, s.sequence = d.FinishReason, d.StopSequence
 	}
 }
 
@@ -80,10 +277,10 @@ func ParseNormalizedEvent(ev UnifiedEvent) []NormalizedDelta {
 type chatCompletionChunk struct {
 	Choices []struct {
 		Delta struct {
-			Role             string `json:"role"`
-			Content          string `json:"content"`
-			ReasoningContent string `json:"reasoning_content"`
-			Reasoning        string `json:"reasoning"`
+			Role             string      `json:"role"`
+			Content          chatContent `json:"content"`
+			ReasoningContent string      `json:"reasoning_content"`
+			Reasoning        string      `json:"reasoning"`
 			ReasoningDetails []struct {
 				Text string `json:"text"`
 			} `json:"reasoning_details"`
@@ -96,18 +293,56 @@ type chatCompletionChunk struct {
 					Arguments string `json:"arguments"`
 				} `json:"function"`
 			} `json:"tool_calls"`
+			Audio *chatAudio `json:"audio"`
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
-	Usage *struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
+	ServiceTier string `json:"service_tier"`
+	Model       string `json:"model"`
+	Usage       *struct {
+		PromptTokens        int `json:"prompt_tokens"`
+		CompletionTokens    int `json:"completion_tokens"`
+		PromptTokensDetails *struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+		CompletionTokensDetails *struct {
+			ReasoningTokens int `json:"reasoning_tokens"`
+		} `json:"completion_tokens_details"`
 	} `json:"usage"`
 }
 
+// chatAudio is the audio object of a Chat Completions message or delta.
+type chatAudio struct {
+	ID         string `json:"id"`
+	Data       string `json:"data"`
+	Transcript string `json:"transcript"`
+}
+
+func (a *chatAudio) delta() (NormalizedDelta, bool) {
+	if a == nil || (a.ID == "" && a.Data == "" && a.Transcript == "") {
+		return NormalizedDelta{}, false
+	}
+	return NormalizedDelta{Type: DeltaAudio, AudioID: a.ID, AudioData: a.Data, AudioTranscript: a.Transcript}, true
+}
+
+func (c chatCompletionChunk) usageDelta() NormalizedDelta {
+	d := NormalizedDelta{
+		Type:         DeltaUsage,
+		InputTokens:  c.Usage.PromptTokens,
+		OutputTokens: c.Usage.CompletionTokens,
+	}
+	if c.Usage.PromptTokensDetails != nil {
+		d.CachedInputTokens = c.Usage.PromptTokensDetails.CachedTokens
+	}
+	if c.Usage.CompletionTokensDetails != nil {
+		d.ReasoningTokens = c.Usage.CompletionTokensDetails.ReasoningTokens
+	}
+	return d
+}
+
 func parseChatCompletionsDelta(ev UnifiedEvent) []NormalizedDelta {
 	var chunk chatCompletionChunk
-	if err := json.Unmarshal(ev.Data, &chunk); err != nil {
+	if err := jsonUnmarshal(ev.Data, &chunk); err != nil {
 		return nil
 	}
 
@@ -117,11 +352,10 @@ func parseChatCompletionsDelta(ev UnifiedEvent) []NormalizedDelta {
 	var out []NormalizedDelta
 	if len(chunk.Choices) == 0 {
 		if chunk.Usage != nil && (chunk.Usage.PromptTokens > 0 || chunk.Usage.CompletionTokens > 0) {
-			return []NormalizedDelta{{
-				Type:         DeltaUsage,
-				InputTokens:  chunk.Usage.PromptTokens,
-				OutputTokens: chunk.Usage.CompletionTokens,
-			}}
+			d := chunk.usageDelta()
+			d.ServiceTier = chunk.ServiceTier
+			d.Model = chunk.Model
+			return []NormalizedDelta{d}
 		}
 		return nil
 	}
@@ -139,8 +373,11 @@ func parseChatCompletionsDelta(ev UnifiedEvent) []NormalizedDelta {
 			out = append(out, NormalizedDelta{Type: DeltaReasoning, Content: detail.Text})
 		}
 	}
-	if delta.Content != "" {
-		out = append(out, NormalizedDelta{Type: DeltaText, Content: delta.Content})
+	if text := delta.Content.text(); text != "" {
+		out = append(out, NormalizedDelta{Type: DeltaText, Content: text})
+	}
+	if d, ok := delta.Audio.delta(); ok {
+		out = append(out, d)
 	}
 	for _, tc := range delta.ToolCalls {
 		if tc.Function.Name != "" || tc.ID != "" {
@@ -162,15 +399,15 @@ func parseChatCompletionsDelta(ev UnifiedEvent) []NormalizedDelta {
 
 	if chunk.Choices[0].FinishReason != "" {
 		if chunk.Usage != nil && (chunk.Usage.PromptTokens > 0 || chunk.Usage.CompletionTokens > 0) {
-			out = append(out, NormalizedDelta{
-				Type:         DeltaUsage,
-				InputTokens:  chunk.Usage.PromptTokens,
-				OutputTokens: chunk.Usage.CompletionTokens,
-			})
+			out = append(out, chunk.usageDelta())
 		}
-		out = append(out, NormalizedDelta{Type: DeltaDone})
+		out = append(out, NormalizedDelta{Type: DeltaDone, FinishReason: chunk.Choices[0].FinishReason})
 	}
 
+	for i := range out {
+		out[i].ServiceTier = chunk.ServiceTier
+		out[i].Model = chunk.Model
+	}
 	return out
 }
 
@@ -178,16 +415,66 @@ func parseChatCompletionsDelta(ev UnifiedEvent) []NormalizedDelta {
 // responses (OpenAI Responses API)
 // ---------------------------------------------------------------------------
 
+// ServerToolCall is a built-in tool call the provider runs itself, such as
+// a Responses web_search or file_search call.
+type ServerToolCall struct {
+	ID string `json:"id"`
+	// Type is the output item type, "web_search_call" or "file_search_call".
+	Type string `json:"type"`
+	// Status is e.g. "in_progress", "searching", "completed" or "failed".
+	Status  string   `json:"status,omitempty"`
+	Queries []string `json:"queries,omitempty"`
+	// Results holds the results as the provider sent them (file_search
+	// results, web_search sources) when it reports them.
+	Results json.RawMessage `json:"results,omitempty"`
+}
+
+// responsesServerToolItem holds the fields of web_search_call and
+// file_search_call output items.
+type responsesServerToolItem struct {
+	Status  string          `json:"status"`
+	Queries []string        `json:"queries"`
+	Results json.RawMessage `json:"results"`
+	Action  *struct {
+		Query   string          `json:"query"`
+		Queries []string        `json:"queries"`
+		Sources json.RawMessage `json:"sources"`
+	} `json:"action"`
+}
+
+func isServerToolItem(typ string) bool {
+	return typ == "web_search_call" || typ == "file_search_call"
+}
+
+func (it responsesServerToolItem) call(id, typ string) *ServerToolCall {
+	call := &ServerToolCall{ID: id, Type: typ, Status: it.Status, Queries: it.Queries, Results: it.Results}
+	if a := it.Action; a != nil {
+		if a.Query != "" {
+			call.Queries = append(call.Queries, a.Query)
+		}
+		call.Queries = append(call.Queries, a.Queries...)
+		if call.Results == nil {
+			call.Results = a.Sources
+		}
+	}
+	if string(call.Results) == "null" {
+		call.Results = nil
+	}
+	return call
+}
+
 // responsesEvent is the minimal shape of a typed Responses API SSE payload.
 type responsesEvent struct {
 	Type  string `json:"type"`
 	Delta string `json:"delta"`
 	// For tool call events.
 	Item struct {
-		Type   string `json:"type"`
-		ID     string `json:"id"`
-		CallID string `json:"call_id"`
-		Name   string `json:"name"`
+		Type             string `json:"type"`
+		ID               string `json:"id"`
+		CallID           string `json:"call_id"`
+		Name             string `json:"name"`
+		EncryptedContent string `json:"encrypted_content"`
+		responsesServerToolItem
 	} `json:"item"`
 	OutputIndex int    `json:"output_index"`
 	Name        string `json:"name"`
@@ -196,37 +483,60 @@ type responsesEvent struct {
 	Arguments   string `json:"arguments"`
 	// For response.completed / response.done events.
 	Response *struct {
-		Usage *struct {
-			InputTokens  int `json:"input_tokens"`
-			OutputTokens int `json:"output_tokens"`
+		Model       string `json:"model"`
+		ServiceTier string `json:"service_tier"`
+		Usage       *struct {
+			InputTokens        int `json:"input_tokens"`
+			OutputTokens       int `json:"output_tokens"`
+			InputTokensDetails *struct {
+				CachedTokens int `json:"cached_tokens"`
+			} `json:"input_tokens_details"`
+			OutputTokensDetails *struct {
+				ReasoningTokens int `json:"reasoning_tokens"`
+			} `json:"output_tokens_details"`
 		} `json:"usage"`
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+		IncompleteDetails *struct {
+			Reason string `json:"reason"`
+		} `json:"incomplete_details"`
 	} `json:"response"`
 }
 
 func parseResponsesDelta(ev UnifiedEvent) []NormalizedDelta {
+	out, _ := responsesDeltas(ev)
+	return out
+}
+
+// responsesDeltas parses a Responses event; known reports whether its type
+// is one the parser handles, even when it yields no deltas.
+func responsesDeltas(ev UnifiedEvent) (out []NormalizedDelta, known bool) {
 	var e responsesEvent
-	if err := json.Unmarshal(ev.Data, &e); err != nil {
-		return nil
+	if err := jsonUnmarshal(ev.Data, &e); err != nil {
+		return nil, false
 	}
 
-	switch e.Type {
-	case "response.output_text.delta":
+	switch canonicalResponsesEvent(e.Type) {
+	case ResponsesEventOutputTextDelta:
 		if e.Delta != "" {
-			return []NormalizedDelta{{Type: DeltaText, Content: e.Delta}}
+			return []NormalizedDelta{{Type: DeltaText, Content: e.Delta, BlockIndex: e.OutputIndex}}, true
 		}
-	case "response.reasoning_summary_text.delta", "response.reasoning.delta", "response.reasoning_text.delta":
+	case ResponsesEventReasoningSummaryTextDelta, ResponsesEventReasoningTextDelta:
 		if e.Delta != "" {
-			return []NormalizedDelta{{Type: DeltaReasoning, Content: e.Delta}}
+			return []NormalizedDelta{{Type: DeltaReasoning, Content: e.Delta, BlockIndex: e.OutputIndex}}, true
 		}
-	case "response.function_call_arguments_delta", "response.function_call_arguments.delta":
+	case ResponsesEventFunctionCallArgumentsDelta:
 		if e.Delta != "" {
 			return []NormalizedDelta{{
 				Type:           DeltaToolCallArgumentsDelta,
 				ToolCallIndex:  e.OutputIndex,
+				BlockIndex:     e.OutputIndex,
 				ArgumentsDelta: e.Delta,
-			}}
+			}}, true
 		}
-	case "response.function_call_arguments_done", "response.function_call_arguments.done":
+	case ResponsesEventFunctionCallArgumentsDone:
 		callID := e.CallID
 		if callID == "" {
 			callID = e.ItemID
@@ -234,12 +544,29 @@ func parseResponsesDelta(ev UnifiedEvent) []NormalizedDelta {
 		return []NormalizedDelta{{
 			Type:          DeltaToolCallDone,
 			ToolCallIndex: e.OutputIndex,
+			BlockIndex:    e.OutputIndex,
 			ToolCallID:    callID,
 			ToolCallName:  e.Name,
 			ArgumentsFull: e.Arguments,
-		}}
-	case "response.output_item.added":
-		if e.Item.Type == "function_call" {
+		}}, true
+	case ResponsesEventOutputItemAdded, ResponsesEventOutputItemDone:
+		if isServerToolItem(e.Item.Type) {
+			return []NormalizedDelta{{
+				Type:           DeltaServerToolCall,
+				ToolCallIndex:  e.OutputIndex,
+				BlockIndex:     e.OutputIndex,
+				ServerToolCall: e.Item.call(e.Item.ID, e.Item.Type),
+			}}, true
+		}
+		if canonicalResponsesEvent(e.Type) == ResponsesEventOutputItemDone && e.Item.Type == "reasoning" && e.Item.EncryptedContent != "" {
+			var raw struct {
+				Item json.RawMessage `json:"item"`
+			}
+			if err := jsonUnmarshal(ev.Data, &raw); err == nil {
+				return []NormalizedDelta{{Type: DeltaProviderState, BlockIndex: e.OutputIndex, Part: raw.Item}}, true
+			}
+		}
+		if e.Type == ResponsesEventOutputItemAdded && e.Item.Type == "function_call" {
 			callID := e.Item.CallID
 			name := e.Item.Name
 			if callID == "" || name == "" {
@@ -260,32 +587,72 @@ func parseResponsesDelta(ev UnifiedEvent) []NormalizedDelta {
 			return []NormalizedDelta{{
 				Type:          DeltaToolCallBegin,
 				ToolCallIndex: e.OutputIndex,
+				BlockIndex:    e.OutputIndex,
 				ToolCallID:    callID,
 				ToolCallName:  name,
-			}}
+			}}, true
+		}
+	case ResponsesEventWebSearchCallInProgress, ResponsesEventWebSearchCallSearching, ResponsesEventWebSearchCallCompleted,
+		ResponsesEventFileSearchCallInProgress, ResponsesEventFileSearchCallSearching, ResponsesEventFileSearchCallCompleted:
+		typ, status, _ := strings.Cut(strings.TrimPrefix(e.Type, "response."), ".")
+		return []NormalizedDelta{{
+			Type:           DeltaServerToolCall,
+			ToolCallIndex:  e.OutputIndex,
+			BlockIndex:     e.OutputIndex,
+			ServerToolCall: &ServerToolCall{ID: e.ItemID, Type: typ, Status: status},
+		}}, true
+	case ResponsesEventCreated:
+		if e.Response != nil && e.Response.Model != "" {
+			return []NormalizedDelta{{Type: DeltaMeta, Model: e.Response.Model}}, true
+		}
+	case ResponsesEventInProgress:
+		// Status snapshot without content.
+	case ResponsesEventFailed:
+		apiErr := &APIError{Body: ev.Data}
+		if e.Response != nil && e.Response.Error != nil {
+			apiErr.Type = e.Response.Error.Code
+			apiErr.Message = e.Response.Error.Message
+		}
+		return []NormalizedDelta{{Type: DeltaError, Err: apiErr}}, true
+	case ResponsesEventCompleted, ResponsesEventIncomplete:
+		var tier, model, reason string
+		if e.Response != nil {
+			tier, model = e.Response.ServiceTier, e.Response.Model
+			if e.Response.IncompleteDetails != nil {
+				reason = e.Response.IncompleteDetails.Reason
+			}
 		}
-	case "response.completed", "response.done":
-		var out []NormalizedDelta
 		if e.Response != nil && e.Response.Usage != nil {
 			u := e.Response.Usage
 			if u.InputTokens > 0 || u.OutputTokens > 0 {
-				out = append(out, NormalizedDelta{
+				d := NormalizedDelta{
 					Type:         DeltaUsage,
 					InputTokens:  u.InputTokens,
 					OutputTokens: u.OutputTokens,
-				})
+					ServiceTier:  tier,
+					Model:        model,
+				}
+				if u.InputTokensDetails != nil {
+					d.CachedInputTokens = u.InputTokensDetails.CachedTokens
+				}
+				if u.OutputTokensDetails != nil {
+					d.ReasoningTokens = u.OutputTokensDetails.ReasoningTokens
+				}
+				out = append(out, d)
 			}
 		}
-		out = append(out, NormalizedDelta{Type: DeltaDone})
-		return out
+		out = append(out, NormalizedDelta{Type: DeltaDone, FinishReason: reason, ServiceTier: tier, Model: model})
+		return out, true
+	default:
+		return nil, false
 	}
 
-	return nil
+	return nil, true
 }
 
 func extractResponsesFunctionCallItem(raw json.RawMessage) (string, string) {
 	var root map[string]any
-	if err := json.Unmarshal(raw, &root); err != nil {
+	if err := jsonUnmarshal(raw, &root); err != nil {
 		return "", ""
 	}
 	item, ok := root["item"].(map[string]any)
@@ -327,31 +694,74 @@ type anthropicStreamEvent struct {
 		Thinking string `json:"thinking"`
 		// For tool use input deltas.
 		PartialJSON string `json:"partial_json"`
+		// For message_delta.
+		StopReason   string `json:"stop_reason"`
+		StopSequence string `json:"stop_sequence"`
 	} `json:"delta"`
 	ContentBlock struct {
-		Type  string `json:"type"`
-		ID    string `json:"id"`
-		Name  string `json:"name"`
-		Input string `json:"input"`
+		Type  string          `json:"type"`
+		ID    string          `json:"id"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
 	} `json:"content_block"`
 	// For message_start usage.
 	Message *struct {
-		Usage *struct {
-			InputTokens int `json:"input_tokens"`
-		} `json:"usage"`
+		Model string               `json:"model"`
+		Usage *anthropicInputUsage `json:"usage"`
 	} `json:"message"`
 	// For message_delta usage (top-level usage field).
 	Usage *struct {
 		OutputTokens int `json:"output_tokens"`
 	} `json:"usage"`
+	// For error events.
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicErrorStatus maps the error types Anthropic reports mid-stream to
+// the HTTP status the same error gets before a stream starts.
+var anthropicErrorStatus = map[string]int{
+	"invalid_request_error": http.StatusBadRequest,
+	"authentication_error":  http.StatusUnauthorized,
+	"permission_error":      http.StatusForbidden,
+	"not_found_error":       http.StatusNotFound,
+	"request_too_large":     http.StatusRequestEntityTooLarge,
+	"rate_limit_error":      http.StatusTooManyRequests,
+	"api_error":             http.StatusInternalServerError,
+	"overloaded_error":      StatusOverloaded,
+}
+
+// anthropicInputUsage is the input side of an Anthropic usage object.
+type anthropicInputUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// delta returns u as a DeltaUsage.
+func (u anthropicInputUsage) delta() NormalizedDelta {
+	return NormalizedDelta{
+		Type:                  DeltaUsage,
+		InputTokens:           u.InputTokens,
+		CachedInputTokens:     u.CacheReadInputTokens,
+		CacheWriteInputTokens: u.CacheCreationInputTokens,
+	}
 }
 
 func parseMessagesDelta(ev UnifiedEvent) []NormalizedDelta {
+	out, _ := messagesDeltas(ev)
+	return out
+}
+
+// messagesDeltas parses a Messages event; known is as for responsesDeltas.
+func messagesDeltas(ev UnifiedEvent) (out []NormalizedDelta, known bool) {
 	// Anthropic uses the SSE "event:" line for the type, but also includes
 	// "type" in the JSON body. Both are supported.
 	var e anthropicStreamEvent
-	if err := json.Unmarshal(ev.Data, &e); err != nil {
-		return nil
+	if err := jsonUnmarshal(ev.Data, &e); err != nil {
+		return nil, false
 	}
 
 	// Prefer the JSON body "type" field; fall back to the SSE event name.
@@ -361,57 +771,81 @@ func parseMessagesDelta(ev UnifiedEvent) []NormalizedDelta {
 	}
 
 	switch evType {
-	case "message_start":
-		if e.Message != nil && e.Message.Usage != nil && e.Message.Usage.InputTokens > 0 {
-			return []NormalizedDelta{{
-				Type:        DeltaUsage,
-				InputTokens: e.Message.Usage.InputTokens,
-			}}
+	case MessagesEventMessageStart:
+		if e.Message == nil {
+			return nil, true
+		}
+		if e.Message.Model != "" {
+			out = append(out, NormalizedDelta{Type: DeltaMeta, Model: e.Message.Model})
+		}
+		if u := e.Message.Usage; u != nil && *u != (anthropicInputUsage{}) {
+			out = append(out, u.delta())
 		}
-	case "content_block_start":
+		return out, true
+	case MessagesEventContentBlockStart:
 		if e.ContentBlock.Type == "tool_use" {
 			return []NormalizedDelta{{
 				Type:          DeltaToolCallBegin,
 				ToolCallIndex: e.Index,
+				BlockIndex:    e.Index,
 				ToolCallID:    e.ContentBlock.ID,
 				ToolCallName:  e.ContentBlock.Name,
-			}}
+			}}, true
 		}
-	case "message_delta":
-		if e.Usage != nil && e.Usage.OutputTokens > 0 {
-			return []NormalizedDelta{{
-				Type:         DeltaUsage,
-				OutputTokens: e.Usage.OutputTokens,
-			}}
+	case MessagesEventMessageDelta:
+		d := NormalizedDelta{
+			Type:         DeltaUsage,
+			FinishReason: e.Delta.StopReason,
+			StopSequence: e.Delta.StopSequence,
+		}
+		if e.Usage != nil {
+			d.OutputTokens = e.Usage.OutputTokens
+		}
+		if d.OutputTokens > 0 || d.FinishReason != "" {
+			return []NormalizedDelta{d}, true
 		}
-	case "content_block_delta":
+	case MessagesEventContentBlockDelta:
 		switch e.Delta.Type {
 		case "text_delta":
 			if e.Delta.Text != "" {
-				return []NormalizedDelta{{Type: DeltaText, Content: e.Delta.Text}}
+				return []NormalizedDelta{{Type: DeltaText, Content: e.Delta.Text, BlockIndex: e.Index}}, true
 			}
 		case "thinking_delta":
 			if e.Delta.Thinking != "" {
-				return []NormalizedDelta{{Type: DeltaReasoning, Content: e.Delta.Thinking}}
+				return []NormalizedDelta{{Type: DeltaReasoning, Content: e.Delta.Thinking, BlockIndex: e.Index}}, true
 			}
 		case "input_json_delta":
 			if e.Delta.PartialJSON != "" {
 				return []NormalizedDelta{{
 					Type:           DeltaToolCallArgumentsDelta,
 					ToolCallIndex:  e.Index,
+					BlockIndex:     e.Index,
 					ArgumentsDelta: e.Delta.PartialJSON,
-				}}
+				}}, true
 			}
 		}
-	case "content_block_stop":
+	case MessagesEventContentBlockStop:
 		// No content; signal completion only for tool_use blocks would require
 		// state from content_block_start. Consumers that need DeltaToolCallDone
 		// should accumulate themselves. We emit nothing here.
-	case "message_stop":
-		return []NormalizedDelta{{Type: DeltaDone}}
+	case MessagesEventMessageStop:
+		return []NormalizedDelta{{Type: DeltaDone}}, true
+	case MessagesEventPing:
+		// Keep-alive without content. It does not count as progress for
+		// Config.GenerationStallTimeout.
+	case MessagesEventError:
+		apiErr := &APIError{Body: ev.Data}
+		if e.Error != nil {
+			apiErr.Type = e.Error.Type
+			apiErr.Message = e.Error.Message
+			apiErr.StatusCode = anthropicErrorStatus[e.Error.Type]
+		}
+		return []NormalizedDelta{{Type: DeltaError, Err: apiErr}}, true
+	default:
+		return nil, false
 	}
 
-	return nil
+	return nil, true
 }
 
 // ---------------------------------------------------------------------------
@@ -420,21 +854,75 @@ func parseMessagesDelta(ev UnifiedEvent) []NormalizedDelta {
 
 // geminiChunk is the minimal shape of a Gemini SSE chunk.
 type geminiChunk struct {
-	Candidates []struct {
-		Content struct {
-			Parts []struct {
-				Text             string    `json:"text"`
-				Thought          bool      `json:"thought"`
-				FunctionCall     *geminiFC `json:"functionCall,omitempty"`
-				ThoughtSignature string    `json:"thoughtSignature"`
-			} `json:"parts"`
-		} `json:"content"`
-		FinishReason string `json:"finishReason"`
-	} `json:"candidates"`
+	Candidates    []geminiCandidate `json:"candidates"`
 	UsageMetadata *struct {
-		PromptTokenCount     int `json:"promptTokenCount"`
-		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
 	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+type geminiCandidate struct {
+	Content struct {
+		Parts []geminiChunkPart `json:"parts"`
+	} `json:"content"`
+	FinishReason     string          `json:"finishReason"`
+	Index            *int            `json:"index"`
+	SafetyRatings    json.RawMessage `json:"safetyRatings"`
+	CitationMetadata json.RawMessage `json:"citationMetadata"`
+}
+
+// finishDetails returns the safetyRatings and citationMetadata of a
+// candidate stopped for safety or recitation, or nil.
+func (c geminiCandidate) finishDetails() json.RawMessage {
+	switch NormalizeStopReason(c.FinishReason) {
+	case StopReasonContentFilter, StopReasonRecitation:
+	default:
+		return nil
+	}
+	details := map[string]json.RawMessage{}
+	if len(c.SafetyRatings) > 0 {
+		details["safetyRatings"] = c.SafetyRatings
+	}
+	if len(c.CitationMetadata) > 0 {
+		details["citationMetadata"] = c.CitationMetadata
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	raw, err := jsonMarshal(details)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+type geminiChunkPart struct {
+	Text                string          `json:"text"`
+	Thought             bool            `json:"thought"`
+	FunctionCall        *geminiFC       `json:"functionCall,omitempty"`
+	ExecutableCode      json.RawMessage `json:"executableCode,omitempty"`
+	CodeExecutionResult json.RawMessage `json:"codeExecutionResult,omitempty"`
+	ThoughtSignature    string          `json:"thoughtSignature"`
+}
+
+// rawPart returns the JSON of a part without a normalized form, or nil for
+// other parts.
+func (p geminiChunkPart) rawPart() json.RawMessage {
+	if p.ExecutableCode == nil && p.CodeExecutionResult == nil {
+		return nil
+	}
+	raw, err := jsonMarshal(GeminiPart{
+		ExecutableCode:      p.ExecutableCode,
+		CodeExecutionResult: p.CodeExecutionResult,
+		ThoughtSignature:    p.ThoughtSignature,
+	})
+	if err != nil {
+		return nil
+	}
+	return raw
 }
 
 type geminiFC struct {
@@ -444,7 +932,7 @@ type geminiFC struct {
 
 func parseGeminiDelta(ev UnifiedEvent) []NormalizedDelta {
 	var chunk geminiChunk
-	if err := json.Unmarshal(ev.Data, &chunk); err != nil {
+	if err := jsonUnmarshal(ev.Data, &chunk); err != nil {
 		return nil
 	}
 
@@ -456,28 +944,51 @@ func parseGeminiDelta(ev UnifiedEvent) []NormalizedDelta {
 		outToks := chunk.UsageMetadata.CandidatesTokenCount
 		if in > 0 || outToks > 0 {
 			out = append(out, NormalizedDelta{
-				Type:         DeltaUsage,
-				InputTokens:  in,
-				OutputTokens: outToks,
+				Type:              DeltaUsage,
+				InputTokens:       in,
+				OutputTokens:      outToks,
+				ReasoningTokens:   chunk.UsageMetadata.ThoughtsTokenCount,
+				CachedInputTokens: chunk.UsageMetadata.CachedContentTokenCount,
 			})
 		}
 	}
 
-	if len(chunk.Candidates) == 0 {
-		return out
+	for position, cand := range chunk.Candidates {
+		choice := position
+		if cand.Index != nil {
+			choice = *cand.Index
+		}
+		out = append(out, parseGeminiCandidate(choice, cand)...)
+	}
+	for i := range out {
+		out[i].Model = chunk.ModelVersion
 	}
 
-	cand := chunk.Candidates[0]
+	return out
+}
 
-	for i, part := range cand.Content.Parts {
+// parseGeminiCandidate parses the parts of one candidate. Function calls
+// are numbered among the candidate's calls, not its parts, so a streamed
+// call gets the same id as in the complete response, where text and thought
+// parts precede it. Candidates other than the first get their own tool call
+// ids so their calls stay apart.
+func parseGeminiCandidate(choice int, cand geminiCandidate) []NormalizedDelta {
+	var out []NormalizedDelta
+	i := -1
+	for _, part := range cand.Content.Parts {
 		if part.FunctionCall != nil {
+			i++
 			callID := fmt.Sprintf("gemini-%d", i)
+			if choice > 0 {
+				callID = fmt.Sprintf("gemini-%d-%d", choice, i)
+			}
 			out = append(out, NormalizedDelta{
 				Type:              DeltaToolCallBegin,
 				ToolCallIndex:     i,
 				ToolCallID:        callID,
 				ToolCallName:      part.FunctionCall.Name,
 				ToolCallSignature: part.ThoughtSignature,
+				ChoiceIndex:       choice,
 			})
 			if len(part.FunctionCall.Args) > 0 {
 				args := string(part.FunctionCall.Args)
@@ -486,6 +997,7 @@ func parseGeminiDelta(ev UnifiedEvent) []NormalizedDelta {
 					ToolCallIndex:  i,
 					ToolCallID:     callID,
 					ArgumentsDelta: args,
+					ChoiceIndex:    choice,
 				})
 				out = append(out, NormalizedDelta{
 					Type:              DeltaToolCallDone,
@@ -494,24 +1006,28 @@ func parseGeminiDelta(ev UnifiedEvent) []NormalizedDelta {
 					ToolCallName:      part.FunctionCall.Name,
 					ToolCallSignature: part.ThoughtSignature,
 					ArgumentsFull:     args,
+					ChoiceIndex:       choice,
 				})
 			}
 			continue
 		}
+		if raw := part.rawPart(); raw != nil {
+			out = append(out, NormalizedDelta{Type: DeltaGeminiPart, Part: raw, ChoiceIndex: choice})
+			continue
+		}
 		text := strings.TrimRight(part.Text, "")
 		if text == "" {
 			continue
 		}
 		if part.Thought {
-			out = append(out, NormalizedDelta{Type: DeltaReasoning, Content: text})
+			out = append(out, NormalizedDelta{Type: DeltaReasoning, Content: text, ChoiceIndex: choice})
 		} else {
-			out = append(out, NormalizedDelta{Type: DeltaText, Content: text})
+			out = append(out, NormalizedDelta{Type: DeltaText, Content: text, ChoiceIndex: choice})
 		}
 	}
 
 	if cand.FinishReason != "" && cand.FinishReason != "FINISH_REASON_UNSPECIFIED" {
-		out = append(out, NormalizedDelta{Type: DeltaDone})
+		out = append(out, NormalizedDelta{Type: DeltaDone, ChoiceIndex: choice, FinishReason: cand.FinishReason, FinishDetails: cand.finishDetails()})
 	}
-
 	return out
 }
@@ -3,8 +3,11 @@ package zen
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -147,7 +150,7 @@ func TestParseChatCompletionsUsageOnSplitChunk(t *testing.T) {
 // ---------------------------------------------------------------------------
 
 func TestParseResponsesText(t *testing.T) {
-	ev := makeEventNamed(EndpointResponses, "response.output_text.delta", `{"type":"response.output_text.delta","delta":"Hi"}`)
+	ev := makeEventNamed(EndpointResponses, ResponsesEventOutputTextDelta, `{"type":"response.output_text.delta","delta":"Hi"}`)
 	deltas := ParseNormalizedEvent(ev)
 	if len(deltas) != 1 || deltas[0].Type != DeltaText || deltas[0].Content != "Hi" {
 		t.Fatalf("expected text delta 'Hi', got %+v", deltas)
@@ -155,7 +158,7 @@ func TestParseResponsesText(t *testing.T) {
 }
 
 func TestParseResponsesReasoningSummary(t *testing.T) {
-	ev := makeEventNamed(EndpointResponses, "response.reasoning_summary_text.delta", `{"type":"response.reasoning_summary_text.delta","delta":"reasoning..."}`)
+	ev := makeEventNamed(EndpointResponses, ResponsesEventReasoningSummaryTextDelta, `{"type":"response.reasoning_summary_text.delta","delta":"reasoning..."}`)
 	deltas := ParseNormalizedEvent(ev)
 	if len(deltas) != 1 || deltas[0].Type != DeltaReasoning || deltas[0].Content != "reasoning..." {
 		t.Fatalf("expected reasoning delta, got %+v", deltas)
@@ -213,12 +216,87 @@ func TestParseResponsesUsage(t *testing.T) {
 	}
 }
 
+func TestParseResponsesIncludedSections(t *testing.T) {
+	// include=["reasoning.encrypted_content"] adds encrypted reasoning items to
+	// output_item events and to the completed response. The finished item is
+	// reported whole, to be sent back; the completed response repeats it.
+	raw := `{"type":"reasoning","id":"rs_1","summary":[{"type":"summary_text","text":"hm"}],"encrypted_content":"gAAAA"}`
+	item := makeEvent(EndpointResponses, `{"type":"response.output_item.done","output_index":0,"item":`+raw+`}`)
+	deltas := ParseNormalizedEvent(item)
+	if len(deltas) != 1 || deltas[0].Type != DeltaProviderState || string(deltas[0].Part) != raw {
+		t.Fatalf("expected the reasoning item as provider state, got %+v", deltas)
+	}
+	ev := makeEvent(EndpointResponses, `{"type":"response.completed","response":{"truncation":"auto","output":[{"type":"reasoning","encrypted_content":"gAAAA","summary":[]},{"type":"message","content":[{"type":"output_text","text":"ok","annotations":[]}]}],"usage":{"input_tokens":3,"output_tokens":4,"output_tokens_details":{"reasoning_tokens":2}}}}`)
+	deltas = ParseNormalizedEvent(ev)
+	assertDeltaSequence(t, deltas, DeltaUsage, DeltaDone)
+	if deltas[0].InputTokens != 3 || deltas[0].OutputTokens != 4 {
+		t.Fatalf("usage tokens wrong: %+v", deltas[0])
+	}
+}
+
+func TestParseServiceTier(t *testing.T) {
+	chat := ParseNormalizedEvent(makeEvent(EndpointChatCompletions, `{"service_tier":"flex","choices":[{"delta":{"content":"hi"}}]}`))
+	if len(chat) != 1 || chat[0].ServiceTier != "flex" {
+		t.Fatalf("chat service tier not surfaced: %+v", chat)
+	}
+	resp := ParseNormalizedEvent(makeEvent(EndpointResponses, `{"type":"response.completed","response":{"service_tier":"priority","usage":{"input_tokens":1,"output_tokens":2}}}`))
+	assertDeltaSequence(t, resp, DeltaUsage, DeltaDone)
+	if resp[1].ServiceTier != "priority" {
+		t.Fatalf("responses service tier not surfaced: %+v", resp)
+	}
+}
+
+func TestParseResponsesIncompleteAndFailed(t *testing.T) {
+	ev := makeEvent(EndpointResponses, `{"type":"response.incomplete","response":{"incomplete_details":{"reason":"max_output_tokens"},"usage":{"input_tokens":5,"output_tokens":9}}}`)
+	deltas := ParseNormalizedEvent(ev)
+	assertDeltaSequence(t, deltas, DeltaUsage, DeltaDone)
+	if deltas[1].StopReason != StopReasonMaxTokens {
+		t.Fatalf("expected max_tokens stop reason, got %+v", deltas[1])
+	}
+
+	ev = makeEvent(EndpointResponses, `{"type":"response.failed","response":{"error":{"code":"server_error","message":"boom"}}}`)
+	deltas = ParseNormalizedEvent(ev)
+	if len(deltas) != 1 || deltas[0].Type != DeltaError {
+		t.Fatalf("expected error delta, got %+v", deltas)
+	}
+	var apiErr *APIError
+	if !errors.As(deltas[0].Err, &apiErr) || apiErr.Type != "server_error" || apiErr.Message != "boom" {
+		t.Fatalf("unexpected error: %#v", deltas[0].Err)
+	}
+}
+
+// TestParserHandlesListedEvents keeps the event tables behind Capabilities
+// in step with the parsers: every listed type must be handled.
+func TestParserHandlesListedEvents(t *testing.T) {
+	responses := append([]string{}, responsesEventTypes...)
+	for alias := range responsesEventAliases {
+		responses = append(responses, alias)
+	}
+	for _, typ := range responses {
+		if _, known := responsesDeltas(makeEvent(EndpointResponses, `{"type":"`+typ+`"}`)); !known {
+			t.Errorf("responses event %q is listed but not handled", typ)
+		}
+	}
+	for _, typ := range messagesEventTypes {
+		if _, known := messagesDeltas(makeEventNamed(EndpointMessages, typ, `{"type":"`+typ+`"}`)); !known {
+			t.Errorf("messages event %q is listed but not handled", typ)
+		}
+	}
+
+	if _, known := responsesDeltas(makeEvent(EndpointResponses, `{"type":"response.bogus"}`)); known {
+		t.Fatal("expected an unlisted responses event to be unknown")
+	}
+	if _, known := messagesDeltas(makeEventNamed(EndpointMessages, "bogus", `{"type":"bogus"}`)); known {
+		t.Fatal("expected an unlisted messages event to be unknown")
+	}
+}
+
 // ---------------------------------------------------------------------------
 // messages (Anthropic)
 // ---------------------------------------------------------------------------
 
 func TestParseMessagesText(t *testing.T) {
-	ev := makeEventNamed(EndpointMessages, "content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`)
+	ev := makeEventNamed(EndpointMessages, MessagesEventContentBlockDelta, `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`)
 	deltas := ParseNormalizedEvent(ev)
 	if len(deltas) != 1 || deltas[0].Type != DeltaText || deltas[0].Content != "Hello" {
 		t.Fatalf("expected text delta, got %+v", deltas)
@@ -226,7 +304,7 @@ func TestParseMessagesText(t *testing.T) {
 }
 
 func TestParseMessagesThinkingDelta(t *testing.T) {
-	ev := makeEventNamed(EndpointMessages, "content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"I need to think..."}}`)
+	ev := makeEventNamed(EndpointMessages, MessagesEventContentBlockDelta, `{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"I need to think..."}}`)
 	deltas := ParseNormalizedEvent(ev)
 	if len(deltas) != 1 || deltas[0].Type != DeltaReasoning || deltas[0].Content != "I need to think..." {
 		t.Fatalf("expected reasoning delta, got %+v", deltas)
@@ -234,7 +312,7 @@ func TestParseMessagesThinkingDelta(t *testing.T) {
 }
 
 func TestParseMessagesToolUseStart(t *testing.T) {
-	ev := makeEventNamed(EndpointMessages, "content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_abc","name":"calculator"}}`)
+	ev := makeEventNamed(EndpointMessages, MessagesEventContentBlockStart, `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_abc","name":"calculator"}}`)
 	deltas := ParseNormalizedEvent(ev)
 	if len(deltas) != 1 || deltas[0].Type != DeltaToolCallBegin {
 		t.Fatalf("expected tool_call_begin, got %+v", deltas)
@@ -247,8 +325,91 @@ func TestParseMessagesToolUseStart(t *testing.T) {
 	}
 }
 
+func TestParseResponsesServerToolCalls(t *testing.T) {
+	sse := `data: {"type":"response.output_item.added","output_index":0,"item":{"type":"web_search_call","id":"ws_1","status":"in_progress"}}
+
+data: {"type":"response.web_search_call.searching","output_index":0,"item_id":"ws_1"}
+
+data: {"type":"response.web_search_call.completed","output_index":0,"item_id":"ws_1"}
+
+data: {"type":"response.output_item.done","output_index":0,"item":{"type":"web_search_call","id":"ws_1","status":"completed","action":{"type":"search","query":"go 1.24 release","sources":[{"url":"https://go.dev/doc/go1.24"}]}}}
+
+data: {"type":"response.output_item.done","output_index":1,"item":{"type":"file_search_call","id":"fs_1","status":"completed","queries":["refund policy"],"results":null}}
+
+data: {"type":"response.output_text.delta","delta":"Done."}
+
+data: {"type":"response.completed","response":{}}
+
+`
+	server, client := newSSETestServer(t, sse)
+	defer server.Close()
+
+	deltas, errs, err := client.Stream(testCtx(t), NormalizedRequest{Model: "gpt-5.2", Messages: []NormalizedMessage{{Role: "user", Content: "search"}}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var got []NormalizedDelta
+	for d := range deltas {
+		got = append(got, d)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	assertDeltaSequence(t, got, DeltaServerToolCall, DeltaServerToolCall, DeltaServerToolCall, DeltaServerToolCall, DeltaServerToolCall, DeltaText, DeltaDone)
+	if c := got[1].ServerToolCall; c == nil || c.ID != "ws_1" || c.Type != "web_search_call" || c.Status != "searching" {
+		t.Fatalf("searching event mismatch: %+v", c)
+	}
+
+	rc := newResponseCollector()
+	for _, d := range got {
+		rc.add(d)
+	}
+	calls := rc.response(false).ServerToolCalls
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 server tool calls, got %+v", calls)
+	}
+	web, file := calls[0], calls[1]
+	if web.Status != "completed" || len(web.Queries) != 1 || web.Queries[0] != "go 1.24 release" || string(web.Results) != `[{"url":"https://go.dev/doc/go1.24"}]` {
+		t.Fatalf("web search call mismatch: %+v", web)
+	}
+	if file.Type != "file_search_call" || len(file.Queries) != 1 || file.Results != nil {
+		t.Fatalf("file search call mismatch: %+v", file)
+	}
+
+	body := `{"output":[{"type":"web_search_call","id":"ws_1","status":"completed","action":{"type":"search","query":"go 1.24 release"}},{"type":"message","content":[{"type":"output_text","text":"Done."}]}]}`
+	resp, err := ParseNormalizedResponse(EndpointResponses, []byte(body))
+	if err != nil {
+		t.Fatalf("ParseNormalizedResponse: %v", err)
+	}
+	if len(resp.ServerToolCalls) != 1 || resp.ServerToolCalls[0].ID != "ws_1" || resp.ServerToolCalls[0].Queries[0] != "go 1.24 release" || resp.Text != "Done." {
+		t.Fatalf("non-streaming server tool call mismatch: %+v", resp)
+	}
+}
+
+func TestParseMessagesComputerUseToolCall(t *testing.T) {
+	events := []UnifiedEvent{
+		makeEventNamed(EndpointMessages, MessagesEventContentBlockStart, `{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_01","name":"computer","input":{}}}`),
+		makeEventNamed(EndpointMessages, MessagesEventContentBlockDelta, `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"action\":\"left_click\","}}`),
+		makeEventNamed(EndpointMessages, MessagesEventContentBlockDelta, `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"coordinate\":[512,384]}"}}`),
+		makeEventNamed(EndpointMessages, MessagesEventContentBlockStop, `{"type":"content_block_stop","index":0}`),
+	}
+	acc := NewToolCallAccumulator()
+	for _, ev := range events {
+		for _, d := range ParseNormalizedEvent(ev) {
+			acc.Apply(d)
+		}
+	}
+	calls := acc.CompleteCalls()
+	if len(calls) != 1 || calls[0].ID != "toolu_01" || calls[0].Name != "computer" {
+		t.Fatalf("computer tool call not assembled: %+v", calls)
+	}
+	if string(calls[0].Arguments) != `{"action":"left_click","coordinate":[512,384]}` {
+		t.Fatalf("arguments mismatch: %s", calls[0].Arguments)
+	}
+}
+
 func TestParseMessagesToolInputDelta(t *testing.T) {
-	ev := makeEventNamed(EndpointMessages, "content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"x\":"}}`)
+	ev := makeEventNamed(EndpointMessages, MessagesEventContentBlockDelta, `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"x\":"}}`)
 	deltas := ParseNormalizedEvent(ev)
 	if len(deltas) != 1 || deltas[0].Type != DeltaToolCallArgumentsDelta {
 		t.Fatalf("expected tool_call_arguments_delta, got %+v", deltas)
@@ -259,7 +420,7 @@ func TestParseMessagesToolInputDelta(t *testing.T) {
 }
 
 func TestParseMessagesStop(t *testing.T) {
-	ev := makeEventNamed(EndpointMessages, "message_stop", `{"type":"message_stop"}`)
+	ev := makeEventNamed(EndpointMessages, MessagesEventMessageStop, `{"type":"message_stop"}`)
 	deltas := ParseNormalizedEvent(ev)
 	if len(deltas) != 1 || deltas[0].Type != DeltaDone {
 		t.Fatalf("expected done delta, got %+v", deltas)
@@ -268,7 +429,7 @@ func TestParseMessagesStop(t *testing.T) {
 
 func TestParseMessagesUsageStart(t *testing.T) {
 	// message_start carries input tokens.
-	ev := makeEventNamed(EndpointMessages, "message_start", `{"type":"message_start","message":{"usage":{"input_tokens":120}}}`)
+	ev := makeEventNamed(EndpointMessages, MessagesEventMessageStart, `{"type":"message_start","message":{"usage":{"input_tokens":120}}}`)
 	deltas := ParseNormalizedEvent(ev)
 	assertDeltaSequence(t, deltas, DeltaUsage)
 	if deltas[0].InputTokens != 120 || deltas[0].OutputTokens != 0 {
@@ -278,7 +439,7 @@ func TestParseMessagesUsageStart(t *testing.T) {
 
 func TestParseMessagesUsageDelta(t *testing.T) {
 	// message_delta carries output tokens at the top-level usage field.
-	ev := makeEventNamed(EndpointMessages, "message_delta", `{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":45}}`)
+	ev := makeEventNamed(EndpointMessages, MessagesEventMessageDelta, `{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":45}}`)
 	deltas := ParseNormalizedEvent(ev)
 	assertDeltaSequence(t, deltas, DeltaUsage)
 	if deltas[0].OutputTokens != 45 || deltas[0].InputTokens != 0 {
@@ -401,12 +562,22 @@ func TestStreamChatCompletions(t *testing.T) {
 		t.Fatalf("stream error: %v", err)
 	}
 
-	assertDeltaSequence(t, deltas, DeltaReasoning, DeltaText, DeltaDone)
-	if deltas[0].Content != "thinking" {
-		t.Fatalf("reasoning content: want 'thinking', got %q", deltas[0].Content)
-	}
-	if deltas[1].Content != "answer" {
-		t.Fatalf("text content: want 'answer', got %q", deltas[1].Content)
+	assertDeltaSequence(t, deltas, DeltaReasoningBegin, DeltaReasoning, DeltaReasoningDone, DeltaText, DeltaDone)
+	if deltas[1].Content != "thinking" {
+		t.Fatalf("reasoning content: want 'thinking', got %q", deltas[1].Content)
+	}
+	if deltas[3].Content != "answer" {
+		t.Fatalf("text content: want 'answer', got %q", deltas[3].Content)
+	}
+	// Reasoning markers share the event of the delta that caused them.
+	wantSeq := []int64{1, 1, 2, 2, 3}
+	for i, d := range deltas {
+		if d.Seq != wantSeq[i] || d.ReceivedAt.IsZero() {
+			t.Fatalf("delta[%d]: want seq %d with a receive time, got seq %d at %v", i, wantSeq[i], d.Seq, d.ReceivedAt)
+		}
+		if i > 0 && d.ReceivedAt.Before(deltas[i-1].ReceivedAt) {
+			t.Fatalf("delta[%d]: receive times must not go backwards", i)
+		}
 	}
 }
 
@@ -436,7 +607,72 @@ func TestStreamMessages(t *testing.T) {
 		t.Fatalf("stream error: %v", err)
 	}
 
-	assertDeltaSequence(t, deltas, DeltaReasoning, DeltaText, DeltaDone)
+	assertDeltaSequence(t, deltas, DeltaReasoningBegin, DeltaReasoning, DeltaReasoningDone, DeltaText, DeltaDone)
+}
+
+// overloadedMessagesSSE is an Anthropic stream that was cut off by an
+// overloaded_error event after its first text delta.
+const overloadedMessagesSSE = "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_01XFDUDYJgAACzvnptvVoYEL\",\"type\":\"message\",\"role\":\"assistant\",\"content\":[],\"model\":\"claude-sonnet-4-6\",\"stop_reason\":null,\"stop_sequence\":null,\"usage\":{\"input_tokens\":25,\"output_tokens\":1}}}\n\n" +
+	"event: ping\ndata: {\"type\": \"ping\"}\n\n" +
+	"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n" +
+	"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n" +
+	"event: ping\ndata: {\"type\": \"ping\"}\n\n" +
+	"event: error\ndata: {\"type\": \"error\", \"error\": {\"type\": \"overloaded_error\", \"message\": \"Overloaded\"}}\n\n"
+
+func TestParseMessagesPingAndError(t *testing.T) {
+	if deltas := ParseNormalizedEvent(makeEventNamed(EndpointMessages, MessagesEventPing, `{"type": "ping"}`)); len(deltas) != 0 {
+		t.Fatalf("expected no deltas for ping, got %+v", deltas)
+	}
+
+	ev := makeEventNamed(EndpointMessages, MessagesEventError, `{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`)
+	deltas := ParseNormalizedEvent(ev)
+	if len(deltas) != 1 || deltas[0].Type != DeltaError {
+		t.Fatalf("expected error delta, got %+v", deltas)
+	}
+	var apiErr *APIError
+	if !errors.As(deltas[0].Err, &apiErr) || !apiErr.IsOverloaded() || apiErr.StatusCode != StatusOverloaded || apiErr.Message != "Overloaded" {
+		t.Fatalf("unexpected error: %#v", deltas[0].Err)
+	}
+}
+
+func TestStreamMessagesOverloaded(t *testing.T) {
+	server, client := newSSETestServer(t, overloadedMessagesSSE)
+	defer server.Close()
+
+	req := NormalizedRequest{
+		Model:    "claude-sonnet-4-6",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	}
+	deltaCh, errCh, err := client.Stream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var deltas []NormalizedDelta
+	for d := range deltaCh {
+		deltas = append(deltas, d)
+	}
+	var apiErr *APIError
+	if err := <-errCh; !errors.As(err, &apiErr) || !apiErr.IsOverloaded() {
+		t.Fatalf("expected overloaded error, got %v", err)
+	}
+	assertDeltaSequence(t, deltas, DeltaMeta, DeltaUsage, DeltaText, DeltaError)
+
+	resp, err := client.CollectStream(testCtx(t), req)
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || !errors.As(err, &apiErr) || !apiErr.IsOverloaded() {
+		t.Fatalf("expected overloaded StreamError, got %v", err)
+	}
+	if resp.Text != "Hello" {
+		t.Fatalf("partial text not kept: %+v", resp)
+	}
+	if !isRetryableBatchError(err) {
+		t.Fatal("overloaded stream should be retryable")
+	}
+
+	req.ForceStream = true
+	if _, err := client.CreateNormalized(testCtx(t), req); !errors.As(err, &apiErr) || !apiErr.IsOverloaded() {
+		t.Fatalf("expected overloaded error from forced stream, got %v", err)
+	}
 }
 
 func TestStreamResponses(t *testing.T) {
@@ -465,7 +701,7 @@ func TestStreamResponses(t *testing.T) {
 		t.Fatalf("stream error: %v", err)
 	}
 
-	assertDeltaSequence(t, deltas, DeltaReasoning, DeltaText, DeltaDone)
+	assertDeltaSequence(t, deltas, DeltaReasoningBegin, DeltaReasoning, DeltaReasoningDone, DeltaText, DeltaDone)
 }
 
 func TestStreamGemini(t *testing.T) {
@@ -493,5 +729,314 @@ func TestStreamGemini(t *testing.T) {
 		t.Fatalf("stream error: %v", err)
 	}
 
-	assertDeltaSequence(t, deltas, DeltaReasoning, DeltaText, DeltaDone)
+	assertDeltaSequence(t, deltas, DeltaReasoningBegin, DeltaReasoning, DeltaReasoningDone, DeltaText, DeltaDone)
+}
+
+func TestStreamGeminiWithFileInput(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		body = string(payload)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"A cat video.\"}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":1200,\"candidatesTokenCount\":4}}\n\n"))
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	resp, err := client.CollectStream(testCtx(t), NormalizedRequest{
+		Model: "gemini-3-flash",
+		Messages: []NormalizedMessage{{Role: "user", Parts: []NormalizedContentPart{
+			{Type: ContentPartFile, FileURI: "files/abc", MediaType: "video/mp4"},
+			{Type: ContentPartText, Text: "What is this?"},
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	if !strings.Contains(body, `"fileData":{"fileUri":"files/abc","mimeType":"video/mp4"}`) {
+		t.Fatalf("request should carry fileData, got %s", body)
+	}
+	if resp.Text != "A cat video." || resp.InputTokens != 1200 {
+		t.Fatalf("response mismatch: %+v", resp)
+	}
+}
+
+func TestStreamMessagesStopSequence(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		body = string(payload)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"answer\"}}\n\n" +
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"stop_sequence\",\"stop_sequence\":\"[END]\"},\"usage\":{\"output_tokens\":3}}\n\n" +
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req := NormalizedRequest{
+		Model:         "claude-sonnet-4-6",
+		Messages:      []NormalizedMessage{{Role: "user", Content: "hi"}},
+		StopSequences: []string{"[END]", "[STOP]"},
+	}
+
+	deltaCh, errCh, err := client.Stream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var deltas []NormalizedDelta
+	for d := range deltaCh {
+		deltas = append(deltas, d)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	assertDeltaSequence(t, deltas, DeltaText, DeltaUsage, DeltaDone)
+	if done := deltas[2]; done.FinishReason != "stop_sequence" || done.StopSequence != "[END]" {
+		t.Fatalf("done should carry the stop details, got %+v", done)
+	}
+	if !strings.Contains(body, `"stop_sequences":["[END]","[STOP]"]`) {
+		t.Fatalf("request should carry stop_sequences, got %s", body)
+	}
+
+	resp, err := client.CollectStream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	if resp.FinishReason != "stop_sequence" || resp.StopSequence != "[END]" {
+		t.Fatalf("response stop details mismatch: %+v", resp)
+	}
+}
+
+func TestStreamChatCompletionsFinishReason(t *testing.T) {
+	sse := "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"answer\"}}]}\n\n" +
+		"data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: [DONE]\n\n"
+	server, client := newSSETestServer(t, sse)
+	defer server.Close()
+
+	resp, err := client.CollectStream(testCtx(t), NormalizedRequest{
+		Model:         "kimi-k2",
+		Messages:      []NormalizedMessage{{Role: "user", Content: "hi"}},
+		StopSequences: []string{"[END]"},
+	})
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	// Chat Completions does not report which stop string matched.
+	if resp.FinishReason != "stop" || resp.StopSequence != "" {
+		t.Fatalf("response stop details mismatch: %+v", resp)
+	}
+}
+
+func TestParseGeminiMultipleCandidates(t *testing.T) {
+	body := `{"candidates":[` +
+		`{"index":0,"content":{"role":"model","parts":[{"text":"Paris"},{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]},"finishReason":"STOP"},` +
+		`{"index":1,"content":{"role":"model","parts":[{"text":"Rome"},{"functionCall":{"name":"get_weather","args":{"city":"Rome"}}}]},"finishReason":"STOP"}` +
+		`],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":8}}`
+
+	deltas := ParseNormalizedEvent(makeEvent(EndpointModels, body))
+	for _, d := range deltas {
+		if d.Type == DeltaToolCallBegin && d.ChoiceIndex == 1 && d.ToolCallID != "gemini-1-0" {
+			t.Fatalf("second candidate should get its own call id, got %q", d.ToolCallID)
+		}
+	}
+
+	resp, err := ParseNormalizedResponse(EndpointModels, []byte(body))
+	if err != nil {
+		t.Fatalf("ParseNormalizedResponse: %v", err)
+	}
+	if resp.Text != "Paris" || len(resp.ToolCalls) != 1 || string(resp.ToolCalls[0].Arguments) != `{"city":"Paris"}` {
+		t.Fatalf("first candidate should fill the top-level fields: %+v", resp)
+	}
+	if len(resp.Choices) != 2 {
+		t.Fatalf("expected 2 choices, got %+v", resp.Choices)
+	}
+	second := resp.Choices[1]
+	if second.Index != 1 || second.Text != "Rome" || len(second.ToolCalls) != 1 || string(second.ToolCalls[0].Arguments) != `{"city":"Rome"}` {
+		t.Fatalf("second candidate mismatch: %+v", second)
+	}
+
+	assembled, err := AssembleResponse(EndpointModels, []UnifiedEvent{makeEvent(EndpointModels, body)})
+	if err != nil {
+		t.Fatalf("AssembleResponse: %v", err)
+	}
+	assertJSONEquivalent(t, []byte(body), assembled)
+
+	req := NormalizedRequest{Model: "gemini-3-pro", CandidateCount: 2, Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+	gemini, err := req.ToGeminiRequest()
+	if err != nil {
+		t.Fatalf("ToGeminiRequest: %v", err)
+	}
+	if gemini.GenerationConfig == nil || gemini.GenerationConfig.CandidateCount == nil || *gemini.GenerationConfig.CandidateCount != 2 {
+		t.Fatalf("candidate count should be forwarded: %+v", gemini.GenerationConfig)
+	}
+}
+
+func TestParseChatCompletionsAudio(t *testing.T) {
+	events := []UnifiedEvent{
+		makeEvent(EndpointChatCompletions, `{"choices":[{"delta":{"role":"assistant","audio":{"id":"audio_1","transcript":"Hel"}}}]}`),
+		makeEvent(EndpointChatCompletions, `{"choices":[{"delta":{"audio":{"data":"AAEC","transcript":"lo"}}}]}`),
+		makeEvent(EndpointChatCompletions, `{"choices":[{"delta":{"audio":{"data":"AwQ="}}}]}`),
+		makeEvent(EndpointChatCompletions, `{"choices":[{"delta":{},"finish_reason":"stop"}]}`),
+	}
+	rc := newResponseCollector()
+	var audioDeltas int
+	for _, ev := range events {
+		for _, d := range ParseNormalizedEvent(ev) {
+			if d.Type == DeltaAudio {
+				audioDeltas++
+			}
+			rc.add(d)
+		}
+	}
+	resp := rc.response(false)
+	if audioDeltas != 3 || resp.Audio == nil {
+		t.Fatalf("expected 3 audio deltas and an assembled audio, got %d %+v", audioDeltas, resp.Audio)
+	}
+	if resp.Audio.ID != "audio_1" || resp.Audio.Transcript != "Hello" || string(resp.Audio.Data) != "\x00\x01\x02\x03\x04" || resp.Text != "" {
+		t.Fatalf("audio mismatch: %+v", resp.Audio)
+	}
+
+	body, err := AssembleResponse(EndpointChatCompletions, events)
+	if err != nil {
+		t.Fatalf("AssembleResponse: %v", err)
+	}
+	parsed, err := ParseNormalizedResponse(EndpointChatCompletions, body)
+	if err != nil {
+		t.Fatalf("ParseNormalizedResponse: %v", err)
+	}
+	if parsed.Audio == nil || parsed.Audio.ID != "audio_1" || string(parsed.Audio.Data) != string(resp.Audio.Data) || parsed.Audio.Transcript != "Hello" {
+		t.Fatalf("assembled audio mismatch: %s", body)
+	}
+}
+
+func TestStreamSingleDone(t *testing.T) {
+	cases := []struct {
+		name   string
+		model  string
+		sse    string
+		want   []NormalizedDeltaType
+		finish string
+	}{
+		{
+			name:  "chat finish_reason repeated with usage",
+			model: "kimi-k2",
+			sse: `data: {"choices":[{"delta":{"content":"Hi"}}]}
+
+data: {"choices":[{"delta":{},"finish_reason":"stop"}]}
+
+data: {"choices":[{"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1}}
+
+data: [DONE]
+
+`,
+			want:   []NormalizedDeltaType{DeltaText, DeltaUsage, DeltaDone},
+			finish: "stop",
+		},
+		{
+			name:  "chat usage chunk after finish",
+			model: "kimi-k2",
+			sse: `data: {"choices":[{"delta":{"content":"Hi"},"finish_reason":"length"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":5,"completion_tokens":1}}
+
+data: [DONE]
+
+`,
+			want:   []NormalizedDeltaType{DeltaText, DeltaUsage, DeltaDone},
+			finish: "length",
+		},
+		{
+			name:  "responses completed and done",
+			model: "gpt-5.1",
+			sse: `data: {"type":"response.output_text.delta","delta":"Hi"}
+
+data: {"type":"response.completed","response":{"model":"gpt-5.1","usage":{"input_tokens":5,"output_tokens":1}}}
+
+data: {"type":"response.done","response":{"model":"gpt-5.1"}}
+
+`,
+			want: []NormalizedDeltaType{DeltaText, DeltaUsage, DeltaDone},
+		},
+		{
+			name:  "messages repeated message_stop",
+			model: "claude-sonnet-4-6",
+			sse: `event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`,
+			want:   []NormalizedDeltaType{DeltaText, DeltaUsage, DeltaDone},
+			finish: "end_turn",
+		},
+		{
+			name:  "gemini finishReason before the last chunk",
+			model: "gemini-3-flash",
+			sse: `data: {"candidates":[{"content":{"parts":[{"text":"Hel"}]},"finishReason":"MAX_TOKENS"}]}
+
+data: {"candidates":[{"content":{"parts":[{"text":"lo"}]}}]}
+
+data: {"candidates":[{"content":{"parts":[]},"finishReason":"STOP"}]}
+
+`,
+			want:   []NormalizedDeltaType{DeltaText, DeltaText, DeltaDone},
+			finish: "MAX_TOKENS",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := newSSETestServer(t, tc.sse)
+			defer server.Close()
+			deltas, errs, err := client.Stream(testCtx(t), NormalizedRequest{Model: tc.model, Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}})
+			if err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+			var got []NormalizedDelta
+			for d := range deltas {
+				got = append(got, d)
+			}
+			if err := <-errs; err != nil {
+				t.Fatalf("stream error: %v", err)
+			}
+			assertDeltaSequence(t, got, tc.want...)
+			if done := got[len(got)-1]; done.FinishReason != tc.finish {
+				t.Fatalf("finish reason: want %q, got %q", tc.finish, done.FinishReason)
+			}
+		})
+	}
+}
+
+func TestDeltaTypeSets(t *testing.T) {
+	for _, typ := range []NormalizedDeltaType{DeltaToolCallBegin, DeltaToolCallArgumentsDelta, DeltaToolCallDone} {
+		if !IsToolDelta(typ) || IsContentDelta(typ) || typ.IsTerminal() {
+			t.Fatalf("%s should only be a tool delta", typ)
+		}
+	}
+	for _, typ := range []NormalizedDeltaType{DeltaText, DeltaReasoning, DeltaAudio, DeltaGeminiPart} {
+		if !IsContentDelta(typ) || IsToolDelta(typ) || typ.IsTerminal() {
+			t.Fatalf("%s should only be a content delta", typ)
+		}
+	}
+	for _, typ := range []NormalizedDeltaType{DeltaServerToolCall, DeltaUsage, DeltaMeta, DeltaError, DeltaReasoningBegin} {
+		if IsContentDelta(typ) || IsToolDelta(typ) || typ.IsTerminal() {
+			t.Fatalf("%s should be in no set", typ)
+		}
+	}
+	if !DeltaDone.IsTerminal() || DeltaDone.String() != "done" {
+		t.Fatalf("DeltaDone should be terminal and print as done")
+	}
 }
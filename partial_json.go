@@ -0,0 +1,108 @@
+package zen
+
+import "encoding/json"
+
+// completePartialJSON turns a truncated JSON document into a valid one by
+// closing an unterminated string and any open objects and arrays. Trailing
+// fragments that cannot be completed (a key without a value, a partial
+// literal) are dropped. It reports false when nothing parseable remains.
+func completePartialJSON(s string) (string, bool) {
+	type cut struct {
+		end   int    // prefix length to keep
+		stack []byte // open containers at that point
+	}
+
+	var stack []byte
+	var cuts []cut
+	inString, escaped := false, false
+	stringStart := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+			stringStart = i
+		case '{', '[':
+			stack = append(stack, c)
+			cuts = append(cuts, cut{end: i + 1, stack: append([]byte(nil), stack...)})
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			cuts = append(cuts, cut{end: i + 1, stack: append([]byte(nil), stack...)})
+		case ',':
+			cuts = append(cuts, cut{end: i, stack: append([]byte(nil), stack...)})
+		}
+	}
+
+	closeWith := func(prefix string, open []byte) string {
+		b := []byte(prefix)
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i] == '{' {
+				b = append(b, '}')
+			} else {
+				b = append(b, ']')
+			}
+		}
+		return string(b)
+	}
+
+	// First try to keep everything, terminating an open string.
+	prefix := s
+	if inString {
+		prefix = trimPartialEscape(s[stringStart:], escaped)
+		prefix = s[:stringStart] + prefix + `"`
+	}
+	if candidate := closeWith(prefix, stack); json.Valid([]byte(candidate)) {
+		return candidate, true
+	}
+
+	// Otherwise back off to the last structural boundary that completes.
+	for i := len(cuts) - 1; i >= 0; i-- {
+		candidate := closeWith(s[:cuts[i].end], cuts[i].stack)
+		if json.Valid([]byte(candidate)) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// trimPartialEscape removes an incomplete escape sequence from the end of an
+// unterminated string literal (which starts with its opening quote).
+func trimPartialEscape(str string, escaped bool) string {
+	if escaped {
+		return str[:len(str)-1]
+	}
+	// A \u escape needs four hex digits.
+	for n := 1; n <= 5 && n < len(str); n++ {
+		tail := str[len(str)-n:]
+		if len(tail) >= 2 && tail[0] == '\\' && tail[1] == 'u' && !precededByBackslash(str, len(str)-n) {
+			if len(tail) < 6 {
+				return str[:len(str)-n]
+			}
+			break
+		}
+	}
+	return str
+}
+
+// precededByBackslash reports whether the backslash at i is itself escaped.
+func precededByBackslash(s string, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && s[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
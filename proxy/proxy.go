@@ -0,0 +1,357 @@
+// Package proxy exposes a zen.Client behind an OpenAI-compatible
+// chat/completions HTTP endpoint, so tools that only speak the OpenAI wire
+// format can reach every model the gateway serves.
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+// Handler serves POST requests carrying an OpenAI chat/completions body. Each
+// request is imported into a zen.NormalizedRequest, routed by model through
+// Client.Stream, and answered with a chat.completion object or, when the
+// request set "stream": true, with chat.completion.chunk SSE events —
+// regardless of which backend endpoint actually served the model.
+type Handler struct {
+	Client *zen.Client
+
+	// ForwardExtra forwards request keys that have no normalized equivalent
+	// (seed, user, ...) to the backend as they are. It is off by default
+	// because the routed backend is not necessarily a chat/completions
+	// endpoint and may reject keys it does not know. Keys with a normalized
+	// equivalent, such as stop or max_completion_tokens, are always
+	// translated for the backend.
+	ForwardExtra bool
+}
+
+// NewHandler returns a Handler serving requests through client.
+func NewHandler(client *zen.Client) *Handler {
+	return &Handler{Client: client}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req, err := zen.NormalizedRequestFromChatCompletions(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	includeUsage := streamIncludesUsage(req.Extra)
+	stream := req.Stream
+	if !h.ForwardExtra {
+		req.Extra = nil
+	} else {
+		delete(req.Extra, "stream_options")
+	}
+
+	deltas, errs, err := h.Client.Stream(r.Context(), req)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	meta := completionMeta{
+		ID:      newCompletionID(),
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+	}
+	if stream {
+		serveStream(w, meta, deltas, errs, includeUsage)
+		return
+	}
+	serveCompletion(w, meta, deltas, errs)
+}
+
+type completionMeta struct {
+	ID      string
+	Created int64
+	Model   string
+}
+
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type toolCall struct {
+	Index    *int             `json:"index,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function toolCallFunction `json:"function"`
+}
+
+type toolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+func serveCompletion(w http.ResponseWriter, meta completionMeta, deltas <-chan zen.NormalizedDelta, errs <-chan error) {
+	resp, err := zen.CollectDeltas(deltas, errs)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	message := map[string]any{"role": "assistant", "content": nil}
+	if resp.Text != "" {
+		message["content"] = resp.Text
+	}
+	if resp.Reasoning != "" {
+		message["reasoning_content"] = resp.Reasoning
+	}
+	if len(resp.ToolCalls) > 0 {
+		calls := make([]toolCall, 0, len(resp.ToolCalls))
+		for _, tc := range resp.ToolCalls {
+			calls = append(calls, toolCall{
+				ID:       tc.ID,
+				Type:     "function",
+				Function: toolCallFunction{Name: tc.Name, Arguments: string(tc.Arguments)},
+			})
+		}
+		message["tool_calls"] = calls
+	}
+
+	body := map[string]any{
+		"id":      meta.ID,
+		"object":  "chat.completion",
+		"created": meta.Created,
+		"model":   meta.Model,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"message":       message,
+			"finish_reason": finishReason(resp.StopReason, len(resp.ToolCalls) > 0),
+		}},
+		"usage": usage{
+			PromptTokens:     resp.InputTokens,
+			CompletionTokens: resp.OutputTokens,
+			TotalTokens:      resp.InputTokens + resp.OutputTokens,
+		},
+	}
+	if resp.ServiceTier != "" {
+		body["service_tier"] = resp.ServiceTier
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// chunkWriter re-emits normalized deltas as chat.completion.chunk events.
+type chunkWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	meta    completionMeta
+
+	// indexes maps the backend's tool call index (an output index or content
+	// block index, depending on the endpoint) to the dense 0-based index
+	// OpenAI clients expect.
+	indexes   map[int]int
+	argsSent  map[int]bool
+	toolCalls int
+}
+
+func serveStream(w http.ResponseWriter, meta completionMeta, deltas <-chan zen.NormalizedDelta, errs <-chan error, includeUsage bool) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	cw := &chunkWriter{
+		w:        w,
+		meta:     meta,
+		indexes:  map[int]int{},
+		argsSent: map[int]bool{},
+	}
+	cw.flusher, _ = w.(http.Flusher)
+
+	cw.delta(map[string]any{"role": "assistant", "content": ""})
+
+	var in, out int
+	var stop zen.StopReason
+	for d := range deltas {
+		if d.StopReason != "" {
+			stop = d.StopReason
+		}
+		switch d.Type {
+		case zen.DeltaText:
+			cw.delta(map[string]any{"content": d.Content})
+		case zen.DeltaReasoning:
+			cw.delta(map[string]any{"reasoning_content": d.Content})
+		case zen.DeltaToolCallBegin:
+			cw.beginToolCall(d)
+		case zen.DeltaToolCallArgumentsDelta:
+			idx, ok := cw.indexes[d.ToolCallIndex]
+			if !ok {
+				idx = cw.beginToolCall(d)
+			}
+			cw.argsSent[idx] = true
+			cw.delta(map[string]any{"tool_calls": []toolCall{{
+				Index:    &idx,
+				Function: toolCallFunction{Arguments: d.ArgumentsDelta},
+			}}})
+		case zen.DeltaToolCallDone:
+			idx, ok := cw.indexes[d.ToolCallIndex]
+			if !ok {
+				idx = cw.beginToolCall(d)
+			}
+			// Some backends only report the arguments on completion.
+			if !cw.argsSent[idx] && d.ArgumentsFull != "" {
+				cw.argsSent[idx] = true
+				cw.delta(map[string]any{"tool_calls": []toolCall{{
+					Index:    &idx,
+					Function: toolCallFunction{Arguments: d.ArgumentsFull},
+				}}})
+			}
+		case zen.DeltaUsage:
+			if d.InputTokens > in {
+				in = d.InputTokens
+			}
+			if d.OutputTokens > out {
+				out = d.OutputTokens
+			}
+		}
+	}
+
+	if err := <-errs; err != nil {
+		// The status has been sent: report the error in the stream and
+		// still end it the way clients expect.
+		cw.write(map[string]any{"error": errorBody(err)})
+		cw.write(cw.chunk([]any{map[string]any{"index": 0, "delta": map[string]any{}, "finish_reason": "error"}}))
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+		cw.flush()
+		return
+	}
+	cw.write(cw.chunk([]any{map[string]any{"index": 0, "delta": map[string]any{}, "finish_reason": finishReason(stop, cw.toolCalls > 0)}}))
+	if includeUsage {
+		final := cw.chunk([]any{})
+		final["usage"] = usage{PromptTokens: in, CompletionTokens: out, TotalTokens: in + out}
+		cw.write(final)
+	}
+	_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	cw.flush()
+}
+
+// finishReason maps the backend's stop reason to a chat/completions
+// finish_reason. Clients rely on "length" to detect truncated replies.
+func finishReason(reason zen.StopReason, toolCalls bool) string {
+	switch {
+	case reason == zen.StopReasonMaxTokens:
+		return "length"
+	case reason == zen.StopReasonContentFilter, reason == zen.StopReasonRecitation:
+		return "content_filter"
+	case toolCalls, reason == zen.StopReasonToolCalls:
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func (cw *chunkWriter) beginToolCall(d zen.NormalizedDelta) int {
+	idx := cw.toolCalls
+	cw.toolCalls++
+	cw.indexes[d.ToolCallIndex] = idx
+	cw.delta(map[string]any{"tool_calls": []toolCall{{
+		Index:    &idx,
+		ID:       d.ToolCallID,
+		Type:     "function",
+		Function: toolCallFunction{Name: d.ToolCallName},
+	}}})
+	return idx
+}
+
+func (cw *chunkWriter) chunk(choices []any) map[string]any {
+	return map[string]any{
+		"id":      cw.meta.ID,
+		"object":  "chat.completion.chunk",
+		"created": cw.meta.Created,
+		"model":   cw.meta.Model,
+		"choices": choices,
+	}
+}
+
+func (cw *chunkWriter) delta(delta map[string]any) {
+	cw.write(cw.chunk([]any{map[string]any{"index": 0, "delta": delta, "finish_reason": nil}}))
+}
+
+func (cw *chunkWriter) write(v any) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	_, _ = io.WriteString(cw.w, "data: ")
+	_, _ = cw.w.Write(payload)
+	_, _ = io.WriteString(cw.w, "\n\n")
+	cw.flush()
+}
+
+func (cw *chunkWriter) flush() {
+	if cw.flusher != nil {
+		cw.flusher.Flush()
+	}
+}
+
+func streamIncludesUsage(extra map[string]any) bool {
+	raw, ok := extra["stream_options"].(json.RawMessage)
+	if !ok {
+		return false
+	}
+	var opts struct {
+		IncludeUsage bool `json:"include_usage"`
+	}
+	_ = json.Unmarshal(raw, &opts)
+	return opts.IncludeUsage
+}
+
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	var apiErr *zen.APIError
+	// Error events in a stream carry no status, or one only Anthropic
+	// knows; those are reported as a bad gateway.
+	if errors.As(err, &apiErr) && apiErr.StatusCode >= 100 && apiErr.StatusCode <= 999 {
+		status = apiErr.StatusCode
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": errorBody(err)})
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{
+		"message": msg,
+		"type":    "invalid_request_error",
+	}})
+}
+
+func errorBody(err error) map[string]any {
+	body := map[string]any{"message": err.Error(), "type": "upstream_error"}
+	var apiErr *zen.APIError
+	if errors.As(err, &apiErr) && apiErr.Message != "" {
+		body["message"] = apiErr.Message
+	}
+	return body
+}
+
+func newCompletionID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "chatcmpl-" + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,346 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+const anthropicToolSSE = "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":42}}}\n\n" +
+	"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\"}}\n\n" +
+	"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Checking.\"}}\n\n" +
+	"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"get_weather\"}}\n\n" +
+	"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"city\\\":\"}}\n\n" +
+	"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\\\"Paris\\\"}\"}}\n\n" +
+	"event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":17}}\n\n" +
+	"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+const geminiToolSSE = "data: {\"candidates\":[{\"content\":{\"parts\":[{\"functionCall\":{\"name\":\"get_weather\",\"args\":{\"city\":\"Paris\"}}}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":10,\"candidatesTokenCount\":5}}\n\n"
+
+// newProxy starts a mock zen backend that answers every request with sse and
+// a proxy in front of it. The backend records the last path and body it saw.
+func newProxy(t *testing.T, sse string) (proxyURL string, lastPath *string, lastBody *map[string]any) {
+	t.Helper()
+	var path string
+	var body map[string]any
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		payload, _ := io.ReadAll(r.Body)
+		body = nil
+		_ = json.Unmarshal(payload, &body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(sse))
+	}))
+	t.Cleanup(backend.Close)
+
+	client, err := zen.NewClient(zen.Config{APIKey: "key", BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	front := httptest.NewServer(NewHandler(client))
+	t.Cleanup(front.Close)
+	return front.URL, &path, &body
+}
+
+func postJSON(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(url+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestProxyStreamingToolCallFromMessagesBackend(t *testing.T) {
+	url, path, backendBody := newProxy(t, anthropicToolSSE)
+
+	resp := postJSON(t, url, `{
+		"model": "claude-sonnet-4-6",
+		"stream": true,
+		"stream_options": {"include_usage": true},
+		"stop": ["END"],
+		"seed": 7,
+		"messages": [{"role": "user", "content": "Weather in Paris?"}],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}]
+	}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if *path != "/messages" {
+		t.Fatalf("expected routing to /messages, got %s", *path)
+	}
+	if _, ok := (*backendBody)["seed"]; ok {
+		t.Fatalf("chat-only keys must not be forwarded by default")
+	}
+	if stop, _ := (*backendBody)["stop_sequences"].([]any); len(stop) != 1 || stop[0] != "END" {
+		t.Fatalf("stop must be forwarded as stop_sequences, got %v", *backendBody)
+	}
+
+	var text strings.Builder
+	var args strings.Builder
+	var toolID, toolName, finish string
+	var promptTokens, completionTokens float64
+	sawDone := false
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		data := strings.TrimPrefix(line, "data: ")
+		if data == "[DONE]" {
+			sawDone = true
+			break
+		}
+		var chunk struct {
+			Object  string `json:"object"`
+			Choices []struct {
+				Delta struct {
+					Content   string `json:"content"`
+					ToolCalls []struct {
+						Index    int    `json:"index"`
+						ID       string `json:"id"`
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *struct {
+				PromptTokens     float64 `json:"prompt_tokens"`
+				CompletionTokens float64 `json:"completion_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("bad chunk %s: %v", data, err)
+		}
+		if chunk.Object != "chat.completion.chunk" {
+			t.Fatalf("unexpected object %q", chunk.Object)
+		}
+		if chunk.Usage != nil {
+			promptTokens = chunk.Usage.PromptTokens
+			completionTokens = chunk.Usage.CompletionTokens
+		}
+		for _, c := range chunk.Choices {
+			text.WriteString(c.Delta.Content)
+			for _, tc := range c.Delta.ToolCalls {
+				if tc.Index != 0 {
+					t.Fatalf("tool call index should be remapped to 0, got %d", tc.Index)
+				}
+				if tc.ID != "" {
+					toolID = tc.ID
+				}
+				if tc.Function.Name != "" {
+					toolName = tc.Function.Name
+				}
+				args.WriteString(tc.Function.Arguments)
+			}
+			if c.FinishReason != nil {
+				finish = *c.FinishReason
+			}
+		}
+	}
+
+	if !sawDone {
+		t.Fatalf("stream did not end with [DONE]")
+	}
+	if text.String() != "Checking." {
+		t.Fatalf("text mismatch: %q", text.String())
+	}
+	if toolID != "toolu_1" || toolName != "get_weather" || args.String() != `{"city":"Paris"}` {
+		t.Fatalf("tool call mismatch: id=%q name=%q args=%q", toolID, toolName, args.String())
+	}
+	if finish != "tool_calls" {
+		t.Fatalf("finish_reason: want tool_calls, got %q", finish)
+	}
+	if promptTokens != 42 || completionTokens != 17 {
+		t.Fatalf("usage mismatch: prompt=%v completion=%v", promptTokens, completionTokens)
+	}
+}
+
+func TestProxyNonStreamingToolCallFromGeminiBackend(t *testing.T) {
+	url, path, _ := newProxy(t, geminiToolSSE)
+
+	resp := postJSON(t, url, `{
+		"model": "gemini-3-flash",
+		"messages": [{"role": "user", "content": "Weather in Paris?"}],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}]
+	}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(*path, "/models/gemini-3-flash") {
+		t.Fatalf("expected routing to gemini, got %s", *path)
+	}
+
+	var completion struct {
+		Object  string `json:"object"`
+		Choices []struct {
+			Message struct {
+				Role      string  `json:"role"`
+				Content   *string `json:"content"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Type     string `json:"type"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if completion.Object != "chat.completion" || len(completion.Choices) != 1 {
+		t.Fatalf("unexpected completion: %+v", completion)
+	}
+	choice := completion.Choices[0]
+	if choice.FinishReason != "tool_calls" || choice.Message.Content != nil {
+		t.Fatalf("unexpected choice: %+v", choice)
+	}
+	if len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("expected one tool call, got %+v", choice.Message.ToolCalls)
+	}
+	tc := choice.Message.ToolCalls[0]
+	if tc.Type != "function" || tc.Function.Name != "get_weather" || tc.Function.Arguments != `{"city":"Paris"}` || tc.ID == "" {
+		t.Fatalf("tool call mismatch: %+v", tc)
+	}
+	if completion.Usage.PromptTokens != 10 || completion.Usage.CompletionTokens != 5 || completion.Usage.TotalTokens != 15 {
+		t.Fatalf("usage mismatch: %+v", completion.Usage)
+	}
+}
+
+func TestProxyRelaysUpstreamErrors(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"message":"slow down"}}`))
+	}))
+	defer backend.Close()
+	client, err := zen.NewClient(zen.Config{APIKey: "key", BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	front := httptest.NewServer(NewHandler(client))
+	defer front.Close()
+
+	resp := postJSON(t, front.URL, `{"model":"glm-5","messages":[{"role":"user","content":"hi"}]}`)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 to be relayed, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if body.Error.Message != "slow down" {
+		t.Fatalf("error message not relayed: %+v", body)
+	}
+
+	bad := postJSON(t, front.URL, `not json`)
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid body, got %d", bad.StatusCode)
+	}
+}
+
+func TestProxyCompletionUpstreamStreamFailure(t *testing.T) {
+	url, _, _ := newProxy(t, `data: {"type":"response.output_text.delta","output_index":0,"delta":"Hel"}`+"\n\n"+
+		`data: {"type":"response.failed","response":{"error":{"code":"server_error","message":"boom"}}}`+"\n\n")
+
+	resp := postJSON(t, url, `{"model":"gpt-5.1","messages":[{"role":"user","content":"hi"}]}`)
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502 for a failure without a status, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error.Message != "boom" {
+		t.Fatalf("error message not relayed: %+v (%v)", body, err)
+	}
+}
+
+func TestProxyStreamEndsAfterUpstreamError(t *testing.T) {
+	url, _, _ := newProxy(t, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n"+
+		"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+
+	resp := postJSON(t, url, `{"model":"claude-sonnet-4-6","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	payload, _ := io.ReadAll(resp.Body)
+	var events []string
+	for _, line := range strings.Split(string(payload), "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			events = append(events, data)
+		}
+	}
+	if len(events) < 3 || events[len(events)-1] != "[DONE]" {
+		t.Fatalf("stream must end with [DONE], got %q", events)
+	}
+	var errEvent struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(events[len(events)-3]), &errEvent); err != nil || errEvent.Error.Message != "Overloaded" {
+		t.Fatalf("expected the error event before the terminal chunk, got %q", events)
+	}
+	var final struct {
+		Choices []struct {
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal([]byte(events[len(events)-2]), &final); err != nil || len(final.Choices) != 1 || final.Choices[0].FinishReason != "error" {
+		t.Fatalf("expected a terminal chunk, got %s", events[len(events)-2])
+	}
+}
+
+func TestProxyFinishReason(t *testing.T) {
+	truncated := "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Once upon\"}}\n\n" +
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\"},\"usage\":{\"output_tokens\":2}}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+	filtered := "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Sure\"}]},\"finishReason\":\"SAFETY\"}]}\n\n"
+
+	for _, tc := range []struct {
+		name, sse, model, want string
+	}{
+		{"max_tokens", truncated, "claude-sonnet-4-6", "length"},
+		{"safety", filtered, "gemini-3-flash", "content_filter"},
+		{"tool_use", anthropicToolSSE, "claude-sonnet-4-6", "tool_calls"},
+	} {
+		url, _, _ := newProxy(t, tc.sse)
+
+		resp := postJSON(t, url, `{"model":"`+tc.model+`","messages":[{"role":"user","content":"hi"}]}`)
+		var completion struct {
+			Choices []struct {
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil || len(completion.Choices) != 1 || completion.Choices[0].FinishReason != tc.want {
+			t.Fatalf("%s: expected finish_reason %q, got %+v (%v)", tc.name, tc.want, completion, err)
+		}
+
+		stream := postJSON(t, url, `{"model":"`+tc.model+`","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+		payload, _ := io.ReadAll(stream.Body)
+		if !strings.Contains(string(payload), `"finish_reason":"`+tc.want+`"`) {
+			t.Fatalf("%s: expected streamed finish_reason %q, got %s", tc.name, tc.want, payload)
+		}
+	}
+}
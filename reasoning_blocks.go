@@ -0,0 +1,78 @@
+package zen
+
+// ReasoningBlock is one reasoning segment of a response. Index is the
+// BlockIndex of its deltas.
+type ReasoningBlock struct {
+	Index int
+	Text  string
+	// Signature is the signature of an Anthropic thinking block and Data
+	// the encrypted content of a redacted_thinking block, whose Text is
+	// empty. NormalizedRequestFromMessages sets them; responses do not.
+	Signature string
+	Data      string
+}
+
+// endsReasoning reports whether a delta of type t closes an open reasoning
+// block of the same candidate.
+func endsReasoning(t NormalizedDeltaType) bool {
+	switch t {
+	case DeltaText, DeltaToolCallBegin, DeltaToolCallArgumentsDelta, DeltaToolCallDone,
+		DeltaAudio, DeltaServerToolCall, DeltaGeminiPart, DeltaProviderState, DeltaUsage, DeltaDone:
+		return true
+	}
+	return false
+}
+
+// reasoningTracker brackets the reasoning deltas of each block of a stream
+// with DeltaReasoningBegin and DeltaReasoningDone. A block ends when a
+// reasoning delta of another block or any other content arrives, or when
+// the stream ends.
+type reasoningTracker struct {
+	// open maps a candidate to the BlockIndex of its open reasoning block.
+	open map[int]int
+}
+
+func (t *reasoningTracker) apply(d NormalizedDelta) []NormalizedDelta {
+	block, open := t.open[d.ChoiceIndex]
+	switch {
+	case d.Type == DeltaReasoning:
+		if open && block == d.BlockIndex {
+			return []NormalizedDelta{d}
+		}
+		var out []NormalizedDelta
+		if open {
+			out = append(out, reasoningMarker(DeltaReasoningDone, block, d))
+		}
+		if t.open == nil {
+			t.open = map[int]int{}
+		}
+		t.open[d.ChoiceIndex] = d.BlockIndex
+		return append(out, reasoningMarker(DeltaReasoningBegin, d.BlockIndex, d), d)
+	case open && endsReasoning(d.Type):
+		delete(t.open, d.ChoiceIndex)
+		return []NormalizedDelta{reasoningMarker(DeltaReasoningDone, block, d), d}
+	}
+	return []NormalizedDelta{d}
+}
+
+// flush closes the blocks still open at the end of a stream.
+func (t *reasoningTracker) flush() []NormalizedDelta {
+	var out []NormalizedDelta
+	for _, choice := range sortedKeys(t.open) {
+		out = append(out, NormalizedDelta{Type: DeltaReasoningDone, BlockIndex: t.open[choice], ChoiceIndex: choice})
+	}
+	t.open = nil
+	return out
+}
+
+// reasoningMarker returns a marker of type typ for block, timed like the
+// delta that caused it.
+func reasoningMarker(typ NormalizedDeltaType, block int, d NormalizedDelta) NormalizedDelta {
+	return NormalizedDelta{
+		Type:        typ,
+		BlockIndex:  block,
+		ChoiceIndex: d.ChoiceIndex,
+		ReceivedAt:  d.ReceivedAt,
+		Seq:         d.Seq,
+	}
+}
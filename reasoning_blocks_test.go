@@ -0,0 +1,94 @@
+package zen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// interleavedMessagesSSE reasons, calls a tool and reasons again in one turn.
+const interleavedMessagesSSE = "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"thinking\",\"thinking\":\"\"}}\n\n" +
+	"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"check \"}}\n\n" +
+	"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"weather\"}}\n\n" +
+	"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n" +
+	"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"get_weather\",\"input\":{}}}\n\n" +
+	"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"city\\\":\\\"Paris\\\"}\"}}\n\n" +
+	"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}\n\n" +
+	"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":2,\"content_block\":{\"type\":\"thinking\",\"thinking\":\"\"}}\n\n" +
+	"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":2,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"then answer\"}}\n\n" +
+	"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":2}\n\n" +
+	"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+func TestReasoningBlocks(t *testing.T) {
+	server, client := newSSETestServer(t, interleavedMessagesSSE)
+	defer server.Close()
+	req := NormalizedRequest{Model: "claude-sonnet-4-6", Messages: []NormalizedMessage{{Role: "user", Content: "weather?"}}}
+
+	deltaCh, errCh, err := client.Stream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var deltas []NormalizedDelta
+	for d := range deltaCh {
+		deltas = append(deltas, d)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	assertDeltaSequence(t, deltas,
+		DeltaReasoningBegin, DeltaReasoning, DeltaReasoning, DeltaReasoningDone,
+		DeltaToolCallBegin, DeltaToolCallArgumentsDelta,
+		DeltaReasoningBegin, DeltaReasoning, DeltaReasoningDone, DeltaDone)
+	for i, want := range map[int]int{0: 0, 3: 0, 6: 2, 8: 2} {
+		if deltas[i].BlockIndex != want {
+			t.Fatalf("delta[%d] %s: want BlockIndex %d, got %d", i, deltas[i].Type, want, deltas[i].BlockIndex)
+		}
+	}
+
+	resp, err := CollectDeltas(sliceDeltas(deltas), closedErrs())
+	if err != nil {
+		t.Fatalf("CollectDeltas: %v", err)
+	}
+	want := []ReasoningBlock{{Index: 0, Text: "check weather"}, {Index: 2, Text: "then answer"}}
+	assertReasoningBlocks(t, resp, want)
+	if resp.Reasoning != "check weatherthen answer" {
+		t.Fatalf("Reasoning: got %q", resp.Reasoning)
+	}
+
+	collected, err := client.CollectStream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	assertReasoningBlocks(t, collected, want)
+}
+
+func TestReasoningBlocksNonStreaming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"resp_1","status":"completed","output":[` +
+			`{"type":"reasoning","summary":[{"text":"first"}]},` +
+			`{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{}"},` +
+			`{"type":"reasoning","summary":[{"text":"second, "},{"text":"continued"}]}]}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	resp, err := client.CreateNormalized(testCtx(t), NormalizedRequest{Model: "gpt-5.1", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("CreateNormalized: %v", err)
+	}
+	assertReasoningBlocks(t, resp, []ReasoningBlock{{Index: 0, Text: "first"}, {Index: 2, Text: "second, continued"}})
+}
+
+func assertReasoningBlocks(t *testing.T, resp *NormalizedResponse, want []ReasoningBlock) {
+	t.Helper()
+	if len(resp.ReasoningBlocks) != len(want) {
+		t.Fatalf("ReasoningBlocks: want %+v, got %+v", want, resp.ReasoningBlocks)
+	}
+	for i := range want {
+		if resp.ReasoningBlocks[i] != want[i] {
+			t.Fatalf("ReasoningBlocks[%d]: want %+v, got %+v", i, want[i], resp.ReasoningBlocks[i])
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package zen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxReassembledPayloadBytes bounds the data payload a payloadJoiner builds
+// from events whose data is truncated JSON.
+const maxReassembledPayloadBytes = 4 << 20
+
+// payloadJoiner reassembles JSON data payloads that a gateway split across
+// consecutive SSE events, each of which is invalid on its own; parsers
+// would otherwise drop both halves. An event whose data is the start of a
+// JSON value is held and joined with the data of the events after it until
+// the result is valid, and released unchanged when it cannot be.
+type payloadJoiner struct {
+	pending []UnifiedEvent
+	size    int
+	// joined is called with the number of events merged into one.
+	joined func(parts int)
+}
+
+// add returns the events to parse in place of ev.
+func (j *payloadJoiner) add(ev UnifiedEvent) []UnifiedEvent {
+	if ev.Event == CommentEvent || len(ev.Data) == 0 {
+		return []UnifiedEvent{ev}
+	}
+	if len(j.pending) == 0 {
+		if len(ev.Data) > maxReassembledPayloadBytes || !truncatedJSON(ev.Data) {
+			return []UnifiedEvent{ev}
+		}
+		j.pending, j.size = []UnifiedEvent{ev}, len(ev.Data)
+		return nil
+	}
+
+	data := make([]byte, 0, j.size+len(ev.Data))
+	for _, p := range j.pending {
+		data = append(data, p.Data...)
+	}
+	data = append(data, ev.Data...)
+	switch {
+	case json.Valid(data):
+		first := j.pending[0]
+		merged := ev
+		merged.Event, merged.Data = first.Event, data
+		var raw bytes.Buffer
+		for _, p := range j.pending {
+			raw.WriteString(p.Raw)
+		}
+		merged.Raw = raw.String() + ev.Raw
+		parts := len(j.pending) + 1
+		j.pending, j.size = nil, 0
+		if j.joined != nil {
+			j.joined(parts)
+		}
+		return []UnifiedEvent{merged}
+	case len(data) <= maxReassembledPayloadBytes && truncatedJSON(data):
+		j.pending, j.size = append(j.pending, ev), len(data)
+		return nil
+	}
+	// Not the continuation of the held payload: give the held events up
+	// and start over with ev.
+	out := j.flush()
+	return append(out, j.add(ev)...)
+}
+
+// flush releases the events still held, e.g. at the end of the stream.
+func (j *payloadJoiner) flush() []UnifiedEvent {
+	out := j.pending
+	j.pending, j.size = nil, 0
+	return out
+}
+
+// truncatedJSON reports whether data is invalid only because it ends early.
+func truncatedJSON(data []byte) bool {
+	if json.Valid(data) {
+		return false
+	}
+	var v json.RawMessage
+	err := json.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// streamWarning reports a repair made to the stream of endpoint to
+// Config.OnWarning.
+func (c *Client) streamWarning(ctx context.Context, endpoint EndpointType, reason string) {
+	if c.cfg.OnWarning != nil {
+		c.cfg.OnWarning(ctx, Warning{Field: "Stream", Endpoint: endpoint, Reason: reason})
+	}
+}
+
+// newPayloadJoiner returns a payloadJoiner for a stream of endpoint that
+// reports each reassembly as a Warning.
+func (c *Client) newPayloadJoiner(ctx context.Context, endpoint EndpointType) *payloadJoiner {
+	return &payloadJoiner{joined: func(parts int) {
+		c.streamWarning(ctx, endpoint, fmt.Sprintf("reassembled a JSON payload split across %d events", parts))
+	}}
+}
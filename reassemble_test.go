@@ -0,0 +1,63 @@
+package zen
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestStreamReassemblesSyntheticSplitPayload uses a hand-written stream with
+// one Gemini chunk cut across two data events, the way the gateway has been
+// seen to split them.
+func TestStreamReassemblesSyntheticSplitPayload(t *testing.T) {
+	sse, err := os.ReadFile("testdata/models/synthetic_split_chunk.sse")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	server, _ := newSSETestServer(t, string(sse))
+	defer server.Close()
+	var warnings []Warning
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, OnWarning: func(_ context.Context, w Warning) {
+		warnings = append(warnings, w)
+	}})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	resp, err := client.CollectStream(testCtx(t), NormalizedRequest{Model: "gemini-3-pro", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	if !strings.Contains(resp.Text, "the second half of this sentence") {
+		t.Fatalf("split chunk lost: %q", resp.Text)
+	}
+	if len(warnings) != 1 || warnings[0].Field != "Stream" || warnings[0].Endpoint != EndpointModels {
+		t.Fatalf("expected one stream warning, got %+v", warnings)
+	}
+}
+
+func TestPayloadJoinerGivesUp(t *testing.T) {
+	var j payloadJoiner
+	event := func(data string) UnifiedEvent {
+		return UnifiedEvent{Endpoint: EndpointModels, Data: []byte(data)}
+	}
+	if out := j.add(event(`{"candidates":[{"content":{"parts":[{"text":"cut`)); len(out) != 0 {
+		t.Fatalf("truncated payload should be held, got %d events", len(out))
+	}
+	// A complete payload instead of the rest: both are passed on as is.
+	out := j.add(event(`{"candidates":[]}`))
+	if len(out) != 2 || string(out[1].Data) != `{"candidates":[]}` {
+		t.Fatalf("expected the held and the new event, got %+v", out)
+	}
+	if out := j.add(event(`not json`)); len(out) != 1 {
+		t.Fatalf("invalid payloads are not held, got %+v", out)
+	}
+
+	j.add(event(`{"text":"` + strings.Repeat("x", maxReassembledPayloadBytes-20)))
+	if out := j.add(event(strings.Repeat("x", 100))); len(out) != 2 {
+		t.Fatalf("payloads over the limit should be given up, got %d events", len(out))
+	}
+	if out := j.flush(); len(out) != 0 {
+		t.Fatalf("nothing should be held, got %+v", out)
+	}
+}
@@ -0,0 +1,222 @@
+package zen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RedactionPolicy selects what RedactRequest, RedactResponse and
+// RedactDelta keep. The zero value redacts all message content, tool
+// arguments and tool results, replacing each with its length.
+type RedactionPolicy struct {
+	// KeepSystem keeps the system prompt and system/developer messages.
+	KeepSystem bool
+	// KeepRoles lists further message roles whose content is kept, e.g.
+	// "assistant". Tool results have role "tool".
+	KeepRoles []string
+	// IncludeHash adds a truncated SHA-256 of the redacted content, so equal
+	// content can be correlated across log lines. Short content can be
+	// recovered from its hash by guessing; leave it off for such data.
+	IncludeHash bool
+}
+
+func (p RedactionPolicy) keeps(role string) bool {
+	if p.KeepSystem && (role == "system" || role == "developer") {
+		return true
+	}
+	for _, r := range p.KeepRoles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RedactionPolicy) text(s string) string {
+	if s == "" {
+		return ""
+	}
+	if p.IncludeHash {
+		sum := sha256.Sum256([]byte(s))
+		return fmt.Sprintf("[redacted %d bytes sha256:%s]", len(s), hex.EncodeToString(sum[:6]))
+	}
+	return fmt.Sprintf("[redacted %d bytes]", len(s))
+}
+
+// arguments replaces tool arguments with a JSON string describing them, so
+// the result is still valid JSON.
+func (p RedactionPolicy) arguments(args json.RawMessage) json.RawMessage {
+	if len(args) == 0 {
+		return args
+	}
+	encoded, _ := json.Marshal(p.text(string(args)))
+	return encoded
+}
+
+// RedactRequest returns a copy of req safe for structural logging: message
+// content, file references, tool-call arguments and tool results are
+// replaced by their length (and optionally a hash) unless policy keeps the
+// message's role. Roles, tool names and IDs, message and tool counts, tool
+// definitions and request parameters are preserved. req is not modified.
+func RedactRequest(req NormalizedRequest, policy RedactionPolicy) NormalizedRequest {
+	if !policy.KeepSystem {
+		req.System = policy.text(req.System)
+		if req.SystemParts != nil {
+			parts := make([]SystemPart, len(req.SystemParts))
+			for i, p := range req.SystemParts {
+				parts[i] = SystemPart{Text: policy.text(p.Text), Cache: p.Cache}
+			}
+			req.SystemParts = parts
+		}
+	}
+	if req.Messages == nil {
+		return req
+	}
+	msgs := make([]NormalizedMessage, len(req.Messages))
+	for i, msg := range req.Messages {
+		if policy.keeps(msg.Role) {
+			msgs[i] = msg
+			continue
+		}
+		msg.Content = policy.text(msg.Content)
+		msg.ResultJSON = policy.arguments(msg.ResultJSON)
+		if msg.Parts != nil {
+			parts := make([]NormalizedContentPart, len(msg.Parts))
+			for j, part := range msg.Parts {
+				part.Text = policy.text(part.Text)
+				part.FileURI = policy.text(part.FileURI)
+				part.AudioData = policy.text(part.AudioData)
+				part.Raw = policy.arguments(part.Raw)
+				parts[j] = part
+			}
+			msg.Parts = parts
+		}
+		msg.ReasoningBlocks = policy.reasoningBlocks(msg.ReasoningBlocks)
+		if msg.ToolCalls != nil {
+			calls := make([]NormalizedToolCall, len(msg.ToolCalls))
+			for j, call := range msg.ToolCalls {
+				call.Arguments = policy.arguments(call.Arguments)
+				calls[j] = call
+			}
+			msg.ToolCalls = calls
+		}
+		msgs[i] = msg
+	}
+	req.Messages = msgs
+	return req
+}
+
+// RedactResponse returns a copy of resp with text, reasoning and tool-call
+// arguments redacted, those of every choice included, unless policy keeps the "assistant" role. Info.Body,
+// the raw request payload, is always dropped.
+func RedactResponse(resp *NormalizedResponse, policy RedactionPolicy) *NormalizedResponse {
+	if resp == nil {
+		return nil
+	}
+	out := *resp
+	out.Info.Body = nil
+	if policy.keeps("assistant") {
+		return &out
+	}
+	out.Text = policy.text(out.Text)
+	out.Reasoning = policy.text(out.Reasoning)
+	out.ReasoningBlocks = policy.reasoningBlocks(out.ReasoningBlocks)
+	if out.Parts != nil {
+		parts := make([]NormalizedContentPart, len(out.Parts))
+		for i, part := range out.Parts {
+			part.Text = policy.text(part.Text)
+			part.Raw = policy.arguments(part.Raw)
+			parts[i] = part
+		}
+		out.Parts = parts
+	}
+	out.ToolCalls = policy.streamToolCalls(out.ToolCalls)
+	if out.Choices != nil {
+		choices := make([]NormalizedChoice, len(out.Choices))
+		for i, choice := range out.Choices {
+			choice.Text = policy.text(choice.Text)
+			choice.Reasoning = policy.text(choice.Reasoning)
+			choice.ToolCalls = policy.streamToolCalls(choice.ToolCalls)
+			choices[i] = choice
+		}
+		out.Choices = choices
+	}
+	if out.ServerToolCalls != nil {
+		calls := make([]ServerToolCall, len(out.ServerToolCalls))
+		for i, call := range out.ServerToolCalls {
+			calls[i] = policy.serverToolCall(call)
+		}
+		out.ServerToolCalls = calls
+	}
+	if out.Audio != nil {
+		audio := *out.Audio
+		if len(audio.Data) > 0 {
+			audio.Data = []byte(policy.text(string(audio.Data)))
+		}
+		audio.Transcript = policy.text(audio.Transcript)
+		out.Audio = &audio
+	}
+	return &out
+}
+
+// RedactDelta returns d with its content and argument fragments redacted,
+// unless policy keeps the "assistant" role.
+func RedactDelta(d NormalizedDelta, policy RedactionPolicy) NormalizedDelta {
+	if policy.keeps("assistant") {
+		return d
+	}
+	d.Content = policy.text(d.Content)
+	d.ArgumentsDelta = policy.text(d.ArgumentsDelta)
+	d.ArgumentsFull = policy.text(d.ArgumentsFull)
+	d.AudioData = policy.text(d.AudioData)
+	d.AudioTranscript = policy.text(d.AudioTranscript)
+	d.Part = policy.arguments(d.Part)
+	if d.ServerToolCall != nil {
+		call := policy.serverToolCall(*d.ServerToolCall)
+		d.ServerToolCall = &call
+	}
+	return d
+}
+
+// streamToolCalls redacts the arguments of tool calls in a response.
+func (p RedactionPolicy) streamToolCalls(calls []StreamToolCall) []StreamToolCall {
+	if calls == nil {
+		return nil
+	}
+	out := make([]StreamToolCall, len(calls))
+	for i, call := range calls {
+		call.Arguments = p.arguments(call.Arguments)
+		out[i] = call
+	}
+	return out
+}
+
+// serverToolCall redacts the queries and results of a server tool call.
+func (p RedactionPolicy) serverToolCall(call ServerToolCall) ServerToolCall {
+	if call.Queries != nil {
+		queries := make([]string, len(call.Queries))
+		for i, q := range call.Queries {
+			queries[i] = p.text(q)
+		}
+		call.Queries = queries
+	}
+	call.Results = p.arguments(call.Results)
+	return call
+}
+
+// reasoningBlocks redacts the text of reasoning blocks. Signatures and
+// redacted data are opaque to the caller and kept.
+func (p RedactionPolicy) reasoningBlocks(blocks []ReasoningBlock) []ReasoningBlock {
+	if blocks == nil {
+		return nil
+	}
+	out := make([]ReasoningBlock, len(blocks))
+	for i, block := range blocks {
+		block.Text = p.text(block.Text)
+		out[i] = block
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func redactionFixture() NormalizedRequest {
+	return NormalizedRequest{
+		Model:  "claude-sonnet-4-6",
+		System: "You are helpful.",
+		Messages: []NormalizedMessage{
+			{Role: "user", Content: "my card is 4111"},
+			{Role: "assistant", ToolCalls: []NormalizedToolCall{{ID: "c1", Name: "lookup", Arguments: json.RawMessage(`{"card":"4111"}`)}}, ReasoningBlocks: []ReasoningBlock{{Text: "card 4111", Signature: "sig"}}},
+			{Role: "tool", ToolCallID: "c1", FunctionName: "lookup", Content: "balance 12"},
+			{Role: "user", Parts: []NormalizedContentPart{{Type: ContentPartFile, FileURI: "files/secret", MediaType: "image/png"}}},
+		},
+		Tools: []NormalizedTool{{Name: "lookup", Parameters: json.RawMessage(`{"type":"object","properties":{"card":{"type":"string"}}}`)}},
+	}
+}
+
+func TestRedactRequest(t *testing.T) {
+	req := redactionFixture()
+	got := RedactRequest(req, RedactionPolicy{KeepSystem: true})
+
+	if got.System != req.System {
+		t.Fatalf("system prompt should be kept, got %q", got.System)
+	}
+	if got.Messages[0].Content != "[redacted 15 bytes]" {
+		t.Fatalf("user content should be redacted, got %q", got.Messages[0].Content)
+	}
+	call := got.Messages[1].ToolCalls[0]
+	if call.Name != "lookup" || call.ID != "c1" || string(call.Arguments) != `"[redacted 15 bytes]"` {
+		t.Fatalf("tool call mismatch: %+v", call)
+	}
+	if block := got.Messages[1].ReasoningBlocks[0]; block.Text != "[redacted 9 bytes]" || block.Signature != "sig" {
+		t.Fatalf("reasoning block mismatch: %+v", block)
+	}
+	if m := got.Messages[2]; m.Content != "[redacted 10 bytes]" || m.FunctionName != "lookup" || m.Role != "tool" {
+		t.Fatalf("tool result mismatch: %+v", m)
+	}
+	if part := got.Messages[3].Parts[0]; part.FileURI != "[redacted 12 bytes]" || part.MediaType != "image/png" {
+		t.Fatalf("file part mismatch: %+v", part)
+	}
+	if string(got.Tools[0].Parameters) != string(req.Tools[0].Parameters) {
+		t.Fatalf("tool schemas should be kept")
+	}
+
+	if req.Messages[0].Content != "my card is 4111" || string(req.Messages[1].ToolCalls[0].Arguments) != `{"card":"4111"}` || req.Messages[3].Parts[0].FileURI != "files/secret" {
+		t.Fatalf("input request must not be modified")
+	}
+	if _, err := got.ToGeminiRequest(); err != nil {
+		t.Fatalf("redacted request should still convert: %v", err)
+	}
+
+	hashed := RedactRequest(req, RedactionPolicy{IncludeHash: true, KeepRoles: []string{"tool"}})
+	if !strings.HasPrefix(hashed.System, "[redacted 16 bytes sha256:") {
+		t.Fatalf("system should be redacted with a hash, got %q", hashed.System)
+	}
+	if hashed.Messages[2].Content != "balance 12" {
+		t.Fatalf("kept roles should not be redacted")
+	}
+	if again := RedactRequest(req, RedactionPolicy{IncludeHash: true}); again.Messages[0].Content != hashed.Messages[0].Content {
+		t.Fatalf("hashes should be stable")
+	}
+}
+
+func TestRedactResponseAndDelta(t *testing.T) {
+	resp := &NormalizedResponse{
+		Text:            "secret answer",
+		Reasoning:       "thinking",
+		ReasoningBlocks: []ReasoningBlock{{Text: "thinking"}},
+		Parts:           []NormalizedContentPart{{Type: ContentPartGemini, Raw: json.RawMessage(`{"executableCode":{}}`)}},
+		ToolCalls:       []StreamToolCall{{ID: "c1", Name: "lookup", Arguments: json.RawMessage(`{}`)}},
+		Info:            StreamInfo{Body: []byte(`{"messages":[]}`), Endpoint: EndpointMessages},
+		Choices: []NormalizedChoice{
+			{Text: "secret answer", Reasoning: "thinking", ToolCalls: []StreamToolCall{{ID: "c1", Name: "lookup", Arguments: json.RawMessage(`{}`)}}},
+			{Index: 1, Text: "other answer", FinishReason: "stop"},
+		},
+	}
+	got := RedactResponse(resp, RedactionPolicy{})
+	if got.Text != "[redacted 13 bytes]" || got.Reasoning != "[redacted 8 bytes]" || string(got.ToolCalls[0].Arguments) != `"[redacted 2 bytes]"` {
+		t.Fatalf("response not redacted: %+v", got)
+	}
+	if got.ReasoningBlocks[0].Text != "[redacted 8 bytes]" || resp.ReasoningBlocks[0].Text != "thinking" {
+		t.Fatalf("reasoning blocks not redacted: %+v", got)
+	}
+	if string(got.Parts[0].Raw) != `"[redacted 21 bytes]"` || string(resp.Parts[0].Raw) != `{"executableCode":{}}` {
+		t.Fatalf("parts not redacted: %+v", got)
+	}
+	if c := got.Choices; c[0].Text != "[redacted 13 bytes]" || c[0].Reasoning != "[redacted 8 bytes]" || string(c[0].ToolCalls[0].Arguments) != `"[redacted 2 bytes]"` ||
+		c[1].Text != "[redacted 12 bytes]" || c[1].Index != 1 || c[1].FinishReason != "stop" {
+		t.Fatalf("choices not redacted: %+v", got.Choices)
+	}
+	if resp.Choices[1].Text != "other answer" || string(resp.Choices[0].ToolCalls[0].Arguments) != `{}` {
+		t.Fatalf("input choices must not be modified")
+	}
+	if got.Info.Body != nil || got.Info.Endpoint != EndpointMessages {
+		t.Fatalf("info body should be dropped: %+v", got.Info)
+	}
+	if resp.Text != "secret answer" || string(resp.ToolCalls[0].Arguments) != `{}` {
+		t.Fatalf("input response must not be modified")
+	}
+
+	d := RedactDelta(NormalizedDelta{Type: DeltaText, Content: "hello"}, RedactionPolicy{})
+	if d.Content != "[redacted 5 bytes]" || d.Type != DeltaText {
+		t.Fatalf("delta mismatch: %+v", d)
+	}
+	if kept := RedactDelta(NormalizedDelta{Type: DeltaText, Content: "hello"}, RedactionPolicy{KeepRoles: []string{"assistant"}}); kept.Content != "hello" {
+		t.Fatalf("assistant output should be kept when the role is kept")
+	}
+}
+
+func TestLogRequestRedaction(t *testing.T) {
+	server, client := newSSETestServer(t, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	defer server.Close()
+
+	var logged []NormalizedRequest
+	client = client.With(func(cfg *Config) {
+		cfg.LogRequest = func(_ context.Context, endpoint EndpointType, req NormalizedRequest) {
+			if endpoint != EndpointMessages {
+				t.Errorf("unexpected endpoint %q", endpoint)
+			}
+			logged = append(logged, req)
+		}
+		cfg.LogRedaction = &RedactionPolicy{KeepSystem: true}
+	})
+
+	req := redactionFixture()
+	req.Messages = req.Messages[:3]
+	if _, err := client.CollectStream(testCtx(t), req); err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	if len(logged) != 1 {
+		t.Fatalf("expected one logged request, got %d", len(logged))
+	}
+	if logged[0].Messages[0].Content != "[redacted 15 bytes]" || logged[0].System != "You are helpful." || !logged[0].Stream {
+		t.Fatalf("logged request mismatch: %+v", logged[0])
+	}
+}
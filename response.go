@@ -0,0 +1,321 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// CreateNormalized sends req as a non-streaming request to the endpoint it
+// routes to and returns the response as a NormalizedResponse, assembled the
+// same way CollectStream assembles a stream. With Config.ForceStream or
+// req.ForceStream set the request is sent through CollectStream instead.
+func (c *Client) CreateNormalized(ctx context.Context, req NormalizedRequest) (*NormalizedResponse, error) {
+	return c.withToolChoiceEmulation(ctx, req, c.createNormalized)
+}
+
+func (c *Client) createNormalized(ctx context.Context, req NormalizedRequest) (*NormalizedResponse, error) {
+	if c.forceStream(req) {
+		return c.collectStream(ctx, req)
+	}
+	ctx, endpoint, path, payload, err := c.buildRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	body, header, err := c.doRequest(ctx, "POST", path, payload, endpoint, false)
+	if err != nil {
+		return nil, err
+	}
+	deltas, err := responseDeltas(endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	rc := newResponseCollector()
+	rc.suppressReasoning = req.SuppressReasoning
+	for _, d := range deltas {
+		d, keep, err := c.filterDelta(d)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			rc.add(d)
+		}
+	}
+	resp := rc.response(false)
+	if err := c.checkServedModel(ctx, endpoint, req.Model, resp.Model); err != nil {
+		return nil, err
+	}
+	resp.Info = StreamInfo{
+		Endpoint:  endpoint,
+		Path:      path,
+		Body:      payload,
+		Header:    header,
+		RequestID: requestIDFromHeader(header),
+	}
+	resp.Stats = StreamStats{RequestBytes: len(payload), ResponseBytes: int64(len(body))}
+	return resp, nil
+}
+
+func (c *Client) forceStream(req NormalizedRequest) bool {
+	return c.cfg.ForceStream || req.ForceStream
+}
+
+// ParseNormalizedResponse converts a provider's non-streaming response body
+// into a NormalizedResponse. Content is taken in the order the provider
+// returned it: text and reasoning blocks are concatenated and tool calls keep
+// their position among them in ToolCalls. Tool-call arguments are kept byte
+// for byte as the provider sent them.
+func ParseNormalizedResponse(endpoint EndpointType, body []byte) (*NormalizedResponse, error) {
+	deltas, err := responseDeltas(endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	rc := newResponseCollector()
+	for _, d := range deltas {
+		rc.add(d)
+	}
+	return rc.response(false), nil
+}
+
+// responseDeltas converts a non-streaming response body into the deltas a
+// stream of the same response would carry.
+func responseDeltas(endpoint EndpointType, body []byte) ([]NormalizedDelta, error) {
+	var (
+		deltas []NormalizedDelta
+		err    error
+	)
+	switch endpoint {
+	case EndpointMessages:
+		deltas, err = messagesResponseDeltas(body)
+	case EndpointChatCompletions:
+		deltas, err = chatCompletionResponseDeltas(body)
+	case EndpointResponses:
+		deltas, err = responsesResponseDeltas(body)
+	case EndpointModels:
+		if !json.Valid(body) {
+			return nil, errors.New("zen: invalid models response body")
+		}
+		// A generateContent body has the shape of one streamed chunk.
+		deltas = ParseNormalizedEvent(UnifiedEvent{Endpoint: EndpointModels, Data: body})
+	default:
+		return nil, errors.New("zen: unsupported endpoint")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range deltas {
+		deltas[i].StopReason = NormalizeStopReason(deltas[i].FinishReason)
+	}
+	return deltas, nil
+}
+
+type messagesResponseBody struct {
+	Content []struct {
+		Type     string          `json:"type"`
+		Text     string          `json:"text"`
+		Thinking string          `json:"thinking"`
+		ID       string          `json:"id"`
+		Name     string          `json:"name"`
+		Input    json.RawMessage `json:"input"`
+	} `json:"content"`
+	Model        string `json:"model"`
+	StopReason   string `json:"stop_reason"`
+	StopSequence string `json:"stop_sequence"`
+	Usage        *struct {
+		anthropicInputUsage
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func messagesResponseDeltas(body []byte) ([]NormalizedDelta, error) {
+	var msg messagesResponseBody
+	if err := jsonUnmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("zen: decode messages response: %w", err)
+	}
+	var out []NormalizedDelta
+	for i, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			out = append(out, NormalizedDelta{Type: DeltaText, Content: block.Text, BlockIndex: i})
+		case "thinking":
+			out = append(out, NormalizedDelta{Type: DeltaReasoning, Content: block.Thinking, BlockIndex: i})
+		case "tool_use":
+			out = append(out,
+				NormalizedDelta{Type: DeltaToolCallBegin, ToolCallIndex: i, BlockIndex: i, ToolCallID: block.ID, ToolCallName: block.Name},
+				NormalizedDelta{Type: DeltaToolCallDone, ToolCallIndex: i, BlockIndex: i, ToolCallID: block.ID, ToolCallName: block.Name, ArgumentsFull: string(block.Input)},
+			)
+		}
+	}
+	if msg.Usage != nil {
+		d := msg.Usage.delta()
+		d.OutputTokens = msg.Usage.OutputTokens
+		out = append(out, d)
+	}
+	out = append(out, NormalizedDelta{Type: DeltaDone, FinishReason: msg.StopReason, StopSequence: msg.StopSequence})
+	for i := range out {
+		out[i].Model = msg.Model
+	}
+	return out, nil
+}
+
+type chatCompletionResponseBody struct {
+	Choices []struct {
+		Message struct {
+			Content          chatContent `json:"content"`
+			ReasoningContent string      `json:"reasoning_content"`
+			Reasoning        string      `json:"reasoning"`
+			Audio            *chatAudio  `json:"audio"`
+			ToolCalls        []struct {
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	ServiceTier string `json:"service_tier"`
+	Model       string `json:"model"`
+	Usage       *struct {
+		PromptTokens        int `json:"prompt_tokens"`
+		CompletionTokens    int `json:"completion_tokens"`
+		PromptTokensDetails *struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+		CompletionTokensDetails *struct {
+			ReasoningTokens int `json:"reasoning_tokens"`
+		} `json:"completion_tokens_details"`
+	} `json:"usage"`
+}
+
+func chatCompletionResponseDeltas(body []byte) ([]NormalizedDelta, error) {
+	var resp chatCompletionResponseBody
+	if err := jsonUnmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("zen: decode chat completion response: %w", err)
+	}
+	var out []NormalizedDelta
+	finish := ""
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		finish = choice.FinishReason
+		for _, reasoning := range []string{choice.Message.ReasoningContent, choice.Message.Reasoning} {
+			if reasoning != "" {
+				out = append(out, NormalizedDelta{Type: DeltaReasoning, Content: reasoning})
+			}
+		}
+		if text := choice.Message.Content.text(); text != "" {
+			out = append(out, NormalizedDelta{Type: DeltaText, Content: text})
+		}
+		if d, ok := choice.Message.Audio.delta(); ok {
+			out = append(out, d)
+		}
+		for i, tc := range choice.Message.ToolCalls {
+			out = append(out,
+				NormalizedDelta{Type: DeltaToolCallBegin, ToolCallIndex: i, ToolCallID: tc.ID, ToolCallName: tc.Function.Name},
+				NormalizedDelta{Type: DeltaToolCallDone, ToolCallIndex: i, ToolCallID: tc.ID, ToolCallName: tc.Function.Name, ArgumentsFull: tc.Function.Arguments},
+			)
+		}
+	}
+	if u := resp.Usage; u != nil {
+		d := NormalizedDelta{Type: DeltaUsage, InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
+		if u.PromptTokensDetails != nil {
+			d.CachedInputTokens = u.PromptTokensDetails.CachedTokens
+		}
+		if u.CompletionTokensDetails != nil {
+			d.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+		}
+		out = append(out, d)
+	}
+	out = append(out, NormalizedDelta{Type: DeltaDone, FinishReason: finish})
+	for i := range out {
+		out[i].ServiceTier = resp.ServiceTier
+		out[i].Model = resp.Model
+	}
+	return out, nil
+}
+
+type responsesResponseBody struct {
+	Output []struct {
+		Type    string `json:"type"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Summary []struct {
+			Text string `json:"text"`
+		} `json:"summary"`
+		EncryptedContent string `json:"encrypted_content"`
+		ID               string `json:"id"`
+		CallID           string `json:"call_id"`
+		Name             string `json:"name"`
+		Arguments        string `json:"arguments"`
+		responsesServerToolItem
+	} `json:"output"`
+	ServiceTier string `json:"service_tier"`
+	Model       string `json:"model"`
+	Usage       *struct {
+		InputTokens        int `json:"input_tokens"`
+		OutputTokens       int `json:"output_tokens"`
+		InputTokensDetails *struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"input_tokens_details"`
+		OutputTokensDetails *struct {
+			ReasoningTokens int `json:"reasoning_tokens"`
+		} `json:"output_tokens_details"`
+	} `json:"usage"`
+}
+
+func responsesResponseDeltas(body []byte) ([]NormalizedDelta, error) {
+	var resp responsesResponseBody
+	if err := jsonUnmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("zen: decode responses response: %w", err)
+	}
+	var out []NormalizedDelta
+	for i, item := range resp.Output {
+		switch item.Type {
+		case "message":
+			for _, c := range item.Content {
+				if c.Type == "output_text" {
+					out = append(out, NormalizedDelta{Type: DeltaText, Content: c.Text, BlockIndex: i})
+				}
+			}
+		case "reasoning":
+			for _, s := range item.Summary {
+				out = append(out, NormalizedDelta{Type: DeltaReasoning, Content: s.Text, BlockIndex: i})
+			}
+			if item.EncryptedContent != "" {
+				var raw struct {
+					Output []json.RawMessage `json:"output"`
+				}
+				if err := jsonUnmarshal(body, &raw); err == nil && i < len(raw.Output) {
+					out = append(out, NormalizedDelta{Type: DeltaProviderState, BlockIndex: i, Part: raw.Output[i]})
+				}
+			}
+		case "function_call":
+			out = append(out,
+				NormalizedDelta{Type: DeltaToolCallBegin, ToolCallIndex: i, BlockIndex: i, ToolCallID: item.CallID, ToolCallName: item.Name},
+				NormalizedDelta{Type: DeltaToolCallDone, ToolCallIndex: i, BlockIndex: i, ToolCallID: item.CallID, ToolCallName: item.Name, ArgumentsFull: item.Arguments},
+			)
+		case "web_search_call", "file_search_call":
+			out = append(out, NormalizedDelta{Type: DeltaServerToolCall, ToolCallIndex: i, BlockIndex: i, ServerToolCall: item.call(item.ID, item.Type)})
+		}
+	}
+	if u := resp.Usage; u != nil {
+		d := NormalizedDelta{Type: DeltaUsage, InputTokens: u.InputTokens, OutputTokens: u.OutputTokens}
+		if u.InputTokensDetails != nil {
+			d.CachedInputTokens = u.InputTokensDetails.CachedTokens
+		}
+		if u.OutputTokensDetails != nil {
+			d.ReasoningTokens = u.OutputTokensDetails.ReasoningTokens
+		}
+		out = append(out, d)
+	}
+	out = append(out, NormalizedDelta{Type: DeltaDone})
+	for i := range out {
+		out[i].ServiceTier = resp.ServiceTier
+		out[i].Model = resp.Model
+	}
+	return out, nil
+}
@@ -0,0 +1,214 @@
+package zen
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const messagesToolUseFixture = `{
+  "id": "msg_01",
+  "type": "message",
+  "role": "assistant",
+  "content": [
+    {"type": "thinking", "thinking": "Two lookups needed.", "signature": "sig"},
+    {"type": "text", "text": "Checking Paris. "},
+    {"type": "tool_use", "id": "toolu_01", "name": "get_weather", "input": {"city": "Paris",  "units": ["c", 1.50]}},
+    {"type": "text", "text": "Then Rome."},
+    {"type": "tool_use", "id": "toolu_02", "name": "get_weather", "input": {"z": true, "a": {}}}
+  ],
+  "stop_reason": "tool_use",
+  "stop_sequence": null,
+  "usage": {"input_tokens": 312, "output_tokens": 88}
+}`
+
+func TestParseMessagesResponseToolUse(t *testing.T) {
+	resp, err := ParseNormalizedResponse(EndpointMessages, []byte(messagesToolUseFixture))
+	if err != nil {
+		t.Fatalf("ParseNormalizedResponse: %v", err)
+	}
+	if resp.Text != "Checking Paris. Then Rome." || resp.Reasoning != "Two lookups needed." {
+		t.Fatalf("content mismatch: text %q reasoning %q", resp.Text, resp.Reasoning)
+	}
+	if len(resp.ToolCalls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %+v", resp.ToolCalls)
+	}
+	first, second := resp.ToolCalls[0], resp.ToolCalls[1]
+	if first.ID != "toolu_01" || first.Name != "get_weather" || string(first.Arguments) != `{"city": "Paris",  "units": ["c", 1.50]}` {
+		t.Fatalf("first call should keep its input byte for byte: %+v (%s)", first, first.Arguments)
+	}
+	if second.ID != "toolu_02" || string(second.Arguments) != `{"z": true, "a": {}}` {
+		t.Fatalf("second call mismatch: %+v (%s)", second, second.Arguments)
+	}
+	if resp.InputTokens != 312 || resp.OutputTokens != 88 || resp.FinishReason != "tool_use" || resp.StopSequence != "" {
+		t.Fatalf("metadata mismatch: %+v", resp)
+	}
+}
+
+func TestParseMessagesResponseStopSequence(t *testing.T) {
+	body := `{"content":[{"type":"text","text":"answer"}],"stop_reason":"stop_sequence","stop_sequence":"[END]","usage":{"input_tokens":5,"output_tokens":2}}`
+	resp, err := ParseNormalizedResponse(EndpointMessages, []byte(body))
+	if err != nil {
+		t.Fatalf("ParseNormalizedResponse: %v", err)
+	}
+	if resp.FinishReason != "stop_sequence" || resp.StopSequence != "[END]" || resp.ToolCalls != nil {
+		t.Fatalf("stop details mismatch: %+v", resp)
+	}
+}
+
+func TestParseNormalizedResponseOtherEndpoints(t *testing.T) {
+	chat := `{"choices":[{"message":{"role":"assistant","content":null,"reasoning_content":"hmm","tool_calls":[{"id":"call_1","type":"function","function":{"name":"add","arguments":"{\"a\":1}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":10,"completion_tokens":4,"completion_tokens_details":{"reasoning_tokens":2}}}`
+	resp, err := ParseNormalizedResponse(EndpointChatCompletions, []byte(chat))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if resp.Reasoning != "hmm" || len(resp.ToolCalls) != 1 || string(resp.ToolCalls[0].Arguments) != `{"a":1}` || resp.ReasoningTokens != 2 || resp.FinishReason != "tool_calls" {
+		t.Fatalf("chat response mismatch: %+v", resp)
+	}
+
+	responses := `{"output":[{"type":"reasoning","summary":[{"type":"summary_text","text":"plan"}],"encrypted_content":"gAAAA"},{"type":"message","content":[{"type":"output_text","text":"hi"}]},{"type":"function_call","call_id":"call_2","name":"add","arguments":"{}"}],"usage":{"input_tokens":7,"output_tokens":3}}`
+	resp, err = ParseNormalizedResponse(EndpointResponses, []byte(responses))
+	if err != nil {
+		t.Fatalf("responses: %v", err)
+	}
+	if resp.Reasoning != "plan" || resp.Text != "hi" || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_2" || resp.InputTokens != 7 ||
+		len(resp.ProviderState) != 1 || !strings.Contains(string(resp.ProviderState[0]), `"encrypted_content":"gAAAA"`) {
+		t.Fatalf("responses response mismatch: %+v", resp)
+	}
+
+	gemini := `{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":1}}`
+	resp, err = ParseNormalizedResponse(EndpointModels, []byte(gemini))
+	if err != nil {
+		t.Fatalf("gemini: %v", err)
+	}
+	if resp.Text != "ok" || resp.InputTokens != 4 {
+		t.Fatalf("gemini response mismatch: %+v", resp)
+	}
+
+	if _, err := ParseNormalizedResponse(EndpointMessages, []byte("not json")); err == nil {
+		t.Fatalf("invalid bodies should fail")
+	}
+}
+
+func TestChatArrayContent(t *testing.T) {
+	body := `{"choices":[{"message":{"role":"assistant","content":[{"type":"text","text":"Hello, "},{"type":"text","text":"world"}]},"finish_reason":"stop"}]}`
+	resp, err := ParseNormalizedResponse(EndpointChatCompletions, []byte(body))
+	if err != nil {
+		t.Fatalf("ParseNormalizedResponse: %v", err)
+	}
+	if resp.Text != "Hello, world" {
+		t.Fatalf("array content should be flattened, got %q", resp.Text)
+	}
+
+	deltas := ParseNormalizedEvent(makeEvent(EndpointChatCompletions, `{"choices":[{"delta":{"content":[{"type":"text","text":"Hi"}]}}]}`))
+	if len(deltas) != 1 || deltas[0].Type != DeltaText || deltas[0].Content != "Hi" {
+		t.Fatalf("array delta content should be flattened, got %+v", deltas)
+	}
+
+	for _, raw := range []string{
+		`{"role":"user","content":"plain"}`,
+		`{"role":"user","content":[{"type":"text","text":"a"},{"type":"input_audio","input_audio":{"data":"AAEC","format":"wav"}}]}`,
+	} {
+		var msg ChatMessage
+		if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+			t.Fatalf("Unmarshal %s: %v", raw, err)
+		}
+		out, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if string(out) != raw {
+			t.Fatalf("round trip changed the message:\nwant %s\ngot  %s", raw, out)
+		}
+	}
+	var msg ChatMessage
+	if err := json.Unmarshal([]byte(`{"role":"user","content":{"text":"x"}}`), &msg); err == nil {
+		t.Fatalf("object content should be rejected")
+	}
+}
+
+func TestCreateNormalized(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		body = string(payload)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("request-id", "req_9")
+		_, _ = io.WriteString(w, messagesToolUseFixture)
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	resp, err := client.CreateNormalized(testCtx(t), NormalizedRequest{
+		Model:    "claude-sonnet-4-6",
+		Messages: []NormalizedMessage{{Role: "user", Content: "weather?"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateNormalized: %v", err)
+	}
+	if strings.Contains(body, `"stream"`) {
+		t.Fatalf("request should not stream, got %s", body)
+	}
+	if len(resp.ToolCalls) != 2 || resp.Info.Endpoint != EndpointMessages || resp.Info.RequestID != "req_9" {
+		t.Fatalf("response mismatch: %+v", resp)
+	}
+}
+
+func TestCreateNormalizedForceStream(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		body = string(payload)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"id\":\"chatcmpl-1\",\"model\":\"kimi-k2\",\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n"+
+			"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n"+
+			"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n"+
+			"data: [DONE]\n\n")
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}, ForceStream: true}
+
+	resp, err := client.CreateNormalized(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("CreateNormalized: %v", err)
+	}
+	if !strings.Contains(body, `"stream":true`) {
+		t.Fatalf("request should stream, got %s", body)
+	}
+	if resp.Text != "Hello" || resp.InputTokens != 3 || resp.Info.Endpoint != EndpointChatCompletions {
+		t.Fatalf("response mismatch: %+v", resp)
+	}
+
+	req.ForceStream = false
+	client = client.With(func(cfg *Config) { cfg.ForceStream = true })
+	var completion struct {
+		Object  string `json:"object"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := client.CreateNormalizedInto(testCtx(t), req, &completion); err != nil {
+		t.Fatalf("CreateNormalizedInto: %v", err)
+	}
+	if completion.Object != "chat.completion" || completion.Model != "kimi-k2" || len(completion.Choices) != 1 ||
+		completion.Choices[0].Message.Content != "Hello" || completion.Choices[0].FinishReason != "stop" || completion.Usage.TotalTokens != 5 {
+		t.Fatalf("assembled completion mismatch: %+v", completion)
+	}
+}
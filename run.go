@@ -0,0 +1,219 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const defaultRunMaxSteps = 10
+
+// ErrMaxSteps is returned by RunTools when the model still requests tools
+// after RunOptions.MaxSteps steps.
+var ErrMaxSteps = errors.New("zen: run exceeded maximum steps")
+
+// RunOptions controls RunTools.
+type RunOptions struct {
+	// MaxSteps bounds the number of model calls (default 10).
+	MaxSteps int
+
+	// MaxReasoningTokens bounds the reasoning tokens spent across all steps
+	// (0 = unlimited). As the budget depletes, each step's reasoning effort
+	// and budget are lowered to fit what remains; once it is spent the run
+	// stops with a *ReasoningBudgetError before the next step. Providers that
+	// do not report reasoning usage (Anthropic) are charged an estimate of
+	// one token per four bytes of reasoning text.
+	MaxReasoningTokens int
+
+	// MaxCost bounds the EstimatedCost of the run in USD (0 = unlimited).
+	// Before each step, the run stops with a *CostBudgetError when the
+	// cost so far plus the estimated cost of the step's input would exceed
+	// it. Steps on models without configured prices cost nothing.
+	MaxCost float64
+}
+
+// RunResult is the outcome of RunTools. It is returned alongside errors
+// raised after the first step, so the run can be inspected or resumed.
+type RunResult struct {
+	// Messages is the conversation: the request's messages followed by
+	// every assistant message and tool result of the run.
+	Messages []NormalizedMessage
+	// Response is the last model response.
+	Response *NormalizedResponse
+	Steps    int
+
+	InputTokens     int
+	OutputTokens    int
+	ReasoningTokens int
+
+	// TotalUsage is the usage reported by all steps, and UsageByModel the
+	// same split by the model that served each step: the reported model,
+	// or the requested one. EstimatedCost sums Client.EstimateCost of each
+	// step's usage.
+	TotalUsage    NormalizedUsage
+	UsageByModel  map[string]NormalizedUsage
+	EstimatedCost float64
+}
+
+// ReasoningBudgetError is returned by RunTools when
+// RunOptions.MaxReasoningTokens is spent before the model finished.
+type ReasoningBudgetError struct {
+	Limit int
+	Used  int
+}
+
+func (e *ReasoningBudgetError) Error() string {
+	return fmt.Sprintf("zen: reasoning budget exhausted (used %d of %d tokens)", e.Used, e.Limit)
+}
+
+// CostBudgetError is returned by RunTools when the next step could take the
+// run's estimated cost over RunOptions.MaxCost. Spent is the cost so far.
+type CostBudgetError struct {
+	Limit float64
+	Spent float64
+}
+
+func (e *CostBudgetError) Error() string {
+	return fmt.Sprintf("zen: cost budget exhausted (spent $%.4f of $%.4f)", e.Spent, e.Limit)
+}
+
+// RunTools runs req as an agent loop: it streams a response, executes the
+// requested tool calls with tools, appends the calls and their results to
+// the conversation and repeats until the model answers without calling a
+// tool. req.Tools defaults to tools.Tools(). Calls are checked with
+// CheckToolCalls first, so calls to undeclared tools are answered with an
+// error result instead of being executed. With tools.CancelledResults, a
+// cancellation during tool execution ends the run with ctx's error once every
+// call has a result, so RunTools can resume from RunResult.Messages.
+func (c *Client) RunTools(ctx context.Context, req NormalizedRequest, tools *ToolSet, opts RunOptions) (*RunResult, error) {
+	maxSteps := opts.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultRunMaxSteps
+	}
+	if len(req.Tools) == 0 {
+		req.Tools = tools.Tools()
+	}
+
+	result := &RunResult{Messages: append([]NormalizedMessage(nil), req.Messages...)}
+	for result.Steps < maxSteps {
+		step := req
+		step.Messages = result.Messages
+		if opts.MaxReasoningTokens > 0 {
+			remaining := opts.MaxReasoningTokens - result.ReasoningTokens
+			if remaining <= 0 {
+				return result, &ReasoningBudgetError{Limit: opts.MaxReasoningTokens, Used: result.ReasoningTokens}
+			}
+			step.Reasoning = reasoningWithin(req.Reasoning, remaining)
+		}
+		if opts.MaxCost > 0 {
+			input, err := c.CountTokens(ctx, step)
+			if err != nil {
+				return result, err
+			}
+			next, _ := c.EstimateCost(step.Model, NormalizedUsage{InputTokens: input})
+			if result.EstimatedCost+next > opts.MaxCost {
+				return result, &CostBudgetError{Limit: opts.MaxCost, Spent: result.EstimatedCost}
+			}
+		}
+
+		resp, err := c.CollectStream(ctx, step)
+		if resp != nil {
+			result.Response = resp
+			result.Steps++
+			result.InputTokens += resp.InputTokens
+			result.OutputTokens += resp.OutputTokens
+			result.ReasoningTokens += reasoningUsage(resp)
+			result.addUsage(step.Model, resp)
+			if cost, ok := c.EstimateCost(resp.servedModel(step.Model), resp.Usage()); ok {
+				result.EstimatedCost += cost
+			}
+		}
+		if err != nil {
+			return result, err
+		}
+
+		if len(resp.ToolCalls) == 0 {
+			result.Messages = append(result.Messages, NormalizedMessage{Role: "assistant", Content: resp.Text, Parts: resp.Parts, ProviderState: resp.ProviderState})
+			return result, nil
+		}
+		calls, rejected := CheckToolCalls(resp.ToolCalls, step.Tools)
+		assistant := AssistantMessage(resp.Text, calls)
+		assistant.Parts = resp.Parts
+		assistant.ProviderState = resp.ProviderState
+		result.Messages = append(result.Messages, assistant)
+		for _, call := range calls {
+			if msg, ok := rejected[call.ID]; ok {
+				result.Messages = append(result.Messages, msg)
+				continue
+			}
+			result.Messages = append(result.Messages, tools.Execute(ctx, call))
+		}
+		if tools.CancelledResults && ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+	}
+	return result, ErrMaxSteps
+}
+
+// addUsage adds the usage of resp, a response to a request for model.
+func (r *RunResult) addUsage(model string, resp *NormalizedResponse) {
+	usage := resp.Usage()
+	r.TotalUsage = r.TotalUsage.Add(usage)
+	if r.UsageByModel == nil {
+		r.UsageByModel = map[string]NormalizedUsage{}
+	}
+	served := resp.servedModel(model)
+	r.UsageByModel[served] = r.UsageByModel[served].Add(usage)
+}
+
+// servedModel returns the model r reports, or requested when it reports
+// none.
+func (r *NormalizedResponse) servedModel(requested string) string {
+	if r.Model != "" {
+		return r.Model
+	}
+	return stripOpencodePrefix(requested)
+}
+
+// reasoningUsage returns the reported reasoning tokens of resp, or an
+// estimate from the reasoning text when the provider reports none.
+func reasoningUsage(resp *NormalizedResponse) int {
+	if resp.ReasoningTokens > 0 {
+		return resp.ReasoningTokens
+	}
+	return (resp.Summary.ReasoningBytes + 3) / 4
+}
+
+// anthropicMinThinkingBudget is the smallest budget_tokens Anthropic accepts.
+const anthropicMinThinkingBudget = 1024
+
+var effortLevels = []string{"low", "medium", "high"}
+
+// reasoningWithin lowers r so a step does not plan to spend more than
+// remaining reasoning tokens. Budgets are capped at remaining (but not below
+// the smallest budget providers accept); efforts step down to the highest
+// level whose budget (see mapEffortToBudget) fits. Unrecognized efforts are
+// kept. A nil r stays nil.
+func reasoningWithin(r *NormalizedReasoning, remaining int) *NormalizedReasoning {
+	if r == nil {
+		return nil
+	}
+	out := *r
+	if out.BudgetTokens > remaining {
+		out.BudgetTokens = max(remaining, anthropicMinThinkingBudget)
+	}
+	level := -1
+	for i, l := range effortLevels {
+		if strings.EqualFold(strings.TrimSpace(out.Effort), l) {
+			level = i
+		}
+	}
+	if level > 0 {
+		for level > 0 && mapEffortToBudget(effortLevels[level]) > remaining {
+			level--
+		}
+		out.Effort = effortLevels[level]
+	}
+	return &out
+}
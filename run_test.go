@@ -0,0 +1,411 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// scriptedChatServer answers the n-th chat/completions request with
+// steps[n] and records the reasoning_effort each request carried.
+type scriptedChatServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	efforts []string
+	bodies  []map[string]any
+}
+
+func newScriptedChatServer(t *testing.T, steps []string) *scriptedChatServer {
+	t.Helper()
+	s := &scriptedChatServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(payload, &body); err != nil {
+			t.Errorf("bad request body: %v", err)
+		}
+		s.mu.Lock()
+		n := len(s.bodies)
+		s.bodies = append(s.bodies, body)
+		effort, _ := body["reasoning_effort"].(string)
+		s.efforts = append(s.efforts, effort)
+		s.mu.Unlock()
+
+		if n >= len(steps) {
+			t.Errorf("unexpected request %d", n+1)
+			http.Error(w, "no more steps", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, steps[n])
+	}))
+	return s
+}
+
+func toolCallStep(id, args string, reasoningTokens int) string {
+	return fmt.Sprintf("data: {\"choices\":[{\"index\":0,\"delta\":{\"reasoning_content\":\"thinking\",\"tool_calls\":[{\"index\":0,\"id\":%q,\"type\":\"function\",\"function\":{\"name\":\"add\",\"arguments\":%q}}]}}]}\n\n", id, args) +
+		fmt.Sprintf("data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"tool_calls\"}],\"usage\":{\"prompt_tokens\":100,\"completion_tokens\":%d,\"completion_tokens_details\":{\"reasoning_tokens\":%d}}}\n\n", reasoningTokens+10, reasoningTokens) +
+		"data: [DONE]\n\n"
+}
+
+func answerStep(text string, reasoningTokens int) string {
+	return fmt.Sprintf("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", text) +
+		fmt.Sprintf("data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":100,\"completion_tokens\":%d,\"completion_tokens_details\":{\"reasoning_tokens\":%d}}}\n\n", reasoningTokens+5, reasoningTokens) +
+		"data: [DONE]\n\n"
+}
+
+func addToolSet() *ToolSet {
+	set := NewToolSet()
+	set.Add(NormalizedTool{
+		Name:       "add",
+		Parameters: json.RawMessage(`{"type":"object","properties":{"a":{"type":"number"},"b":{"type":"number"}}}`),
+	}, func(_ context.Context, args json.RawMessage) (string, error) {
+		var in struct{ A, B float64 }
+		if err := json.Unmarshal(args, &in); err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(in.A+in.B, 'f', -1, 64), nil
+	})
+	return set
+}
+
+func TestRunToolsReasoningBudget(t *testing.T) {
+	server := newScriptedChatServer(t, []string{
+		toolCallStep("call_1", `{"a":3,"b":4}`, 3000),
+		toolCallStep("call_2", `{"a":7,"b":7}`, 1500),
+		answerStep("14", 300),
+	})
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req := NormalizedRequest{
+		Model:     "kimi-k2",
+		Messages:  []NormalizedMessage{{Role: "user", Content: "What is 3 + 4, doubled?"}},
+		Reasoning: &NormalizedReasoning{Effort: "high"},
+	}
+	result, err := client.RunTools(testCtx(t), req, addToolSet(), RunOptions{MaxReasoningTokens: 6000})
+	if err != nil {
+		t.Fatalf("RunTools: %v", err)
+	}
+
+	// 6000 left allows high, 3000 left only medium, 1500 left only low.
+	if want := []string{"high", "medium", "low"}; fmt.Sprint(server.efforts) != fmt.Sprint(want) {
+		t.Fatalf("efforts per step: want %v, got %v", want, server.efforts)
+	}
+	if result.Steps != 3 || result.ReasoningTokens != 4800 || result.InputTokens != 300 {
+		t.Fatalf("accounting mismatch: %+v", result)
+	}
+	if result.Response.Text != "14" {
+		t.Fatalf("final response mismatch: %+v", result.Response)
+	}
+	roles := make([]string, len(result.Messages))
+	for i, m := range result.Messages {
+		roles[i] = m.Role
+	}
+	if fmt.Sprint(roles) != "[user assistant tool assistant tool assistant]" {
+		t.Fatalf("unexpected history roles: %v", roles)
+	}
+	if result.Messages[2].Content != "7" || result.Messages[4].Content != "14" {
+		t.Fatalf("tool results mismatch: %+v", result.Messages)
+	}
+	if req.Reasoning.Effort != "high" {
+		t.Fatalf("caller's request must not be modified")
+	}
+	// The second request must carry the first step's call and result.
+	if msgs, _ := server.bodies[1]["messages"].([]any); len(msgs) != 3 {
+		t.Fatalf("second step should send 3 messages, got %d", len(msgs))
+	}
+}
+
+func TestRunToolsReasoningBudgetExhausted(t *testing.T) {
+	server := newScriptedChatServer(t, []string{
+		toolCallStep("call_1", `{"a":1,"b":1}`, 3000),
+		toolCallStep("call_2", `{"a":2,"b":2}`, 2500),
+	})
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req := NormalizedRequest{
+		Model:     "kimi-k2",
+		Messages:  []NormalizedMessage{{Role: "user", Content: "go"}},
+		Reasoning: &NormalizedReasoning{Effort: "medium"},
+	}
+	result, err := client.RunTools(testCtx(t), req, addToolSet(), RunOptions{MaxReasoningTokens: 5000})
+	var budgetErr *ReasoningBudgetError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("expected *ReasoningBudgetError, got %v", err)
+	}
+	if budgetErr.Limit != 5000 || budgetErr.Used != 5500 {
+		t.Fatalf("budget error mismatch: %+v", budgetErr)
+	}
+	if result == nil || result.Steps != 2 || len(result.Messages) != 5 {
+		t.Fatalf("partial result mismatch: %+v", result)
+	}
+	if len(server.bodies) != 2 {
+		t.Fatalf("no request should be sent once the budget is spent, got %d", len(server.bodies))
+	}
+}
+
+func TestRunToolsMaxSteps(t *testing.T) {
+	server := newScriptedChatServer(t, []string{
+		toolCallStep("call_1", `{"a":1,"b":1}`, 0),
+		toolCallStep("call_2", `{"a":2,"b":2}`, 0),
+	})
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "go"}}}
+	result, err := client.RunTools(testCtx(t), req, addToolSet(), RunOptions{MaxSteps: 2})
+	if !errors.Is(err, ErrMaxSteps) || result.Steps != 2 {
+		t.Fatalf("expected ErrMaxSteps after 2 steps, got %v (%+v)", err, result)
+	}
+	// Reasoning text without reported usage is estimated.
+	if result.ReasoningTokens != 4 {
+		t.Fatalf("expected estimated reasoning usage, got %d", result.ReasoningTokens)
+	}
+}
+
+func TestReasoningWithin(t *testing.T) {
+	cases := []struct {
+		in        *NormalizedReasoning
+		remaining int
+		want      *NormalizedReasoning
+	}{
+		{nil, 100, nil},
+		{&NormalizedReasoning{Effort: "high"}, 5000, &NormalizedReasoning{Effort: "high"}},
+		{&NormalizedReasoning{Effort: "high"}, 3000, &NormalizedReasoning{Effort: "medium"}},
+		{&NormalizedReasoning{Effort: "medium"}, 10, &NormalizedReasoning{Effort: "low"}},
+		{&NormalizedReasoning{Effort: "minimal"}, 10, &NormalizedReasoning{Effort: "minimal"}},
+		{&NormalizedReasoning{BudgetTokens: 8000}, 3000, &NormalizedReasoning{BudgetTokens: 3000}},
+		{&NormalizedReasoning{BudgetTokens: 8000}, 200, &NormalizedReasoning{BudgetTokens: 1024}},
+		{&NormalizedReasoning{BudgetTokens: 2000}, 3000, &NormalizedReasoning{BudgetTokens: 2000}},
+	}
+	for _, tc := range cases {
+		got := reasoningWithin(tc.in, tc.remaining)
+		if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+			t.Fatalf("reasoningWithin(%+v, %d) = %+v, want %+v", tc.in, tc.remaining, got, tc.want)
+		}
+	}
+}
+
+func TestRunToolsUsageAndCost(t *testing.T) {
+	server := newScriptedChatServer(t, []string{
+		toolCallStep("call_1", `{"a":1,"b":1}`, 0),
+		"data: {\"model\":\"kimi-k2-0905\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"2\"},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":200,\"completion_tokens\":20,\"prompt_tokens_details\":{\"cached_tokens\":100}}}\n\ndata: [DONE]\n\n",
+	})
+	defer server.Close()
+	client, err := NewClient(Config{
+		APIKey:  "key",
+		BaseURL: server.URL,
+		ModelDefaults: map[string]ModelDefaults{
+			"kimi-":   {InputPricePer1M: 1, CachedInputPricePer1M: 0.5, OutputPricePer1M: 4},
+			"claude-": {InputPricePer1M: 1, CachedInputPricePer1M: 0.5, OutputPricePer1M: 4},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req := NormalizedRequest{Model: "opencode/kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "1 + 1?"}}}
+	result, err := client.RunTools(testCtx(t), req, addToolSet(), RunOptions{})
+	if err != nil {
+		t.Fatalf("RunTools: %v", err)
+	}
+	want := NormalizedUsage{InputTokens: 300, OutputTokens: 30, CachedInputTokens: 100}
+	if result.TotalUsage != want {
+		t.Fatalf("total usage: want %+v, got %+v", want, result.TotalUsage)
+	}
+	if len(result.UsageByModel) != 2 || result.UsageByModel["kimi-k2"].InputTokens != 100 || result.UsageByModel["kimi-k2-0905"].CachedInputTokens != 100 {
+		t.Fatalf("usage by model mismatch: %+v", result.UsageByModel)
+	}
+	// (100 + 100) * $1 + 100 * $0.50 + 30 * $4 per million tokens.
+	if cost := result.EstimatedCost * 1e6; cost < 369.999 || cost > 370.001 {
+		t.Fatalf("estimated cost: want 370e-6, got %v", result.EstimatedCost)
+	}
+	// Anthropic counts cache reads and writes apart from InputTokens:
+	// (100 + 10) * $1 + 100 * $0.50 + 30 * $4 per million tokens.
+	claude := NormalizedUsage{InputTokens: 100, OutputTokens: 30, CachedInputTokens: 100, CacheWriteInputTokens: 10}
+	if cost, _ := client.EstimateCost("claude-sonnet-4-6", claude); cost*1e6 < 279.999 || cost*1e6 > 280.001 {
+		t.Fatalf("claude cost: want 280e-6, got %v", cost)
+	}
+	if _, ok := client.EstimateCost("gpt-5", want); ok {
+		t.Fatal("a model without prices must not be estimated")
+	}
+}
+
+func TestRunToolsCostBudgetExhausted(t *testing.T) {
+	server := newScriptedChatServer(t, []string{
+		toolCallStep("call_1", `{"a":1,"b":1}`, 0),
+		toolCallStep("call_2", `{"a":2,"b":2}`, 0),
+	})
+	defer server.Close()
+	client, err := NewClient(Config{
+		APIKey:   "key",
+		BaseURL:  server.URL,
+		Defaults: ModelDefaults{InputPricePer1M: 1000},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "go"}}}
+	result, err := client.RunTools(testCtx(t), req, addToolSet(), RunOptions{MaxCost: 0.15})
+	var costErr *CostBudgetError
+	if !errors.As(err, &costErr) {
+		t.Fatalf("expected *CostBudgetError, got %v", err)
+	}
+	// The first step's 100 input tokens cost $0.10; the second step's
+	// estimated input would take the run past $0.15.
+	if costErr.Limit != 0.15 || costErr.Spent < 0.0999 || costErr.Spent > 0.1001 {
+		t.Fatalf("budget error mismatch: %+v", costErr)
+	}
+	if result.Steps != 1 || len(server.bodies) != 1 {
+		t.Fatalf("expected the run to stop after one step, got %d steps and %d requests", result.Steps, len(server.bodies))
+	}
+}
+
+func TestRunToolsCancelledDuringToolExecution(t *testing.T) {
+	threeCalls := "data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[" +
+		"{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"work\",\"arguments\":\"{\\\"n\\\":1}\"}}," +
+		"{\"index\":1,\"id\":\"call_2\",\"type\":\"function\",\"function\":{\"name\":\"work\",\"arguments\":\"{\\\"n\\\":2}\"}}," +
+		"{\"index\":2,\"id\":\"call_3\",\"type\":\"function\",\"function\":{\"name\":\"work\",\"arguments\":\"{\\\"n\\\":3}\"}}]}}]}\n\n" +
+		"data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"tool_calls\"}]}\n\n" +
+		"data: [DONE]\n\n"
+	server := newScriptedChatServer(t, []string{threeCalls, answerStep("done", 0)})
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(testCtx(t))
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	var ran []int
+	tools := NewToolSet()
+	tools.CancelledResults = true
+	tools.Add(NormalizedTool{Name: "work"}, func(_ context.Context, args json.RawMessage) (string, error) {
+		var in struct{ N int }
+		_ = json.Unmarshal(args, &in)
+		ran = append(ran, in.N)
+		if in.N == 2 {
+			// The user aborts while the second call is running, and
+			// the tool does not return until the test ends.
+			cancel()
+			<-release
+		}
+		return "ok " + strconv.Itoa(in.N), nil
+	})
+
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "go"}}}
+	result, err := client.RunTools(ctx, req, tools, RunOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if fmt.Sprint(ran) != "[1 2]" {
+		t.Fatalf("expected calls 1 and 2 to start, got %v", ran)
+	}
+	results := result.Messages[2:]
+	if len(results) != 3 {
+		t.Fatalf("expected a result per call, got %+v", results)
+	}
+	want := []struct {
+		id      string
+		content string
+		isError bool
+	}{
+		{"call_1", "ok 1", false},
+		{"call_2", "cancelled by user", true},
+		{"call_3", "cancelled by user", true},
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.Role != "tool" || got.ToolCallID != w.id || got.Content != w.content || got.IsError != w.isError {
+			t.Fatalf("result %d: want %+v, got %+v", i, w, got)
+		}
+	}
+
+	req.Messages = result.Messages
+	resumed, err := client.RunTools(testCtx(t), req, tools, RunOptions{})
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if resumed.Response.Text != "done" || len(server.bodies) != 2 {
+		t.Fatalf("unexpected resumed run: %q after %d requests", resumed.Response.Text, len(server.bodies))
+	}
+	if sent, _ := server.bodies[1]["messages"].([]any); len(sent) != 5 {
+		t.Fatalf("expected the resumed request to carry 5 messages, got %d", len(sent))
+	}
+}
+
+func TestRunToolsReplaysEncryptedReasoning(t *testing.T) {
+	reasoning := `{"type":"reasoning","id":"rs_1","summary":[],"encrypted_content":"gAAAAB"}`
+	server := newScriptedChatServer(t, []string{
+		"data: " + `{"type":"response.output_item.done","output_index":0,"item":` + reasoning + "}\n\n" +
+			"data: " + `{"type":"response.output_item.added","output_index":1,"item":{"type":"function_call","call_id":"call_1","name":"add"}}` + "\n\n" +
+			"data: " + `{"type":"response.function_call_arguments.done","output_index":1,"call_id":"call_1","name":"add","arguments":"{\"a\":3,\"b\":4}"}` + "\n\n" +
+			"data: " + `{"type":"response.completed","response":{"usage":{"input_tokens":10,"output_tokens":5}}}` + "\n\n",
+		"data: " + `{"type":"response.output_text.delta","output_index":0,"delta":"7"}` + "\n\n" +
+			"data: " + `{"type":"response.completed","response":{"usage":{"input_tokens":20,"output_tokens":1}}}` + "\n\n",
+	})
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	store := false
+	req := NormalizedRequest{
+		Model:     "gpt-5",
+		Messages:  []NormalizedMessage{{Role: "user", Content: "What is 3 + 4?"}},
+		Reasoning: &NormalizedReasoning{Effort: "high"},
+		Store:     &store,
+		Include:   []string{"reasoning.encrypted_content"},
+	}
+	result, err := client.RunTools(testCtx(t), req, addToolSet(), RunOptions{})
+	if err != nil {
+		t.Fatalf("RunTools: %v", err)
+	}
+	if state := result.Messages[1].ProviderState; len(state) != 1 || string(state[0]) != reasoning {
+		t.Fatalf("reasoning item not kept on the assistant message: %+v", result.Messages[1])
+	}
+
+	first := server.bodies[0]
+	if first["store"] != false || fmt.Sprint(first["include"]) != "[reasoning.encrypted_content]" {
+		t.Fatalf("store and include not sent: %v", first)
+	}
+	input, _ := server.bodies[1]["input"].([]any)
+	var types []string
+	for _, item := range input {
+		m, _ := item.(map[string]any)
+		types = append(types, fmt.Sprint(m["type"]))
+	}
+	if got := strings.Join(types, ","); got != "message,reasoning,function_call,function_call_output" {
+		t.Fatalf("unexpected input items %s", got)
+	}
+	if item := input[1].(map[string]any); item["encrypted_content"] != "gAAAAB" || item["id"] != "rs_1" {
+		t.Fatalf("reasoning item not replayed verbatim: %v", item)
+	}
+
+	if prepared := PrepareHistoryFor(EndpointMessages, result.Messages); prepared[1].ProviderState != nil {
+		t.Fatalf("provider state kept for another endpoint: %+v", prepared[1])
+	}
+}
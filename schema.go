@@ -0,0 +1,480 @@
+package zen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaViolation describes a single way a value fails a JSON Schema.
+type SchemaViolation struct {
+	// Path is a JSON Pointer to the offending value ("" is the root).
+	Path    string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + v.Message
+}
+
+// ToolArgumentsError is returned by ValidateToolCall when a call's arguments
+// do not satisfy the tool's Parameters schema. Its message is written to be
+// fed back to the model as an error tool result.
+type ToolArgumentsError struct {
+	Tool       string
+	Violations []SchemaViolation
+}
+
+func (e *ToolArgumentsError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, v.String())
+	}
+	return fmt.Sprintf("invalid arguments for tool %q: %s", e.Tool, strings.Join(parts, "; "))
+}
+
+// ValidateToolCall validates call.Arguments against tool.Parameters.
+//
+// A practical subset of JSON Schema draft 2020-12 is supported: type, enum,
+// const, properties, required, additionalProperties, items, prefixItems,
+// minItems/maxItems, uniqueItems, minLength/maxLength, pattern, minimum,
+// maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, allOf, anyOf,
+// oneOf, not, and local $ref ("#/$defs/..." or "#/definitions/..."). Other
+// keywords (format, dependentSchemas, ...) are ignored. Empty arguments are
+// treated as {}.
+func ValidateToolCall(call StreamToolCall, tool NormalizedTool) error {
+	args := bytes.TrimSpace(call.Arguments)
+	if len(args) == 0 {
+		args = []byte("{}")
+	}
+	value, err := decodeJSONNumbers(args)
+	if err != nil {
+		return &ToolArgumentsError{Tool: tool.Name, Violations: []SchemaViolation{{Message: "arguments are not valid JSON: " + err.Error()}}}
+	}
+
+	if len(bytes.TrimSpace(tool.Parameters)) == 0 {
+		if _, ok := value.(map[string]any); !ok {
+			return &ToolArgumentsError{Tool: tool.Name, Violations: []SchemaViolation{{Message: "arguments must be a JSON object"}}}
+		}
+		return nil
+	}
+
+	schema, err := decodeJSONNumbers(tool.Parameters)
+	if err != nil {
+		return fmt.Errorf("zen: invalid parameters schema for tool %q: %w", tool.Name, err)
+	}
+
+	v := &schemaValidator{root: schema}
+	v.validate(schema, value, "")
+	if len(v.violations) > 0 {
+		return &ToolArgumentsError{Tool: tool.Name, Violations: v.violations}
+	}
+	return nil
+}
+
+func decodeJSONNumbers(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+type schemaValidator struct {
+	root       any
+	violations []SchemaViolation
+	depth      int
+}
+
+const maxSchemaDepth = 64
+
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	v.violations = append(v.violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// check validates into a scratch validator and reports whether value matched,
+// used by the combinators that must not leak their branches' violations.
+func (v *schemaValidator) check(schema, value any, path string) bool {
+	sub := &schemaValidator{root: v.root, depth: v.depth}
+	sub.validate(schema, value, path)
+	return len(sub.violations) == 0
+}
+
+func (v *schemaValidator) validate(schemaVal, value any, path string) {
+	switch s := schemaVal.(type) {
+	case bool:
+		if !s {
+			v.fail(path, "no value is allowed here")
+		}
+		return
+	case map[string]any:
+		v.validateObjectSchema(s, value, path)
+	}
+}
+
+func (v *schemaValidator) validateObjectSchema(s map[string]any, value any, path string) {
+	v.depth++
+	defer func() { v.depth-- }()
+	if v.depth > maxSchemaDepth {
+		v.fail(path, "schema nesting too deep")
+		return
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := resolveSchemaRef(v.root, ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		v.validate(target, value, path)
+	}
+
+	if t, ok := s["type"]; ok && !matchesSchemaType(t, value) {
+		v.fail(path, "expected %s, got %s", describeSchemaType(t), jsonTypeName(value))
+		return
+	}
+
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "must be one of %s", compactJSON(enum))
+		}
+	}
+	if c, ok := s["const"]; ok && !jsonEqual(c, value) {
+		v.fail(path, "must equal %s", compactJSON(c))
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateObject(s, val, path)
+	case []any:
+		v.validateArray(s, val, path)
+	case string:
+		v.validateString(s, val, path)
+	case json.Number:
+		v.validateNumber(s, val, path)
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			v.validate(sub, value, path)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if v.check(sub, value, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "does not match any of the allowed schemas")
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if v.check(sub, value, path) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(path, "must match exactly one of the allowed schemas (matched %d)", matches)
+		}
+	}
+	if not, ok := s["not"]; ok && v.check(not, value, path) {
+		v.fail(path, "matches a schema it must not match")
+	}
+}
+
+func (v *schemaValidator) validateObject(s map[string]any, obj map[string]any, path string) {
+	if required, ok := s["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := obj[name]; !present {
+				v.fail(path, "missing required property %q", name)
+			}
+		}
+	}
+
+	props, _ := s["properties"].(map[string]any)
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	additional, hasAdditional := s["additionalProperties"]
+	for _, k := range keys {
+		childPath := path + "/" + escapeJSONPointer(k)
+		if propSchema, ok := props[k]; ok {
+			v.validate(propSchema, obj[k], childPath)
+			continue
+		}
+		if !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok {
+			if !allowed {
+				v.fail(childPath, "unexpected property %q", k)
+			}
+			continue
+		}
+		v.validate(additional, obj[k], childPath)
+	}
+}
+
+func (v *schemaValidator) validateArray(s map[string]any, arr []any, path string) {
+	if n, ok := schemaInt(s["minItems"]); ok && len(arr) < n {
+		v.fail(path, "must have at least %d items, got %d", n, len(arr))
+	}
+	if n, ok := schemaInt(s["maxItems"]); ok && len(arr) > n {
+		v.fail(path, "must have at most %d items, got %d", n, len(arr))
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+		for i := 0; i < len(arr); i++ {
+			for j := i + 1; j < len(arr); j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					v.fail(path, "items %d and %d are equal but must be unique", i, j)
+				}
+			}
+		}
+	}
+
+	start := 0
+	if prefix, ok := s["prefixItems"].([]any); ok {
+		for i, sub := range prefix {
+			if i >= len(arr) {
+				break
+			}
+			v.validate(sub, arr[i], path+"/"+strconv.Itoa(i))
+		}
+		start = len(prefix)
+	}
+	if items, ok := s["items"]; ok {
+		for i := start; i < len(arr); i++ {
+			v.validate(items, arr[i], path+"/"+strconv.Itoa(i))
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(s map[string]any, str, path string) {
+	length := utf8.RuneCountInString(str)
+	if n, ok := schemaInt(s["minLength"]); ok && length < n {
+		v.fail(path, "must be at least %d characters, got %d", n, length)
+	}
+	if n, ok := schemaInt(s["maxLength"]); ok && length > n {
+		v.fail(path, "must be at most %d characters, got %d", n, length)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err == nil && !re.MatchString(str) {
+			v.fail(path, "must match pattern %q", pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(s map[string]any, num json.Number, path string) {
+	f, err := num.Float64()
+	if err != nil {
+		return
+	}
+	if m, ok := schemaFloat(s["minimum"]); ok && f < m {
+		v.fail(path, "must be >= %v, got %v", m, num)
+	}
+	if m, ok := schemaFloat(s["maximum"]); ok && f > m {
+		v.fail(path, "must be <= %v, got %v", m, num)
+	}
+	if m, ok := schemaFloat(s["exclusiveMinimum"]); ok && f <= m {
+		v.fail(path, "must be > %v, got %v", m, num)
+	}
+	if m, ok := schemaFloat(s["exclusiveMaximum"]); ok && f >= m {
+		v.fail(path, "must be < %v, got %v", m, num)
+	}
+	if m, ok := schemaFloat(s["multipleOf"]); ok && m > 0 {
+		q := f / m
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			v.fail(path, "must be a multiple of %v", m)
+		}
+	}
+}
+
+func resolveSchemaRef(root any, ref string) (any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q (only local references are supported)", ref)
+	}
+	current := root
+	pointer := strings.TrimPrefix(ref, "#")
+	if pointer == "" {
+		return current, nil
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		current, ok = obj[token]
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return current, nil
+}
+
+func matchesSchemaType(t any, value any) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesSingleType(tt, value)
+	case []any:
+		for _, item := range tt {
+			if name, ok := item.(string); ok && matchesSingleType(name, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func matchesSingleType(name string, value any) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		num, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		if _, err := num.Int64(); err == nil {
+			return true
+		}
+		f, err := num.Float64()
+		return err == nil && f == math.Trunc(f)
+	default:
+		return true
+	}
+}
+
+func describeSchemaType(t any) string {
+	if list, ok := t.([]any); ok {
+		names := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return strings.Join(names, " or ")
+	}
+	if s, ok := t.(string); ok {
+		return s
+	}
+	return "valid type"
+}
+
+func jsonTypeName(value any) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func jsonEqual(a, b any) bool {
+	an, aNum := a.(json.Number)
+	bn, bNum := b.(json.Number)
+	if aNum && bNum {
+		af, err1 := an.Float64()
+		bf, err2 := bn.Float64()
+		return err1 == nil && err2 == nil && af == bf
+	}
+	return compactJSON(a) == compactJSON(b)
+}
+
+func compactJSON(v any) string {
+	data, err := jsonMarshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func schemaInt(v any) (int, bool) {
+	num, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	n, err := num.Int64()
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
+
+func schemaFloat(v any) (float64, bool) {
+	num, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := num.Float64()
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,124 @@
+package zen
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var weatherTool = NormalizedTool{
+	Name: "get_weather",
+	Parameters: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"city": {"type": "string", "minLength": 1},
+			"days": {"type": "integer", "minimum": 1, "maximum": 7},
+			"units": {"enum": ["c", "f"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"location": {"$ref": "#/$defs/point"}
+		},
+		"required": ["city"],
+		"additionalProperties": false,
+		"$defs": {
+			"point": {
+				"type": "object",
+				"properties": {"lat": {"type": "number"}, "lon": {"type": "number"}},
+				"required": ["lat", "lon"]
+			}
+		}
+	}`),
+}
+
+func validateArgs(t *testing.T, args string) *ToolArgumentsError {
+	t.Helper()
+	err := ValidateToolCall(StreamToolCall{Name: weatherTool.Name, Arguments: json.RawMessage(args)}, weatherTool)
+	if err == nil {
+		return nil
+	}
+	var argErr *ToolArgumentsError
+	if !errors.As(err, &argErr) {
+		t.Fatalf("expected *ToolArgumentsError, got %T: %v", err, err)
+	}
+	return argErr
+}
+
+func TestValidateToolCallAccepts(t *testing.T) {
+	valid := []string{
+		`{"city":"Paris"}`,
+		`{"city":"Paris","days":3,"units":"c","tags":["a","b"],"location":{"lat":48.8,"lon":2.3}}`,
+		`{"city":"Paris","days":3.0}`,
+	}
+	for _, args := range valid {
+		if err := validateArgs(t, args); err != nil {
+			t.Fatalf("%s: unexpected violations: %v", args, err)
+		}
+	}
+}
+
+func TestValidateToolCallRejects(t *testing.T) {
+	cases := []struct {
+		args string
+		path string
+		msg  string
+	}{
+		{`{}`, "", `missing required property "city"`},
+		{`{"city":42}`, "/city", "expected string, got integer"},
+		{`{"city":""}`, "/city", "at least 1 characters"},
+		{`{"city":"x","days":2.5}`, "/days", "expected integer"},
+		{`{"city":"x","days":9}`, "/days", "must be <= 7"},
+		{`{"city":"x","units":"k"}`, "/units", `must be one of ["c","f"]`},
+		{`{"city":"x","tags":["a",1]}`, "/tags/1", "expected string"},
+		{`{"city":"x","tags":["a","b","c"]}`, "/tags", "at most 2 items"},
+		{`{"city":"x","location":{"lat":1}}`, "/location", `missing required property "lon"`},
+		{`{"city":"x","extra":true}`, "/extra", `unexpected property "extra"`},
+		{`{"city":`, "", "not valid JSON"},
+		{`"Paris"`, "", "expected object, got string"},
+	}
+	for _, tc := range cases {
+		argErr := validateArgs(t, tc.args)
+		if argErr == nil {
+			t.Fatalf("%s: expected violation", tc.args)
+		}
+		found := false
+		for _, v := range argErr.Violations {
+			if v.Path == tc.path && strings.Contains(v.Message, tc.msg) {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("%s: want violation at %q containing %q, got %v", tc.args, tc.path, tc.msg, argErr.Violations)
+		}
+	}
+}
+
+func TestValidateToolCallCombinators(t *testing.T) {
+	tool := NormalizedTool{Name: "t", Parameters: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"id": {"anyOf": [{"type": "string"}, {"type": "integer"}]},
+			"mode": {"oneOf": [{"const": "a"}, {"const": "b"}]},
+			"flag": {"not": {"const": true}}
+		}
+	}`)}
+	ok := StreamToolCall{Arguments: json.RawMessage(`{"id":1,"mode":"a","flag":false}`)}
+	if err := ValidateToolCall(ok, tool); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bad := StreamToolCall{Arguments: json.RawMessage(`{"id":true,"mode":"c","flag":true}`)}
+	err := ValidateToolCall(bad, tool)
+	var argErr *ToolArgumentsError
+	if !errors.As(err, &argErr) || len(argErr.Violations) != 3 {
+		t.Fatalf("expected 3 violations, got %v", err)
+	}
+}
+
+func TestValidateToolCallWithoutSchema(t *testing.T) {
+	tool := NormalizedTool{Name: "t"}
+	if err := ValidateToolCall(StreamToolCall{}, tool); err != nil {
+		t.Fatalf("empty arguments should be accepted: %v", err)
+	}
+	if err := ValidateToolCall(StreamToolCall{Arguments: json.RawMessage(`[1]`)}, tool); err == nil {
+		t.Fatalf("non-object arguments should be rejected")
+	}
+}
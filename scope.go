@@ -0,0 +1,71 @@
+package zen
+
+import (
+	"context"
+	"net/http"
+)
+
+// Default names of the scoping headers (Config.OrganizationHeader and
+// ProjectHeader).
+const (
+	DefaultOrganizationHeader = "OpenAI-Organization"
+	DefaultProjectHeader      = "OpenAI-Project"
+)
+
+// Scope identifies the organization and project a request is made for, so
+// a gateway serving several tenants can attribute it. Empty fields are not
+// sent.
+type Scope struct {
+	Organization string
+	Project      string
+}
+
+type scopeKey struct{}
+
+// ContextWithScope returns a context whose requests are made for s instead
+// of Config.Organization and Config.Project. Fields left empty in s keep
+// the client's values.
+func ContextWithScope(ctx context.Context, s Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, s)
+}
+
+// ScopeFromContext returns the Scope attached with ContextWithScope. Inside
+// the Config hooks that receive a request's context (LogRequest,
+// OnWarning and the like) it is the scope the request is made for.
+func ScopeFromContext(ctx context.Context) Scope {
+	s, _ := ctx.Value(scopeKey{}).(Scope)
+	return s
+}
+
+// scope returns the Scope of requests made with ctx.
+func (c *Client) scope(ctx context.Context) Scope {
+	s := Scope{Organization: c.cfg.Organization, Project: c.cfg.Project}
+	override := ScopeFromContext(ctx)
+	if override.Organization != "" {
+		s.Organization = override.Organization
+	}
+	if override.Project != "" {
+		s.Project = override.Project
+	}
+	return s
+}
+
+// withScope attaches the Scope of requests made with ctx to it, for hooks
+// to read with ScopeFromContext.
+func (c *Client) withScope(ctx context.Context) context.Context {
+	s := c.scope(ctx)
+	if s == (Scope{}) || s == ScopeFromContext(ctx) {
+		return ctx
+	}
+	return ContextWithScope(ctx, s)
+}
+
+func (c *Client) applyScopeHeaders(req *http.Request) {
+	s := c.scope(req.Context())
+	if s.Organization != "" {
+		req.Header.Set(c.cfg.OrganizationHeader, s.Organization)
+	}
+	if s.Project != "" {
+		req.Header.Set(c.cfg.ProjectHeader, s.Project)
+	}
+}
@@ -0,0 +1,66 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrGenerationStalled is returned when a stream produced no text, reasoning
+// or tool call output for Config.GenerationStallTimeout. CollectStream
+// returns it inside a *StreamError carrying the partial response.
+var ErrGenerationStalled = errors.New("zen: generation stalled")
+
+// stallWatch cancels a stream once no output delta has been seen for its
+// timeout. Keep-alive comments and usage or unknown events do not count as
+// progress. A nil stallWatch watches nothing.
+type stallWatch struct {
+	timeout time.Duration
+	timer   Timer
+	done    chan struct{}
+	fired   atomic.Bool
+}
+
+func newStallWatch(clock Clock, timeout time.Duration, cancel context.CancelFunc) *stallWatch {
+	if timeout <= 0 {
+		return nil
+	}
+	w := &stallWatch{timeout: timeout, timer: clock.NewTimer(timeout), done: make(chan struct{})}
+	go func() {
+		select {
+		case <-w.timer.C():
+			w.fired.Store(true)
+			cancel()
+		case <-w.done:
+		}
+	}()
+	return w
+}
+
+// observe restarts the timer when d is model output.
+func (w *stallWatch) observe(d NormalizedDelta) {
+	if w == nil {
+		return
+	}
+	switch d.Type {
+	case DeltaText, DeltaReasoning, DeltaToolCallBegin, DeltaToolCallArgumentsDelta, DeltaToolCallDone:
+		w.timer.Reset(w.timeout)
+	}
+}
+
+func (w *stallWatch) stop() {
+	if w != nil {
+		w.timer.Stop()
+		close(w.done)
+	}
+}
+
+// err replaces the error of a stream the watch cancelled with
+// ErrGenerationStalled.
+func (w *stallWatch) err(err error) error {
+	if w != nil && w.fired.Load() {
+		return ErrGenerationStalled
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package zen
+
+import "strings"
+
+// StopReason is a provider's finish reason mapped to one vocabulary shared by
+// all endpoints; NormalizedDelta.FinishReason keeps the raw value.
+type StopReason string
+
+const (
+	// StopReasonEndTurn is a natural end of the reply ("stop", "end_turn",
+	// Gemini "STOP").
+	StopReasonEndTurn StopReason = "end_turn"
+	// StopReasonMaxTokens is a reply cut off by the output token limit.
+	StopReasonMaxTokens StopReason = "max_tokens"
+	// StopReasonStopSequence is a reply ended by a stop sequence.
+	StopReasonStopSequence StopReason = "stop_sequence"
+	// StopReasonToolCalls is a reply that ended to have tools called.
+	StopReasonToolCalls StopReason = "tool_calls"
+	// StopReasonContentFilter is a reply blocked or cut off by the
+	// provider's safety filters (Gemini "SAFETY", Chat Completions
+	// "content_filter", Anthropic "refusal").
+	StopReasonContentFilter StopReason = "content_filter"
+	// StopReasonRecitation is a Gemini reply stopped for reciting training
+	// data.
+	StopReasonRecitation StopReason = "recitation"
+	// StopReasonOther is any other finish reason.
+	StopReasonOther StopReason = "other"
+)
+
+// NormalizeStopReason maps a raw finish reason of any endpoint to a
+// StopReason. It returns "" for an empty reason.
+func NormalizeStopReason(raw string) StopReason {
+	switch strings.ToLower(raw) {
+	case "":
+		return ""
+	case "stop", "end_turn":
+		return StopReasonEndTurn
+	case "length", "max_tokens", "max_output_tokens", "model_context_window_exceeded":
+		return StopReasonMaxTokens
+	case "stop_sequence":
+		return StopReasonStopSequence
+	case "tool_calls", "tool_use", "function_call":
+		return StopReasonToolCalls
+	case "content_filter", "refusal", "safety", "blocklist", "prohibited_content", "spii", "image_safety":
+		return StopReasonContentFilter
+	case "recitation":
+		return StopReasonRecitation
+	}
+	return StopReasonOther
+}
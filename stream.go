@@ -6,55 +6,160 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ErrStreamDisconnected marks a stream whose connection ended before the
+// response was complete, e.g. a reset connection, an HTTP/2 GOAWAY during a
+// deploy or a truncated body ("unexpected EOF"). The transport error is
+// wrapped alongside it, and a cancelled or expired request context is
+// reported as the context error instead.
+var ErrStreamDisconnected = errors.New("zen: stream disconnected")
+
+// CommentEvent is the Event name of SSE comment lines (": ping"), which
+// streams only report with Config.StreamIncludeComments set. Their Raw holds
+// the comment text without the leading colon and Data is empty.
+const CommentEvent = "comment"
+
 type StreamEvent struct {
+	// ID is the SSE last event id in effect when the event was dispatched.
+	ID    string
 	Event string
 	Data  json.RawMessage
 	Raw   string
+	// ReceivedAt is when the event was read off the wire and Seq its
+	// 1-based position in the stream.
+	ReceivedAt time.Time
+	Seq        int64
 }
 
 type Stream struct {
 	Events <-chan StreamEvent
 	Err    error
-	Close  func() error
+	// Close stops the stream and closes its body. The reader goroutine
+	// exits even when Events is no longer being read. Close may be called
+	// more than once and from any goroutine.
+	Close func() error
+	// StatusCode and Header are the response status and headers.
+	StatusCode int
+	Header     http.Header
+
+	received *atomic.Int64
 }
 
 func (c *Client) startStream(ctx context.Context, endpoint EndpointType, method, path string, body []byte) (*Stream, error) {
 	url := joinURL(c.cfg.BaseURL, path)
-	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if body == nil && !isBodyless(method) {
+		body = []byte{}
+	}
+	ctx = c.withScope(ctx)
+	req, err := newRequest(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
 
-	c.applyRequestHeaders(req, endpoint, true, false)
+	key := c.keys.pick(c.cfg.Clock.Now())
+	c.applyRequestHeaders(req, endpoint, true, false, c.keys.keys[key])
+
+	release, err := c.limiter.acquire(ctx, c.cfg.FailFastOnConcurrencyLimit)
+	if err != nil {
+		return nil, err
+	}
 
+	scope := c.scope(ctx)
+	metrics := RequestMetrics{
+		Method:       method,
+		Endpoint:     endpoint,
+		Path:         path,
+		Stream:       true,
+		RequestBytes: len(body),
+		KeyIndex:     key,
+		Organization: scope.Organization,
+		Project:      scope.Project,
+	}
+	start := c.cfg.Clock.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		release()
+		metrics.Duration = c.cfg.Clock.Now().Sub(start)
+		metrics.Err = err
+		c.reportMetrics(ctx, metrics)
 		return nil, err
 	}
+	metrics.StatusCode = resp.StatusCode
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		payload, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
-		return nil, newAPIError(resp.StatusCode, resp.Header, payload)
+		release()
+		apiErr := newAPIError(resp.StatusCode, resp.Header, payload)
+		c.captureRequest(ctx, apiErr, body)
+		c.keyRateLimited(key, resp)
+		metrics.ResponseBytes = int64(len(payload))
+		metrics.Duration = c.cfg.Clock.Now().Sub(start)
+		metrics.Err = apiErr
+		c.reportMetrics(ctx, metrics)
+		return nil, apiErr
+	}
+
+	if c.cfg.ReleaseSlotAfterHeaders {
+		release()
 	}
 
 	events := make(chan StreamEvent)
+	done := make(chan struct{})
+	var closeOnce sync.Once
 	stream := &Stream{
-		Events: events,
-		Close:  resp.Body.Close,
+		Events:     events,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		received:   new(atomic.Int64),
+		Close: func() error {
+			var err error
+			closeOnce.Do(func() {
+				close(done)
+				err = resp.Body.Close()
+				release()
+			})
+			return err
+		},
+	}
+	// send delivers ev unless the stream is closed or ctx is done first.
+	send := func(ev StreamEvent) bool {
+		select {
+		case events <- ev:
+			return true
+		case <-done:
+		case <-ctx.Done():
+			stream.Err = ctx.Err()
+		}
+		return false
 	}
 
 	go func() {
+		defer release()
 		defer close(events)
-		reader := bufio.NewReader(resp.Body)
-		var eventName string
+		// Runs before events is closed, so the request has been reported
+		// by the time its stream ends.
+		defer func() {
+			metrics.ResponseBytes = stream.received.Load()
+			metrics.Duration = c.cfg.Clock.Now().Sub(start)
+			metrics.Err = stream.Err
+			c.reportMetrics(ctx, metrics)
+		}()
+		reader := bufio.NewReader(countingReader{r: resp.Body, n: stream.received})
+		var eventName, lastID string
 		var dataBuf bytes.Buffer
+		var seq int64
 
+		// flush dispatches the buffered event and reports whether reading
+		// should stop.
 		flush := func() bool {
 			if dataBuf.Len() == 0 {
 				eventName = ""
@@ -65,16 +170,19 @@ func (c *Client) startStream(ctx context.Context, endpoint EndpointType, method,
 			name := eventName
 			eventName = ""
 
-			if raw == "[DONE]" {
+			if strings.TrimSpace(raw) == "[DONE]" {
 				return true
 			}
 
-			events <- StreamEvent{
-				Event: name,
-				Data:  json.RawMessage(raw),
-				Raw:   raw,
-			}
-			return false
+			seq++
+			return !send(StreamEvent{
+				ID:         lastID,
+				Event:      name,
+				Data:       json.RawMessage(raw),
+				Raw:        raw,
+				ReceivedAt: c.cfg.Clock.Now(),
+				Seq:        seq,
+			})
 		}
 
 		for {
@@ -83,20 +191,33 @@ func (c *Client) startStream(ctx context.Context, endpoint EndpointType, method,
 				if errors.Is(err, io.EOF) {
 					flush()
 				} else {
-					stream.Err = err
+					stream.Err = classifyStreamReadError(ctx, err)
 				}
 				return
 			}
 
 			line = strings.TrimRight(line, "\r\n")
 			if line == "" {
-				if done := flush(); done {
+				if flush() {
 					return
 				}
 				continue
 			}
 
 			if strings.HasPrefix(line, ":") {
+				if c.cfg.StreamIncludeComments {
+					seq++
+					comment := strings.TrimPrefix(strings.TrimPrefix(line, ":"), " ")
+					if !send(StreamEvent{
+						ID:         lastID,
+						Event:      CommentEvent,
+						Raw:        comment,
+						ReceivedAt: c.cfg.Clock.Now(),
+						Seq:        seq,
+					}) {
+						return
+					}
+				}
 				continue
 			}
 
@@ -105,8 +226,17 @@ func (c *Client) startStream(ctx context.Context, endpoint EndpointType, method,
 				continue
 			}
 
+			if strings.HasPrefix(line, "id:") {
+				if id := strings.TrimSpace(strings.TrimPrefix(line, "id:")); !strings.ContainsRune(id, 0) {
+					lastID = id
+				}
+				continue
+			}
+
 			if strings.HasPrefix(line, "data:") {
-				data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+				// Only the space after the colon is dropped: a payload
+				// split across events may end or start with spaces.
+				data := strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
 				dataBuf.WriteString(data)
 				dataBuf.WriteByte('\n')
 			}
@@ -115,3 +245,16 @@ func (c *Client) startStream(ctx context.Context, endpoint EndpointType, method,
 
 	return stream, nil
 }
+
+// classifyStreamReadError wraps a mid-body read failure in
+// ErrStreamDisconnected. Reads from the body only fail for transport reasons,
+// so anything other than a finished context counts as a disconnect.
+func classifyStreamReadError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(err, ctxErr) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+	return fmt.Errorf("%w: %w", ErrStreamDisconnected, err)
+}
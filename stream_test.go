@@ -2,6 +2,7 @@ package zen
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -135,3 +136,95 @@ func TestSlowStreamNotKilledByClientTimeout(t *testing.T) {
 		t.Fatalf("expected 3 events, got %d", len(events))
 	}
 }
+
+func TestStreamDisconnectClassification(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	server := newBlockingStreamServer(t, unblock)
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	// Cancelling the context is not a disconnect.
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errs, err := client.StreamEvents(ctx, req)
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+	<-events
+	cancel()
+	for range events {
+	}
+	err = <-errs
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrStreamDisconnected) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// Closing connections server-side is.
+	events, errs, err = client.StreamEvents(context.Background(), req)
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+	<-events
+	server.CloseClientConnections()
+	for range events {
+	}
+	if err := <-errs; !errors.Is(err, ErrStreamDisconnected) {
+		t.Fatalf("expected ErrStreamDisconnected, got %v", err)
+	}
+}
+
+func TestStreamIncludeComments(t *testing.T) {
+	sse := ": ping\n\n" +
+		"event: message_start\n" +
+		":keep-alive\n" +
+		"data: {\"type\":\"message_start\"}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+	server, client := newSSETestServer(t, sse)
+	defer server.Close()
+	req := NormalizedRequest{Model: "claude-sonnet-4-6", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	collect := func(c *Client) []UnifiedEvent {
+		t.Helper()
+		events, errs, err := c.StreamEvents(testCtx(t), req)
+		if err != nil {
+			t.Fatalf("StreamEvents: %v", err)
+		}
+		var out []UnifiedEvent
+		for ev := range events {
+			out = append(out, ev)
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+		return out
+	}
+
+	if got := collect(client); len(got) != 2 || got[0].Event != MessagesEventMessageStart {
+		t.Fatalf("comments should be dropped by default, got %+v", got)
+	}
+
+	client = client.With(func(cfg *Config) { cfg.StreamIncludeComments = true })
+	got := collect(client)
+	if len(got) != 4 {
+		t.Fatalf("expected 4 events, got %+v", got)
+	}
+	if got[0].Event != CommentEvent || got[0].Raw != "ping" || len(got[0].Data) != 0 || got[0].Seq != 1 {
+		t.Fatalf("first comment mismatch: %+v", got[0])
+	}
+	if got[1].Event != CommentEvent || got[1].Raw != "keep-alive" {
+		t.Fatalf("second comment mismatch: %+v", got[1])
+	}
+	if got[2].Event != MessagesEventMessageStart || string(got[2].Data) != `{"type":"message_start"}` || got[2].Seq != 3 {
+		t.Fatalf("comment inside an event should not disturb it: %+v", got[2])
+	}
+
+	resp, err := client.CollectStream(testCtx(t), req)
+	if err != nil || resp.Summary.TextDeltas != 0 {
+		t.Fatalf("normalized streams should ignore comments: %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,94 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrModelSubstituted is wrapped by the *ModelSubstitutionError returned
+// under Config.StrictModelMatch.
+var ErrModelSubstituted = errors.New("zen: provider served a different model")
+
+// ModelSubstitution reports a response served by a model of another family
+// than requested, see Config.OnModelSubstitution.
+type ModelSubstitution struct {
+	Endpoint  EndpointType
+	Requested string
+	Served    string
+}
+
+// ModelSubstitutionError fails a request under Config.StrictModelMatch when
+// the provider served a model of another family than requested.
+type ModelSubstitutionError struct {
+	ModelSubstitution
+}
+
+func (e *ModelSubstitutionError) Error() string {
+	return fmt.Sprintf("zen: requested %s but the provider served %s", e.Requested, e.Served)
+}
+
+func (e *ModelSubstitutionError) Unwrap() error {
+	return ErrModelSubstituted
+}
+
+// modelSnapshotSuffix matches the suffixes providers add to name a snapshot
+// of a model: dates ("-20250929", "-2024-08-06"), version numbers ("-002",
+// "-0613"), "-latest", "-preview" and Vertex "@" versions.
+var modelSnapshotSuffix = regexp.MustCompile(`(-\d{8}|-\d{4}-\d{2}-\d{2}|-\d{3,4}|-latest|-preview|@.*)$`)
+
+// ModelFamily returns model without its provider prefix and snapshot
+// suffixes, so that an alias and the dated snapshot it resolves to share a
+// family: "claude-sonnet-4-5-20250929" and "opencode/claude-sonnet-4-5"
+// both give "claude-sonnet-4-5".
+func ModelFamily(model string) string {
+	m := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	for {
+		trimmed := modelSnapshotSuffix.ReplaceAllString(m, "")
+		if trimmed == m || trimmed == "" {
+			return m
+		}
+		m = trimmed
+	}
+}
+
+// checkServedModel reports served to Config.OnModelSubstitution when its
+// family differs from requested, and fails under Config.StrictModelMatch.
+func (c *Client) checkServedModel(ctx context.Context, endpoint EndpointType, requested, served string) error {
+	if served == "" || (c.cfg.OnModelSubstitution == nil && !c.cfg.StrictModelMatch) {
+		return nil
+	}
+	if ModelFamily(requested) == ModelFamily(served) {
+		return nil
+	}
+	sub := ModelSubstitution{Endpoint: endpoint, Requested: stripOpencodePrefix(requested), Served: served}
+	if c.cfg.OnModelSubstitution != nil {
+		c.cfg.OnModelSubstitution(ctx, sub)
+	}
+	if c.cfg.StrictModelMatch {
+		return &ModelSubstitutionError{sub}
+	}
+	return nil
+}
+
+// servedModelCheck runs checkServedModel on the first model a stream
+// reports.
+type servedModelCheck struct {
+	c         *Client
+	endpoint  EndpointType
+	requested string
+	done      bool
+}
+
+func (m *servedModelCheck) observe(ctx context.Context, d NormalizedDelta) error {
+	if m.done || d.Model == "" {
+		return nil
+	}
+	m.done = true
+	return m.c.checkServedModel(ctx, m.endpoint, m.requested, d.Model)
+}
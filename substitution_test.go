@@ -0,0 +1,115 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestModelFamily(t *testing.T) {
+	cases := map[string]string{
+		"claude-sonnet-4-5-20250929":   "claude-sonnet-4-5",
+		"opencode/claude-sonnet-4-5":   "claude-sonnet-4-5",
+		"gpt-4o-2024-08-06":            "gpt-4o",
+		"gpt-4-0613":                   "gpt-4",
+		"models/gemini-1.5-pro-002":    "gemini-1.5-pro",
+		"gemini-3-pro-preview":         "gemini-3-pro",
+		"claude-3-5-sonnet-latest":     "claude-3-5-sonnet",
+		"claude-opus-4-1@20250805":     "claude-opus-4-1",
+		"moonshotai/Kimi-K2-Instruct":  "kimi-k2-instruct",
+		"claude-sonnet-4-6":            "claude-sonnet-4-6",
+		"glm-4.6":                      "glm-4.6",
+		"qwen3-coder-480b-a35b-2507":   "qwen3-coder-480b-a35b",
+		"deepseek-v3.1-terminus":       "deepseek-v3.1-terminus",
+		"grok-code-fast-1":             "grok-code-fast-1",
+		"gpt-5.1-codex-max-2025-11-19": "gpt-5.1-codex-max",
+	}
+	for model, want := range cases {
+		if got := ModelFamily(model); got != want {
+			t.Errorf("ModelFamily(%q): want %q, got %q", model, want, got)
+		}
+	}
+}
+
+const substitutedChatSSE = `data: {"id":"c1","model":"glm-4.6","choices":[{"index":0,"delta":{"content":"Hi"}}]}
+
+data: {"id":"c1","model":"glm-4.6","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+
+func TestModelSubstitution(t *testing.T) {
+	server, _ := newSSETestServer(t, substitutedChatSSE)
+	defer server.Close()
+
+	var reported []ModelSubstitution
+	cfg := Config{
+		APIKey:              "key",
+		BaseURL:             server.URL,
+		OnModelSubstitution: func(_ context.Context, sub ModelSubstitution) { reported = append(reported, sub) },
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+	resp, err := client.CollectStream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	if resp.Model != "glm-4.6" || resp.Text != "Hi" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	want := ModelSubstitution{Endpoint: EndpointChatCompletions, Requested: "kimi-k2", Served: "glm-4.6"}
+	if len(reported) != 1 || reported[0] != want {
+		t.Fatalf("expected one substitution %+v, got %+v", want, reported)
+	}
+
+	cfg.StrictModelMatch = true
+	strict, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	deltas, errs, err := strict.Stream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var got []NormalizedDelta
+	for d := range deltas {
+		got = append(got, d)
+	}
+	var subErr *ModelSubstitutionError
+	if err := <-errs; !errors.As(err, &subErr) || !errors.Is(err, ErrModelSubstituted) || subErr.Served != "glm-4.6" {
+		t.Fatalf("expected *ModelSubstitutionError, got %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected no deltas from the substituted model, got %+v", got)
+	}
+	if _, err := strict.CollectStream(testCtx(t), req); !errors.Is(err, ErrModelSubstituted) {
+		t.Fatalf("CollectStream: expected ErrModelSubstituted, got %v", err)
+	}
+}
+
+func TestModelSubstitutionIgnoresSnapshots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","model":"claude-sonnet-4-5-20250929","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, StrictModelMatch: true})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	resp, err := client.CreateNormalized(testCtx(t), NormalizedRequest{
+		Model:    "opencode/claude-sonnet-4-5",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateNormalized: %v", err)
+	}
+	if resp.Model != "claude-sonnet-4-5-20250929" {
+		t.Fatalf("Model: got %q", resp.Model)
+	}
+}
@@ -0,0 +1,67 @@
+package zen
+
+// TeeOptions tunes Tee.
+type TeeOptions struct {
+	// Buffer is the capacity of each output channel.
+	Buffer int
+	// DropWhenFull skips an output whose buffer is full instead of waiting
+	// for its consumer, so a slow or abandoned consumer never holds up the
+	// others. Such a consumer may miss any item, DeltaDone included, so use
+	// it for best-effort consumers such as forwarding to a UI. OnDrop, when
+	// set, is called with the index of the output each dropped item was
+	// meant for.
+	DropWhenFull bool
+	OnDrop       func(output int)
+}
+
+// Tee copies every item of in to n output channels, in order, and closes
+// them once in is closed. By default the slowest consumer sets the pace:
+// each item is handed to every output before the next is read, so every
+// output must be read to its end, or the others stall once its buffer is
+// full. See TeeOptions.DropWhenFull for consumers that may stop early.
+//
+// The error channel of a stream is not copied; read it once, from one place,
+// after in is closed.
+func Tee[T any](in <-chan T, n int, opts TeeOptions) []<-chan T {
+	outs := make([]chan T, n)
+	result := make([]<-chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T, opts.Buffer)
+		result[i] = outs[i]
+	}
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for item := range in {
+			for i, out := range outs {
+				if !opts.DropWhenFull {
+					out <- item
+					continue
+				}
+				select {
+				case out <- item:
+				default:
+					if opts.OnDrop != nil {
+						opts.OnDrop(i)
+					}
+				}
+			}
+		}
+	}()
+	return result
+}
+
+// TeeDeltas copies the deltas of a stream to n channels, with the slowest
+// consumer setting the pace; see Tee.
+func TeeDeltas(in <-chan NormalizedDelta, n int) []<-chan NormalizedDelta {
+	return Tee(in, n, TeeOptions{})
+}
+
+// TeeEvents copies the raw events of a stream to n channels, with the
+// slowest consumer setting the pace; see Tee.
+func TeeEvents(in <-chan UnifiedEvent, n int) []<-chan UnifiedEvent {
+	return Tee(in, n, TeeOptions{})
+}
@@ -0,0 +1,120 @@
+package zen
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+const teeChatSSE = "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+	"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n" +
+	"data: [DONE]\n\n"
+
+func TestTeeDeltas(t *testing.T) {
+	server, client := newSSETestServer(t, teeChatSSE)
+	defer server.Close()
+	deltas, errs, err := client.Stream(testCtx(t), NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	outs := TeeDeltas(deltas, 3)
+	got := make([][]NormalizedDelta, len(outs))
+	var wg sync.WaitGroup
+	for i, out := range outs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range out {
+				got[i] = append(got[i], d)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := <-errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	for i := range outs {
+		assertDeltaSequence(t, got[i], DeltaText, DeltaText, DeltaDone)
+		if got[i][0].Content != "Hel" || got[i][1].Content != "lo" {
+			t.Fatalf("consumer %d got %+v", i, got[i])
+		}
+	}
+}
+
+func TestTeeAbandonedConsumer(t *testing.T) {
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 0; i < 100; i++ {
+			in <- i
+		}
+	}()
+
+	var mu sync.Mutex
+	drops := map[int]int{}
+	outs := Tee(in, 2, TeeOptions{Buffer: 4, DropWhenFull: true, OnDrop: func(output int) {
+		mu.Lock()
+		drops[output]++
+		mu.Unlock()
+	}})
+
+	// outs[1] is never read; outs[0] must still see every item.
+	done := make(chan []int)
+	go func() {
+		var seen []int
+		for v := range outs[0] {
+			seen = append(seen, v)
+			// Let the producer run ahead so outs[0]'s buffer fills too.
+			time.Sleep(time.Microsecond)
+		}
+		done <- seen
+	}()
+	select {
+	case seen := <-done:
+		mu.Lock()
+		defer mu.Unlock()
+		if len(seen)+drops[0] != 100 || drops[1] != 96 {
+			t.Fatalf("want 100 items split between reads and drops on 0 and 96 drops on 1, got %d read, drops %v", len(seen), drops)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("an abandoned consumer stalled the others")
+	}
+
+	// The abandoned output still holds its buffer and is closed.
+	n := 0
+	for range outs[1] {
+		n++
+	}
+	if n != 4 {
+		t.Fatalf("abandoned output should keep its 4 buffered items, got %d", n)
+	}
+}
+
+func TestStreamWithHandlers(t *testing.T) {
+	server, client := newSSETestServer(t, teeChatSSE)
+	defer server.Close()
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	var forwarded []NormalizedDelta
+	acc := NewToolCallAccumulator()
+	err := client.StreamWithHandlers(testCtx(t), req,
+		func(d NormalizedDelta) error { forwarded = append(forwarded, d); return nil },
+		func(d NormalizedDelta) error { acc.Apply(d); return nil },
+	)
+	if err != nil {
+		t.Fatalf("StreamWithHandlers: %v", err)
+	}
+	assertDeltaSequence(t, forwarded, DeltaText, DeltaText, DeltaDone)
+
+	errStop := errors.New("websocket closed")
+	calls := 0
+	err = client.StreamWithHandlers(testCtx(t), req, func(NormalizedDelta) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Fatalf("a handler error must stop the stream, got %v after %d calls", err, calls)
+	}
+}
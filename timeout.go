@@ -0,0 +1,51 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRequestTimeout is wrapped together with context.DeadlineExceeded by
+// the error of a non-streaming request attempt that exceeded
+// Config.RequestTimeout.
+var ErrRequestTimeout = errors.New("zen: request timed out")
+
+type requestTimeoutKey struct{}
+
+// ContextWithRequestTimeout returns a context whose non-streaming requests
+// use d instead of Config.RequestTimeout. A negative d disables the timeout.
+func ContextWithRequestTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, requestTimeoutKey{}, d)
+}
+
+func (c *Client) requestTimeout(ctx context.Context) time.Duration {
+	if d, ok := ctx.Value(requestTimeoutKey{}).(time.Duration); ok {
+		return d
+	}
+	return c.cfg.RequestTimeout
+}
+
+// withAttemptTimeout derives the context of one request attempt, bounded
+// by the request timeout when there is one.
+func (c *Client) withAttemptTimeout(ctx context.Context) (context.Context, context.CancelFunc, time.Duration) {
+	timeout := c.requestTimeout(ctx)
+	if timeout <= 0 {
+		return ctx, func() {}, 0
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	return attemptCtx, cancel, timeout
+}
+
+// attemptTimeoutError wraps err in ErrRequestTimeout when it was caused by
+// the attempt's own deadline rather than by ctx.
+func attemptTimeoutError(ctx, attemptCtx context.Context, timeout time.Duration, err error) error {
+	if err == nil || timeout <= 0 || ctx.Err() != nil || !errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	return fmt.Errorf("%w after %s: %w", ErrRequestTimeout, timeout, err)
+}
@@ -0,0 +1,74 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// wait pauses a test handler for d, or until its request is abandoned.
+func wait(r *http.Request, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-r.Context().Done():
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow-headers":
+			wait(r, 300*time.Millisecond)
+			_, _ = w.Write([]byte(`{}`))
+		case "/slow-body":
+			_, _ = w.Write([]byte(`{"a":`))
+			w.(http.Flusher).Flush()
+			wait(r, 300*time.Millisecond)
+			_, _ = w.Write([]byte(`1}`))
+		case "/chat/completions":
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < 3; i++ {
+				_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"x\"}}]}\n\n"))
+				w.(http.Flusher).Flush()
+				wait(r, 50*time.Millisecond)
+			}
+			_, _ = w.Write([]byte("data: [DONE]\n\n"))
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, RequestTimeout: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	for _, path := range []string{"/slow-headers", "/slow-body"} {
+		err := client.DoRawInto(testCtx(t), "GET", EndpointChatCompletions, path, nil, &map[string]any{})
+		if !errors.Is(err, ErrRequestTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("%s: expected ErrRequestTimeout wrapping context.DeadlineExceeded, got %v", path, err)
+		}
+	}
+
+	ctx := ContextWithRequestTimeout(testCtx(t), -1)
+	if err := client.DoRawInto(ctx, "GET", EndpointChatCompletions, "/slow-headers", nil, nil); err != nil {
+		t.Fatalf("disabled timeout: %v", err)
+	}
+
+	// Streams outlive the timeout.
+	deltas, errs, err := client.Stream(testCtx(t), NormalizedRequest{
+		Model:    "kimi-k2",
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	text := ""
+	for d := range deltas {
+		text += d.Content
+	}
+	if err := <-errs; err != nil || text != "xxx" {
+		t.Fatalf("stream: got %q, err %v", text, err)
+	}
+}
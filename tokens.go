@@ -0,0 +1,181 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Fixed costs used by the token heuristics.
+const (
+	estimatedRequestOverhead  = 3
+	estimatedMessageOverhead  = 4
+	estimatedToolOverhead     = 8
+	estimatedToolCallOverhead = 4
+	// estimatedFileTokens is charged per file or audio part. It matches
+	// Gemini's cost of one image; video and long audio cost far more.
+	estimatedFileTokens = 258
+)
+
+// EstimateTokens returns a local estimate of the input tokens req will use,
+// without a network call. Text is converted at 3.5 characters per token for
+// Claude models and 4 for all others; each message, tool definition and tool
+// call adds a fixed overhead and each file part a fixed cost.
+//
+// For English prose and code the estimate is typically within 25% of the
+// provider's count. Text in scripts that tokenize poorly (e.g. CJK) can be
+// underestimated by half, and file parts other than single images are
+// underestimated. Use Client.CountTokens to prefer an exact counter.
+func EstimateTokens(req NormalizedRequest) int {
+	model := stripOpencodePrefix(req.Model)
+	total := estimatedRequestOverhead
+	if system := req.systemText(); strings.TrimSpace(system) != "" {
+		total += estimatedMessageOverhead + estimateTextTokens(model, system)
+	}
+	for _, msg := range req.Messages {
+		total += EstimateMessageTokens(model, msg)
+	}
+	for _, tool := range req.Tools {
+		total += estimatedToolOverhead +
+			estimateTextTokens(model, tool.Name) +
+			estimateTextTokens(model, tool.Description) +
+			estimateTextTokens(model, string(tool.Parameters))
+	}
+	return total
+}
+
+// EstimateMessageTokens returns the EstimateTokens share of a single message.
+func EstimateMessageTokens(model string, msg NormalizedMessage) int {
+	model = stripOpencodePrefix(model)
+	total := estimatedMessageOverhead
+	if len(msg.Parts) > 0 {
+		for _, part := range msg.Parts {
+			switch part.Type {
+			case ContentPartFile, ContentPartAudio:
+				total += estimatedFileTokens
+			default:
+				total += estimateTextTokens(model, part.Text)
+			}
+		}
+	} else {
+		total += estimateTextTokens(model, msg.Content)
+	}
+	for _, call := range msg.ToolCalls {
+		total += estimatedToolCallOverhead +
+			estimateTextTokens(model, call.Name) +
+			estimateTextTokens(model, string(call.Arguments))
+	}
+	return total
+}
+
+func estimateTextTokens(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	charsPerToken := 4.0
+	if strings.HasPrefix(strings.ToLower(model), "claude-") {
+		charsPerToken = 3.5
+	}
+	return int(math.Ceil(float64(utf8.RuneCountInString(text)) / charsPerToken))
+}
+
+// CountTokens returns the input tokens of req using Config.TokenCounter
+// when one is configured, and EstimateTokens otherwise.
+func (c *Client) CountTokens(ctx context.Context, req NormalizedRequest) (int, error) {
+	req.Model = stripOpencodePrefix(req.Model)
+	if c.cfg.TokenCounter != nil {
+		return c.cfg.TokenCounter(ctx, req)
+	}
+	return EstimateTokens(req), nil
+}
+
+// DefaultAutoMaxTokensMargin is the number of tokens Config.AutoMaxTokens
+// keeps free of the context window when Config.AutoMaxTokensMargin is 0.
+const DefaultAutoMaxTokensMargin = 512
+
+// ErrContextWindowExceeded is returned under Config.AutoMaxTokens when the
+// input leaves no room for output in the model's context window.
+var ErrContextWindowExceeded = errors.New("zen: input leaves no room in the context window")
+
+// autoMaxTokens sets req.MaxTokens from the model's configured limits when
+// Config.AutoMaxTokens is set and the request leaves it nil.
+func (c *Client) autoMaxTokens(ctx context.Context, req NormalizedRequest) (NormalizedRequest, error) {
+	if !c.cfg.AutoMaxTokens || req.MaxTokens != nil {
+		return req, nil
+	}
+	contextWindow, maxOutput := c.cfg.modelLimits(req.Model)
+	limit := maxOutput
+	if contextWindow > 0 {
+		input, err := c.CountTokens(ctx, req)
+		if err != nil {
+			return req, err
+		}
+		margin := c.cfg.AutoMaxTokensMargin
+		if margin == 0 {
+			margin = DefaultAutoMaxTokensMargin
+		}
+		available := contextWindow - input - margin
+		if available <= 0 {
+			return req, fmt.Errorf("%w: %s has %d tokens, the input uses about %d", ErrContextWindowExceeded, req.Model, contextWindow, input)
+		}
+		if limit <= 0 || available < limit {
+			limit = available
+		}
+	}
+	if limit > 0 {
+		req.MaxTokens = &limit
+	}
+	return req, nil
+}
+
+// MaxTokensPolicy selects the max_tokens Config.MessagesMaxTokensPolicy sends
+// to Anthropic's messages API, which requires one, for requests that leave
+// MaxTokens nil after defaults and AutoMaxTokens.
+type MaxTokensPolicy int
+
+const (
+	// MaxTokensDefault sends 1024, or twice the thinking budget when that
+	// is not below 1024.
+	MaxTokensDefault MaxTokensPolicy = iota
+	// MaxTokensModelCeiling sends the model's MaxOutputTokens from Defaults
+	// and ModelDefaults. Models without one get MaxTokensDefault.
+	MaxTokensModelCeiling
+	// MaxTokensFixed sends Config.MessagesMaxTokens.
+	MaxTokensFixed
+	// MaxTokensRequired fails the request with ErrMaxTokensRequired.
+	MaxTokensRequired
+)
+
+// ErrMaxTokensRequired is returned under MaxTokensRequired for a messages
+// request without MaxTokens.
+var ErrMaxTokensRequired = errors.New("zen: max_tokens is required")
+
+// messagesMaxTokens applies Config.MessagesMaxTokensPolicy to a messages
+// request that leaves MaxTokens nil. Unlike the default, the model ceiling
+// and fixed values are not raised to fit the thinking budget; a request whose
+// budget does not fit below them fails before it is sent.
+func (c *Client) messagesMaxTokens(endpoint EndpointType, req NormalizedRequest) (NormalizedRequest, error) {
+	if endpoint != EndpointMessages || req.MaxTokens != nil {
+		return req, nil
+	}
+	var limit int
+	switch c.cfg.MessagesMaxTokensPolicy {
+	case MaxTokensModelCeiling:
+		_, limit = c.cfg.modelLimits(req.Model)
+	case MaxTokensFixed:
+		limit = c.cfg.MessagesMaxTokens
+	case MaxTokensRequired:
+		return req, fmt.Errorf("%w: %s", ErrMaxTokensRequired, req.Model)
+	}
+	if limit <= 0 {
+		return req, nil
+	}
+	if budget := req.thinkingBudget(); budget >= limit {
+		return req, fmt.Errorf("zen: max_tokens %d of %s must exceed the thinking budget %d", limit, req.Model, budget)
+	}
+	req.MaxTokens = &limit
+	return req, nil
+}
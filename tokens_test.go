@@ -0,0 +1,237 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTextTokensAgainstTokenizer(t *testing.T) {
+	// Exact counts from OpenAI's cl100k_base tokenizer.
+	cases := []struct {
+		text  string
+		exact int
+	}{
+		{"Hello, world!", 4},
+		{"The quick brown fox jumps over the lazy dog", 9},
+	}
+	for _, tc := range cases {
+		got := estimateTextTokens("gpt-5.1", tc.text)
+		if diff := float64(got-tc.exact) / float64(tc.exact); diff > 0.25 || diff < -0.25 {
+			t.Fatalf("estimate for %q: got %d, exact %d (off by %.0f%%)", tc.text, got, tc.exact, diff*100)
+		}
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	base := NormalizedRequest{
+		Model:    "gpt-5.1",
+		Messages: []NormalizedMessage{{Role: "user", Content: "abcdefgh"}},
+	}
+	if got := EstimateTokens(base); got != estimatedRequestOverhead+estimatedMessageOverhead+2 {
+		t.Fatalf("unexpected estimate: %d", got)
+	}
+
+	claude := base
+	claude.Model = "opencode/claude-sonnet-4-6"
+	if EstimateTokens(claude) != EstimateTokens(base)+1 {
+		t.Fatalf("claude text should count at 3.5 characters per token")
+	}
+
+	withFile := base
+	withFile.Messages = []NormalizedMessage{{Role: "user", Parts: []NormalizedContentPart{
+		{Type: ContentPartText, Text: "abcdefgh"},
+		{Type: ContentPartFile, FileURI: "files/a", MediaType: "image/png"},
+	}}}
+	if EstimateTokens(withFile) != EstimateTokens(base)+estimatedFileTokens {
+		t.Fatalf("file parts should add a fixed cost")
+	}
+
+	withTools := base
+	withTools.System = "be brief"
+	withTools.Tools = []NormalizedTool{{Name: "add", Parameters: json.RawMessage(`{"type":"object"}`)}}
+	withTools.Messages = append(withTools.Messages, NormalizedMessage{
+		Role:      "assistant",
+		ToolCalls: []NormalizedToolCall{{ID: "1", Name: "add", Arguments: json.RawMessage(`{"a":1}`)}},
+	})
+	want := EstimateTokens(base) +
+		estimatedMessageOverhead + 2 + // system
+		estimatedToolOverhead + 1 + 5 + // tool definition
+		EstimateMessageTokens("gpt-5.1", withTools.Messages[1])
+	if got := EstimateTokens(withTools); got != want {
+		t.Fatalf("estimate with tools: want %d, got %d", want, got)
+	}
+}
+
+func TestClientCountTokens(t *testing.T) {
+	req := NormalizedRequest{Model: "opencode/claude-sonnet-4-6", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	client, err := NewClient(Config{APIKey: "key"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if got, err := client.CountTokens(context.Background(), req); err != nil || got != EstimateTokens(req) {
+		t.Fatalf("without a counter CountTokens should estimate, got %d, %v", got, err)
+	}
+
+	var model string
+	exact := client.With(func(cfg *Config) {
+		cfg.TokenCounter = func(_ context.Context, r NormalizedRequest) (int, error) {
+			model = r.Model
+			return 42, nil
+		}
+	})
+	if got, err := exact.CountTokens(context.Background(), req); err != nil || got != 42 {
+		t.Fatalf("counter result should be returned, got %d, %v", got, err)
+	}
+	if model != "claude-sonnet-4-6" {
+		t.Fatalf("counter should receive the model without prefix, got %q", model)
+	}
+}
+
+func TestAutoMaxTokens(t *testing.T) {
+	counter := func(ctx context.Context, req NormalizedRequest) (int, error) { return 100, nil }
+	c, err := NewClient(Config{
+		APIKey:              "key",
+		AutoMaxTokens:       true,
+		AutoMaxTokensMargin: 10,
+		TokenCounter:        counter,
+		ModelDefaults: map[string]ModelDefaults{
+			"small-":       {ContextWindow: 150},
+			"capped-":      {ContextWindow: 1000, MaxOutputTokens: 64},
+			"output-only-": {MaxOutputTokens: 32},
+			"tiny-":        {ContextWindow: 110},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	cases := []struct {
+		model string
+		want  int // 0 = left unset
+	}{
+		{"small-1", 40},
+		{"capped-1", 64},
+		{"output-only-1", 32},
+		{"unknown-1", 0},
+	}
+	for _, tc := range cases {
+		req, err := c.autoMaxTokens(testCtx(t), NormalizedRequest{Model: tc.model})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.model, err)
+		}
+		if tc.want == 0 {
+			if req.MaxTokens != nil {
+				t.Fatalf("%s: MaxTokens should stay nil, got %d", tc.model, *req.MaxTokens)
+			}
+			continue
+		}
+		if req.MaxTokens == nil || *req.MaxTokens != tc.want {
+			t.Fatalf("%s: want MaxTokens %d, got %v", tc.model, tc.want, req.MaxTokens)
+		}
+	}
+
+	explicit := 5
+	req, err := c.autoMaxTokens(testCtx(t), NormalizedRequest{Model: "small-1", MaxTokens: &explicit})
+	if err != nil || *req.MaxTokens != 5 {
+		t.Fatalf("explicit MaxTokens must be kept: %v %v", req.MaxTokens, err)
+	}
+
+	_, _, _, _, err = c.buildRequest(testCtx(t), NormalizedRequest{Model: "tiny-1", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}, false)
+	if !errors.Is(err, ErrContextWindowExceeded) {
+		t.Fatalf("expected ErrContextWindowExceeded, got %v", err)
+	}
+}
+
+func TestMessagesMaxTokensPolicy(t *testing.T) {
+	newClient := func(policy MaxTokensPolicy, fixed int) *Client {
+		c, err := NewClient(Config{
+			APIKey:                  "key",
+			MessagesMaxTokensPolicy: policy,
+			MessagesMaxTokens:       fixed,
+			ModelDefaults:           map[string]ModelDefaults{"claude-sonnet-4": {MaxOutputTokens: 64000}},
+		})
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		return c
+	}
+	build := func(c *Client, model string, budget int) (int, error) {
+		req := NormalizedRequest{Model: model, Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+		if budget > 0 {
+			req.Reasoning = &NormalizedReasoning{BudgetTokens: budget}
+		}
+		_, endpoint, _, payload, err := c.buildRequest(testCtx(t), req, false)
+		if err != nil {
+			return 0, err
+		}
+		if endpoint != EndpointMessages {
+			t.Fatalf("%s: expected messages endpoint, got %s", model, endpoint)
+		}
+		var body struct {
+			MaxTokens int `json:"max_tokens"`
+		}
+		if err := json.Unmarshal(payload, &body); err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		return body.MaxTokens, nil
+	}
+
+	cases := []struct {
+		name    string
+		policy  MaxTokensPolicy
+		fixed   int
+		model   string
+		budget  int
+		want    int
+		wantErr bool
+	}{
+		{"default", MaxTokensDefault, 0, "claude-sonnet-4", 0, 1024, false},
+		{"default thinking", MaxTokensDefault, 0, "claude-sonnet-4", 4096, 8192, false},
+		{"ceiling", MaxTokensModelCeiling, 0, "claude-sonnet-4", 0, 64000, false},
+		{"ceiling thinking", MaxTokensModelCeiling, 0, "claude-sonnet-4", 4096, 64000, false},
+		{"ceiling budget too large", MaxTokensModelCeiling, 0, "claude-sonnet-4", 64000, 0, true},
+		{"ceiling unknown model", MaxTokensModelCeiling, 0, "claude-opus-4", 4096, 8192, false},
+		{"fixed", MaxTokensFixed, 2000, "claude-sonnet-4", 0, 2000, false},
+		{"fixed thinking", MaxTokensFixed, 2000, "claude-sonnet-4", 1024, 2000, false},
+		{"fixed budget too large", MaxTokensFixed, 2000, "claude-sonnet-4", 2000, 0, true},
+	}
+	for _, tc := range cases {
+		got, err := build(newClient(tc.policy, tc.fixed), tc.model, tc.budget)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("%s: expected an error, got max_tokens %d", tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: want max_tokens %d, got %d", tc.name, tc.want, got)
+		}
+	}
+
+	required := newClient(MaxTokensRequired, 0)
+	for _, budget := range []int{0, 4096} {
+		if _, err := build(required, "claude-sonnet-4", budget); !errors.Is(err, ErrMaxTokensRequired) {
+			t.Fatalf("budget %d: expected ErrMaxTokensRequired, got %v", budget, err)
+		}
+	}
+	explicit := 500
+	_, _, _, payload, err := required.buildRequest(testCtx(t), NormalizedRequest{
+		Model:     "claude-sonnet-4",
+		MaxTokens: &explicit,
+		Messages:  []NormalizedMessage{{Role: "user", Content: "hi"}},
+	}, false)
+	if err != nil || !strings.Contains(string(payload), `"max_tokens":500`) {
+		t.Fatalf("explicit MaxTokens must be sent under MaxTokensRequired: %s %v", payload, err)
+	}
+
+	if _, err := NewClient(Config{APIKey: "key", MessagesMaxTokensPolicy: MaxTokensFixed}); err == nil {
+		t.Fatal("expected an error for MaxTokensFixed without MessagesMaxTokens")
+	}
+}
@@ -2,10 +2,19 @@ package zen
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 )
 
+// DefaultMaxToolArgumentBytes is the per-call argument size limit used when
+// none is configured.
+const DefaultMaxToolArgumentBytes = 4 << 20
+
+// ErrToolArgumentsTooLarge is carried by the DeltaError emitted when a tool
+// call's arguments exceed the configured size limit.
+var ErrToolArgumentsTooLarge = errors.New("zen: tool call arguments exceed size limit")
+
 // StreamToolCall is a fully assembled tool call extracted from a stream.
 type StreamToolCall struct {
 	ID               string
@@ -17,34 +26,54 @@ type StreamToolCall struct {
 // ToolCallAccumulator stitches streaming tool call deltas into complete calls.
 // Call Apply for every NormalizedDelta, then call CompleteCalls at the end.
 type ToolCallAccumulator struct {
-	calls map[int]*toolCallState
-	order []int
+	// MaxArgumentBytes limits the arguments kept per call (0 =
+	// DefaultMaxToolArgumentBytes, negative = unlimited). A call that
+	// exceeds it stops accumulating and is reported by Truncated instead
+	// of CompleteCalls.
+	MaxArgumentBytes int
+
+	calls map[toolCallKey]*toolCallState
+	order []toolCallKey
+}
+
+// toolCallKey identifies a call by candidate and index, so calls of
+// different Gemini candidates sharing an index stay apart.
+type toolCallKey struct {
+	choice, index int
+}
+
+func toolCallKeyOf(d NormalizedDelta) toolCallKey {
+	return toolCallKey{choice: d.ChoiceIndex, index: d.ToolCallIndex}
 }
 
 type toolCallState struct {
-	index int
-	id    string
-	name  string
-	sig   string
-	args  strings.Builder
-	full  string
+	key  toolCallKey
+	id   string
+	name string
+	sig  string
+	args strings.Builder
+	full string
+
+	truncated bool
 }
 
 // NewToolCallAccumulator creates a new accumulator for streaming tool calls.
 func NewToolCallAccumulator() *ToolCallAccumulator {
-	return &ToolCallAccumulator{calls: map[int]*toolCallState{}}
+	return &ToolCallAccumulator{calls: map[toolCallKey]*toolCallState{}}
 }
 
 // Apply ingests a single delta. It returns true if the delta affected tool state.
 func (a *ToolCallAccumulator) Apply(delta NormalizedDelta) bool {
-	switch delta.Type {
-	case DeltaToolCallBegin, DeltaToolCallArgumentsDelta, DeltaToolCallDone:
+	switch {
+	case IsToolDelta(delta.Type):
+		// continue
+	case delta.Type == DeltaError && errors.Is(delta.Err, ErrToolArgumentsTooLarge):
 		// continue
 	default:
 		return false
 	}
 
-	call := a.ensure(delta.ToolCallIndex)
+	call := a.ensure(toolCallKeyOf(delta))
 	switch delta.Type {
 	case DeltaToolCallBegin:
 		if call.id == "" {
@@ -57,7 +86,16 @@ func (a *ToolCallAccumulator) Apply(delta NormalizedDelta) bool {
 			call.sig = delta.ToolCallSignature
 		}
 	case DeltaToolCallArgumentsDelta:
+		if call.truncated {
+			break
+		}
+		if limit := toolArgumentLimit(a.MaxArgumentBytes); limit > 0 && call.args.Len()+len(delta.ArgumentsDelta) > limit {
+			call.truncate()
+			break
+		}
 		call.args.WriteString(delta.ArgumentsDelta)
+	case DeltaError:
+		call.truncate()
 	case DeltaToolCallDone:
 		if call.id == "" {
 			call.id = delta.ToolCallID
@@ -68,6 +106,13 @@ func (a *ToolCallAccumulator) Apply(delta NormalizedDelta) bool {
 		if call.sig == "" {
 			call.sig = delta.ToolCallSignature
 		}
+		if call.truncated {
+			break
+		}
+		if limit := toolArgumentLimit(a.MaxArgumentBytes); limit > 0 && len(delta.ArgumentsFull) > limit {
+			call.truncate()
+			break
+		}
 		if delta.ArgumentsFull != "" {
 			call.full = delta.ArgumentsFull
 		}
@@ -76,28 +121,54 @@ func (a *ToolCallAccumulator) Apply(delta NormalizedDelta) bool {
 	return true
 }
 
+// callID returns the call's id, or a stable synthetic one when the provider
+// sent none.
+func (c *toolCallState) callID() string {
+	switch {
+	case c.id != "":
+		return c.id
+	case c.key.choice > 0:
+		return fmt.Sprintf("tool-%d-%d", c.key.choice, c.key.index)
+	}
+	return fmt.Sprintf("tool-%d", c.key.index)
+}
+
+func (c *toolCallState) truncate() {
+	c.truncated = true
+	c.args = strings.Builder{}
+	c.full = ""
+}
+
+// toolArgumentLimit resolves a configured limit; 0 means no limit.
+func toolArgumentLimit(configured int) int {
+	switch {
+	case configured == 0:
+		return DefaultMaxToolArgumentBytes
+	case configured < 0:
+		return 0
+	}
+	return configured
+}
+
 // CompleteCalls returns fully assembled tool calls in their original order.
-// Any missing IDs are filled with stable synthetic IDs.
+// Any missing IDs are filled with stable synthetic IDs. Truncated calls are
+// left out.
 func (a *ToolCallAccumulator) CompleteCalls() []StreamToolCall {
 	if len(a.order) == 0 {
 		return nil
 	}
 	out := make([]StreamToolCall, 0, len(a.order))
-	for _, idx := range a.order {
-		call := a.calls[idx]
-		if call == nil {
+	for _, key := range a.order {
+		call := a.calls[key]
+		if call == nil || call.truncated {
 			continue
 		}
-		id := call.id
-		if id == "" {
-			id = fmt.Sprintf("tool-%d", call.index)
-		}
 		full := call.full
 		if full == "" {
 			full = call.args.String()
 		}
 		out = append(out, StreamToolCall{
-			ID:               id,
+			ID:               call.callID(),
 			Name:             call.name,
 			Arguments:        json.RawMessage(full),
 			ThoughtSignature: call.sig,
@@ -106,13 +177,129 @@ func (a *ToolCallAccumulator) CompleteCalls() []StreamToolCall {
 	return out
 }
 
-func (a *ToolCallAccumulator) ensure(index int) *toolCallState {
-	call := a.calls[index]
+// Truncated returns the calls whose arguments exceeded the size limit, with
+// ID and Name set and no Arguments. Callers typically answer each with an
+// error tool result so the model can retry with a smaller payload.
+func (a *ToolCallAccumulator) Truncated() []StreamToolCall {
+	var out []StreamToolCall
+	for _, key := range a.order {
+		call := a.calls[key]
+		if call == nil || !call.truncated {
+			continue
+		}
+		out = append(out, StreamToolCall{ID: call.callID(), Name: call.name, ThoughtSignature: call.sig})
+	}
+	return out
+}
+
+// Preview returns a best-effort parse of the arguments received so far for
+// the call at index (of the first candidate), so a UI can show e.g. the path
+// a tool is about to read before the call completes. Unterminated strings,
+// objects and arrays are closed; a trailing key without a value is dropped.
+// It reports false when no object can be recovered yet.
+func (a *ToolCallAccumulator) Preview(index int) (map[string]any, bool) {
+	call := a.calls[toolCallKey{index: index}]
+	if call == nil || call.truncated {
+		return nil, false
+	}
+	raw := call.full
+	if raw == "" {
+		raw = call.args.String()
+	}
+	completed, ok := completePartialJSON(raw)
+	if !ok {
+		return nil, false
+	}
+	var out map[string]any
+	if err := jsonUnmarshal([]byte(completed), &out); err != nil || out == nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func (a *ToolCallAccumulator) ensure(key toolCallKey) *toolCallState {
+	call := a.calls[key]
 	if call != nil {
 		return call
 	}
-	call = &toolCallState{index: index}
-	a.calls[index] = call
-	a.order = append(a.order, index)
+	call = &toolCallState{key: key}
+	a.calls[key] = call
+	a.order = append(a.order, key)
 	return call
 }
+
+// toolArgumentGuard applies the argument size limit to a delta stream: once
+// a call exceeds it, a DeltaError is emitted in place of the offending delta
+// and further arguments for that call are dropped.
+type toolArgumentGuard struct {
+	limit int
+	sizes map[toolCallKey]int
+	over  map[toolCallKey]bool
+}
+
+func newToolArgumentGuard(configured int) *toolArgumentGuard {
+	return &toolArgumentGuard{limit: toolArgumentLimit(configured), sizes: map[toolCallKey]int{}, over: map[toolCallKey]bool{}}
+}
+
+func (g *toolArgumentGuard) filter(d NormalizedDelta) []NormalizedDelta {
+	if g.limit <= 0 {
+		return []NormalizedDelta{d}
+	}
+	key := toolCallKeyOf(d)
+	switch d.Type {
+	case DeltaToolCallArgumentsDelta:
+		if g.over[key] {
+			return nil
+		}
+		g.sizes[key] += len(d.ArgumentsDelta)
+		if g.sizes[key] > g.limit {
+			return []NormalizedDelta{g.exceeded(d)}
+		}
+	case DeltaToolCallDone:
+		if g.over[key] {
+			d.ArgumentsFull = ""
+			return []NormalizedDelta{d}
+		}
+		if len(d.ArgumentsFull) > g.limit {
+			errDelta := g.exceeded(d)
+			d.ArgumentsFull = ""
+			return []NormalizedDelta{errDelta, d}
+		}
+	}
+	return []NormalizedDelta{d}
+}
+
+func (g *toolArgumentGuard) exceeded(d NormalizedDelta) NormalizedDelta {
+	g.over[toolCallKeyOf(d)] = true
+	return NormalizedDelta{
+		Type:          DeltaError,
+		Err:           fmt.Errorf("%w: tool call %d exceeded %d bytes", ErrToolArgumentsTooLarge, d.ToolCallIndex, g.limit),
+		ToolCallIndex: d.ToolCallIndex,
+		ToolCallID:    d.ToolCallID,
+		ToolCallName:  d.ToolCallName,
+		ChoiceIndex:   d.ChoiceIndex,
+		ReceivedAt:    d.ReceivedAt,
+		Seq:           d.Seq,
+	}
+}
+
+// toolAnnotator sets NormalizedDelta.Tool and UnknownTool. Its zero value
+// leaves deltas alone.
+type toolAnnotator map[string]*NormalizedTool
+
+func newToolAnnotator(tools []NormalizedTool) toolAnnotator {
+	a := make(toolAnnotator, len(tools))
+	for _, t := range tools {
+		t := t
+		a[t.Name] = &t
+	}
+	return a
+}
+
+func (a toolAnnotator) apply(d *NormalizedDelta) {
+	if a == nil || (d.Type != DeltaToolCallBegin && d.Type != DeltaToolCallDone) {
+		return
+	}
+	d.Tool = a[d.ToolCallName]
+	d.UnknownTool = d.Tool == nil
+}
@@ -0,0 +1,213 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToolCallAccumulatorPreview(t *testing.T) {
+	cases := []struct {
+		partial string
+		want    map[string]any
+	}{
+		{``, nil},
+		{`{`, map[string]any{}},
+		{`{"pa`, map[string]any{}},
+		{`{"path"`, map[string]any{}},
+		{`{"path":`, map[string]any{}},
+		{`{"path":"/tmp/da`, map[string]any{"path": "/tmp/da"}},
+		{`{"path":"/tmp/data.txt","lim`, map[string]any{"path": "/tmp/data.txt"}},
+		{`{"path":"/tmp/data.txt","limit":1`, map[string]any{"path": "/tmp/data.txt", "limit": float64(1)}},
+		{`{"path":"/tmp/data.txt","limit":1.`, map[string]any{"path": "/tmp/data.txt"}},
+		{`{"flag":tr`, map[string]any{}},
+		{`{"q":"say \"hi`, map[string]any{"q": `say "hi`}},
+		{`{"q":"say \"hi\"`, map[string]any{"q": `say "hi"`}},
+		{`{"q":"line\`, map[string]any{"q": "line"}},
+		{`{"q":"caf\u00`, map[string]any{"q": "caf"}},
+		{`{"q":"a\\`, map[string]any{"q": `a\`}},
+		{`{"opts":{"mode":"fast","tags":["a","b`, map[string]any{"opts": map[string]any{"mode": "fast", "tags": []any{"a", "b"}}}},
+		{`{"opts":{"mode":"fast"},"items":[{"id":1},{"id"`, map[string]any{"opts": map[string]any{"mode": "fast"}, "items": []any{map[string]any{"id": float64(1)}, map[string]any{}}}},
+		{`["not", "an object"`, nil},
+	}
+
+	for _, tc := range cases {
+		acc := NewToolCallAccumulator()
+		acc.Apply(NormalizedDelta{Type: DeltaToolCallBegin, ToolCallIndex: 0, ToolCallID: "c", ToolCallName: "read"})
+		// Feed one byte at a time to mimic fine-grained streaming.
+		for i := 0; i < len(tc.partial); i++ {
+			acc.Apply(NormalizedDelta{Type: DeltaToolCallArgumentsDelta, ToolCallIndex: 0, ArgumentsDelta: tc.partial[i : i+1]})
+		}
+		got, ok := acc.Preview(0)
+		if tc.want == nil {
+			if ok {
+				t.Fatalf("%q: expected no preview, got %v", tc.partial, got)
+			}
+			continue
+		}
+		if !ok || !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%q: preview mismatch:\nwant %#v\ngot  %#v (ok=%v)", tc.partial, tc.want, got, ok)
+		}
+	}
+
+	if _, ok := NewToolCallAccumulator().Preview(3); ok {
+		t.Fatalf("unknown index should have no preview")
+	}
+}
+
+func TestToolCallAccumulatorArgumentLimit(t *testing.T) {
+	acc := NewToolCallAccumulator()
+	acc.MaxArgumentBytes = 8
+	acc.Apply(NormalizedDelta{Type: DeltaToolCallBegin, ToolCallIndex: 0, ToolCallID: "big", ToolCallName: "write"})
+	acc.Apply(NormalizedDelta{Type: DeltaToolCallArgumentsDelta, ToolCallIndex: 0, ArgumentsDelta: `{"data":`})
+	acc.Apply(NormalizedDelta{Type: DeltaToolCallArgumentsDelta, ToolCallIndex: 0, ArgumentsDelta: `"xxxxxxxx"}`})
+	acc.Apply(NormalizedDelta{Type: DeltaToolCallBegin, ToolCallIndex: 1, ToolCallID: "small", ToolCallName: "read"})
+	acc.Apply(NormalizedDelta{Type: DeltaToolCallArgumentsDelta, ToolCallIndex: 1, ArgumentsDelta: `{}`})
+	acc.Apply(NormalizedDelta{Type: DeltaToolCallDone, ToolCallIndex: 2, ToolCallID: "full", ToolCallName: "write", ArgumentsFull: `{"data":"xxxxxxxx"}`})
+
+	calls := acc.CompleteCalls()
+	if len(calls) != 1 || calls[0].ID != "small" || string(calls[0].Arguments) != `{}` {
+		t.Fatalf("only the small call should complete, got %+v", calls)
+	}
+	truncated := acc.Truncated()
+	if len(truncated) != 2 || truncated[0].ID != "big" || truncated[1].ID != "full" || truncated[0].Arguments != nil {
+		t.Fatalf("unexpected truncated calls: %+v", truncated)
+	}
+	if _, ok := acc.Preview(0); ok {
+		t.Fatalf("truncated calls should have no preview")
+	}
+
+	unlimited := NewToolCallAccumulator()
+	unlimited.MaxArgumentBytes = -1
+	unlimited.Apply(NormalizedDelta{Type: DeltaToolCallDone, ToolCallIndex: 0, ToolCallID: "c", ArgumentsFull: `"` + strings.Repeat("x", DefaultMaxToolArgumentBytes) + `"`})
+	if len(unlimited.CompleteCalls()) != 1 || len(unlimited.Truncated()) != 0 {
+		t.Fatalf("negative limit should disable the check")
+	}
+}
+
+func TestStreamToolArgumentLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_big","type":"function","function":{"name":"write","arguments":""}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"data\":\""}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"xxxxxxxxxxxxxxxx"}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"}"}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_small","type":"function","function":{"name":"read","arguments":"{}"}}]}}]}`,
+		}
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, MaxToolArgumentBytes: 16})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "go"}}}
+
+	deltas, errs, err := client.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var errDeltas, bigArgs int
+	for d := range deltas {
+		switch {
+		case d.Type == DeltaError:
+			errDeltas++
+			if !errors.Is(d.Err, ErrToolArgumentsTooLarge) || d.ToolCallIndex != 0 {
+				t.Fatalf("unexpected error delta: %+v", d)
+			}
+		case d.Type == DeltaToolCallArgumentsDelta && d.ToolCallIndex == 0:
+			bigArgs++
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if errDeltas != 1 || bigArgs != 1 {
+		t.Fatalf("expected one error delta and only the first argument fragment, got %d and %d", errDeltas, bigArgs)
+	}
+
+	resp, err := client.CollectStream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_small" {
+		t.Fatalf("oversized call should be dropped, got %+v", resp.ToolCalls)
+	}
+	if resp.Summary.ToolCallsTruncated != 1 || resp.Summary.ToolCallsBegun != 2 {
+		t.Fatalf("summary mismatch: %+v", resp.Summary)
+	}
+}
+
+func TestStreamAnnotateToolCalls(t *testing.T) {
+	sse := `data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"add","arguments":"{}"}}]}}]}` + "\n\n" +
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"rm_rf","arguments":"{}"}}]}}]}` + "\n\n" +
+		"data: [DONE]\n\n"
+	server, client := newSSETestServer(t, sse)
+	defer server.Close()
+
+	req := NormalizedRequest{
+		Model:    "kimi-k2",
+		Messages: []NormalizedMessage{{Role: "user", Content: "go"}},
+		Tools:    []NormalizedTool{{Name: "add", Description: "Adds", Parameters: weatherTool.Parameters}},
+	}
+
+	collect := func(c *Client) []NormalizedDelta {
+		deltas, errs, err := c.Stream(testCtx(t), req)
+		if err != nil {
+			t.Fatalf("Stream: %v", err)
+		}
+		var begins []NormalizedDelta
+		for d := range deltas {
+			if d.Type == DeltaToolCallBegin {
+				begins = append(begins, d)
+			}
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+		return begins
+	}
+
+	if begins := collect(client); len(begins) != 2 || begins[0].Tool != nil || begins[1].UnknownTool {
+		t.Fatalf("deltas should not be annotated by default: %+v", begins)
+	}
+
+	begins := collect(client.With(func(cfg *Config) { cfg.AnnotateToolCalls = true }))
+	if len(begins) != 2 {
+		t.Fatalf("expected 2 begin deltas, got %d", len(begins))
+	}
+	if begins[0].Tool == nil || begins[0].Tool.Description != "Adds" || begins[0].UnknownTool {
+		t.Fatalf("declared tool should be attached: %+v", begins[0])
+	}
+	if begins[1].Tool != nil || !begins[1].UnknownTool {
+		t.Fatalf("undeclared tool should be flagged: %+v", begins[1])
+	}
+}
+
+func TestToolCallAccumulatorKeysCallsPerChoice(t *testing.T) {
+	acc := NewToolCallAccumulator()
+	for choice, city := range []string{"Paris", "Rome"} {
+		acc.Apply(NormalizedDelta{Type: DeltaToolCallBegin, ChoiceIndex: choice, ToolCallName: "get_weather"})
+		acc.Apply(NormalizedDelta{Type: DeltaToolCallArgumentsDelta, ChoiceIndex: choice, ArgumentsDelta: `{"city":"` + city + `"}`})
+	}
+	calls := acc.CompleteCalls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %+v", calls)
+	}
+	if calls[0].ID != "tool-0" || string(calls[0].Arguments) != `{"city":"Paris"}` {
+		t.Fatalf("first call mismatch: %+v", calls[0])
+	}
+	if calls[1].ID != "tool-1-0" || string(calls[1].Arguments) != `{"city":"Rome"}` {
+		t.Fatalf("second call mismatch: %+v", calls[1])
+	}
+}
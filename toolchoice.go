@@ -0,0 +1,109 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrToolCallExpected is wrapped by the *ToolCallExpectedError returned when
+// tool choice emulation could not get the model to call a tool.
+var ErrToolCallExpected = errors.New("zen: expected a tool call")
+
+// ToolCallExpectedError is returned by CreateNormalized and CollectStream
+// under tool choice emulation when the retry still answered without a tool
+// call. Response is that retry's response.
+type ToolCallExpectedError struct {
+	Response *NormalizedResponse
+}
+
+func (e *ToolCallExpectedError) Error() string {
+	return "zen: expected a tool call, the model answered with text"
+}
+
+func (e *ToolCallExpectedError) Unwrap() error {
+	return ErrToolCallExpected
+}
+
+// Tool choice emulation strategies, see ToolChoiceEmulation.
+const (
+	// ToolChoiceEmulationNudge retries with an instruction to call a tool
+	// appended to the system prompt.
+	ToolChoiceEmulationNudge = "nudge"
+	// ToolChoiceEmulationForceTool retries a "required" choice with a
+	// single declared tool as a choice of that tool.
+	ToolChoiceEmulationForceTool = "force_tool"
+)
+
+// ToolChoiceEmulation describes one retry made by tool choice emulation,
+// reported to Config.OnToolChoiceEmulation.
+type ToolChoiceEmulation struct {
+	Model    string
+	Endpoint EndpointType
+	Strategy string
+	// Text is what the model answered instead of calling a tool.
+	Text string
+	// Recovered reports whether the retry produced a tool call.
+	Recovered bool
+}
+
+func (c *Client) emulateToolChoice(req NormalizedRequest) bool {
+	if !c.cfg.EmulateToolChoice && !req.EmulateToolChoice {
+		return false
+	}
+	if req.ToolChoice == nil || len(req.Tools) == 0 {
+		return false
+	}
+	return req.ToolChoice.Type == ToolChoiceRequired || req.ToolChoice.Type == ToolChoiceTool
+}
+
+// withToolChoiceEmulation runs create and, when emulation applies and the
+// response has no tool call, retries once with a stronger tool choice.
+func (c *Client) withToolChoiceEmulation(ctx context.Context, req NormalizedRequest, create func(context.Context, NormalizedRequest) (*NormalizedResponse, error)) (*NormalizedResponse, error) {
+	resp, err := create(ctx, req)
+	if err != nil || !c.emulateToolChoice(req) || len(resp.ToolCalls) > 0 {
+		return resp, err
+	}
+
+	retry, strategy := toolChoiceRetry(req)
+	retried, err := create(ctx, retry)
+	if err != nil {
+		return retried, err
+	}
+	recovered := len(retried.ToolCalls) > 0
+	if c.cfg.OnToolChoiceEmulation != nil {
+		c.cfg.OnToolChoiceEmulation(ctx, ToolChoiceEmulation{
+			Model:     req.Model,
+			Endpoint:  retried.Info.Endpoint,
+			Strategy:  strategy,
+			Text:      resp.Text,
+			Recovered: recovered,
+		})
+	}
+	if !recovered {
+		return retried, &ToolCallExpectedError{Response: retried}
+	}
+	return retried, nil
+}
+
+// toolChoiceRetry returns the request to retry req with after it was
+// answered without a tool call, and the strategy used.
+func toolChoiceRetry(req NormalizedRequest) (NormalizedRequest, string) {
+	if req.ToolChoice.Type == ToolChoiceRequired && len(req.Tools) == 1 {
+		req.ToolChoice = &NormalizedToolChoice{Type: ToolChoiceTool, Name: req.Tools[0].Name}
+		return req, ToolChoiceEmulationForceTool
+	}
+	nudge := "You must respond by calling one of the provided tools. Do not answer with text."
+	if req.ToolChoice.Type == ToolChoiceTool {
+		nudge = fmt.Sprintf("You must respond by calling the %s tool. Do not answer with text.", req.ToolChoice.Name)
+	}
+	if len(req.SystemParts) > 0 {
+		req.SystemParts = append(slices.Clip(req.SystemParts), SystemPart{Text: "\n\n" + nudge})
+	} else if req.System != "" {
+		req.System += "\n\n" + nudge
+	} else {
+		req.System = nudge
+	}
+	return req, ToolChoiceEmulationNudge
+}
@@ -0,0 +1,114 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	chatTextResponse     = `{"choices":[{"message":{"role":"assistant","content":"It is sunny."},"finish_reason":"stop"}]}`
+	chatToolCallResponse = `{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}`
+)
+
+type toolChoiceBody struct {
+	Messages   []json.RawMessage `json:"messages"`
+	ToolChoice json.RawMessage   `json:"tool_choice"`
+}
+
+func newToolChoiceServer(t *testing.T, responses ...string) (*httptest.Server, *[]toolChoiceBody) {
+	t.Helper()
+	var bodies []toolChoiceBody
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body toolChoiceBody
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(responses[min(len(bodies), len(responses))-1]))
+	}))
+	return server, &bodies
+}
+
+func TestEmulateToolChoiceForcesSingleTool(t *testing.T) {
+	server, bodies := newToolChoiceServer(t, chatTextResponse, chatToolCallResponse)
+	defer server.Close()
+
+	var events []ToolChoiceEmulation
+	client, err := NewClient(Config{
+		APIKey:            "key",
+		BaseURL:           server.URL,
+		EmulateToolChoice: true,
+		OnToolChoiceEmulation: func(ctx context.Context, ev ToolChoiceEmulation) {
+			events = append(events, ev)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	resp, err := client.CreateNormalized(testCtx(t), NormalizedRequest{
+		Model:      "kimi-k2",
+		Messages:   []NormalizedMessage{{Role: "user", Content: "Weather in Paris?"}},
+		Tools:      []NormalizedTool{weatherTool},
+		ToolChoice: &NormalizedToolChoice{Type: ToolChoiceRequired},
+	})
+	if err != nil {
+		t.Fatalf("CreateNormalized: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "get_weather" {
+		t.Fatalf("expected the retried tool call, got %+v", resp.ToolCalls)
+	}
+	if len(*bodies) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(*bodies))
+	}
+	if forced := (*bodies)[1].ToolChoice; string(forced) != `{"function":{"name":"get_weather"},"type":"function"}` {
+		t.Fatalf("retry should force the tool, got %s", forced)
+	}
+	if len(events) != 1 || events[0].Strategy != ToolChoiceEmulationForceTool || !events[0].Recovered || events[0].Text != "It is sunny." {
+		t.Fatalf("unexpected emulation events: %+v", events)
+	}
+}
+
+func TestEmulateToolChoiceReportsText(t *testing.T) {
+	server, bodies := newToolChoiceServer(t, chatTextResponse)
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	other := weatherTool
+	other.Name = "get_time"
+	req := NormalizedRequest{
+		Model:      "kimi-k2",
+		Messages:   []NormalizedMessage{{Role: "user", Content: "Weather in Paris?"}},
+		Tools:      []NormalizedTool{weatherTool, other},
+		ToolChoice: &NormalizedToolChoice{Type: ToolChoiceRequired},
+	}
+
+	if _, err := client.CreateNormalized(testCtx(t), req); err != nil {
+		t.Fatalf("emulation is off by default: %v", err)
+	}
+
+	req.EmulateToolChoice = true
+	resp, err := client.CreateNormalized(testCtx(t), req)
+	var expected *ToolCallExpectedError
+	if !errors.As(err, &expected) || !errors.Is(err, ErrToolCallExpected) {
+		t.Fatalf("expected ToolCallExpectedError, got %v", err)
+	}
+	if resp.Text != "It is sunny." || expected.Response != resp {
+		t.Fatalf("text answer not returned: %+v", resp)
+	}
+	if len(*bodies) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(*bodies))
+	}
+	if nudged := (*bodies)[2].Messages[0]; string(nudged) != `{"role":"system","content":"You must respond by calling one of the provided tools. Do not answer with text."}` {
+		t.Fatalf("retry should nudge in the system prompt, got %s", nudged)
+	}
+}
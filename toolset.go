@@ -0,0 +1,209 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ToolFunc executes a tool with the arguments the model supplied. The returned
+// string becomes the tool result content; a non-nil error produces a tool
+// result with IsError set and the error text as content.
+type ToolFunc func(ctx context.Context, args json.RawMessage) (string, error)
+
+// ToolSet holds the tools offered to the model together with their
+// implementations, and turns completed tool calls into tool-result messages.
+type ToolSet struct {
+	// ValidateArguments checks each call's arguments against the tool's
+	// Parameters schema (see ValidateToolCall) before running it. Invalid
+	// calls are not executed; they produce an error tool result describing
+	// the violations, which models reliably use to correct their next call.
+	ValidateArguments bool
+
+	// CancelledResults answers calls with an error tool result reading
+	// "cancelled by user" once ctx is cancelled, instead of running them
+	// with the cancelled context: calls not yet started are not run, and
+	// Execute stops waiting for a running call, which keeps running until
+	// its ToolFunc notices the cancellation. Every call thus still gets a
+	// result, which providers require before the conversation can resume.
+	CancelledResults bool
+
+	tools []NormalizedTool
+	funcs map[string]ToolFunc
+}
+
+// NewToolSet returns an empty tool set.
+func NewToolSet() *ToolSet {
+	return &ToolSet{funcs: map[string]ToolFunc{}}
+}
+
+// Add registers a tool. Registering a name twice replaces the earlier tool.
+func (s *ToolSet) Add(tool NormalizedTool, fn ToolFunc) {
+	if _, exists := s.funcs[tool.Name]; exists {
+		for i := range s.tools {
+			if s.tools[i].Name == tool.Name {
+				s.tools[i] = tool
+			}
+		}
+	} else {
+		s.tools = append(s.tools, tool)
+	}
+	s.funcs[tool.Name] = fn
+}
+
+// Tools returns the registered tool definitions, for NormalizedRequest.Tools.
+func (s *ToolSet) Tools() []NormalizedTool {
+	out := make([]NormalizedTool, len(s.tools))
+	copy(out, s.tools)
+	return out
+}
+
+// Lookup returns the definition of the named tool.
+func (s *ToolSet) Lookup(name string) (NormalizedTool, bool) {
+	for _, t := range s.tools {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return NormalizedTool{}, false
+}
+
+// Execute runs a single call and returns its tool-result message.
+func (s *ToolSet) Execute(ctx context.Context, call StreamToolCall) NormalizedMessage {
+	msg := NormalizedMessage{
+		Role:         "tool",
+		ToolCallID:   call.ID,
+		FunctionName: call.Name,
+	}
+
+	tool, ok := s.Lookup(call.Name)
+	fn := s.funcs[call.Name]
+	if !ok || fn == nil {
+		msg.Content = "unknown tool: " + call.Name
+		msg.IsError = true
+		return msg
+	}
+
+	if s.ValidateArguments {
+		if err := ValidateToolCall(call, tool); err != nil {
+			msg.Content = err.Error()
+			msg.IsError = true
+			return msg
+		}
+	}
+
+	if s.CancelledResults {
+		return s.executeCancellable(ctx, call, fn, msg)
+	}
+	out, err := fn(ctx, call.Arguments)
+	if err != nil {
+		msg.Content = "error: " + err.Error()
+		msg.IsError = true
+		return msg
+	}
+	msg.Content = out
+	return msg
+}
+
+// cancelledToolResult is the content of the results CancelledResults
+// synthesizes.
+const cancelledToolResult = "cancelled by user"
+
+// executeCancellable runs fn for call unless ctx is cancelled first, in which
+// case msg becomes a cancelled result.
+func (s *ToolSet) executeCancellable(ctx context.Context, call StreamToolCall, fn ToolFunc, msg NormalizedMessage) NormalizedMessage {
+	msg.IsError = true
+	msg.Content = cancelledToolResult
+	if ctx.Err() != nil {
+		return msg
+	}
+	type outcome struct {
+		out string
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		out, err := fn(ctx, call.Arguments)
+		done <- outcome{out, err}
+	}()
+	select {
+	case <-ctx.Done():
+		return msg
+	case o := <-done:
+		switch {
+		case o.err != nil && ctx.Err() != nil:
+		case o.err != nil:
+			msg.Content = "error: " + o.err.Error()
+		default:
+			msg.Content = o.out
+			msg.IsError = false
+		}
+		return msg
+	}
+}
+
+// ExecuteAll runs each call in order and returns one tool-result message per
+// call, ready to append after the assistant message that made the calls.
+func (s *ToolSet) ExecuteAll(ctx context.Context, calls []StreamToolCall) []NormalizedMessage {
+	out := make([]NormalizedMessage, 0, len(calls))
+	for _, call := range calls {
+		out = append(out, s.Execute(ctx, call))
+	}
+	return out
+}
+
+// CheckToolCalls matches calls against the declared tools before execution.
+// Calls with an empty name are assigned the only declared tool when there is
+// exactly one, which works around backends that drop the name. For every
+// remaining call naming an undeclared tool, rejected holds an error tool
+// result keyed by call ID, e.g.
+//
+//	{"error":"unknown_tool","tool":"rm_rf","available_tools":["add","now"]}
+//
+// The returned calls keep their order and include rejected ones, so the
+// assistant message can carry all of them; execute only calls without a
+// rejection and append the rejections in their place.
+func CheckToolCalls(calls []StreamToolCall, tools []NormalizedTool) (checked []StreamToolCall, rejected map[string]NormalizedMessage) {
+	declared := make(map[string]bool, len(tools))
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		declared[t.Name] = true
+		names = append(names, t.Name)
+	}
+
+	checked = make([]StreamToolCall, len(calls))
+	for i, call := range calls {
+		if call.Name == "" && len(tools) == 1 {
+			call.Name = tools[0].Name
+		}
+		checked[i] = call
+		if declared[call.Name] {
+			continue
+		}
+		content, _ := jsonMarshal(struct {
+			Error     string   `json:"error"`
+			Tool      string   `json:"tool"`
+			Available []string `json:"available_tools"`
+		}{"unknown_tool", call.Name, names})
+		if rejected == nil {
+			rejected = map[string]NormalizedMessage{}
+		}
+		rejected[call.ID] = NormalizedMessage{
+			Role:         "tool",
+			ToolCallID:   call.ID,
+			FunctionName: call.Name,
+			Content:      string(content),
+			IsError:      true,
+		}
+	}
+	return checked, rejected
+}
+
+// AssistantMessage builds the assistant history entry for a turn that ended
+// with tool calls.
+func AssistantMessage(text string, calls []StreamToolCall) NormalizedMessage {
+	msg := NormalizedMessage{Role: "assistant", Content: text}
+	for _, c := range calls {
+		msg.ToolCalls = append(msg.ToolCalls, NormalizedToolCall(c))
+	}
+	return msg
+}
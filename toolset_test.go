@@ -0,0 +1,131 @@
+package zen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestToolSetExecute(t *testing.T) {
+	set := NewToolSet()
+	set.ValidateArguments = true
+	ran := 0
+	set.Add(weatherTool, func(_ context.Context, args json.RawMessage) (string, error) {
+		ran++
+		var in struct {
+			City string `json:"city"`
+		}
+		if err := json.Unmarshal(args, &in); err != nil {
+			return "", err
+		}
+		if in.City == "Atlantis" {
+			return "", errors.New("city not found")
+		}
+		return "Sunny in " + in.City, nil
+	})
+
+	results := set.ExecuteAll(context.Background(), []StreamToolCall{
+		{ID: "1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+		{ID: "2", Name: "get_weather", Arguments: json.RawMessage(`{"city":7}`)},
+		{ID: "3", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Atlantis"}`)},
+		{ID: "4", Name: "get_time", Arguments: json.RawMessage(`{}`)},
+	})
+
+	if r := results[0]; r.Role != "tool" || r.ToolCallID != "1" || r.FunctionName != "get_weather" || r.Content != "Sunny in Paris" || r.IsError {
+		t.Fatalf("result[0] mismatch: %+v", r)
+	}
+	if r := results[1]; !r.IsError || !strings.Contains(r.Content, "/city: expected string") {
+		t.Fatalf("result[1] should describe the violation: %+v", r)
+	}
+	if r := results[2]; !r.IsError || r.Content != "error: city not found" {
+		t.Fatalf("result[2] mismatch: %+v", r)
+	}
+	if r := results[3]; !r.IsError || r.Content != "unknown tool: get_time" {
+		t.Fatalf("result[3] mismatch: %+v", r)
+	}
+	if ran != 2 {
+		t.Fatalf("invalid and unknown calls must not execute; ran %d times", ran)
+	}
+}
+
+func TestToolSetAddReplaces(t *testing.T) {
+	set := NewToolSet()
+	set.Add(NormalizedTool{Name: "a", Description: "old"}, func(context.Context, json.RawMessage) (string, error) { return "old", nil })
+	set.Add(NormalizedTool{Name: "a", Description: "new"}, func(context.Context, json.RawMessage) (string, error) { return "new", nil })
+	tools := set.Tools()
+	if len(tools) != 1 || tools[0].Description != "new" {
+		t.Fatalf("expected replaced tool, got %+v", tools)
+	}
+	if got := set.Execute(context.Background(), StreamToolCall{Name: "a"}); got.Content != "new" {
+		t.Fatalf("expected new implementation, got %q", got.Content)
+	}
+}
+
+func TestCheckToolCallsSingleToolAutofill(t *testing.T) {
+	tools := []NormalizedTool{{Name: "add"}}
+	calls, rejected := CheckToolCalls([]StreamToolCall{
+		{ID: "1", Arguments: json.RawMessage(`{"a":1,"b":2}`)},
+		{ID: "2", Name: "add", Arguments: json.RawMessage(`{}`)},
+	}, tools)
+	if len(rejected) != 0 {
+		t.Fatalf("no call should be rejected: %+v", rejected)
+	}
+	if calls[0].Name != "add" || calls[1].Name != "add" || string(calls[0].Arguments) != `{"a":1,"b":2}` {
+		t.Fatalf("empty name should be filled with the only tool: %+v", calls)
+	}
+}
+
+func TestCheckToolCallsMultiToolRejection(t *testing.T) {
+	tools := []NormalizedTool{{Name: "add"}, {Name: "now"}}
+	in := []StreamToolCall{
+		{ID: "1", Name: "add"},
+		{ID: "2", Name: "rm_rf"},
+		{ID: "3"},
+	}
+	calls, rejected := CheckToolCalls(in, tools)
+	if len(calls) != 3 || calls[2].Name != "" {
+		t.Fatalf("calls should be returned in order without autofill: %+v", calls)
+	}
+	if len(rejected) != 2 {
+		t.Fatalf("expected 2 rejections, got %+v", rejected)
+	}
+	msg := rejected["2"]
+	if msg.Role != "tool" || msg.ToolCallID != "2" || msg.FunctionName != "rm_rf" || !msg.IsError {
+		t.Fatalf("rejection mismatch: %+v", msg)
+	}
+	if msg.Content != `{"error":"unknown_tool","tool":"rm_rf","available_tools":["add","now"]}` {
+		t.Fatalf("unexpected rejection content: %s", msg.Content)
+	}
+	if _, ok := rejected["3"]; !ok {
+		t.Fatalf("a nameless call with several tools declared should be rejected")
+	}
+}
+
+func TestRunToolsRejectsUnknownTool(t *testing.T) {
+	unknown := "data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"rm_rf\",\"arguments\":\"{}\"}}]}}]}\n\n" +
+		"data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"tool_calls\"}]}\n\ndata: [DONE]\n\n"
+	server := newScriptedChatServer(t, []string{unknown, answerStep("sorry", 0)})
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	set := NewToolSet()
+	ran := false
+	set.Add(NormalizedTool{Name: "add"}, func(context.Context, json.RawMessage) (string, error) { ran = true; return "", nil })
+	set.Add(NormalizedTool{Name: "now"}, func(context.Context, json.RawMessage) (string, error) { ran = true; return "", nil })
+
+	result, err := client.RunTools(testCtx(t), NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "go"}}}, set, RunOptions{})
+	if err != nil {
+		t.Fatalf("RunTools: %v", err)
+	}
+	if ran {
+		t.Fatalf("no tool should run")
+	}
+	if m := result.Messages[2]; !m.IsError || !strings.Contains(m.Content, `"unknown_tool"`) {
+		t.Fatalf("unknown call should get an error result: %+v", m)
+	}
+}
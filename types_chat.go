@@ -1,6 +1,10 @@
 package zen
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
 
 type ChatCompletionsRequest struct {
 	Model       string
@@ -10,8 +14,22 @@ type ChatCompletionsRequest struct {
 	ToolChoice  any
 	Temperature *float64
 	MaxTokens   *int
-	Stream      bool
-	Extra       map[string]any
+	// Stop is sent as stop. The API does not report which entry matched.
+	Stop   []string
+	Stream bool
+	// ServiceTier selects the processing tier, e.g. "auto", "flex" or
+	// "priority". Values are passed through unchecked.
+	ServiceTier string
+	// Modalities and Audio request audio output, e.g. Modalities
+	// ["text", "audio"] with Audio{Voice: "alloy", Format: "wav"}.
+	Modalities []string
+	Audio      *ChatAudioConfig
+	Extra      map[string]any
+}
+
+type ChatAudioConfig struct {
+	Voice  string `json:"voice"`
+	Format string `json:"format"`
 }
 
 type ChatMessageToolCall struct {
@@ -30,6 +48,82 @@ type ChatMessage struct {
 	Content    string                `json:"content,omitempty"`
 	ToolCalls  []ChatMessageToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string                `json:"tool_call_id,omitempty"`
+	// Parts, when set, is sent as content instead of Content. Decoding an
+	// array-valued content fills Parts and leaves Content empty.
+	Parts []ChatContentPart `json:"-"`
+}
+
+// ChatContentPart is an element of an array-valued message content.
+type ChatContentPart struct {
+	Type       string          `json:"type"`
+	Text       string          `json:"text,omitempty"`
+	InputAudio *ChatInputAudio `json:"input_audio,omitempty"`
+}
+
+// ChatInputAudio is base64-encoded audio input in Format ("wav" or "mp3").
+type ChatInputAudio struct {
+	Data   string `json:"data"`
+	Format string `json:"format"`
+}
+
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	type plain ChatMessage
+	if len(m.Parts) == 0 {
+		return jsonMarshal(plain(m))
+	}
+	return jsonMarshal(struct {
+		plain
+		Content []ChatContentPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+func (m *ChatMessage) UnmarshalJSON(data []byte) error {
+	type plain ChatMessage
+	var v struct {
+		plain
+		Content chatContent `json:"content"`
+	}
+	if err := jsonUnmarshal(data, &v); err != nil {
+		return err
+	}
+	*m = ChatMessage(v.plain)
+	m.Content = v.Content.Text
+	m.Parts = v.Content.Parts
+	return nil
+}
+
+// chatContent is a Chat Completions content value, which backends send
+// either as a string or as an array of content parts.
+type chatContent struct {
+	Text  string
+	Parts []ChatContentPart
+}
+
+func (c *chatContent) UnmarshalJSON(data []byte) error {
+	switch {
+	case string(data) == "null":
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		return jsonUnmarshal(data, &c.Text)
+	}
+	if err := jsonUnmarshal(data, &c.Parts); err != nil {
+		return errors.New("zen: content must be a string or an array of content parts")
+	}
+	return nil
+}
+
+// text returns the string content, or the joined text of the content parts.
+func (c chatContent) text() string {
+	if c.Parts == nil {
+		return c.Text
+	}
+	var b strings.Builder
+	for _, p := range c.Parts {
+		if p.Type == "text" || p.Type == "output_text" {
+			b.WriteString(p.Text)
+		}
+	}
+	return b.String()
 }
 
 type ChatReasoning struct {
@@ -67,9 +161,21 @@ func (r ChatCompletionsRequest) MarshalJSON() ([]byte, error) {
 	if r.MaxTokens != nil {
 		base["max_tokens"] = r.MaxTokens
 	}
+	if len(r.Stop) > 0 {
+		base["stop"] = r.Stop
+	}
 	if r.Stream {
 		base["stream"] = r.Stream
 	}
+	if r.ServiceTier != "" {
+		base["service_tier"] = r.ServiceTier
+	}
+	if len(r.Modalities) > 0 {
+		base["modalities"] = r.Modalities
+	}
+	if r.Audio != nil {
+		base["audio"] = r.Audio
+	}
 
 	return marshalWithExtra(base, r.Extra)
 }
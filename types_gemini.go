@@ -40,7 +40,7 @@ func (b GeminiFunctionResponseBody) MarshalJSON() ([]byte, error) {
 	if len(b.Content) > 0 {
 		return b.Content, nil
 	}
-	return json.Marshal(struct {
+	return jsonMarshal(struct {
 		Output string `json:"output"`
 	}{Output: b.Output})
 }
@@ -49,7 +49,30 @@ type GeminiPart struct {
 	Text             string                  `json:"text,omitempty"`
 	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
 	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
-	ThoughtSignature string                  `json:"thoughtSignature,omitempty"`
+	FileData         *GeminiFileData         `json:"fileData,omitempty"`
+	// ExecutableCode and CodeExecutionResult are the parts of a model turn
+	// that used the code execution tool, kept as sent by the provider.
+	ExecutableCode      json.RawMessage `json:"executableCode,omitempty"`
+	CodeExecutionResult json.RawMessage `json:"codeExecutionResult,omitempty"`
+	ThoughtSignature    string          `json:"thoughtSignature,omitempty"`
+	// Raw, when set, is marshalled as the entire part instead of the
+	// fields above.
+	Raw json.RawMessage `json:"-"`
+}
+
+func (p GeminiPart) MarshalJSON() ([]byte, error) {
+	if len(p.Raw) > 0 {
+		return p.Raw, nil
+	}
+	type plain GeminiPart
+	return jsonMarshal(plain(p))
+}
+
+// GeminiFileData references a file uploaded through the Files API (or a
+// supported external URI) instead of inlining its bytes.
+type GeminiFileData struct {
+	FileURI  string `json:"fileUri"`
+	MimeType string `json:"mimeType,omitempty"`
 }
 
 type GeminiTool struct {
@@ -74,6 +97,8 @@ type GeminiFunctionCallingConfig struct {
 type GeminiGenerationConfig struct {
 	Temperature     *float64              `json:"temperature,omitempty"`
 	MaxOutputTokens *int                  `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string              `json:"stopSequences,omitempty"`
+	CandidateCount  *int                  `json:"candidateCount,omitempty"`
 	ThinkingConfig  *GeminiThinkingConfig `json:"thinkingConfig,omitempty"`
 }
 
@@ -91,7 +116,11 @@ func (r GeminiRequest) MarshalJSON() ([]byte, error) {
 		base["systemInstruction"] = r.SystemInstruction
 	}
 	if r.GenerationConfig != nil {
-		base["generationConfig"] = r.GenerationConfig
+		config, err := withObjectExtra(r.GenerationConfig, r.Extra["generationConfig"])
+		if err != nil {
+			return nil, err
+		}
+		base["generationConfig"] = config
 	}
 	if len(r.Tools) > 0 {
 		base["tools"] = r.Tools
@@ -3,16 +3,33 @@ package zen
 import "encoding/json"
 
 type MessagesRequest struct {
-	Model       string
-	System      string
-	Messages    []AnthropicMessage
-	Tools       []AnthropicTool
-	ToolChoice  *AnthropicToolChoice
-	Thinking    *AnthropicThinking
-	Temperature *float64
-	MaxTokens   *int
-	Stream      bool
-	Extra       map[string]any
+	Model  string
+	System string
+	// SystemBlocks, when set, is sent as system instead of System.
+	SystemBlocks []AnthropicSystemBlock
+	Messages     []AnthropicMessage
+	Tools        []AnthropicTool
+	ToolChoice   *AnthropicToolChoice
+	Thinking     *AnthropicThinking
+	Temperature  *float64
+	MaxTokens    *int
+	// StopSequences is sent as stop_sequences. When one matches, the
+	// stream reports it as NormalizedDelta.StopSequence.
+	StopSequences []string
+	Stream        bool
+	Extra         map[string]any
+}
+
+// AnthropicSystemBlock is a text block of an array-valued system prompt.
+type AnthropicSystemBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *AnthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// AnthropicCacheControl marks the end of a prompt prefix to cache.
+type AnthropicCacheControl struct {
+	Type string `json:"type"`
 }
 
 type AnthropicContentBlock struct {
@@ -22,7 +39,35 @@ type AnthropicContentBlock struct {
 	Name      string          `json:"name,omitempty"`
 	Input     json.RawMessage `json:"input,omitempty"`
 	ToolUseID string          `json:"tool_use_id,omitempty"`
-	Content   string          `json:"content,omitempty"`
+	// Content is the content of a tool_result block: a string or
+	// []AnthropicContentBlock of text and image blocks.
+	Content any                   `json:"content,omitempty"`
+	IsError bool                  `json:"is_error,omitempty"`
+	Source  *AnthropicImageSource `json:"source,omitempty"`
+	// Thinking and Signature are set on thinking blocks, Data on
+	// redacted_thinking blocks.
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+// AnthropicImageSource is the source of an image or document block: inline
+// base64 data or a URL.
+type AnthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// MarshalJSON leaves out an empty string Content, as when Content was a
+// plain string field.
+func (b AnthropicContentBlock) MarshalJSON() ([]byte, error) {
+	type block AnthropicContentBlock
+	if s, ok := b.Content.(string); ok && s == "" {
+		b.Content = nil
+	}
+	return jsonMarshal(block(b))
 }
 
 type AnthropicMessage struct {
@@ -31,9 +76,33 @@ type AnthropicMessage struct {
 }
 
 type AnthropicTool struct {
+	// Type and Version declare a built-in tool, e.g. "computer" and
+	// "20250124" are sent as type "computer_20250124", without an
+	// input_schema. Extra holds its settings, e.g. display_width_px. A Type
+	// that already carries its version is sent as is; "custom" and an
+	// empty Type declare a regular tool.
+	Type        string          `json:"type,omitempty"`
+	Version     string          `json:"-"`
 	Name        string          `json:"name"`
 	Description string          `json:"description,omitempty"`
 	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+	Extra       map[string]any  `json:"-"`
+}
+
+func (t AnthropicTool) MarshalJSON() ([]byte, error) {
+	type tool AnthropicTool
+	if t.Type == "" || t.Type == "custom" {
+		return jsonMarshal(tool(t))
+	}
+	typ := t.Type
+	if t.Version != "" {
+		typ += "_" + t.Version
+	}
+	base := map[string]any{
+		"type": typ,
+		"name": t.Name,
+	}
+	return marshalWithExtra(base, t.Extra)
 }
 
 type AnthropicToolChoice struct {
@@ -51,7 +120,9 @@ func (r MessagesRequest) MarshalJSON() ([]byte, error) {
 		"model":    r.Model,
 		"messages": r.Messages,
 	}
-	if r.System != "" {
+	if len(r.SystemBlocks) > 0 {
+		base["system"] = r.SystemBlocks
+	} else if r.System != "" {
 		base["system"] = r.System
 	}
 	if len(r.Tools) > 0 {
@@ -69,6 +140,9 @@ func (r MessagesRequest) MarshalJSON() ([]byte, error) {
 	if r.MaxTokens != nil {
 		base["max_tokens"] = r.MaxTokens
 	}
+	if len(r.StopSequences) > 0 {
+		base["stop_sequences"] = r.StopSequences
+	}
 	if r.Stream {
 		base["stream"] = r.Stream
 	}
@@ -12,7 +12,22 @@ type ResponsesRequest struct {
 	Temperature     *float64
 	MaxOutputTokens *int
 	Stream          bool
-	Extra           map[string]any
+	// Truncation is "auto" to let the server drop older input items that do
+	// not fit the context window, or "disabled" (the server default).
+	Truncation string
+	// Store, when false, asks the server not to keep the response for
+	// later reference by id.
+	Store *bool
+	// Include requests additional output sections, e.g.
+	// "reasoning.encrypted_content".
+	Include []string
+	// ServiceTier selects the processing tier, e.g. "auto", "flex" or
+	// "priority". Values are passed through unchecked.
+	ServiceTier string
+	// Background runs the response asynchronously: the request returns
+	// at once with a queued response to poll (see Client.WaitForResponse).
+	Background bool
+	Extra      map[string]any
 }
 
 type ResponsesReasoning struct {
@@ -30,11 +45,25 @@ type ResponsesTool struct {
 	Parameters  json.RawMessage `json:"parameters,omitempty"`
 }
 
+// ResponsesInputMessage is a message item in the Responses API input array.
+// Type is always "message", and sent as such when left empty. ID and Status
+// are set on assistant items that replay an earlier output message.
 type ResponsesInputMessage struct {
+	Type    string                  `json:"type"`
+	ID      string                  `json:"id,omitempty"`
 	Role    string                  `json:"role"`
+	Status  string                  `json:"status,omitempty"`
 	Content []ResponsesInputContent `json:"content"`
 }
 
+func (m ResponsesInputMessage) MarshalJSON() ([]byte, error) {
+	if m.Type == "" {
+		m.Type = "message"
+	}
+	type plain ResponsesInputMessage
+	return jsonMarshal(plain(m))
+}
+
 type ResponsesInputContent struct {
 	Type string `json:"type"`
 	Text string `json:"text,omitempty"`
@@ -50,11 +79,25 @@ type ResponsesFunctionCall struct {
 }
 
 // ResponsesFunctionCallOutput represents a tool result item in the Responses
-// API input array.
+// API input array. OutputJSON, when set, is sent as the output in place of
+// the Output string, without encoding it as a string first.
 type ResponsesFunctionCallOutput struct {
-	Type   string `json:"type"`
-	CallID string `json:"call_id"`
-	Output string `json:"output"`
+	Type       string          `json:"type"`
+	CallID     string          `json:"call_id"`
+	Output     string          `json:"output"`
+	OutputJSON json.RawMessage `json:"-"`
+}
+
+func (o ResponsesFunctionCallOutput) MarshalJSON() ([]byte, error) {
+	var output any = o.Output
+	if len(o.OutputJSON) > 0 {
+		output = o.OutputJSON
+	}
+	return jsonMarshal(struct {
+		Type   string `json:"type"`
+		CallID string `json:"call_id"`
+		Output any    `json:"output"`
+	}{o.Type, o.CallID, output})
 }
 
 func (r ResponsesRequest) MarshalJSON() ([]byte, error) {
@@ -85,6 +128,21 @@ func (r ResponsesRequest) MarshalJSON() ([]byte, error) {
 	if r.Stream {
 		base["stream"] = r.Stream
 	}
+	if r.Truncation != "" {
+		base["truncation"] = r.Truncation
+	}
+	if r.Store != nil {
+		base["store"] = *r.Store
+	}
+	if len(r.Include) > 0 {
+		base["include"] = r.Include
+	}
+	if r.ServiceTier != "" {
+		base["service_tier"] = r.ServiceTier
+	}
+	if r.Background {
+		base["background"] = true
+	}
 
 	return marshalWithExtra(base, r.Extra)
 }
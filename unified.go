@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type EndpointType string
@@ -19,60 +23,80 @@ const (
 
 type UnifiedEvent struct {
 	Endpoint EndpointType
+	ID       string
 	Event    string
 	Data     json.RawMessage
 	Raw      string
+	// ReceivedAt and Seq are copied from the underlying StreamEvent.
+	ReceivedAt time.Time
+	Seq        int64
+}
+
+// StreamInfo describes how a normalized request was sent.
+type StreamInfo struct {
+	Endpoint EndpointType
+	// Path is the request path relative to Config.BaseURL.
+	Path string
+	// Body is the marshalled provider payload.
+	Body []byte
+	// Header holds the response headers and RequestID the provider's
+	// request id taken from them.
+	Header    http.Header
+	RequestID string
+	// StatusCode is the HTTP status of a streaming response; it is zero for
+	// non-streaming requests.
+	StatusCode int
+
+	// received counts the response bytes of a stream.
+	received *atomic.Int64
+}
+
+// StreamHandle is an open normalized stream together with its StreamInfo.
+// Deltas and Errs behave as the channels returned by Client.Stream; Next,
+// Err and Close read them in the required order instead.
+type StreamHandle struct {
+	Deltas <-chan NormalizedDelta
+	Errs   <-chan error
+	Info   StreamInfo
+
+	pump *channelPump[NormalizedDelta]
+	// lastEventID is the id of the last event that had one; it is set once
+	// Errs has been read.
+	lastEventID string
 }
 
 // StreamEvents is the unified streaming API. It routes the request based on
 // the normalized model id and returns raw SSE events with the resolved endpoint.
+// Callers must drain the events channel and then read the error channel
+// exactly once, or cancel ctx to abandon the stream; OpenStreamEvents returns
+// an EventStream that does this.
 func (c *Client) StreamEvents(ctx context.Context, req NormalizedRequest) (<-chan UnifiedEvent, <-chan error, error) {
-	req.Model = stripOpencodePrefix(req.Model)
-	endpoint, path, err := resolveStreamEndpoint(req)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	req.Stream = true
-
-	var body any
-	switch endpoint {
-	case EndpointResponses:
-		payload, err := req.ToResponsesRequest()
-		if err != nil {
-			return nil, nil, err
-		}
-		body = payload
-	case EndpointMessages:
-		payload, err := req.ToMessagesRequest()
-		if err != nil {
-			return nil, nil, err
-		}
-		body = payload
-	case EndpointChatCompletions:
-		payload, err := req.ToChatCompletionsRequest()
-		if err != nil {
-			return nil, nil, err
-		}
-		body = payload
-	case EndpointModels:
-		payload, err := req.ToGeminiRequest()
-		if err != nil {
-			return nil, nil, err
-		}
-		body = payload
-	default:
-		return nil, nil, errors.New("zen: unsupported endpoint")
-	}
+	out, errCh, _, err := c.streamEvents(ctx, req)
+	return out, errCh, err
+}
 
-	payload, err := jsonBody(body, nil)
+func (c *Client) streamEvents(ctx context.Context, req NormalizedRequest) (<-chan UnifiedEvent, <-chan error, *StreamInfo, error) {
+	ctx, endpoint, path, payload, err := c.buildRequest(ctx, req, true)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
+	return c.streamPath(ctx, endpoint, "POST", path, payload)
+}
 
-	stream, err := c.startStream(ctx, endpoint, "POST", path, payload)
+// streamPath opens a stream of raw events from path.
+func (c *Client) streamPath(ctx context.Context, endpoint EndpointType, method, path string, payload []byte) (<-chan UnifiedEvent, <-chan error, *StreamInfo, error) {
+	stream, err := c.startStream(ctx, endpoint, method, path, payload)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	info := &StreamInfo{
+		Endpoint:   endpoint,
+		Path:       path,
+		Body:       payload,
+		Header:     stream.Header,
+		RequestID:  requestIDFromHeader(stream.Header),
+		StatusCode: stream.StatusCode,
+		received:   stream.received,
 	}
 
 	out := make(chan UnifiedEvent)
@@ -83,84 +107,373 @@ func (c *Client) StreamEvents(ctx context.Context, req NormalizedRequest) (<-cha
 		defer close(errCh)
 		defer func() { _ = stream.Close() }()
 
+		joiner := c.newPayloadJoiner(ctx, endpoint)
+		send := func(events []UnifiedEvent) bool {
+			for _, ev := range events {
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					errCh <- ctx.Err()
+					return false
+				}
+			}
+			return true
+		}
 		for ev := range stream.Events {
-			out <- UnifiedEvent{
-				Endpoint: endpoint,
-				Event:    ev.Event,
-				Data:     ev.Data,
-				Raw:      ev.Raw,
+			if !send(joiner.add(UnifiedEvent{
+				Endpoint:   endpoint,
+				ID:         ev.ID,
+				Event:      ev.Event,
+				Data:       ev.Data,
+				Raw:        ev.Raw,
+				ReceivedAt: ev.ReceivedAt,
+				Seq:        ev.Seq,
+			})) {
+				return
 			}
 		}
+		if !send(joiner.flush()) {
+			return
+		}
 		if stream.Err != nil {
 			errCh <- stream.Err
 		}
 	}()
 
-	return out, errCh, nil
+	return out, errCh, info, nil
 }
 
-// Stream parses unified SSE events into normalized deltas.
+// Stream parses unified SSE events into normalized deltas. Callers must drain
+// the delta channel and then read the error channel exactly once; a stream
+// that is no longer read keeps its connection open until ctx is cancelled.
+// OpenStream returns a StreamHandle whose Next and Close handle this.
 func (c *Client) Stream(ctx context.Context, req NormalizedRequest) (<-chan NormalizedDelta, <-chan error, error) {
-	evCh, errCh, err := c.StreamEvents(ctx, req)
+	h, err := c.OpenStream(ctx, req)
 	if err != nil {
 		return nil, nil, err
 	}
+	return h.Deltas, h.Errs, nil
+}
 
+// OpenStream is Stream returning a StreamHandle, which also reports the
+// resolved endpoint, the request body sent and the response headers.
+func (c *Client) OpenStream(ctx context.Context, req NormalizedRequest) (*StreamHandle, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	evCh, errCh, info, err := c.streamEvents(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return c.deltaStream(ctx, cancel, req, evCh, errCh, info), nil
+}
+
+// deltaStream parses the events of a stream opened for req into a
+// StreamHandle. cancel must cancel the stream's context.
+func (c *Client) deltaStream(ctx context.Context, cancel context.CancelFunc, req NormalizedRequest, evCh <-chan UnifiedEvent, errCh <-chan error, info *StreamInfo) *StreamHandle {
 	out := make(chan NormalizedDelta)
 	outErr := make(chan error, 1)
+	h := &StreamHandle{
+		Deltas: out,
+		Errs:   outErr,
+		Info:   *info,
+		pump:   &channelPump[NormalizedDelta]{items: out, errs: outErr, cancel: cancel},
+	}
 
+	guard := newToolArgumentGuard(c.cfg.MaxToolArgumentBytes)
+	var stop stopTracker
+	var done doneTracker
+	var reasoning reasoningTracker
+	var tools toolAnnotator
+	if c.cfg.AnnotateToolCalls {
+		tools = newToolAnnotator(req.Tools)
+	}
+	stall := newStallWatch(c.cfg.Clock, c.cfg.GenerationStallTimeout, cancel)
+	served := servedModelCheck{c: c, endpoint: info.Endpoint, requested: req.Model}
+	// send delivers delta unless ctx is done first, which it reports on
+	// outErr, or Config.DeltaFilter drops it or stops the stream.
+	send := func(delta NormalizedDelta) bool {
+		delta, keep, err := c.filterDelta(delta)
+		if err != nil {
+			outErr <- err
+			return false
+		}
+		if !keep {
+			return true
+		}
+		select {
+		case out <- delta:
+			return true
+		case <-ctx.Done():
+			outErr <- stall.err(ctx.Err())
+			return false
+		}
+	}
+	// emit adds the reasoning block markers around parsed and sends the
+	// deltas the tool argument guard lets through.
+	emit := func(parsed NormalizedDelta) bool {
+		for _, marked := range reasoning.apply(parsed) {
+			for _, delta := range guard.filter(marked) {
+				if !send(delta) {
+					return false
+				}
+			}
+		}
+		return true
+	}
 	go func() {
+		var failed error
+		defer cancel()
 		defer close(out)
 		defer close(outErr)
+		defer stall.stop()
 		for ev := range evCh {
-			for _, delta := range ParseNormalizedEvent(ev) {
-				out <- delta
+			if ev.ID != "" {
+				h.lastEventID = ev.ID
+			}
+			for _, parsed := range ParseNormalizedEvent(ev) {
+				stall.observe(parsed)
+				if err := streamFailure(parsed); err != nil {
+					failed = err
+				}
+				if err := served.observe(ctx, parsed); err != nil {
+					outErr <- err
+					return
+				}
+				if req.SuppressReasoning && parsed.Type == DeltaReasoning {
+					continue
+				}
+				stop.apply(&parsed)
+				if done.hold(parsed) {
+					continue
+				}
+				tools.apply(&parsed)
+				if !emit(parsed) {
+					return
+				}
+			}
+		}
+		for _, parsed := range done.flush(&stop) {
+			if !emit(parsed) {
+				return
 			}
 		}
-		if streamErr := <-errCh; streamErr != nil {
-			outErr <- streamErr
+		streamErr := <-errCh
+		if streamErr == nil && failed == nil {
+			for _, delta := range reasoning.flush() {
+				if !send(delta) {
+					return
+				}
+			}
+		}
+		if streamErr != nil {
+			outErr <- stall.err(streamErr)
+		} else if failed != nil {
+			outErr <- failed
 		}
 	}()
+	return h
+}
+
+// buildRequest applies defaults, resolves the endpoint and path for req and
+// marshals the provider payload. The returned context carries the redacted
+// capture body when one is configured.
+func (c *Client) buildRequest(ctx context.Context, req NormalizedRequest, stream bool) (context.Context, EndpointType, string, []byte, error) {
+	ctx = c.withScope(ctx)
+	if isModelListing(req) {
+		return ctx, EndpointModels, "", nil, modelListingError(stream)
+	}
+	req.Model = stripOpencodePrefix(req.Model)
+	req = c.cfg.applyModelDefaults(req)
+	req, err := c.autoMaxTokens(ctx, req)
+	if err != nil {
+		return ctx, "", "", nil, err
+	}
+	endpoint, path, err := c.resolveEndpoint(req, stream)
+	if err != nil {
+		return ctx, endpoint, "", nil, err
+	}
+	req, err = c.checkReasoning(ctx, endpoint, req)
+	if err != nil {
+		return ctx, endpoint, "", nil, err
+	}
+	req, err = c.messagesMaxTokens(endpoint, req)
+	if err != nil {
+		return ctx, endpoint, "", nil, err
+	}
+
+	if err := c.reportWarnings(ctx, endpoint, req); err != nil {
+		return ctx, endpoint, "", nil, err
+	}
+
+	req.Stream = stream
+	if c.cfg.LogRequest != nil {
+		logged := req
+		if c.cfg.LogRedaction != nil {
+			logged = RedactRequest(req, *c.cfg.LogRedaction)
+		}
+		c.cfg.LogRequest(ctx, endpoint, logged)
+	}
 
-	return out, outErr, nil
+	payload, err := encodeRequest(endpoint, req)
+	if err != nil {
+		return ctx, endpoint, "", nil, err
+	}
+	if err := c.checkRequestSize(req, payload); err != nil {
+		return ctx, endpoint, "", nil, err
+	}
+	if c.cfg.CaptureRequestOnError && c.cfg.CaptureRedaction != nil {
+		redacted, err := encodeRequest(endpoint, RedactRequest(req, *c.cfg.CaptureRedaction))
+		if err != nil {
+			return ctx, endpoint, "", nil, err
+		}
+		ctx = withCapturedRequest(ctx, redacted)
+	}
+	return ctx, endpoint, path, payload, nil
+}
+
+func encodeRequest(endpoint EndpointType, req NormalizedRequest) ([]byte, error) {
+	var (
+		body any
+		err  error
+	)
+	switch endpoint {
+	case EndpointResponses:
+		body, err = req.ToResponsesRequest()
+	case EndpointMessages:
+		body, err = req.ToMessagesRequest()
+	case EndpointChatCompletions:
+		body, err = req.ToChatCompletionsRequest()
+	case EndpointModels:
+		body, err = req.ToGeminiRequest()
+	default:
+		err = errors.New("zen: unsupported endpoint")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jsonBody(body, nil)
 }
 
-func resolveStreamEndpoint(req NormalizedRequest) (EndpointType, string, error) {
+func (c *Client) resolveEndpoint(req NormalizedRequest, stream bool) (EndpointType, string, error) {
 	endpoint := req.Endpoint
+	if req.Path != "" {
+		switch endpoint {
+		case EndpointAuto:
+			return endpoint, "", errors.New("zen: Endpoint is required when Path is set")
+		case EndpointResponses, EndpointMessages, EndpointChatCompletions, EndpointModels:
+			return endpoint, withQuery(req.Path, req.Query), nil
+		default:
+			return endpoint, "", errors.New("zen: unsupported endpoint")
+		}
+	}
 	if endpoint == EndpointAuto {
-		endpoint = routeForModel(req.Model)
+		endpoint = c.routeForModel(req.Model)
 	}
 
 	switch endpoint {
 	case EndpointResponses:
-		return endpoint, "/responses", nil
+		return endpoint, withQuery("/responses", req.Query), nil
 	case EndpointMessages:
-		return endpoint, "/messages", nil
+		return endpoint, withQuery("/messages", req.Query), nil
 	case EndpointChatCompletions:
-		return endpoint, "/chat/completions", nil
+		return endpoint, withQuery("/chat/completions", req.Query), nil
 	case EndpointModels:
+		if isModelListing(req) {
+			return endpoint, withQuery("/models", req.Query), nil
+		}
 		model := strings.TrimSpace(stripOpencodePrefix(req.Model))
 		if model == "" {
-			return endpoint, "", errors.New("zen: model is required for model streaming")
+			return endpoint, "", errors.New("zen: model is required for Gemini requests")
+		}
+		method, query := "generateContent", url.Values(nil)
+		if stream {
+			method, query = "streamGenerateContent", url.Values{"alt": {"sse"}}
+		}
+		if req.GeminiMethod != "" {
+			method = req.GeminiMethod
 		}
-		return endpoint, "/models/" + model + ":streamGenerateContent?alt=sse", nil
+		return endpoint, withQuery(c.geminiPath(model, method, query), req.Query), nil
 	default:
 		return endpoint, "", errors.New("zen: unsupported endpoint")
 	}
 }
 
-func routeForModel(model string) EndpointType {
+// withQuery returns path with query added to the query string it may
+// already have.
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + query.Encode()
+}
+
+// geminiPath returns the path of a Gemini model method, e.g.
+// "/models/gemini-3-pro:countTokens", below Config.GeminiAPIVersion when set.
+func (c *Client) geminiPath(model, method string, query url.Values) string {
+	path := "/models/" + model + ":" + method
+	if v := strings.Trim(c.cfg.GeminiAPIVersion, "/"); v != "" {
+		path = "/" + v + path
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+// ModelRoute sends models whose id starts with Prefix to Endpoint.
+type ModelRoute struct {
+	Prefix   string
+	Endpoint EndpointType
+}
+
+// defaultModelRoutes is the built-in routing table. The first matching
+// prefix wins, so more specific prefixes come first.
+var defaultModelRoutes = []ModelRoute{
+	{Prefix: "gpt-oss", Endpoint: EndpointChatCompletions},
+	{Prefix: "gpt-", Endpoint: EndpointResponses},
+	{Prefix: "chatgpt-", Endpoint: EndpointResponses},
+	{Prefix: "o1", Endpoint: EndpointResponses},
+	{Prefix: "o3", Endpoint: EndpointResponses},
+	{Prefix: "o4", Endpoint: EndpointResponses},
+	{Prefix: "claude-", Endpoint: EndpointMessages},
+	{Prefix: "gemini-", Endpoint: EndpointModels},
+	{Prefix: "kimi-", Endpoint: EndpointChatCompletions},
+	{Prefix: "minimax-", Endpoint: EndpointChatCompletions},
+	{Prefix: "glm-", Endpoint: EndpointChatCompletions},
+	{Prefix: "qwen", Endpoint: EndpointChatCompletions},
+	{Prefix: "grok-", Endpoint: EndpointChatCompletions},
+	{Prefix: "deepseek", Endpoint: EndpointChatCompletions},
+}
+
+// RouteForModel returns the endpoint the SDK sends model to when a request
+// leaves Endpoint unset and Config.ModelRoutes has no match. Unknown models
+// go to chat completions.
+func RouteForModel(model string) EndpointType {
+	if endpoint, ok := matchModelRoute(defaultModelRoutes, model); ok {
+		return endpoint
+	}
+	return EndpointChatCompletions
+}
+
+func (c *Client) routeForModel(model string) EndpointType {
+	if endpoint, ok := matchModelRoute(c.cfg.ModelRoutes, model); ok {
+		return endpoint
+	}
+	return RouteForModel(model)
+}
+
+func matchModelRoute(routes []ModelRoute, model string) (EndpointType, bool) {
 	m := normalizeModelID(model)
-	switch {
-	case strings.HasPrefix(m, "gpt-"):
-		return EndpointResponses
-	case strings.HasPrefix(m, "claude-"):
-		return EndpointMessages
-	case strings.HasPrefix(m, "gemini-"):
-		return EndpointModels
-	default:
-		return EndpointChatCompletions
+	for _, r := range routes {
+		if strings.HasPrefix(m, strings.ToLower(r.Prefix)) {
+			return r.Endpoint, true
+		}
 	}
+	return "", false
 }
 
 func normalizeModelID(model string) string {
@@ -3,12 +3,15 @@ package zen
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 type requestCapture struct {
@@ -137,3 +140,347 @@ func drainStreamEvents(ctx context.Context, client *Client, req NormalizedReques
 	}
 	return out, nil
 }
+
+func TestOpenStreamInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("request-id", "req_123")
+		w.Header().Set("x-ratelimit-remaining-requests", "41")
+		_, _ = w.Write([]byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+	req := NormalizedRequest{Model: "opencode/claude-sonnet-4-6", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	h, err := client.OpenStream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	for range h.Deltas {
+	}
+	if err := <-h.Errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if h.Info.Endpoint != EndpointMessages || h.Info.Path != "/messages" || h.Info.RequestID != "req_123" {
+		t.Fatalf("info mismatch: %+v", h.Info)
+	}
+	if h.Info.StatusCode != http.StatusOK || h.Info.Header.Get("x-ratelimit-remaining-requests") != "41" {
+		t.Fatalf("status and headers not exposed: %d %v", h.Info.StatusCode, h.Info.Header)
+	}
+	if !strings.Contains(string(h.Info.Body), `"model":"claude-sonnet-4-6"`) {
+		t.Fatalf("info body should be the payload sent, got %s", h.Info.Body)
+	}
+
+	resp, err := client.CollectStream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	if resp.Info.Endpoint != EndpointMessages || resp.Info.RequestID != "req_123" {
+		t.Fatalf("collected info mismatch: %+v", resp.Info)
+	}
+}
+
+func TestStreamHandleNext(t *testing.T) {
+	server, client := newSSETestServer(t, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n"+
+		"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n"+
+		"data: [DONE]\n\n")
+	defer server.Close()
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	h, err := client.OpenStream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	var text string
+	for {
+		d, err := h.Next(testCtx(t))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		text += d.Content
+	}
+	if text != "Hello" || h.Err() != nil {
+		t.Fatalf("unexpected result %q, %v", text, h.Err())
+	}
+	if _, err := h.Next(testCtx(t)); !errors.Is(err, io.EOF) {
+		t.Fatalf("Next after the end should keep returning io.EOF, got %v", err)
+	}
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close after the end: %v", err)
+	}
+
+	events, err := client.OpenStreamEvents(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("OpenStreamEvents: %v", err)
+	}
+	var n int
+	for {
+		if _, err := events.Next(testCtx(t)); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("events Next: %v", err)
+			}
+			break
+		}
+		n++
+	}
+	if n != 2 || events.Info.Endpoint != EndpointChatCompletions {
+		t.Fatalf("expected 2 chat events, got %d (%+v)", n, events.Info)
+	}
+}
+
+func TestStreamHandleClose(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	server := newBlockingStreamServer(t, unblock)
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("client error: %v", err)
+	}
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	h, err := client.OpenStream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	if d, err := h.Next(context.Background()); err != nil || d.Content != "hi" {
+		t.Fatalf("first delta: %+v, %v", d, err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := h.Next(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Next should give up with its context, got %v", err)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		_ = h.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Close should cancel a stream the server keeps open")
+	}
+	if _, err := h.Next(context.Background()); err == nil || errors.Is(err, io.EOF) {
+		t.Fatalf("a closed stream should report its cancellation, got %v", err)
+	}
+}
+
+func TestGeminiPath(t *testing.T) {
+	cases := []struct {
+		version, method string
+		stream          bool
+		want            string
+	}{
+		{want: "/models/gemini-3-pro:generateContent"},
+		{stream: true, want: "/models/gemini-3-pro:streamGenerateContent?alt=sse"},
+		{version: "v1beta", want: "/v1beta/models/gemini-3-pro:generateContent"},
+		{version: "/v1beta/", stream: true, want: "/v1beta/models/gemini-3-pro:streamGenerateContent?alt=sse"},
+		{method: "generateAnswer", want: "/models/gemini-3-pro:generateAnswer"},
+		{method: "streamGenerateAnswer", stream: true, want: "/models/gemini-3-pro:streamGenerateAnswer?alt=sse"},
+	}
+	for _, tc := range cases {
+		client, err := NewClient(Config{APIKey: "key", GeminiAPIVersion: tc.version})
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		req := NormalizedRequest{Model: "opencode/gemini-3-pro", GeminiMethod: tc.method}
+		_, _, path, _, err := client.buildRequest(context.Background(), req, tc.stream)
+		if err != nil {
+			t.Fatalf("buildRequest: %v", err)
+		}
+		if path != tc.want {
+			t.Fatalf("version %q method %q stream %v: want %s, got %s", tc.version, tc.method, tc.stream, tc.want, path)
+		}
+	}
+}
+
+func TestCustomPath(t *testing.T) {
+	var mu sync.Mutex
+	var paths, auths []string
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.RequestURI())
+		auths = append(auths, r.Header.Get("x-api-key")+"|"+r.Header.Get("Authorization"))
+		if r.URL.Path == "/ext/rerank" {
+			attempts++
+			if attempts == 1 {
+				http.Error(w, `{"error":{"message":"busy"}}`, http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = io.WriteString(w, `{"results":[{"index":1}]}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: content_block_delta\n"+`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ok"}}`+"\n\n"+
+			"event: message_stop\n"+`data: {"type":"message_stop"}`+"\n\n")
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{APIKey: "key", BaseURL: server.URL, Retry: RetryConfig{MaxRetries: 1, RetryOnNonIdempotent: true, Backoff: func(int) time.Duration { return 0 }}})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// The model would route to Chat Completions; Endpoint decides instead.
+	req := NormalizedRequest{
+		Model:    "kimi-k2",
+		Endpoint: EndpointMessages,
+		Path:     "/ext/messages",
+		Query:    map[string][]string{"tenant": {"a b"}},
+		Messages: []NormalizedMessage{{Role: "user", Content: "hi"}},
+	}
+	h, err := client.OpenStream(testCtx(t), req)
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	if h.Info.Endpoint != EndpointMessages || h.Info.Path != "/ext/messages?tenant=a+b" {
+		t.Fatalf("unexpected stream info: %+v", h.Info)
+	}
+	resp, err := CollectDeltas(h.Deltas, h.Errs)
+	if err != nil || resp.Text != "ok" {
+		t.Fatalf("CollectDeltas: %+v, %v", resp, err)
+	}
+
+	req.Path, req.Query = "ext/rerank", nil
+	var out struct {
+		Results []struct{ Index int }
+	}
+	if err := client.CreateNormalizedInto(testCtx(t), req, &out); err != nil {
+		t.Fatalf("CreateNormalizedInto: %v", err)
+	}
+	if len(out.Results) != 1 || out.Results[0].Index != 1 || attempts != 2 {
+		t.Fatalf("expected a retried custom request, got %+v after %d attempts", out, attempts)
+	}
+	for i, auth := range auths {
+		if auth != "key|" {
+			t.Fatalf("request %d to %s: expected messages authentication, got %q", i, paths[i], auth)
+		}
+	}
+
+	req.Endpoint = EndpointAuto
+	if _, err := client.CreateNormalized(testCtx(t), req); err == nil || !strings.Contains(err.Error(), "Endpoint is required") {
+		t.Fatalf("expected a missing endpoint error, got %v", err)
+	}
+}
+
+func TestRouteForModel(t *testing.T) {
+	cases := map[string]EndpointType{
+		"gpt-5.2":                EndpointResponses,
+		"opencode/GPT-5.1":       EndpointResponses,
+		"gpt-oss-120b":           EndpointChatCompletions,
+		"chatgpt-4o-latest":      EndpointResponses,
+		"o4-mini":                EndpointResponses,
+		"o3":                     EndpointResponses,
+		"claude-sonnet-4-6":      EndpointMessages,
+		"gemini-3-flash":         EndpointModels,
+		"kimi-k2-thinking":       EndpointChatCompletions,
+		"minimax-m2.5":           EndpointChatCompletions,
+		"some-new-model":         EndpointChatCompletions,
+		"opencode/qwen3-coder":   EndpointChatCompletions,
+		"deepseek-v3.2-reasoner": EndpointChatCompletions,
+	}
+	for model, want := range cases {
+		if got := RouteForModel(model); got != want {
+			t.Fatalf("%s: want %s, got %s", model, want, got)
+		}
+	}
+
+	client, err := NewClient(Config{APIKey: "key", ModelRoutes: []ModelRoute{{Prefix: "Minimax-", Endpoint: EndpointMessages}}})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req := NormalizedRequest{Model: "minimax-m2.5", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+	_, endpoint, path, _, err := client.buildRequest(context.Background(), req, false)
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	if endpoint != EndpointMessages || path != "/messages" {
+		t.Fatalf("ModelRoutes override not applied: %s %s", endpoint, path)
+	}
+}
+
+func TestStreamCloseBeforeDrainDoesNotLeak(t *testing.T) {
+	var sse strings.Builder
+	for i := 0; i < 50; i++ {
+		sse.WriteString("data: {\"choices\":[{\"delta\":{\"content\":\"x\"}}]}\n\n")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(sse.String()))
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{
+		APIKey:     "key",
+		BaseURL:    server.URL,
+		HTTPClient: &http.Client{Transport: &http.Transport{DisableKeepAlives: true}},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req := NormalizedRequest{Model: "kimi-k2", Messages: []NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	abandon := map[string]func() error{
+		"OpenStream": func() error {
+			h, err := client.OpenStream(context.Background(), req)
+			if err != nil {
+				return err
+			}
+			<-h.Deltas
+			return h.Close()
+		},
+		"OpenStreamEvents": func() error {
+			s, err := client.OpenStreamEvents(context.Background(), req)
+			if err != nil {
+				return err
+			}
+			<-s.Events
+			return s.Close()
+		},
+		"Stream": func() error {
+			ctx, cancel := context.WithCancel(context.Background())
+			deltas, _, err := client.Stream(ctx, req)
+			if err != nil {
+				cancel()
+				return err
+			}
+			<-deltas
+			cancel()
+			return nil
+		},
+		"StreamEvents": func() error {
+			ctx, cancel := context.WithCancel(context.Background())
+			events, _, err := client.StreamEvents(ctx, req)
+			if err != nil {
+				cancel()
+				return err
+			}
+			<-events
+			cancel()
+			return nil
+		},
+	}
+	for name, fn := range abandon {
+		before := runtime.NumGoroutine()
+		if err := fn(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: goroutines leaked: %d before, %d after", name, before, runtime.NumGoroutine())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
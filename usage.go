@@ -0,0 +1,77 @@
+package zen
+
+// NormalizedUsage is the token usage of one or more responses.
+type NormalizedUsage struct {
+	InputTokens     int
+	OutputTokens    int
+	ReasoningTokens int
+	// CachedInputTokens and CacheWriteInputTokens are the input tokens read
+	// from and written to the prompt cache. Anthropic counts them apart from
+	// InputTokens, the other providers within it; see
+	// NormalizedDelta.CachedInputTokens.
+	CachedInputTokens     int
+	CacheWriteInputTokens int
+}
+
+// Usage returns the token usage reported for r.
+func (r *NormalizedResponse) Usage() NormalizedUsage {
+	return NormalizedUsage{
+		InputTokens:           r.InputTokens,
+		OutputTokens:          r.OutputTokens,
+		ReasoningTokens:       r.ReasoningTokens,
+		CachedInputTokens:     r.CachedInputTokens,
+		CacheWriteInputTokens: r.CacheWriteInputTokens,
+	}
+}
+
+// Add returns the sum of u and other.
+func (u NormalizedUsage) Add(other NormalizedUsage) NormalizedUsage {
+	return NormalizedUsage{
+		InputTokens:           u.InputTokens + other.InputTokens,
+		OutputTokens:          u.OutputTokens + other.OutputTokens,
+		ReasoningTokens:       u.ReasoningTokens + other.ReasoningTokens,
+		CachedInputTokens:     u.CachedInputTokens + other.CachedInputTokens,
+		CacheWriteInputTokens: u.CacheWriteInputTokens + other.CacheWriteInputTokens,
+	}
+}
+
+// EstimateCost returns the cost in USD of usage on model from the prices
+// configured in Config.ModelDefaults, and false when the model has neither
+// an input nor an output price. Cached input is charged at
+// CachedInputPricePer1M, or the input price when that is unset, and cache
+// writes at the input price. Reasoning tokens are charged as far as
+// OutputTokens counts them, which Gemini's do not, so the estimate is low
+// for those.
+func (c *Client) EstimateCost(model string, usage NormalizedUsage) (float64, bool) {
+	model = stripOpencodePrefix(model)
+	return c.cfg.estimateCost(model, usage, c.routeForModel(model) == EndpointMessages)
+}
+
+// estimateCost prices usage on model. cacheApart is set for endpoints that
+// report cached input apart from InputTokens (Anthropic Messages).
+func (c Config) estimateCost(model string, usage NormalizedUsage, cacheApart bool) (float64, bool) {
+	var input, cached, output float64
+	for _, d := range c.defaultLayers(model) {
+		if input == 0 {
+			input = d.InputPricePer1M
+		}
+		if cached == 0 {
+			cached = d.CachedInputPricePer1M
+		}
+		if output == 0 {
+			output = d.OutputPricePer1M
+		}
+	}
+	if input == 0 && output == 0 {
+		return 0, false
+	}
+	if cached == 0 {
+		cached = input
+	}
+	uncached := usage.InputTokens + usage.CacheWriteInputTokens
+	if !cacheApart {
+		uncached = max(usage.InputTokens-usage.CachedInputTokens, 0)
+	}
+	cost := float64(uncached)*input + float64(usage.CachedInputTokens)*cached + float64(usage.OutputTokens)*output
+	return cost / 1e6, true
+}
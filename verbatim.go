@@ -0,0 +1,121 @@
+package zen
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// verbatimMarshaler is implemented by request bodies that embed tool call
+// arguments as JSON values; jsonBody uses it in place of jsonMarshal.
+type verbatimMarshaler interface {
+	marshalVerbatim() ([]byte, error)
+}
+
+// marshalVerbatim marshals v with every value in args sent byte for byte as
+// the model produced it. encoding/json compacts raw values and escapes HTML
+// characters in them, which changes the arguments of replayed tool calls
+// that providers and audit logs compare with what they sent. collect is
+// called with a fresh placeholder for each argument and must return a copy
+// of v that uses them; v itself is left untouched.
+func marshalVerbatim(v any, args []json.RawMessage, collect func(placeholders []json.RawMessage) any) ([]byte, error) {
+	if len(args) == 0 {
+		return jsonMarshal(v)
+	}
+	var nonce [8]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return jsonMarshal(v)
+	}
+	prefix := "zen-verbatim-" + hex.EncodeToString(nonce[:]) + "-"
+	placeholders := make([]json.RawMessage, len(args))
+	for i, arg := range args {
+		if len(arg) == 0 || !json.Valid(arg) {
+			// Left for jsonMarshal to omit or reject as before.
+			placeholders[i] = arg
+			continue
+		}
+		placeholders[i] = json.RawMessage(strconv.Quote(prefix + strconv.Itoa(i)))
+	}
+	out, err := jsonMarshal(collect(placeholders))
+	if err != nil {
+		return nil, err
+	}
+	for i, arg := range args {
+		if !bytes.Equal(placeholders[i], arg) {
+			out = bytes.Replace(out, placeholders[i], arg, 1)
+		}
+	}
+	return out, nil
+}
+
+// toolArguments returns the input of every tool_use block of r, in order.
+func (r MessagesRequest) toolArguments() []json.RawMessage {
+	var args []json.RawMessage
+	for _, m := range r.Messages {
+		blocks, _ := m.Content.([]AnthropicContentBlock)
+		for _, b := range blocks {
+			if b.Type == "tool_use" {
+				args = append(args, b.Input)
+			}
+		}
+	}
+	return args
+}
+
+func (r MessagesRequest) marshalVerbatim() ([]byte, error) {
+	return marshalVerbatim(r, r.toolArguments(), func(placeholders []json.RawMessage) any {
+		out := r
+		out.Messages = make([]AnthropicMessage, len(r.Messages))
+		n := 0
+		for i, m := range r.Messages {
+			if blocks, ok := m.Content.([]AnthropicContentBlock); ok {
+				blocks = append([]AnthropicContentBlock(nil), blocks...)
+				for j := range blocks {
+					if blocks[j].Type == "tool_use" {
+						blocks[j].Input = placeholders[n]
+						n++
+					}
+				}
+				m.Content = blocks
+			}
+			out.Messages[i] = m
+		}
+		return out
+	})
+}
+
+// toolArguments returns the args of every functionCall part of r, in order.
+func (r GeminiRequest) toolArguments() []json.RawMessage {
+	var args []json.RawMessage
+	for _, c := range r.Contents {
+		for _, p := range c.Parts {
+			if p.FunctionCall != nil && len(p.Raw) == 0 {
+				args = append(args, p.FunctionCall.Args)
+			}
+		}
+	}
+	return args
+}
+
+func (r GeminiRequest) marshalVerbatim() ([]byte, error) {
+	return marshalVerbatim(r, r.toolArguments(), func(placeholders []json.RawMessage) any {
+		out := r
+		out.Contents = make([]GeminiContent, len(r.Contents))
+		n := 0
+		for i, c := range r.Contents {
+			c.Parts = append([]GeminiPart(nil), c.Parts...)
+			for j, p := range c.Parts {
+				if p.FunctionCall != nil && len(p.Raw) == 0 {
+					call := *p.FunctionCall
+					call.Args = placeholders[n]
+					c.Parts[j].FunctionCall = &call
+					n++
+				}
+			}
+			out.Contents[i] = c
+		}
+		return out
+	})
+}
@@ -0,0 +1,130 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrUnsupportedField is wrapped by the error Config.StrictConversions
+// returns in place of a conversion Warning.
+var ErrUnsupportedField = errors.New("zen: request field not supported")
+
+// Warning reports a NormalizedRequest field that converting the request for
+// Endpoint drops, because the endpoint has no equivalent. Warnings with
+// Field "Stream" instead report a repair made to a malformed stream, such
+// as a JSON payload reassembled from the events it was split across.
+type Warning struct {
+	Field    string
+	Endpoint EndpointType
+	Reason   string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s dropped for %s: %s", w.Field, w.Endpoint, w.Reason)
+}
+
+// droppedFields lists the request fields that only some endpoints send.
+var droppedFields = []struct {
+	field     string
+	set       func(NormalizedRequest) bool
+	supported []EndpointType
+	reason    string
+}{
+	{
+		field:     "Truncation",
+		set:       func(r NormalizedRequest) bool { return r.Truncation != "" },
+		supported: []EndpointType{EndpointResponses},
+		reason:    "only the Responses API truncates input",
+	},
+	{
+		field:     "ServiceTier",
+		set:       func(r NormalizedRequest) bool { return r.ServiceTier != "" },
+		supported: []EndpointType{EndpointResponses, EndpointChatCompletions},
+		reason:    "the endpoint has no service tier parameter",
+	},
+	{
+		field:     "CandidateCount",
+		set:       func(r NormalizedRequest) bool { return r.CandidateCount > 0 },
+		supported: []EndpointType{EndpointModels},
+		reason:    "only Gemini returns several candidates",
+	},
+	{
+		field:     "GeminiMethod",
+		set:       func(r NormalizedRequest) bool { return r.GeminiMethod != "" },
+		supported: []EndpointType{EndpointModels},
+		reason:    "the endpoint is not Gemini",
+	},
+	{
+		field:     "SystemRole",
+		set:       func(r NormalizedRequest) bool { return r.SystemRole != "" },
+		supported: []EndpointType{EndpointChatCompletions},
+		reason:    "the endpoint takes the system prompt outside the messages",
+	},
+	{
+		field:     "SystemPosition",
+		set:       func(r NormalizedRequest) bool { return r.SystemPosition != "" && r.SystemPosition != SystemFirst },
+		supported: []EndpointType{EndpointChatCompletions},
+		reason:    "the endpoint takes the system prompt outside the messages",
+	},
+	{
+		field:     "Background",
+		set:       func(r NormalizedRequest) bool { return r.Background },
+		supported: []EndpointType{EndpointResponses},
+		reason:    "only the Responses API runs requests in the background",
+	},
+	{
+		field:     "Store",
+		set:       func(r NormalizedRequest) bool { return r.Store != nil },
+		supported: []EndpointType{EndpointResponses},
+		reason:    "only the Responses API stores responses",
+	},
+	{
+		field:     "Include",
+		set:       func(r NormalizedRequest) bool { return len(r.Include) > 0 },
+		supported: []EndpointType{EndpointResponses},
+		reason:    "only the Responses API includes extra output data",
+	},
+	{
+		field:     "Reasoning.BudgetTokens",
+		set:       func(r NormalizedRequest) bool { return r.Reasoning != nil && r.Reasoning.BudgetTokens > 0 },
+		supported: []EndpointType{EndpointMessages, EndpointModels},
+		reason:    "the endpoint only takes a reasoning effort",
+	},
+}
+
+// ConversionWarnings returns the fields of r that are dropped when it is
+// converted for endpoint, including the parts of tool schemas rewritten for
+// Gemini (see NormalizedRequest.RawToolSchemas). Fields that make a
+// conversion fail instead, such as StopSequences for the Responses API, are
+// not reported.
+func (r NormalizedRequest) ConversionWarnings(endpoint EndpointType) []Warning {
+	var out []Warning
+	for _, d := range droppedFields {
+		if d.set(r) && !slices.Contains(d.supported, endpoint) {
+			out = append(out, Warning{Field: d.field, Endpoint: endpoint, Reason: d.reason})
+		}
+	}
+	if endpoint == EndpointModels {
+		out = append(out, r.geminiSchemaWarnings()...)
+	}
+	return out
+}
+
+// reportWarnings passes the conversion warnings of req to
+// Config.OnWarning, or turns the first into an error with
+// Config.StrictConversions.
+func (c *Client) reportWarnings(ctx context.Context, endpoint EndpointType, req NormalizedRequest) error {
+	if c.cfg.OnWarning == nil && !c.cfg.StrictConversions {
+		return nil
+	}
+	warnings := req.ConversionWarnings(endpoint)
+	if c.cfg.StrictConversions && len(warnings) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnsupportedField, warnings[0])
+	}
+	for _, w := range warnings {
+		c.cfg.OnWarning(ctx, w)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConversionWarnings(t *testing.T) {
+	req := NormalizedRequest{
+		Model:          "m",
+		Truncation:     "auto",
+		ServiceTier:    "flex",
+		CandidateCount: 2,
+		GeminiMethod:   "predict",
+		SystemRole:     "developer",
+		SystemPosition: SystemAfterSystemMessages,
+		Reasoning:      &NormalizedReasoning{BudgetTokens: 2048},
+		Background:     true,
+		Store:          new(bool),
+		Include:        []string{"reasoning.encrypted_content"},
+	}
+	// The known drops per endpoint; extend this table with every new one.
+	want := map[EndpointType]string{
+		EndpointResponses:       "CandidateCount,GeminiMethod,SystemRole,SystemPosition,Reasoning.BudgetTokens",
+		EndpointChatCompletions: "Truncation,CandidateCount,GeminiMethod,Background,Store,Include,Reasoning.BudgetTokens",
+		EndpointMessages:        "Truncation,ServiceTier,CandidateCount,GeminiMethod,SystemRole,SystemPosition,Background,Store,Include",
+		EndpointModels:          "Truncation,ServiceTier,SystemRole,SystemPosition,Background,Store,Include",
+	}
+	for endpoint, fields := range want {
+		var got []string
+		for _, w := range req.ConversionWarnings(endpoint) {
+			if w.Endpoint != endpoint || w.Reason == "" {
+				t.Fatalf("incomplete warning %+v", w)
+			}
+			got = append(got, w.Field)
+		}
+		if strings.Join(got, ",") != fields {
+			t.Fatalf("%s: want %s, got %s", endpoint, fields, strings.Join(got, ","))
+		}
+	}
+	if w := (NormalizedRequest{Model: "m"}).ConversionWarnings(EndpointMessages); len(w) != 0 {
+		t.Fatalf("plain request should not warn: %+v", w)
+	}
+}
+
+func TestConversionWarningsReported(t *testing.T) {
+	req := NormalizedRequest{
+		Model:       "claude-sonnet-4-6",
+		Messages:    []NormalizedMessage{{Role: "user", Content: "hi"}},
+		ServiceTier: "flex",
+	}
+
+	var warnings []Warning
+	c, err := NewClient(Config{APIKey: "key", OnWarning: func(ctx context.Context, w Warning) {
+		warnings = append(warnings, w)
+	}})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, _, _, _, err := c.buildRequest(testCtx(t), req, false); err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Field != "ServiceTier" || warnings[0].Endpoint != EndpointMessages {
+		t.Fatalf("unexpected warnings: %+v", warnings)
+	}
+
+	strict, err := NewClient(Config{APIKey: "key", StrictConversions: true})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, _, _, _, err := strict.buildRequest(testCtx(t), req, false); !errors.Is(err, ErrUnsupportedField) {
+		t.Fatalf("expected ErrUnsupportedField, got %v", err)
+	}
+}
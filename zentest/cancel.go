@@ -0,0 +1,45 @@
+package zentest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// AssertNoRequestAfterCancel checks that call stops sending requests once
+// its context is cancelled. call must send requests to baseURL with ctx; the
+// server cancels ctx while answering the first request with a retryable 503.
+// AssertNoRequestAfterCancel fails t if a second request arrives or call
+// returns anything but context.Canceled.
+func AssertNoRequestAfterCancel(t testing.TB, call func(ctx context.Context, baseURL string) error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			cancel()
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- call(ctx, server.URL) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled after cancel, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("call did not return after its context was cancelled")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected exactly 1 request, got %d", n)
+	}
+}
@@ -0,0 +1,27 @@
+package zentest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+func TestNoRequestAfterCancel(t *testing.T) {
+	AssertNoRequestAfterCancel(t, func(ctx context.Context, baseURL string) error {
+		client, err := zen.NewClient(zen.Config{
+			APIKey:  "key",
+			BaseURL: baseURL,
+			Retry: zen.RetryConfig{
+				MaxRetries: 3,
+				Backoff:    func(int) time.Duration { return 0 },
+			},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = client.ListModels(ctx)
+		return err
+	})
+}
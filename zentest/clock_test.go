@@ -0,0 +1,77 @@
+package zentest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+func TestFakeClockRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	clock := NewFakeClock(time.Unix(0, 0))
+	client, err := zen.NewClient(zen.Config{
+		APIKey:  "key",
+		BaseURL: server.URL,
+		Clock:   clock,
+		Retry: zen.RetryConfig{
+			MaxRetries: 3,
+			Backoff:    func(attempt int) time.Duration { return time.Hour },
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.ListModels(context.Background())
+		done <- err
+	}()
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Hour)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ListModels: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not finish after advancing the clock")
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls.Load())
+	}
+	if got := clock.Now(); !got.Equal(time.Unix(0, 0).Add(2 * time.Hour)) {
+		t.Fatalf("unexpected clock time %v", got)
+	}
+}
+
+func TestFakeClockSleepCancel(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- clock.Sleep(ctx, time.Minute) }()
+	clock.BlockUntil(1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if n := clock.Waiters(); n != 0 {
+		t.Fatalf("cancelled sleep left %d timers", n)
+	}
+}
@@ -0,0 +1,92 @@
+package zentest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+// JSONCase is a fixture for CheckJSONFuncs: a request and the SSE stream the
+// backend answers it with.
+type JSONCase struct {
+	Name    string
+	Request zen.NormalizedRequest
+	SSE     []byte
+}
+
+// CheckJSONFuncs streams each case once with encoding/json and once with
+// marshal and unmarshal installed with zen.SetJSONFuncs, and returns how the
+// provider payloads sent and the responses collected differ. As those
+// functions are package-wide, CheckJSONFuncs must not run alongside other
+// requests; it leaves encoding/json installed.
+func CheckJSONFuncs(ctx context.Context, marshal func(any) ([]byte, error), unmarshal func([]byte, any) error, cases []JSONCase) ([]string, error) {
+	defer zen.SetJSONFuncs(nil, nil)
+	var diffs []string
+	for _, jc := range cases {
+		wantWire, want, err := streamJSONCase(ctx, json.Marshal, json.Unmarshal, jc)
+		if err != nil {
+			return nil, fmt.Errorf("%s with encoding/json: %w", jc.Name, err)
+		}
+		gotWire, got, err := streamJSONCase(ctx, marshal, unmarshal, jc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", jc.Name, err)
+		}
+		if !bytes.Equal(wantWire, gotWire) {
+			diffs = append(diffs, fmt.Sprintf("%s: payload %s (encoding/json) != %s (plugged)", jc.Name, wantWire, gotWire))
+		}
+		for _, d := range diffResponses(want, got, "encoding/json", "plugged") {
+			diffs = append(diffs, jc.Name+": "+d)
+		}
+	}
+	return diffs, nil
+}
+
+// AssertJSONFuncs fails t when CheckJSONFuncs reports an error or a
+// difference.
+func AssertJSONFuncs(t testing.TB, marshal func(any) ([]byte, error), unmarshal func([]byte, any) error, cases []JSONCase) {
+	t.Helper()
+	diffs, err := CheckJSONFuncs(context.Background(), marshal, unmarshal, cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diffs {
+		t.Errorf("JSON functions differ from encoding/json: %s", d)
+	}
+}
+
+// streamJSONCase streams jc with marshal and unmarshal installed and returns
+// the payload sent and the response collected.
+func streamJSONCase(ctx context.Context, marshal func(any) ([]byte, error), unmarshal func([]byte, any) error, jc JSONCase) ([]byte, *zen.NormalizedResponse, error) {
+	var mu sync.Mutex
+	var wire []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		wire = body
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write(jc.SSE)
+	}))
+	defer server.Close()
+
+	zen.SetJSONFuncs(marshal, unmarshal)
+	client, err := zen.NewClient(zen.Config{APIKey: "test", BaseURL: server.URL})
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.CollectStream(ctx, jc.Request)
+	if err != nil {
+		return nil, nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return wire, resp, nil
+}
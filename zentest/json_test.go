@@ -0,0 +1,70 @@
+package zentest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+// goldenJSONCases loads the SDK's golden fixtures.
+func goldenJSONCases(t *testing.T) []JSONCase {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "*", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no golden fixtures: %v", err)
+	}
+	var cases []JSONCase
+	for _, file := range files {
+		name := strings.TrimSuffix(file, ".json")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read fixture: %v", err)
+		}
+		var fixture struct {
+			Request zen.NormalizedRequest `json:"request"`
+		}
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			t.Fatalf("decode fixture: %v", err)
+		}
+		sse, err := os.ReadFile(name + ".sse")
+		if err != nil {
+			t.Fatalf("read SSE: %v", err)
+		}
+		fixture.Request.Endpoint = zen.EndpointType(filepath.Base(filepath.Dir(name)))
+		cases = append(cases, JSONCase{Name: filepath.ToSlash(name), Request: fixture.Request, SSE: sse})
+	}
+	return cases
+}
+
+func TestJSONFuncsConformance(t *testing.T) {
+	var marshals, unmarshals atomic.Int64
+	marshal := func(v any) ([]byte, error) {
+		marshals.Add(1)
+		return json.Marshal(v)
+	}
+	unmarshal := func(data []byte, v any) error {
+		unmarshals.Add(1)
+		return json.Unmarshal(data, v)
+	}
+	AssertJSONFuncs(t, marshal, unmarshal, goldenJSONCases(t))
+	if marshals.Load() == 0 || unmarshals.Load() == 0 {
+		t.Fatalf("plugged functions not used: %d marshals, %d unmarshals", marshals.Load(), unmarshals.Load())
+	}
+}
+
+func TestJSONFuncsReportsDifferences(t *testing.T) {
+	indent := func(v any) ([]byte, error) { return json.MarshalIndent(v, "", "  ") }
+	diffs, err := CheckJSONFuncs(context.Background(), indent, json.Unmarshal, goldenJSONCases(t)[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) == 0 || !strings.Contains(diffs[0], "payload") {
+		t.Fatalf("expected a payload difference, got %v", diffs)
+	}
+}
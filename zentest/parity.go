@@ -0,0 +1,112 @@
+package zentest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+// ParityCase scripts a backend for CheckParity: it answers the request with
+// Body when sent without streaming and with the SSE stream otherwise.
+type ParityCase struct {
+	Request zen.NormalizedRequest
+	Body    []byte
+	SSE     []byte
+}
+
+// CheckParity runs pc.Request through Client.CreateNormalized and
+// Client.CollectStream against a mock backend serving pc, using cfg for
+// everything but BaseURL, and returns how the two responses differ. Text,
+// reasoning, tool calls, usage, the finish reason and server tool calls are
+// compared; timing and transport details are not.
+func CheckParity(ctx context.Context, cfg zen.Config, pc ParityCase) ([]string, error) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write(pc.SSE)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(pc.Body)
+	}))
+	defer server.Close()
+
+	cfg.BaseURL = server.URL
+	if cfg.APIKey == "" {
+		cfg.APIKey = "test"
+	}
+	client, err := zen.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	created, err := client.CreateNormalized(ctx, pc.Request)
+	if err != nil {
+		return nil, fmt.Errorf("CreateNormalized: %w", err)
+	}
+	streamed, err := client.CollectStream(ctx, pc.Request)
+	if err != nil {
+		return nil, fmt.Errorf("CollectStream: %w", err)
+	}
+	return diffResponses(created, streamed, "created", "streamed"), nil
+}
+
+// AssertParity fails t when CheckParity reports an error or a difference.
+func AssertParity(t testing.TB, cfg zen.Config, pc ParityCase) {
+	t.Helper()
+	diffs, err := CheckParity(context.Background(), cfg, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diffs {
+		t.Errorf("streaming and non-streaming responses differ: %s", d)
+	}
+}
+
+// diffResponses compares the responses created and streamed, labelled
+// as such in the differences it returns.
+func diffResponses(created, streamed *zen.NormalizedResponse, createdLabel, streamedLabel string) []string {
+	var diffs []string
+	diff := func(field string, a, b any) {
+		if a != b {
+			diffs = append(diffs, fmt.Sprintf("%s: %v (%s) != %v (%s)", field, a, createdLabel, b, streamedLabel))
+		}
+	}
+	diff("Text", created.Text, streamed.Text)
+	diff("Reasoning", created.Reasoning, streamed.Reasoning)
+	diff("len(ReasoningBlocks)", len(created.ReasoningBlocks), len(streamed.ReasoningBlocks))
+	diff("InputTokens", created.InputTokens, streamed.InputTokens)
+	diff("OutputTokens", created.OutputTokens, streamed.OutputTokens)
+	diff("ReasoningTokens", created.ReasoningTokens, streamed.ReasoningTokens)
+	diff("FinishReason", created.FinishReason, streamed.FinishReason)
+	diff("StopSequence", created.StopSequence, streamed.StopSequence)
+	diff("len(ToolCalls)", len(created.ToolCalls), len(streamed.ToolCalls))
+	for i := range min(len(created.ToolCalls), len(streamed.ToolCalls)) {
+		a, b := created.ToolCalls[i], streamed.ToolCalls[i]
+		diff(fmt.Sprintf("ToolCalls[%d].ID", i), a.ID, b.ID)
+		diff(fmt.Sprintf("ToolCalls[%d].Name", i), a.Name, b.Name)
+		diff(fmt.Sprintf("ToolCalls[%d].ThoughtSignature", i), a.ThoughtSignature, b.ThoughtSignature)
+		diff(fmt.Sprintf("ToolCalls[%d].Arguments", i), compactJSON(a.Arguments), compactJSON(b.Arguments))
+	}
+	diff("len(ServerToolCalls)", len(created.ServerToolCalls), len(streamed.ServerToolCalls))
+	for i := range min(len(created.ServerToolCalls), len(streamed.ServerToolCalls)) {
+		a, b := created.ServerToolCalls[i], streamed.ServerToolCalls[i]
+		diff(fmt.Sprintf("ServerToolCalls[%d].ID", i), a.ID, b.ID)
+		diff(fmt.Sprintf("ServerToolCalls[%d].Status", i), a.Status, b.Status)
+	}
+	return diffs
+}
+
+// compactJSON lets arguments that only differ in whitespace compare equal.
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
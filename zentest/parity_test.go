@@ -0,0 +1,40 @@
+package zentest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+func TestParityFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "parity", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no parity fixtures: %v", err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(file, ".json")
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			var fixture struct {
+				Request zen.NormalizedRequest `json:"request"`
+				Body    json.RawMessage       `json:"body"`
+			}
+			if err := json.Unmarshal(data, &fixture); err != nil {
+				t.Fatalf("decode fixture: %v", err)
+			}
+			sse, err := os.ReadFile(name + ".sse")
+			if err != nil {
+				t.Fatalf("read SSE: %v", err)
+			}
+			fixture.Request.Endpoint = zen.EndpointType(filepath.Base(name))
+			AssertParity(t, zen.Config{}, ParityCase{Request: fixture.Request, Body: fixture.Body, SSE: sse})
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package zentest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+// Reply is a scripted answer of a Server: SSE for streaming requests and
+// Body for the others. A Status other than 0 or 200 is sent with Body
+// whether the request streams or not.
+type Reply struct {
+	Status int
+	Body   []byte
+	SSE    []byte
+}
+
+// Request is a request received by a Server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Server is a mock backend answering the requests it receives with its
+// replies in order, for tests of code built on a zen.Client.
+type Server struct {
+	URL string
+
+	t        testing.TB
+	mu       sync.Mutex
+	replies  []Reply
+	requests []Request
+}
+
+// NewServer starts a Server answering with replies, closed when t ends. A
+// request beyond the last reply fails t and gets a 500.
+func NewServer(t testing.TB, replies ...Reply) *Server {
+	t.Helper()
+	s := &Server{t: t, replies: replies}
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(server.Close)
+	s.URL = server.URL
+	return s
+}
+
+// NewClient returns a client for s built from cfg, with BaseURL set to s
+// and APIKey defaulting to "test".
+func (s *Server) NewClient(cfg zen.Config) (*zen.Client, error) {
+	cfg.BaseURL = s.URL
+	if cfg.APIKey == "" {
+		cfg.APIKey = "test"
+	}
+	return zen.NewClient(cfg)
+}
+
+// Requests returns the requests received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	n := len(s.requests)
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header.Clone(), Body: body})
+	s.mu.Unlock()
+
+	if n >= len(s.replies) {
+		s.t.Errorf("zentest: unexpected request %d to %s: only %d replies scripted", n+1, r.URL.Path, len(s.replies))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	reply := s.replies[n]
+	switch {
+	case reply.Status != 0 && reply.Status != http.StatusOK:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(reply.Status)
+		_, _ = w.Write(reply.Body)
+	case r.Header.Get("Accept") == "text/event-stream":
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write(reply.SSE)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(reply.Body)
+	}
+}
@@ -0,0 +1,50 @@
+package zentest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	zen "github.com/sacenox/go-opencode-ai-zen-sdk"
+)
+
+func TestServerReplies(t *testing.T) {
+	server := NewServer(t,
+		Reply{SSE: []byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")},
+		Reply{Body: []byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}]}`)},
+		Reply{Status: 429, Body: []byte(`{"error":{"message":"slow down"}}`)},
+	)
+	client, err := server.NewClient(zen.Config{})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	ctx := context.Background()
+	req := zen.NormalizedRequest{Model: "gpt-4o-mini", Endpoint: zen.EndpointChatCompletions, Messages: []zen.NormalizedMessage{{Role: "user", Content: "hi"}}}
+
+	streamed, err := client.CollectStream(ctx, req)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if streamed.Text != "Hi" {
+		t.Fatalf("expected streamed text Hi, got %q", streamed.Text)
+	}
+	created, err := client.CreateNormalized(ctx, req)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Text != "Hello" {
+		t.Fatalf("expected text Hello, got %q", created.Text)
+	}
+	var apiErr *zen.APIError
+	if _, err := client.CreateNormalized(ctx, req); !errors.As(err, &apiErr) || apiErr.StatusCode != 429 {
+		t.Fatalf("expected a 429 APIError, got %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+	if requests[0].Method != "POST" || requests[0].Path != "/chat/completions" || len(requests[0].Body) == 0 {
+		t.Fatalf("unexpected first request: %+v", requests[0])
+	}
+}